@@ -3,18 +3,34 @@ package main
 import (
 	"context"
 	"log"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.uber.org/zap"
 
+	"company-service/internal/audit"
+	"company-service/internal/autocomplete"
+	"company-service/internal/changestream"
+	"company-service/internal/command"
 	"company-service/internal/config"
+	"company-service/internal/domain"
+	"company-service/internal/encryption"
 	"company-service/internal/handler"
+	"company-service/internal/lease"
+	"company-service/internal/messaging"
+	"company-service/internal/messaging/broker"
+	"company-service/internal/messaging/dispatch"
+	"company-service/internal/messaging/fanout"
 	"company-service/internal/messaging/rabbitmq"
+	"company-service/internal/messaging/spool"
+	"company-service/internal/replay"
+	"company-service/internal/repository/cache"
 	"company-service/internal/repository/mongorepo"
 	"company-service/internal/server"
 	"company-service/internal/service"
+	"company-service/internal/webhook"
 )
 
 func main() {
@@ -50,38 +66,386 @@ func main() {
 
 	db := mongoClient.Database(cfg.MongoDB)
 
+	// Criptografia em repouso dos campos sensíveis, habilitada pelo arquivo de chaves
+	var keyring *encryption.Keyring
+	var repoOpts []mongorepo.Option
+	if cfg.EncryptionKeyFile != "" {
+		keyring, err = encryption.LoadKeyring(cfg.EncryptionKeyFile)
+		if err != nil {
+			logger.Fatal("Failed to load encryption keys", zap.Error(err))
+		}
+		repoOpts = append(repoOpts, mongorepo.WithEncryption(keyring))
+
+		logger.Info("Field-level encryption enabled",
+			zap.String("active_key", keyring.ActiveKeyID()),
+			zap.Strings("fields", mongorepo.EncryptedFields))
+	}
+
 	// Inicializar repositório
 	repo := mongorepo.NewCompanyRepositoryWithTimeout(
 		db,
 		cfg.MongoCollection,
 		10*time.Second, // timeout de 10 segundos
+		repoOpts...,
 	)
 
+	// Garantir índices usados por filtros e ordenação
+	if err := mongorepo.EnsureIndexes(context.Background(), db, cfg.MongoCollection); err != nil {
+		logger.Warn("Failed to ensure MongoDB indexes", zap.Error(err))
+	}
+
 	logger.Info("MongoDB repository initialized",
 		zap.String("database", cfg.MongoDB),
 		zap.String("collection", cfg.MongoCollection))
 
-	// Inicializar produtor RabbitMQ
-	messageProducer, err := rabbitmq.NewProducer(cfg.RabbitMQURI, cfg.QueueName)
+	// Inicializar produtor de eventos no broker selecionado por MESSAGING_DRIVER
+	brokerProducer, err := broker.NewProducer(cfg, logger)
+	if err != nil {
+		logger.Fatal("Failed to create message producer", zap.Error(err))
+	}
+	defer brokerProducer.Close()
+
+	// Estatísticas de publicação (confirmações e latência) e estado do broker expostos na
+	// rota administrativa
+	serverOpts := []server.Option{
+		server.WithMessagingHandler(handler.NewMessagingHandler(brokerProducer, logger)),
+	}
+
+	// JSON Schemas dos eventos e dos payloads da API, em /schemas
+	serverOpts = append(serverOpts, server.WithSchemaHandler(handler.NewSchemaHandler(logger)))
+
+	// Webhooks: os eventos são entregues por HTTP às assinaturas do tenant. Sem o fan-out, a
+	// entrega acontece após a confirmação do broker
+	messageProducer := brokerProducer
+	var dispatcher *webhook.Dispatcher
+	if cfg.WebhooksEnabled {
+		var storeOpts []webhook.StoreOption
+		if keyring != nil {
+			storeOpts = append(storeOpts, webhook.WithKeyring(keyring))
+		}
+		webhookStore := webhook.NewMongoStore(db, cfg.WebhookCollection, cfg.WebhookDeliveryCollection, 10*time.Second, storeOpts...)
+		if err := webhook.EnsureIndexes(context.Background(), db, cfg.WebhookCollection, cfg.WebhookDeliveryCollection,
+			config.ParseDuration(cfg.WebhookDeliveryRetention, 0)); err != nil {
+			logger.Warn("Failed to ensure webhook indexes", zap.Error(err))
+		}
+
+		dispatcher = webhook.NewDispatcher(webhookStore, logger, webhook.Config{
+			Workers:          cfg.WebhookWorkers,
+			QueueSize:        cfg.WebhookQueueSize,
+			MaxAttempts:      cfg.WebhookMaxAttempts,
+			InitialBackoff:   config.ParseDuration(cfg.WebhookInitialBackoff, 10*time.Second),
+			MaxBackoff:       config.ParseDuration(cfg.WebhookMaxBackoff, 10*time.Minute),
+			FailureThreshold: cfg.WebhookFailureThreshold,
+			Timeout:          config.ParseDuration(cfg.WebhookTimeout, 10*time.Second),
+		})
+
+		dispatcherCtx, stopDispatcher := context.WithCancel(context.Background())
+		defer stopDispatcher()
+		go dispatcher.Run(dispatcherCtx)
+
+		serverOpts = append(serverOpts, server.WithWebhookHandler(handler.NewWebhookHandler(webhookStore, logger)))
+
+		logger.Info("Webhook deliveries enabled",
+			zap.Int("workers", cfg.WebhookWorkers),
+			zap.Int("max_attempts", cfg.WebhookMaxAttempts),
+			zap.Int("failure_threshold", cfg.WebhookFailureThreshold))
+	}
+
+	// Spool local: eventos que esgotam as tentativas de envio ao broker são gravados em disco e
+	// reenviados quando o broker volta a ficar disponível
+	var eventSpool *spool.Spool
+	if cfg.SpoolDir != "" {
+		var spoolOpts []spool.Option
+		if keyring != nil {
+			spoolOpts = append(spoolOpts, spool.WithKeyring(keyring))
+		}
+		eventSpool, err = spool.Open(cfg.SpoolDir, cfg.SpoolSegmentSize, spoolOpts...)
+		if err != nil {
+			logger.Fatal("Failed to open event spool", zap.Error(err))
+		}
+		defer eventSpool.Close()
+
+		serverOpts = append(serverOpts, server.WithSpoolHandler(handler.NewSpoolHandler(eventSpool, logger)))
+
+		logger.Info("Event spool enabled",
+			zap.String("dir", cfg.SpoolDir),
+			zap.Bool("encrypted", keyring != nil),
+			zap.Int("pending", eventSpool.Len()))
+	}
+
+	// Fan-out: com EVENT_SINKS, cada evento é enviado em paralelo aos destinos configurados, cada
+	// um com sua fila e política de novas tentativas. O spool passa a ser do destino broker, e a
+	// reentrega republica apenas no broker.
+	var dispatchSpool *spool.Spool
+	var fanoutProducer *fanout.Producer
+	redeliveryProducer := brokerProducer
+	if cfg.EventSinks != "" {
+		var sinks []fanout.Sink
+		for _, name := range strings.Split(cfg.EventSinks, ",") {
+			switch name = strings.TrimSpace(name); name {
+			case "broker":
+				sinks = append(sinks, fanout.Sink{Name: name, Producer: brokerProducer, Spool: eventSpool, Policy: fanout.Policy{
+					QueueSize:      cfg.SinkBrokerQueueSize,
+					MaxAttempts:    cfg.SinkBrokerMaxAttempts,
+					InitialBackoff: config.ParseDuration(cfg.SinkBrokerInitialBackoff, 1*time.Second),
+					MaxBackoff:     config.ParseDuration(cfg.SinkBrokerMaxBackoff, 30*time.Second),
+				}})
+			case "webhooks":
+				if dispatcher == nil {
+					logger.Fatal("EVENT_SINKS includes webhooks but WEBHOOKS_ENABLED is false")
+				}
+				sinks = append(sinks, fanout.Sink{Name: name, Producer: webhook.NewSink(dispatcher), Policy: fanout.Policy{
+					QueueSize:      cfg.SinkWebhooksQueueSize,
+					MaxAttempts:    cfg.SinkWebhooksMaxAttempts,
+					InitialBackoff: config.ParseDuration(cfg.SinkWebhooksInitialBackoff, 1*time.Second),
+					MaxBackoff:     config.ParseDuration(cfg.SinkWebhooksMaxBackoff, 10*time.Second),
+				}})
+			case "audit":
+				if err := audit.EnsureIndexes(context.Background(), db, cfg.AuditCollection,
+					config.ParseDuration(cfg.AuditRetention, 0)); err != nil {
+					logger.Warn("Failed to ensure audit indexes", zap.Error(err))
+				}
+				sinks = append(sinks, fanout.Sink{Name: name, Producer: audit.NewMongoSink(db, cfg.AuditCollection, 10*time.Second), Policy: fanout.Policy{
+					QueueSize:      cfg.SinkAuditQueueSize,
+					MaxAttempts:    cfg.SinkAuditMaxAttempts,
+					InitialBackoff: config.ParseDuration(cfg.SinkAuditInitialBackoff, 1*time.Second),
+					MaxBackoff:     config.ParseDuration(cfg.SinkAuditMaxBackoff, 30*time.Second),
+				}})
+			case "":
+			default:
+				logger.Fatal("Invalid EVENT_SINKS, expected broker, webhooks or audit", zap.String("sink", name))
+			}
+		}
+
+		// Encerrado ao final de main, dentro do prazo do encerramento
+		fanoutProducer = fanout.NewProducer(logger, config.ParseDuration(cfg.ShutdownTimeout, 10*time.Second), sinks...)
+
+		messageProducer = fanoutProducer
+		serverOpts = append(serverOpts, server.WithSinksHandler(handler.NewMessagingHandler(fanoutProducer, logger)))
+
+		logger.Info("Event fan-out enabled", zap.String("sinks", cfg.EventSinks))
+	} else {
+		if dispatcher != nil {
+			messageProducer = webhook.NewProducer(brokerProducer, dispatcher)
+		}
+		dispatchSpool = eventSpool
+		redeliveryProducer = messageProducer
+	}
+
+	if eventSpool != nil {
+		redeliveryCtx, stopRedelivery := context.WithCancel(context.Background())
+		defer stopRedelivery()
+
+		redeliverer := spool.NewRedeliverer(eventSpool, redeliveryProducer, logger,
+			config.ParseDuration(cfg.SpoolRedeliveryInterval, 10*time.Second))
+		go redeliverer.Run(redeliveryCtx)
+	}
+
+	// Com EVENT_SOURCE=changestream os eventos são gerados a partir do change stream do MongoDB,
+	// cobrindo também escritas feitas diretamente no banco; o service deixa de publicá-los
+	serviceProducer := messageProducer
+	if cfg.EventSource == "changestream" {
+		serviceProducer = messaging.NewNopProducer()
+		dispatchSpool = nil
+
+		watcherCtx, stopWatcher := context.WithCancel(context.Background())
+		defer stopWatcher()
+
+		watcher := changestream.NewWatcher(db, cfg.MongoCollection, cfg.ChangeStreamTokenCol, messageProducer, logger,
+			changestream.WithDecoder(mongorepo.NewDocumentDecoder(keyring)))
+		if err := watcher.EnsurePreImages(context.Background()); err != nil {
+			logger.Fatal("EVENT_SOURCE=changestream requires pre-images on the companies collection", zap.Error(err))
+		}
+
+		// Apenas a instância que detém a lease publica; as demais assumem se ela parar de renová-la
+		leader := lease.NewLeader(lease.New(db, lease.Collection, "changestream:"+cfg.MongoCollection,
+			config.ParseDuration(cfg.ChangeStreamLeaseTTL, 30*time.Second)), logger)
+		go leader.Run(watcherCtx, watcher.Run)
+
+		logger.Info("Change stream event source enabled",
+			zap.String("token_collection", cfg.ChangeStreamTokenCol))
+	}
+
+	// Cache de leitura opcional para GetByID/GetByCNPJ
+	var cachedRepo *cache.Repository
+	if cfg.CacheEnabled {
+		cachedRepo = cache.NewRepository(repo, cache.Config{
+			Size:        cfg.CacheSize,
+			TTL:         config.ParseDuration(cfg.CacheTTL, 30*time.Second),
+			NegativeTTL: config.ParseDuration(cfg.CacheNegativeTTL, 5*time.Second),
+		})
+		repo = cachedRepo
+		serverOpts = append(serverOpts, server.WithCacheHandler(handler.NewCacheHandler(cachedRepo, logger)))
+
+		logger.Info("Repository cache enabled",
+			zap.Int("size", cfg.CacheSize),
+			zap.String("ttl", cfg.CacheTTL))
+	}
+
+	// Change feed: cada instância acompanha as escritas de todas as réplicas para manter o
+	// estado local atualizado. O stream é aberto antes da carga inicial, para não perder as
+	// alterações feitas durante ela
+	var follower *changestream.Follower
+	if cfg.ChangeFeedEnabled {
+		follower = changestream.NewFollower(db, cfg.MongoCollection, logger, mongorepo.NewDocumentDecoder(keyring))
+		if err := follower.Open(context.Background()); err != nil {
+			logger.Fatal("Failed to open change feed", zap.Error(err))
+		}
+	}
+
+	// Construir índice de autocomplete a partir das empresas persistidas
+	autocompleteIndex := autocomplete.NewIndex()
+	if err := autocompleteIndex.Rebuild(context.Background(), repo); err != nil {
+		logger.Fatal("Failed to build autocomplete index", zap.Error(err))
+	}
+
+	logger.Info("Autocomplete index built",
+		zap.Int("companies", autocompleteIndex.Len()))
+
+	if follower != nil {
+		follower.Subscribe(changestream.Listener{
+			Changed: autocompleteIndex.Upsert,
+			Removed: autocompleteIndex.Remove,
+			Resync: func(ctx context.Context) error {
+				return autocompleteIndex.Rebuild(ctx, repo)
+			},
+		})
+
+		// Escritas de outras réplicas invalidam o cache local; após uma falha do stream, o cache
+		// é esvaziado, pois as alterações do intervalo não são conhecidas
+		if cachedRepo != nil {
+			follower.Subscribe(changestream.Listener{
+				Changed: func(company *domain.Company) {
+					cachedRepo.Invalidate(company.TenantID, company.ID, company.CNPJ)
+				},
+				Removed: cachedRepo.InvalidateID,
+				Resync: func(ctx context.Context) error {
+					cachedRepo.Purge()
+					return nil
+				},
+			})
+		}
+
+		followerCtx, stopFollower := context.WithCancel(context.Background())
+		defer stopFollower()
+		go follower.Run(followerCtx)
+
+		logger.Info("Change feed enabled")
+	}
+
+	// Replay de eventos: republica pelo mesmo produtor (broker e webhooks) a partir do estado
+	// armazenado das empresas
+	replayer := replay.NewReplayer(repo, messageProducer, logger)
+	serverOpts = append(serverOpts, server.WithReplayHandler(handler.NewReplayHandler(replayer, cfg.ReplayRateLimit, logger)))
+
+	// Dispatcher dos eventos das escritas: fila limitada, workers e novas tentativas com espera
+	// exponencial; com a fila cheia segue EVENT_DISPATCH_OVERFLOW
+	eventDispatcher, err := dispatch.New(serviceProducer, logger, dispatch.Config{
+		QueueSize:      cfg.EventDispatchQueueSize,
+		Workers:        cfg.EventDispatchWorkers,
+		MaxAttempts:    cfg.EventDispatchMaxAttempts,
+		InitialBackoff: config.ParseDuration(cfg.EventDispatchInitialBackoff, 1*time.Second),
+		MaxBackoff:     config.ParseDuration(cfg.EventDispatchMaxBackoff, 30*time.Second),
+		Overflow:       dispatch.OverflowPolicy(cfg.EventDispatchOverflow),
+		Spool:          dispatchSpool,
+	})
 	if err != nil {
-		logger.Fatal("Failed to create RabbitMQ producer", zap.Error(err))
+		logger.Fatal("Failed to create event dispatcher", zap.Error(err))
 	}
-	defer messageProducer.Close()
+	serverOpts = append(serverOpts, server.WithDispatchHandler(handler.NewDispatchHandler(eventDispatcher, logger)))
 
-	logger.Info("RabbitMQ producer initialized",
-		zap.String("queue", cfg.QueueName))
+	logger.Info("Event dispatcher initialized",
+		zap.Int("workers", cfg.EventDispatchWorkers),
+		zap.Int("queue_size", cfg.EventDispatchQueueSize),
+		zap.String("overflow", string(eventDispatcher.Stats().Overflow)))
 
 	// Inicializar service
-	companyService := service.NewCompanyService(repo, messageProducer, logger)
+	companyService := service.NewCompanyService(repo, serviceProducer, logger,
+		service.WithAutocompleteIndex(autocompleteIndex),
+		service.WithDispatcher(eventDispatcher))
+
+	// Comandos: sistemas upstream criam, atualizam e removem empresas publicando na fila de
+	// comandos; cada mensagem é confirmada apenas após ser processada pelo service
+	var commandConsumer *rabbitmq.Consumer
+	consumerCtx, stopConsumer := context.WithCancel(context.Background())
+	defer stopConsumer()
+	consumerDone := make(chan struct{})
+	if cfg.CommandsEnabled {
+		if err := command.CheckTenantSource(cfg.CommandTenantFrom, cfg.CommandTenant); err != nil {
+			logger.Fatal("Invalid command configuration", zap.Error(err))
+		}
+		if err := command.EnsureIndexes(context.Background(), db, cfg.CommandCollection,
+			config.ParseDuration(cfg.CommandRetention, 7*24*time.Hour)); err != nil {
+			logger.Fatal("Failed to create command indexes", zap.Error(err))
+		}
+		processor := command.NewProcessor(companyService, cfg.DefaultTenant, logger,
+			command.WithTenantSource(cfg.CommandTenantFrom, cfg.CommandTenant),
+			command.WithStore(command.NewMongoStore(db, cfg.CommandCollection, 10*time.Second)))
+		commandConsumer, err = rabbitmq.NewConsumer(rabbitmq.ConsumerConfig{
+			URI:               cfg.RabbitMQURI,
+			Queue:             cfg.CommandQueue,
+			ErrorQueue:        cfg.CommandErrorQueue,
+			Workers:           cfg.CommandWorkers,
+			RetryDelay:        config.ParseDuration(cfg.CommandRetryDelay, 5*time.Second),
+			MaxRetries:        cfg.CommandMaxRetries,
+			ConfirmTimeout:    config.ParseDuration(cfg.RabbitMQConfirmTimeout, 5*time.Second),
+			ReconnectDelay:    config.ParseDuration(cfg.RabbitMQReconnectDelay, 1*time.Second),
+			MaxReconnectDelay: config.ParseDuration(cfg.RabbitMQReconnectMaxDelay, 30*time.Second),
+		}, processor.HandleMessage)
+		if err != nil {
+			logger.Fatal("Failed to create command consumer", zap.Error(err))
+		}
+		defer commandConsumer.Close()
+
+		go func() {
+			defer close(consumerDone)
+			commandConsumer.Run(consumerCtx)
+		}()
+
+		logger.Info("Command consumer enabled",
+			zap.String("queue", cfg.CommandQueue),
+			zap.String("error_queue", cfg.CommandErrorQueue),
+			zap.Int("workers", cfg.CommandWorkers),
+			zap.String("tenant_source", cfg.CommandTenantFrom))
+	}
 
 	// Inicializar handlers
 	companyHandler := handler.NewCompanyHandler(companyService, logger)
 
 	// Inicializar e iniciar servidor
-	srv := server.NewServer(companyHandler, logger, cfg)
+	srv := server.NewServer(companyHandler, logger, cfg, serverOpts...)
 
 	logger.Info("Starting HTTP server", zap.String("address", ":"+cfg.ServerPort))
 	if err := srv.Start(); err != nil {
 		logger.Fatal("Failed to start server", zap.Error(err))
 	}
+
+	// Encerramento: todas as etapas dividem o prazo de SHUTDOWN_TIMEOUT contado a partir do sinal,
+	// já consumido em parte pelas requisições HTTP em andamento
+	shutdownCtx, cancelShutdown := context.WithDeadline(context.Background(), srv.ShutdownDeadline())
+	defer cancelShutdown()
+
+	// O consumidor para de receber comandos e conclui os que estão em andamento; os não
+	// concluídos no prazo voltam para a fila quando a conexão é fechada
+	if commandConsumer != nil {
+		stopConsumer()
+		select {
+		case <-consumerDone:
+		case <-shutdownCtx.Done():
+			logger.Warn("Command consumer did not stop within the shutdown timeout")
+		}
+	}
+
+	// Os eventos na fila do dispatcher são enviados até o prazo; os restantes vão para o spool ou
+	// são descartados
+	if err := eventDispatcher.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("Event dispatcher did not drain within the shutdown timeout", zap.Error(err))
+	}
+
+	// O fan-out recebe os últimos eventos do dispatcher e os entrega aos destinos até o prazo
+	if fanoutProducer != nil {
+		if err := fanoutProducer.Shutdown(shutdownCtx); err != nil {
+			logger.Warn("Event fan-out did not drain within the shutdown timeout", zap.Error(err))
+		}
+	}
 }
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+
+	"company-service/internal/config"
+	"company-service/internal/encryption"
+	"company-service/internal/migrations"
+)
+
+func main() {
+	var (
+		dryRun = flag.Bool("dry-run", false, "apenas reporta quantos documentos cada migração alteraria")
+		down   = flag.Bool("down", false, "reverte migrações em vez de aplicar")
+		steps  = flag.Int("steps", 1, "quantidade de migrações a reverter (com -down)")
+		target = flag.Int("target", 0, "aplica migrações até esta versão (0 = todas)")
+		status = flag.Bool("status", false, "lista as migrações e se já foram aplicadas")
+	)
+	flag.Parse()
+
+	// Carregar configuração
+	cfg, err := config.LoadConfig(".")
+	if err != nil {
+		log.Fatal("Failed to load config:", err)
+	}
+
+	// Inicializar logger
+	logger, err := zap.NewProduction()
+	if err != nil {
+		log.Fatal("Failed to create logger:", err)
+	}
+	defer logger.Sync()
+
+	ctx := context.Background()
+
+	// Conectar ao MongoDB
+	mongoClient, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.MongoURI))
+	if err != nil {
+		logger.Fatal("Failed to connect to MongoDB", zap.Error(err))
+	}
+	defer mongoClient.Disconnect(ctx)
+
+	if err := mongoClient.Ping(ctx, nil); err != nil {
+		logger.Fatal("Failed to ping MongoDB", zap.Error(err))
+	}
+
+	// Com criptografia, as migrações leem e gravam os documentos com o keyring do repositório
+	var keyring *encryption.Keyring
+	if cfg.EncryptionKeyFile != "" {
+		keyring, err = encryption.LoadKeyring(cfg.EncryptionKeyFile)
+		if err != nil {
+			logger.Fatal("Failed to load encryption keys", zap.Error(err))
+		}
+	}
+
+	all := migrations.All(cfg.MongoCollection, cfg.DefaultTenant, keyring)
+	migrator, err := migrations.NewMigrator(mongoClient.Database(cfg.MongoDB), all, logger)
+	if err != nil {
+		logger.Fatal("Invalid migrations", zap.Error(err))
+	}
+
+	if *status {
+		printStatus(ctx, migrator, all)
+		return
+	}
+
+	var results []migrations.Result
+	if *down {
+		results, err = migrator.Down(ctx, *steps, *dryRun)
+	} else {
+		results, err = migrator.Up(ctx, *target, *dryRun)
+	}
+
+	for _, r := range results {
+		verb := "applied"
+		if r.DryRun {
+			verb = "would touch"
+		}
+		fmt.Printf("%04d_%s [%s] %s %d document(s)\n", r.Version, r.Name, r.Direction, verb, r.Affected)
+	}
+	if len(results) == 0 && err == nil {
+		fmt.Println("no migrations to run")
+	}
+	if err != nil {
+		logger.Error("Migration failed", zap.Error(err))
+		os.Exit(1)
+	}
+}
+
+func printStatus(ctx context.Context, migrator *migrations.Migrator, all []migrations.Migration) {
+	applied, err := migrator.Applied(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	appliedAt := make(map[int]string, len(applied))
+	for _, r := range applied {
+		appliedAt[r.Version] = r.AppliedAt.Format(time.RFC3339)
+	}
+
+	for _, m := range all {
+		state := "pending"
+		if at, ok := appliedAt[m.Version]; ok {
+			state = "applied at " + at
+		}
+		fmt.Printf("%04d_%s: %s\n", m.Version, m.Name, state)
+	}
+}
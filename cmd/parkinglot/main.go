@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"company-service/internal/config"
+	"company-service/internal/messaging/rabbitmq"
+)
+
+func main() {
+	var (
+		list    = flag.Bool("list", false, "lista as mensagens do parking lot")
+		limit   = flag.Int("limit", 50, "quantidade máxima de mensagens listadas (0 = todas)")
+		inspect = flag.String("inspect", "", "exibe a mensagem com o message_id informado, incluindo o corpo")
+		requeue = flag.String("requeue", "", "reenfileira na fila de origem os message_ids informados (separados por vírgula) ou \"all\"")
+		discard = flag.String("discard", "", "descarta os message_ids informados (separados por vírgula) ou \"all\"")
+	)
+	flag.Parse()
+
+	// Carregar configuração
+	cfg, err := config.LoadConfig(".")
+	if err != nil {
+		log.Fatal("Failed to load config:", err)
+	}
+
+	// Inicializar logger
+	logger, err := zap.NewProduction()
+	if err != nil {
+		log.Fatal("Failed to create logger:", err)
+	}
+	defer logger.Sync()
+
+	if cfg.RabbitMQParkingQueue == "" {
+		logger.Fatal("RABBITMQ_PARKING_QUEUE is required")
+	}
+
+	lot, err := rabbitmq.OpenParkingLot(cfg.RabbitMQURI, cfg.RabbitMQParkingQueue)
+	if err != nil {
+		logger.Fatal("Failed to open parking lot", zap.Error(err))
+	}
+	defer lot.Close()
+
+	switch {
+	case *inspect != "":
+		message, err := lot.Inspect(*inspect)
+		if err != nil {
+			logger.Fatal("Inspect failed", zap.Error(err))
+		}
+		printJSON(message)
+
+	case *requeue != "":
+		requeued, err := lot.Requeue(context.Background(), messageIDs(*requeue)...)
+		fmt.Printf("%d message(s) requeued\n", requeued)
+		if err != nil {
+			logger.Error("Requeue failed", zap.Error(err))
+			os.Exit(1)
+		}
+
+	case *discard != "":
+		discarded, err := lot.Discard(messageIDs(*discard)...)
+		fmt.Printf("%d message(s) discarded\n", discarded)
+		if err != nil {
+			logger.Error("Discard failed", zap.Error(err))
+			os.Exit(1)
+		}
+
+	case *list:
+		messages, err := lot.List(*limit)
+		if err != nil {
+			logger.Fatal("List failed", zap.Error(err))
+		}
+		for _, m := range messages {
+			fmt.Printf("%s %s queue=%s reason=%s count=%d dead_at=%s\n",
+				m.MessageID, m.Type, m.Queue, m.Reason, m.Count, m.DeadAt.Format("2006-01-02T15:04:05Z07:00"))
+		}
+		if len(messages) == 0 {
+			fmt.Println("parking lot is empty")
+		}
+
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+}
+
+// messageIDs interpreta a lista de message_ids; "all" seleciona todas as mensagens
+func messageIDs(value string) []string {
+	if value == "all" {
+		return nil
+	}
+	var ids []string
+	for _, id := range strings.Split(value, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	// Uma lista vazia selecionaria todas as mensagens; exige "all" explícito
+	if len(ids) == 0 {
+		log.Fatalf("no message ids in %q (use \"all\" to select every message)", value)
+	}
+	return ids
+}
+
+func printJSON(v interface{}) {
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(v); err != nil {
+		log.Fatal(err)
+	}
+}
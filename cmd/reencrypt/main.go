@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+
+	"company-service/internal/config"
+	"company-service/internal/encryption"
+	"company-service/internal/repository/mongorepo"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "apenas reporta quantos documentos seriam cifrados novamente")
+	flag.Parse()
+
+	// Carregar configuração
+	cfg, err := config.LoadConfig(".")
+	if err != nil {
+		log.Fatal("Failed to load config:", err)
+	}
+
+	// Inicializar logger
+	logger, err := zap.NewProduction()
+	if err != nil {
+		log.Fatal("Failed to create logger:", err)
+	}
+	defer logger.Sync()
+
+	if cfg.EncryptionKeyFile == "" {
+		logger.Fatal("ENCRYPTION_KEY_FILE is required")
+	}
+	keyring, err := encryption.LoadKeyring(cfg.EncryptionKeyFile)
+	if err != nil {
+		logger.Fatal("Failed to load encryption keys", zap.Error(err))
+	}
+
+	ctx := context.Background()
+
+	// Conectar ao MongoDB
+	mongoClient, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.MongoURI))
+	if err != nil {
+		logger.Fatal("Failed to connect to MongoDB", zap.Error(err))
+	}
+	defer mongoClient.Disconnect(ctx)
+
+	if err := mongoClient.Ping(ctx, nil); err != nil {
+		logger.Fatal("Failed to ping MongoDB", zap.Error(err))
+	}
+
+	result, err := mongorepo.ReEncrypt(ctx, mongoClient.Database(cfg.MongoDB), cfg.MongoCollection, keyring, *dryRun)
+
+	fmt.Printf("active key %s: %d pending, %d re-encrypted, %d skipped\n",
+		keyring.ActiveKeyID(), result.Pending, result.Updated, result.Skipped)
+	if err != nil {
+		logger.Error("Re-encrypt failed", zap.Error(err))
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+
+	"company-service/internal/config"
+	"company-service/internal/encryption"
+	"company-service/internal/messaging"
+	"company-service/internal/messaging/broker"
+	"company-service/internal/replay"
+	"company-service/internal/repository/mongorepo"
+)
+
+func main() {
+	var (
+		ids          = flag.String("ids", "", "IDs das empresas, separados por vírgula")
+		cnpjs        = flag.String("cnpjs", "", "CNPJs das empresas, separados por vírgula")
+		updatedSince = flag.String("updated-since", "", "apenas empresas atualizadas a partir do instante (RFC 3339 ou AAAA-MM-DD)")
+		tenantID     = flag.String("tenant", "", "apenas empresas do tenant informado")
+		eventType    = flag.String("event-type", "updated", "tipo dos eventos gerados: created ou updated")
+		rateLimit    = flag.Float64("rate", -1, "eventos publicados por segundo; 0 não limita (padrão: REPLAY_RATE_LIMIT)")
+		dryRun       = flag.Bool("dry-run", false, "apenas conta as empresas selecionadas, sem publicar")
+	)
+	flag.Parse()
+
+	// Carregar configuração
+	cfg, err := config.LoadConfig(".")
+	if err != nil {
+		log.Fatal("Failed to load config:", err)
+	}
+
+	// Inicializar logger
+	logger, err := zap.NewProduction()
+	if err != nil {
+		log.Fatal("Failed to create logger:", err)
+	}
+	defer logger.Sync()
+
+	filter := replay.Filter{IDs: list(*ids), CNPJs: list(*cnpjs), TenantID: *tenantID}
+	if *updatedSince != "" {
+		since, err := parseTime(*updatedSince)
+		if err != nil {
+			logger.Fatal("Invalid -updated-since", zap.Error(err))
+		}
+		filter.UpdatedSince = &since
+	}
+
+	opts := replay.Options{RateLimit: cfg.ReplayRateLimit, DryRun: *dryRun}
+	if opts.EventType, err = replay.ParseEventType(*eventType); err != nil {
+		logger.Fatal("Invalid -event-type", zap.Error(err))
+	}
+	if *rateLimit >= 0 {
+		opts.RateLimit = *rateLimit
+	}
+
+	// Ctrl+C interrompe o replay; os eventos já publicados não são desfeitos
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Conectar ao MongoDB
+	mongoClient, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.MongoURI))
+	if err != nil {
+		logger.Fatal("Failed to connect to MongoDB", zap.Error(err))
+	}
+	defer mongoClient.Disconnect(context.Background())
+
+	if err := mongoClient.Ping(ctx, nil); err != nil {
+		logger.Fatal("Failed to ping MongoDB", zap.Error(err))
+	}
+
+	var repoOpts []mongorepo.Option
+	if cfg.EncryptionKeyFile != "" {
+		keyring, err := encryption.LoadKeyring(cfg.EncryptionKeyFile)
+		if err != nil {
+			logger.Fatal("Failed to load encryption keys", zap.Error(err))
+		}
+		repoOpts = append(repoOpts, mongorepo.WithEncryption(keyring))
+	}
+	repo := mongorepo.NewCompanyRepositoryWithTimeout(mongoClient.Database(cfg.MongoDB), cfg.MongoCollection, 30*time.Second, repoOpts...)
+
+	// A simulação não precisa do broker
+	var producer messaging.MessageProducer = messaging.NewNopProducer()
+	if !opts.DryRun {
+		if producer, err = broker.NewProducer(cfg, logger); err != nil {
+			logger.Fatal("Failed to create message producer", zap.Error(err))
+		}
+	}
+	defer producer.Close()
+
+	result, err := replay.NewReplayer(repo, producer, logger).Replay(ctx, filter, opts)
+
+	if opts.DryRun {
+		fmt.Printf("%d companies matched (dry run)\n", result.Matched)
+	} else {
+		fmt.Printf("%d companies matched, %d events published, %d failed\n", result.Matched, result.Published, result.Failed)
+		for _, id := range result.FailedIDs {
+			fmt.Printf("failed: %s\n", id)
+		}
+	}
+	if err != nil {
+		logger.Error("Replay failed", zap.Error(err))
+		os.Exit(1)
+	}
+	if result.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// list separa os valores informados por vírgula
+func list(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+// parseTime aceita um instante RFC 3339 ou uma data (meia-noite UTC)
+func parseTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
@@ -0,0 +1,117 @@
+package audit
+
+import (
+	"company-service/internal/domain"
+	"company-service/internal/messaging"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Record é um evento de empresa registrado no log de auditoria. Event é o CloudEvent como
+// publicado no broker, com os mesmos nomes de campos.
+type Record struct {
+	ID        string              `bson:"_id"`
+	Type      messaging.EventType `bson:"type"`
+	CompanyID string              `bson:"company_id"`
+	TenantID  string              `bson:"tenant_id"`
+	Time      time.Time           `bson:"time"`
+	Replay    bool                `bson:"replay,omitempty"`
+	Event     bson.M              `bson:"event"`
+}
+
+// NewRecord converte o evento no registro de auditoria
+func NewRecord(event messaging.CompanyEvent) (*Record, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode event: %w", err)
+	}
+	var doc bson.M
+	if err := bson.UnmarshalExtJSON(body, false, &doc); err != nil {
+		return nil, fmt.Errorf("failed to convert event: %w", err)
+	}
+
+	return &Record{
+		ID:        event.ID,
+		Type:      event.Type,
+		CompanyID: event.Subject,
+		TenantID:  event.TenantID,
+		Time:      event.Time,
+		Replay:    event.Replay,
+		Event:     doc,
+	}, nil
+}
+
+// mongoSink grava os eventos na coleção de auditoria
+type mongoSink struct {
+	collection *mongo.Collection
+	timeout    time.Duration
+}
+
+// NewMongoSink cria um produtor que registra os eventos na coleção de auditoria, para uso como
+// destino do fan-out
+func NewMongoSink(db *mongo.Database, collection string, timeout time.Duration) messaging.MessageProducer {
+	return &mongoSink{collection: db.Collection(collection), timeout: timeout}
+}
+
+// EnsureIndexes cria o índice das consultas por empresa e o índice TTL que remove os registros
+// mais antigos que retention (0 mantém indefinidamente). A criação é idempotente.
+func EnsureIndexes(ctx context.Context, db *mongo.Database, collection string, retention time.Duration) error {
+	models := []mongo.IndexModel{{
+		Keys:    bson.D{{Key: "tenant_id", Value: 1}, {Key: "company_id", Value: 1}, {Key: "time", Value: -1}},
+		Options: options.Index().SetName("idx_tenant_company_time"),
+	}}
+	if retention > 0 {
+		models = append(models, mongo.IndexModel{
+			Keys:    bson.D{{Key: "time", Value: 1}},
+			Options: options.Index().SetName("ttl_time").SetExpireAfterSeconds(int32(retention.Seconds())),
+		})
+	}
+	if _, err := db.Collection(collection).Indexes().CreateMany(ctx, models); err != nil {
+		return fmt.Errorf("failed to create audit indexes: %w", err)
+	}
+	return nil
+}
+
+func (s *mongoSink) SendCompanyCreated(ctx context.Context, company *domain.Company) error {
+	return s.insert(ctx, messaging.NewCompanyEvent(messaging.CompanyCreated, company))
+}
+
+func (s *mongoSink) SendCompanyUpdated(ctx context.Context, previous, company *domain.Company) error {
+	return s.insert(ctx, messaging.NewCompanyUpdatedEvent(previous, company))
+}
+
+func (s *mongoSink) SendCompanyDeleted(ctx context.Context, company *domain.Company) error {
+	return s.insert(ctx, messaging.NewCompanyEvent(messaging.CompanyDeleted, company))
+}
+
+func (s *mongoSink) insert(ctx context.Context, event messaging.CompanyEvent) error {
+	if messaging.IsReplay(ctx) {
+		event.MarkReplay()
+	}
+	record, err := NewRecord(event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	if _, err := s.collection.InsertOne(ctx, record); err != nil {
+		// O ID do evento se repete nas novas tentativas: o evento já foi registrado
+		if mongo.IsDuplicateKeyError(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to insert audit record: %w", err)
+	}
+	return nil
+}
+
+func (s *mongoSink) Close() error {
+	return nil
+}
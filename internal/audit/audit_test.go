@@ -0,0 +1,38 @@
+package audit
+
+import (
+	"company-service/internal/domain"
+	"company-service/internal/messaging"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestNewRecord_KeepsEventAsPublished(t *testing.T) {
+	previous := &domain.Company{ID: "c1", TenantID: "acme", CNPJ: "11444777000161", FantasyName: "Acme"}
+	company := *previous
+	company.FantasyName = "Acme Brasil"
+	event := messaging.NewCompanyUpdatedEvent(previous, &company)
+	event.Replay = true
+
+	record, err := NewRecord(event)
+	require.NoError(t, err)
+
+	assert.Equal(t, event.ID, record.ID)
+	assert.Equal(t, messaging.CompanyUpdated, record.Type)
+	assert.Equal(t, "c1", record.CompanyID)
+	assert.Equal(t, "acme", record.TenantID)
+	assert.WithinDuration(t, event.Time, record.Time, time.Millisecond)
+	assert.True(t, record.Replay)
+
+	// O evento mantém os nomes de campos do CloudEvent
+	assert.Equal(t, event.ID, record.Event["id"])
+	assert.Equal(t, "acme", record.Event["tenantid"])
+	data, ok := record.Event["data"].(bson.M)
+	require.True(t, ok)
+	assert.Equal(t, "Acme Brasil", data["fantasy_name"])
+	assert.Equal(t, bson.A{"fantasy_name"}, data["changed_fields"])
+}
@@ -0,0 +1,325 @@
+package autocomplete
+
+import (
+	"company-service/internal/domain"
+	"company-service/internal/repository"
+	"company-service/internal/tenant"
+	"company-service/pkg/utils"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// rebuildPageSize é o tamanho de página usado ao reconstruir o índice (limite máximo do repositório)
+const rebuildPageSize = 100
+
+// Suggestion representa uma empresa sugerida pelo autocomplete
+type Suggestion struct {
+	ID          string `json:"id"`
+	CNPJ        string `json:"cnpj"`
+	FantasyName string `json:"fantasy_name"`
+}
+
+// term associa uma chave normalizada a uma empresa
+type term struct {
+	key string
+	id  string
+}
+
+// Index é um índice de prefixos em memória para sugestões de empresas.
+// Mantém três listas ordenadas: nomes completos, palavras isoladas dos nomes e CNPJs.
+// Todas as chaves começam pelo tenant, de modo que cada busca só enxerga as empresas
+// do próprio tenant. É seguro para uso concorrente.
+type Index struct {
+	mu      sync.RWMutex
+	entries map[string]Suggestion
+	names   []term
+	words   []term
+	cnpjs   []term
+
+	// rebuilding conta as reconstruções em andamento; enquanto houver alguma, as alterações
+	// recebidas são registradas em changes para serem reaplicadas sobre o índice novo
+	rebuilding int
+	changes    []change
+}
+
+// change é uma alteração aplicada durante uma reconstrução: a empresa atualizada ou, na
+// remoção, apenas o ID
+type change struct {
+	company *domain.Company
+	id      string
+}
+
+// NewIndex cria um índice vazio
+func NewIndex() *Index {
+	return &Index{entries: make(map[string]Suggestion)}
+}
+
+// Rebuild recarrega o índice a partir do repositório, paginando pelas empresas de todos os
+// tenants. As alterações aplicadas por Upsert e Remove durante a leitura são reaplicadas sobre
+// o índice novo antes da troca, para que não sejam perdidas.
+func (idx *Index) Rebuild(ctx context.Context, repo repository.CompanyRepository) error {
+	idx.mu.Lock()
+	idx.rebuilding++
+	start := len(idx.changes)
+	idx.mu.Unlock()
+
+	all, err := loadAll(ctx, repo)
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+	defer idx.finishRebuild()
+	if err != nil {
+		return err
+	}
+
+	fresh := NewIndex()
+	fresh.load(all)
+	for _, c := range idx.changes[start:] {
+		fresh.apply(c)
+	}
+	idx.entries, idx.names, idx.words, idx.cnpjs = fresh.entries, fresh.names, fresh.words, fresh.cnpjs
+	return nil
+}
+
+// loadAll lê as empresas de todos os tenants
+func loadAll(ctx context.Context, repo repository.CompanyRepository) ([]*domain.Company, error) {
+	ctx = tenant.WithAllTenants(ctx)
+	var all []*domain.Company
+	filter := repository.CompanyFilter{Sort: []repository.SortField{{Field: "created_at"}}}
+
+	for page := 1; ; page++ {
+		companies, err := repo.List(ctx, filter, page, rebuildPageSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load companies for autocomplete: %w", err)
+		}
+		all = append(all, companies...)
+		if len(companies) < rebuildPageSize {
+			return all, nil
+		}
+	}
+}
+
+// finishRebuild encerra uma reconstrução, descartando o registro de alterações quando não há
+// outra em andamento; deve ser chamado com o lock de escrita
+func (idx *Index) finishRebuild() {
+	idx.rebuilding--
+	if idx.rebuilding == 0 {
+		idx.changes = nil
+	}
+}
+
+// Upsert adiciona ou atualiza uma empresa no índice
+func (idx *Index) Upsert(company *domain.Company) {
+	if company == nil || company.ID == "" {
+		return
+	}
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	snapshot := *company
+	idx.record(change{company: &snapshot, id: company.ID})
+}
+
+// Remove exclui uma empresa do índice
+func (idx *Index) Remove(id string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.record(change{id: id})
+}
+
+// record aplica a alteração e, durante uma reconstrução, a registra; deve ser chamado com o
+// lock de escrita
+func (idx *Index) record(c change) {
+	idx.apply(c)
+	if idx.rebuilding > 0 {
+		idx.changes = append(idx.changes, c)
+	}
+}
+
+// apply aplica uma alteração; deve ser chamado com o lock de escrita
+func (idx *Index) apply(c change) {
+	idx.remove(c.id)
+	if c.company != nil {
+		idx.insert(c.company)
+	}
+}
+
+// Len retorna a quantidade de empresas indexadas
+func (idx *Index) Len() int {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	return len(idx.entries)
+}
+
+// Suggest retorna até limit empresas cujo nome começa com o prefixo (ignorando acentos e
+// maiúsculas) ou cujo CNPJ começa com os dígitos informados. Correspondências no início do
+// nome têm prioridade sobre correspondências no início de outras palavras do nome.
+func (idx *Index) Suggest(tenantID, prefix string, limit int) []Suggestion {
+	results := make([]Suggestion, 0, limit)
+	if limit < 1 {
+		return results
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	seen := make(map[string]bool, limit)
+	collect := func(terms []term, key string) {
+		start := sort.Search(len(terms), func(i int) bool { return terms[i].key >= key })
+		for i := start; i < len(terms) && len(results) < limit; i++ {
+			if !strings.HasPrefix(terms[i].key, key) {
+				return
+			}
+			if seen[terms[i].id] {
+				continue
+			}
+			seen[terms[i].id] = true
+			results = append(results, idx.entries[terms[i].id])
+		}
+	}
+
+	if digits, ok := cnpjPrefix(prefix); ok {
+		collect(idx.cnpjs, tenantKey(tenantID, digits))
+		return results
+	}
+
+	key := utils.FoldText(prefix)
+	if key == "" {
+		return results
+	}
+	collect(idx.names, tenantKey(tenantID, key))
+	collect(idx.words, tenantKey(tenantID, key))
+	return results
+}
+
+// insert adiciona os termos de uma empresa; deve ser chamado com o lock de escrita
+func (idx *Index) insert(company *domain.Company) {
+	idx.entries[company.ID] = newSuggestion(company)
+
+	names, words, cnpjs := companyTerms(company)
+	for _, t := range names {
+		idx.names = insertTerm(idx.names, t)
+	}
+	for _, t := range words {
+		idx.words = insertTerm(idx.words, t)
+	}
+	for _, t := range cnpjs {
+		idx.cnpjs = insertTerm(idx.cnpjs, t)
+	}
+}
+
+// load carrega um índice vazio em lote, ordenando as listas uma única vez
+func (idx *Index) load(companies []*domain.Company) {
+	for _, company := range companies {
+		if company == nil || company.ID == "" {
+			continue
+		}
+		if _, ok := idx.entries[company.ID]; ok {
+			continue
+		}
+		idx.entries[company.ID] = newSuggestion(company)
+
+		names, words, cnpjs := companyTerms(company)
+		idx.names = append(idx.names, names...)
+		idx.words = append(idx.words, words...)
+		idx.cnpjs = append(idx.cnpjs, cnpjs...)
+	}
+
+	for _, terms := range [][]term{idx.names, idx.words, idx.cnpjs} {
+		sort.Slice(terms, func(i, j int) bool { return termLess(terms[i], terms[j]) })
+	}
+}
+
+func newSuggestion(company *domain.Company) Suggestion {
+	return Suggestion{
+		ID:          company.ID,
+		CNPJ:        utils.FormatCNPJ(company.CNPJ),
+		FantasyName: company.FantasyName,
+	}
+}
+
+// companyTerms gera as chaves normalizadas de uma empresa: nomes completos, sufixos a partir
+// de cada palavra (permitem encontrar "comercio" em "sao joao comercio") e o CNPJ
+func companyTerms(company *domain.Company) (names, words, cnpjs []term) {
+	seenNames := map[string]bool{}
+	seenWords := map[string]bool{}
+	for _, name := range []string{company.FantasyName, company.CorporateName} {
+		folded := utils.FoldText(name)
+		if folded == "" || seenNames[folded] {
+			continue
+		}
+		seenNames[folded] = true
+		names = append(names, term{key: tenantKey(company.TenantID, folded), id: company.ID})
+
+		tokens := strings.Fields(folded)
+		for i := 1; i < len(tokens); i++ {
+			key := strings.Join(tokens[i:], " ")
+			if !seenWords[key] {
+				seenWords[key] = true
+				words = append(words, term{key: tenantKey(company.TenantID, key), id: company.ID})
+			}
+		}
+	}
+	if cnpj := utils.CleanCNPJ(company.CNPJ); cnpj != "" {
+		cnpjs = append(cnpjs, term{key: tenantKey(company.TenantID, cnpj), id: company.ID})
+	}
+	return names, words, cnpjs
+}
+
+// remove exclui todos os termos de uma empresa; deve ser chamado com o lock de escrita
+func (idx *Index) remove(id string) {
+	if _, ok := idx.entries[id]; !ok {
+		return
+	}
+	delete(idx.entries, id)
+	idx.names = removeTerms(idx.names, id)
+	idx.words = removeTerms(idx.words, id)
+	idx.cnpjs = removeTerms(idx.cnpjs, id)
+}
+
+// insertTerm insere mantendo a lista ordenada por chave e, em caso de empate, por ID
+func insertTerm(terms []term, t term) []term {
+	i := sort.Search(len(terms), func(i int) bool { return !termLess(terms[i], t) })
+	terms = append(terms, term{})
+	copy(terms[i+1:], terms[i:])
+	terms[i] = t
+	return terms
+}
+
+// tenantKey prefixa a chave com o tenant; o separador \x00 impede que o prefixo de um
+// tenant case com o de outro (ex.: "a" e "ab")
+func tenantKey(tenantID, key string) string {
+	return tenantID + "\x00" + key
+}
+
+func termLess(a, b term) bool {
+	if a.key != b.key {
+		return a.key < b.key
+	}
+	return a.id < b.id
+}
+
+func removeTerms(terms []term, id string) []term {
+	kept := terms[:0]
+	for _, t := range terms {
+		if t.id != id {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+// cnpjPrefix identifica consultas compostas apenas por dígitos e pontuação de CNPJ
+func cnpjPrefix(prefix string) (string, bool) {
+	prefix = strings.TrimSpace(prefix)
+	if prefix == "" || strings.Trim(prefix, "0123456789./- ") != "" {
+		return "", false
+	}
+	digits := utils.CleanCNPJ(prefix)
+	return digits, digits != ""
+}
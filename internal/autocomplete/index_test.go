@@ -0,0 +1,151 @@
+package autocomplete
+
+import (
+	"company-service/internal/domain"
+	"company-service/internal/repository"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestIndex() *Index {
+	idx := NewIndex()
+	idx.Upsert(&domain.Company{TenantID: "acme", ID: "1", CNPJ: "11444777000161", FantasyName: "São João", CorporateName: "São João Comércio LTDA"})
+	idx.Upsert(&domain.Company{TenantID: "acme", ID: "2", CNPJ: "47960950000121", FantasyName: "Padaria Central", CorporateName: "Central Alimentos LTDA"})
+	idx.Upsert(&domain.Company{TenantID: "acme", ID: "3", CNPJ: "11222333000181", FantasyName: "Comercial Sul", CorporateName: "Sul Comércio de Peças LTDA"})
+	return idx
+}
+
+func TestIndex_Suggest_AccentInsensitiveNamePrefix(t *testing.T) {
+	idx := newTestIndex()
+
+	results := idx.Suggest("acme", "sao jo", 10)
+
+	assert.Len(t, results, 1)
+	assert.Equal(t, "1", results[0].ID)
+	assert.Equal(t, "11.444.777/0001-61", results[0].CNPJ)
+}
+
+func TestIndex_Suggest_NameStartRankedBeforeWordMatch(t *testing.T) {
+	idx := newTestIndex()
+
+	results := idx.Suggest("acme", "Comerc", 10)
+
+	assert.Len(t, results, 2)
+	assert.Equal(t, "3", results[0].ID, "Comercial Sul começa com o prefixo")
+	assert.Equal(t, "1", results[1].ID, "São João Comércio contém uma palavra com o prefixo")
+}
+
+func TestIndex_Suggest_PartialCNPJ(t *testing.T) {
+	idx := newTestIndex()
+
+	results := idx.Suggest("acme", "11.444", 10)
+
+	assert.Len(t, results, 1)
+	assert.Equal(t, "1", results[0].ID)
+}
+
+func TestIndex_Suggest_RespectsLimit(t *testing.T) {
+	idx := newTestIndex()
+
+	assert.Len(t, idx.Suggest("acme", "11", 1), 1)
+	assert.Empty(t, idx.Suggest("acme", "11", 0))
+}
+
+func TestIndex_Upsert_ReplacesPreviousTerms(t *testing.T) {
+	idx := newTestIndex()
+
+	idx.Upsert(&domain.Company{TenantID: "acme", ID: "2", CNPJ: "47960950000121", FantasyName: "Mercado Norte", CorporateName: "Norte Mercados LTDA"})
+
+	assert.Empty(t, idx.Suggest("acme", "padaria", 10))
+	assert.Len(t, idx.Suggest("acme", "mercado", 10), 1)
+	assert.Equal(t, 3, idx.Len())
+}
+
+func TestIndex_Remove_DropsCompany(t *testing.T) {
+	idx := newTestIndex()
+
+	idx.Remove("1")
+
+	assert.Empty(t, idx.Suggest("acme", "sao", 10))
+	assert.Empty(t, idx.Suggest("acme", "11444", 10))
+	assert.Equal(t, 2, idx.Len())
+}
+
+func BenchmarkIndex_Suggest(b *testing.B) {
+	companies := make([]*domain.Company, 0, 50000)
+	for i := 0; i < 50000; i++ {
+		companies = append(companies, &domain.Company{
+			TenantID:      "acme",
+			ID:            fmt.Sprintf("%024d", i),
+			CNPJ:          fmt.Sprintf("%014d", i),
+			FantasyName:   fmt.Sprintf("Empresa %d Comércio", i),
+			CorporateName: fmt.Sprintf("Empresa %d Comércio LTDA", i),
+		})
+	}
+	idx := NewIndex()
+	idx.load(companies)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		idx.Suggest("acme", "empresa 12", 10)
+	}
+}
+
+func TestIndex_Suggest_IsScopedByTenant(t *testing.T) {
+	idx := newTestIndex()
+	idx.Upsert(&domain.Company{TenantID: "other", ID: "4", CNPJ: "11444777000242", FantasyName: "São Jorge", CorporateName: "São Jorge LTDA"})
+
+	assert.Len(t, idx.Suggest("acme", "sao", 10), 1)
+	assert.Len(t, idx.Suggest("other", "sao", 10), 1)
+	assert.Empty(t, idx.Suggest("acme", "11444777000242", 10))
+}
+
+// slowRepository devolve as empresas informadas, avisando em listing quando a leitura começa e
+// aguardando release para concluí-la
+type slowRepository struct {
+	repository.CompanyRepository
+	companies []*domain.Company
+	listing   chan struct{}
+	release   chan struct{}
+}
+
+func (r *slowRepository) List(ctx context.Context, filter repository.CompanyFilter, page, limit int) ([]*domain.Company, error) {
+	close(r.listing)
+	<-r.release
+	return r.companies, nil
+}
+
+func TestIndex_Rebuild_KeepsChangesAppliedDuringLoad(t *testing.T) {
+	idx := newTestIndex()
+	repo := &slowRepository{
+		// Estado lido antes das alterações abaixo: ainda contém a empresa 2 e não a 4
+		companies: []*domain.Company{
+			{TenantID: "acme", ID: "1", CNPJ: "11444777000161", FantasyName: "São João"},
+			{TenantID: "acme", ID: "2", CNPJ: "47960950000121", FantasyName: "Padaria Central"},
+		},
+		listing: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- idx.Rebuild(context.Background(), repo) }()
+	<-repo.listing
+
+	idx.Remove("2")
+	idx.Upsert(&domain.Company{TenantID: "acme", ID: "4", CNPJ: "11222333000181", FantasyName: "Mercado Novo"})
+	idx.Upsert(&domain.Company{TenantID: "acme", ID: "1", CNPJ: "11444777000161", FantasyName: "São João Renomeada"})
+	close(repo.release)
+	require.NoError(t, <-done)
+
+	assert.Empty(t, idx.Suggest("acme", "padaria", 10), "a empresa removida durante a reconstrução não volta")
+	assert.Len(t, idx.Suggest("acme", "mercado", 10), 1, "a empresa criada durante a reconstrução é mantida")
+	suggestions := idx.Suggest("acme", "sao joao", 10)
+	require.Len(t, suggestions, 1)
+	assert.Equal(t, "São João Renomeada", suggestions[0].FantasyName)
+	assert.Equal(t, 2, idx.Len())
+	assert.Empty(t, idx.changes, "o registro é descartado ao fim da reconstrução")
+}
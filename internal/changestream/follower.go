@@ -0,0 +1,147 @@
+package changestream
+
+import (
+	"company-service/internal/domain"
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// Listener recebe as alterações da coleção de empresas observadas por um Follower. Os campos
+// são opcionais.
+type Listener struct {
+	// Changed recebe o documento atual de empresas inseridas ou alteradas
+	Changed func(company *domain.Company)
+	// Removed recebe o ID das empresas removidas
+	Removed func(id string)
+	// Resync é chamado quando o stream é reaberto após uma falha: as alterações feitas enquanto
+	// ele esteve fechado não são entregues e o estado local deve ser recarregado
+	Resync func(ctx context.Context) error
+}
+
+// Follower acompanha o change stream da coleção de empresas a partir do momento em que é aberto,
+// sem persistir resume token, e repassa as alterações aos Listeners. Mantém o estado local de
+// cada instância (índice de autocomplete, cache de leitura) atualizado com as escritas de todas
+// as réplicas, por isso roda em todas elas, independentemente de EVENT_SOURCE.
+type Follower struct {
+	collection *mongo.Collection
+	logger     *zap.Logger
+	retryDelay time.Duration
+	maxDelay   time.Duration
+	decode     func(bson.Raw) (*domain.Company, error)
+	listeners  []Listener
+	stream     *mongo.ChangeStream
+}
+
+// NewFollower cria um Follower para a coleção informada; decode converte os documentos em
+// empresas (nil usa a decodificação padrão, sem campos criptografados)
+func NewFollower(db *mongo.Database, collectionName string, logger *zap.Logger, decode func(bson.Raw) (*domain.Company, error)) *Follower {
+	if decode == nil {
+		decode = decodeCompany
+	}
+	return &Follower{
+		collection: db.Collection(collectionName),
+		logger:     logger,
+		retryDelay: 1 * time.Second,
+		maxDelay:   30 * time.Second,
+		decode:     decode,
+	}
+}
+
+// Subscribe registra um Listener; deve ser chamado antes de Run
+func (f *Follower) Subscribe(listener Listener) {
+	f.listeners = append(f.listeners, listener)
+}
+
+// Open abre o change stream. Deve ser chamado antes de carregar o estado inicial, para que as
+// alterações feitas durante a carga também sejam recebidas.
+func (f *Follower) Open(ctx context.Context) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"operationType": bson.M{"$in": bson.A{"insert", "update", "replace", "delete"}}}}},
+	}
+	stream, err := f.collection.Watch(ctx, pipeline, options.ChangeStream().SetFullDocument(options.UpdateLookup))
+	if err != nil {
+		return fmt.Errorf("failed to open change stream: %w", err)
+	}
+	f.stream = stream
+	return nil
+}
+
+// Run consome o stream aberto por Open até o contexto ser cancelado. Após uma falha, o stream é
+// reaberto e os Listeners são ressincronizados.
+func (f *Follower) Run(ctx context.Context) {
+	delay := f.retryDelay
+	for {
+		if f.stream != nil {
+			err := f.consume(ctx)
+			f.stream.Close(context.Background())
+			f.stream = nil
+			if ctx.Err() != nil {
+				return
+			}
+			f.logger.Error("Change feed interrupted, reopening",
+				zap.Duration("retry_in", delay),
+				zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > f.maxDelay {
+			delay = f.maxDelay
+		}
+
+		if err := f.Open(ctx); err != nil {
+			f.logger.Error("Failed to reopen change feed", zap.Error(err))
+			continue
+		}
+		delay = f.retryDelay
+		f.resync(ctx)
+	}
+}
+
+func (f *Follower) consume(ctx context.Context) error {
+	for f.stream.Next(ctx) {
+		event, err := decodeEvent(f.stream.Current, f.decode)
+		if err != nil {
+			return fmt.Errorf("failed to decode change event: %w", err)
+		}
+		f.notify(event)
+	}
+	return f.stream.Err()
+}
+
+// notify repassa o evento aos Listeners
+func (f *Follower) notify(event *changeEvent) {
+	for _, listener := range f.listeners {
+		switch event.OperationType {
+		case "insert", "update", "replace":
+			// Sem documento, a empresa foi removida antes do lookup; o delete chegará em seguida
+			if event.FullDocument != nil && listener.Changed != nil {
+				listener.Changed(event.company(event.FullDocument))
+			}
+		case "delete":
+			if listener.Removed != nil {
+				listener.Removed(event.DocumentKey.ID.Hex())
+			}
+		}
+	}
+}
+
+func (f *Follower) resync(ctx context.Context) {
+	for _, listener := range f.listeners {
+		if listener.Resync == nil {
+			continue
+		}
+		if err := listener.Resync(ctx); err != nil {
+			f.logger.Error("Failed to resync after change feed reopened", zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package changestream
+
+import (
+	"company-service/internal/domain"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+func TestFollower_Notify_MapsOperations(t *testing.T) {
+	var changed []string
+	var removed []string
+	f := &Follower{}
+	f.Subscribe(Listener{
+		Changed: func(company *domain.Company) { changed = append(changed, company.ID) },
+		Removed: func(id string) { removed = append(removed, id) },
+	})
+	f.Subscribe(Listener{}) // Listeners sem funções são ignorados
+	id := primitive.NewObjectID()
+
+	for _, op := range []string{"insert", "update", "replace", "delete", "drop"} {
+		f.notify(&changeEvent{OperationType: op, DocumentKey: documentKey{ID: id}, FullDocument: &domain.Company{}})
+	}
+	// Atualização de empresa já removida
+	f.notify(&changeEvent{OperationType: "update", DocumentKey: documentKey{ID: id}})
+
+	assert.Equal(t, []string{id.Hex(), id.Hex(), id.Hex()}, changed)
+	assert.Equal(t, []string{id.Hex()}, removed)
+}
+
+func TestFollower_Resync_CallsEveryListener(t *testing.T) {
+	calls := 0
+	f := &Follower{logger: zap.NewNop()}
+	f.Subscribe(Listener{Resync: func(ctx context.Context) error { calls++; return errors.New("falha") }})
+	f.Subscribe(Listener{Resync: func(ctx context.Context) error { calls++; return nil }})
+
+	f.resync(context.Background())
+
+	assert.Equal(t, 2, calls, "a falha de um Listener não impede os demais")
+}
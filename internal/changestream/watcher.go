@@ -0,0 +1,338 @@
+package changestream
+
+import (
+	"company-service/internal/domain"
+	"company-service/internal/messaging"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrHistoryLost indica que o stream foi reiniciado a partir do momento atual, com eventos perdidos
+	ErrHistoryLost = errors.New("change stream history lost")
+	// ErrPreImagesDisabled indica que a coleção não registra pre-images
+	ErrPreImagesDisabled = errors.New("change stream pre-images are disabled")
+)
+
+// changeEvent é o subconjunto de um evento de change stream usado para gerar mensagens
+type changeEvent struct {
+	OperationType            string          `bson:"operationType"`
+	DocumentKey              documentKey     `bson:"documentKey"`
+	FullDocument             *domain.Company `bson:"fullDocument"`
+	FullDocumentBeforeChange *domain.Company `bson:"fullDocumentBeforeChange"`
+}
+
+// rawChangeEvent é o evento como lido do stream; os documentos são convertidos pelo decoder
+// do Watcher, que decifra campos criptografados em repouso
+type rawChangeEvent struct {
+	OperationType            string        `bson:"operationType"`
+	DocumentKey              documentKey   `bson:"documentKey"`
+	FullDocument             bson.RawValue `bson:"fullDocument"`
+	FullDocumentBeforeChange bson.RawValue `bson:"fullDocumentBeforeChange"`
+}
+
+type documentKey struct {
+	ID primitive.ObjectID `bson:"_id"`
+}
+
+// tokenDocument persiste o último resume token processado de uma coleção
+type tokenDocument struct {
+	Collection string    `bson:"_id"`
+	Token      bson.Raw  `bson:"token"`
+	UpdatedAt  time.Time `bson:"updated_at"`
+}
+
+// Watcher acompanha o change stream da coleção de empresas e publica os eventos
+// correspondentes pelo MessageProducer. O resume token é salvo após cada evento publicado,
+// garantindo entrega ao menos uma vez e retomada do ponto exato após reinicializações.
+type Watcher struct {
+	collection *mongo.Collection
+	tokens     *mongo.Collection
+	producer   messaging.MessageProducer
+	logger     *zap.Logger
+	retryDelay time.Duration
+	maxDelay   time.Duration
+	decode     func(bson.Raw) (*domain.Company, error)
+}
+
+// Option configura recursos opcionais do Watcher
+type Option func(*Watcher)
+
+// WithDecoder define como os documentos da coleção são convertidos em empresas,
+// necessário quando há campos criptografados em repouso
+func WithDecoder(decode func(bson.Raw) (*domain.Company, error)) Option {
+	return func(w *Watcher) {
+		w.decode = decode
+	}
+}
+
+// NewWatcher cria um Watcher para a coleção informada; os tokens ficam em tokenCollection
+func NewWatcher(db *mongo.Database, collectionName, tokenCollection string, producer messaging.MessageProducer, logger *zap.Logger, opts ...Option) *Watcher {
+	w := &Watcher{
+		collection: db.Collection(collectionName),
+		tokens:     db.Collection(tokenCollection),
+		producer:   producer,
+		logger:     logger,
+		retryDelay: 1 * time.Second,
+		maxDelay:   30 * time.Second,
+		decode:     decodeCompany,
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	return w
+}
+
+func decodeCompany(raw bson.Raw) (*domain.Company, error) {
+	var company domain.Company
+	if err := bson.Unmarshal(raw, &company); err != nil {
+		return nil, err
+	}
+	return &company, nil
+}
+
+// Run consome o change stream até o contexto ser cancelado, reabrindo-o após falhas
+func (w *Watcher) Run(ctx context.Context) error {
+	delay := w.retryDelay
+	for {
+		err := w.watch(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		w.logger.Error("Change stream interrupted, reopening",
+			zap.Duration("retry_in", delay),
+			zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > w.maxDelay {
+			delay = w.maxDelay
+		}
+	}
+}
+
+func (w *Watcher) watch(ctx context.Context) error {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"operationType": bson.M{"$in": bson.A{"insert", "update", "replace", "delete"}}}}},
+	}
+	opts := options.ChangeStream().
+		SetFullDocument(options.UpdateLookup).
+		SetFullDocumentBeforeChange(options.WhenAvailable)
+
+	saved, err := w.loadToken(ctx)
+	if err != nil {
+		return err
+	}
+	token := saved.Token
+	if token != nil {
+		opts.SetStartAfter(token)
+	}
+
+	stream, err := w.collection.Watch(ctx, pipeline, opts)
+	if err != nil {
+		if isHistoryLost(err) {
+			return w.restartFromNow(ctx, saved, err)
+		}
+		return fmt.Errorf("failed to open change stream: %w", err)
+	}
+	defer stream.Close(context.Background())
+
+	w.logger.Info("Change stream opened",
+		zap.String("collection", w.collection.Name()),
+		zap.Bool("resumed", token != nil))
+
+	for stream.Next(ctx) {
+		event, err := w.decodeEvent(stream.Current)
+		if err != nil {
+			return fmt.Errorf("failed to decode change event: %w", err)
+		}
+
+		if err := w.publishWithRetry(ctx, event); err != nil {
+			return err
+		}
+		saved, err = w.saveToken(ctx, stream.ResumeToken())
+		if err != nil {
+			return err
+		}
+	}
+	if err := stream.Err(); isHistoryLost(err) {
+		return w.restartFromNow(ctx, saved, err)
+	}
+	return stream.Err()
+}
+
+// isHistoryLost indica que o resume token saiu do oplog (ChangeStreamHistoryLost) ou não pode
+// mais ser usado (ChangeStreamFatalError); reabrir a partir dele falharia indefinidamente
+func isHistoryLost(err error) bool {
+	var serverErr mongo.ServerError
+	return errors.As(err, &serverErr) && (serverErr.HasErrorCode(286) || serverErr.HasErrorCode(280))
+}
+
+// restartFromNow descarta o resume token para que o stream seja reaberto a partir do momento
+// atual. Os eventos entre o último token salvo e agora não foram publicados; o intervalo é
+// registrado para que seja coberto por um replay (updated_since).
+func (w *Watcher) restartFromNow(ctx context.Context, lost tokenDocument, cause error) error {
+	w.logger.Error("Change stream history lost, restarting from now; events since the last saved token were not published and must be replayed",
+		zap.String("collection", w.collection.Name()),
+		zap.Time("gap_since", lost.UpdatedAt),
+		zap.Error(cause))
+
+	if _, err := w.tokens.DeleteOne(ctx, bson.M{"_id": w.collection.Name()}); err != nil {
+		return fmt.Errorf("failed to discard resume token: %w", err)
+	}
+	return fmt.Errorf("%w: %w", ErrHistoryLost, cause)
+}
+
+// EnsurePreImages verifica se a coleção registra pre-images, necessárias para publicar o estado
+// anterior nas atualizações e o documento completo nas exclusões, e tenta habilitá-las
+// (MongoDB 6.0+). Sem elas os eventos sairiam incompletos, e o erro deve interromper a inicialização.
+func (w *Watcher) EnsurePreImages(ctx context.Context) error {
+	db, name := w.collection.Database(), w.collection.Name()
+
+	specs, err := db.ListCollectionSpecifications(ctx, bson.M{"name": name})
+	if err != nil {
+		return fmt.Errorf("failed to inspect collection %s: %w", name, err)
+	}
+	if len(specs) > 0 {
+		if enabled, ok := specs[0].Options.Lookup("changeStreamPreAndPostImages", "enabled").BooleanOK(); ok && enabled {
+			return nil
+		}
+	}
+
+	preImages := bson.M{"enabled": true}
+	if len(specs) == 0 {
+		err = db.CreateCollection(ctx, name, options.CreateCollection().SetChangeStreamPreAndPostImages(preImages))
+	} else {
+		err = db.RunCommand(ctx, bson.D{{Key: "collMod", Value: name}, {Key: "changeStreamPreAndPostImages", Value: preImages}}).Err()
+	}
+	if err != nil {
+		return fmt.Errorf("%w on %s and could not be enabled: %w", ErrPreImagesDisabled, name, err)
+	}
+
+	w.logger.Info("Change stream pre-images enabled", zap.String("collection", name))
+	return nil
+}
+
+// decodeEvent converte o evento do stream, decodificando os documentos presentes
+func (w *Watcher) decodeEvent(raw bson.Raw) (*changeEvent, error) {
+	return decodeEvent(raw, w.decode)
+}
+
+func decodeEvent(raw bson.Raw, decode func(bson.Raw) (*domain.Company, error)) (*changeEvent, error) {
+	var rawEvent rawChangeEvent
+	if err := bson.Unmarshal(raw, &rawEvent); err != nil {
+		return nil, err
+	}
+
+	event := &changeEvent{OperationType: rawEvent.OperationType, DocumentKey: rawEvent.DocumentKey}
+	for _, doc := range []struct {
+		value  bson.RawValue
+		target **domain.Company
+	}{
+		{rawEvent.FullDocument, &event.FullDocument},
+		{rawEvent.FullDocumentBeforeChange, &event.FullDocumentBeforeChange},
+	} {
+		if doc.value.Type != bson.TypeEmbeddedDocument {
+			continue
+		}
+		company, err := decode(doc.value.Document())
+		if err != nil {
+			return nil, err
+		}
+		*doc.target = company
+	}
+	return event, nil
+}
+
+// publishWithRetry insiste na publicação até conseguir ou o contexto ser cancelado,
+// pois avançar o resume token sem publicar perderia o evento
+func (w *Watcher) publishWithRetry(ctx context.Context, event *changeEvent) error {
+	delay := w.retryDelay
+	for {
+		err := w.publish(ctx, event)
+		if err == nil {
+			return nil
+		}
+
+		w.logger.Warn("Failed to publish change event, retrying",
+			zap.String("operation", event.OperationType),
+			zap.String("company_id", event.DocumentKey.ID.Hex()),
+			zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		if delay *= 2; delay > w.maxDelay {
+			delay = w.maxDelay
+		}
+	}
+}
+
+// publish converte o evento do change stream na mensagem correspondente
+func (w *Watcher) publish(ctx context.Context, event *changeEvent) error {
+	switch event.OperationType {
+	case "insert":
+		return w.producer.SendCompanyCreated(ctx, event.company(event.FullDocument))
+	case "update", "replace":
+		if event.FullDocument == nil {
+			// Documento removido antes do lookup; o delete correspondente chegará em seguida
+			return nil
+		}
+		// O estado anterior só vem quando a coleção tem pre-images habilitadas
+		return w.producer.SendCompanyUpdated(ctx, event.FullDocumentBeforeChange, event.company(event.FullDocument))
+	case "delete":
+		return w.producer.SendCompanyDeleted(ctx, event.company(event.FullDocumentBeforeChange))
+	default:
+		return nil
+	}
+}
+
+// company retorna o documento do evento ou, sem ele, uma empresa contendo apenas o ID
+func (e *changeEvent) company(doc *domain.Company) *domain.Company {
+	if doc == nil {
+		doc = &domain.Company{}
+	}
+	if doc.ID == "" {
+		doc.ID = e.DocumentKey.ID.Hex()
+	}
+	return doc
+}
+
+// loadToken retorna o último resume token salvo; sem token, o documento vem vazio
+func (w *Watcher) loadToken(ctx context.Context) (tokenDocument, error) {
+	var doc tokenDocument
+	err := w.tokens.FindOne(ctx, bson.M{"_id": w.collection.Name()}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return tokenDocument{}, nil
+	}
+	if err != nil {
+		return tokenDocument{}, fmt.Errorf("failed to load resume token: %w", err)
+	}
+	return doc, nil
+}
+
+func (w *Watcher) saveToken(ctx context.Context, token bson.Raw) (tokenDocument, error) {
+	doc := tokenDocument{Collection: w.collection.Name(), Token: token, UpdatedAt: time.Now()}
+	_, err := w.tokens.UpdateOne(ctx,
+		bson.M{"_id": doc.Collection},
+		bson.M{"$set": bson.M{"token": doc.Token, "updated_at": doc.UpdatedAt}},
+		options.Update().SetUpsert(true))
+	if err != nil {
+		return doc, fmt.Errorf("failed to save resume token: %w", err)
+	}
+	return doc, nil
+}
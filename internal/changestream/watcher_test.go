@@ -0,0 +1,168 @@
+package changestream
+
+import (
+	"company-service/internal/domain"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"go.uber.org/zap"
+)
+
+// recordingProducer registra as operações publicadas
+type recordingProducer struct {
+	operations []string
+	companies  []*domain.Company
+}
+
+func (p *recordingProducer) SendCompanyCreated(ctx context.Context, company *domain.Company) error {
+	return p.record("created", company)
+}
+
+func (p *recordingProducer) SendCompanyUpdated(ctx context.Context, previous, company *domain.Company) error {
+	return p.record("updated", company)
+}
+
+func (p *recordingProducer) SendCompanyDeleted(ctx context.Context, company *domain.Company) error {
+	return p.record("deleted", company)
+}
+
+func (p *recordingProducer) Close() error { return nil }
+
+func (p *recordingProducer) record(operation string, company *domain.Company) error {
+	p.operations = append(p.operations, operation)
+	p.companies = append(p.companies, company)
+	return nil
+}
+
+func TestWatcher_Publish_MapsOperations(t *testing.T) {
+	producer := &recordingProducer{}
+	w := &Watcher{producer: producer}
+	id := primitive.NewObjectID()
+	doc := &domain.Company{ID: id.Hex(), CNPJ: "11444777000161"}
+
+	for _, op := range []string{"insert", "update", "replace", "delete", "drop"} {
+		event := &changeEvent{OperationType: op, DocumentKey: documentKey{ID: id}, FullDocument: doc}
+		assert.NoError(t, w.publish(context.Background(), event))
+	}
+
+	assert.Equal(t, []string{"created", "updated", "updated", "deleted"}, producer.operations)
+}
+
+func TestWatcher_Publish_DeleteWithoutPreImage_SendsID(t *testing.T) {
+	producer := &recordingProducer{}
+	w := &Watcher{producer: producer}
+	id := primitive.NewObjectID()
+
+	err := w.publish(context.Background(), &changeEvent{OperationType: "delete", DocumentKey: documentKey{ID: id}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, id.Hex(), producer.companies[0].ID)
+}
+
+func TestWatcher_Publish_UpdateWithoutDocument_IsSkipped(t *testing.T) {
+	producer := &recordingProducer{}
+	w := &Watcher{producer: producer}
+
+	err := w.publish(context.Background(), &changeEvent{OperationType: "update", DocumentKey: documentKey{ID: primitive.NewObjectID()}})
+
+	assert.NoError(t, err)
+	assert.Empty(t, producer.operations)
+}
+
+func TestWatcher_DecodeEvent_UsesDecoderAndSkipsMissingDocuments(t *testing.T) {
+	var decoded []bson.Raw
+	w := &Watcher{producer: &recordingProducer{}}
+	WithDecoder(func(raw bson.Raw) (*domain.Company, error) {
+		decoded = append(decoded, raw)
+		return &domain.Company{CNPJ: "11444777000161"}, nil
+	})(w)
+	id := primitive.NewObjectID()
+
+	raw, err := bson.Marshal(bson.M{
+		"operationType":            "update",
+		"documentKey":              bson.M{"_id": id},
+		"fullDocument":             bson.M{"_id": id, "cnpj": bson.M{"k": "k1", "c": []byte{1}}},
+		"fullDocumentBeforeChange": nil,
+	})
+	require.NoError(t, err)
+
+	event, err := w.decodeEvent(raw)
+
+	require.NoError(t, err)
+	assert.Len(t, decoded, 1)
+	assert.Equal(t, "11444777000161", event.FullDocument.CNPJ)
+	assert.Nil(t, event.FullDocumentBeforeChange)
+	assert.Equal(t, id, event.DocumentKey.ID)
+}
+
+func TestWatcher_IsHistoryLost(t *testing.T) {
+	assert.True(t, isHistoryLost(mongo.CommandError{Code: 286, Name: "ChangeStreamHistoryLost"}))
+	assert.True(t, isHistoryLost(fmt.Errorf("wrapped: %w", mongo.CommandError{Code: 280, Name: "ChangeStreamFatalError"})))
+	assert.False(t, isHistoryLost(mongo.CommandError{Code: 91, Name: "ShutdownInProgress"}))
+	assert.False(t, isHistoryLost(errors.New("network error")))
+	assert.False(t, isHistoryLost(nil))
+}
+
+func TestWatcher_HistoryLostAndPreImages(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("history lost discards the resume token", func(mt *mtest.T) {
+		w := NewWatcher(mt.DB, "companies", "change_stream_tokens", &recordingProducer{}, zap.NewNop())
+		lost := tokenDocument{Collection: "companies", UpdatedAt: time.Now().Add(-time.Hour)}
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}))
+
+		err := w.restartFromNow(context.Background(), lost, mongo.CommandError{Code: 286})
+
+		assert.ErrorIs(t, err, ErrHistoryLost)
+		started := mt.GetStartedEvent()
+		assert.Equal(t, "delete", started.CommandName)
+		assert.Equal(t, "change_stream_tokens", started.Command.Lookup("delete").StringValue())
+	})
+
+	collectionSpec := func(options bson.D) bson.D {
+		return bson.D{{Key: "name", Value: "companies"}, {Key: "type", Value: "collection"}, {Key: "options", Value: options}}
+	}
+
+	mt.Run("pre-images already enabled", func(mt *mtest.T) {
+		w := NewWatcher(mt.DB, "companies", "change_stream_tokens", &recordingProducer{}, zap.NewNop())
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "db.$cmd.listCollections", mtest.FirstBatch,
+			collectionSpec(bson.D{{Key: "changeStreamPreAndPostImages", Value: bson.D{{Key: "enabled", Value: true}}}})))
+
+		assert.NoError(t, w.EnsurePreImages(context.Background()))
+		mt.GetStartedEvent()
+		assert.Nil(t, mt.GetStartedEvent(), "nenhum comando além do listCollections")
+	})
+
+	mt.Run("pre-images disabled are enabled with collMod", func(mt *mtest.T) {
+		w := NewWatcher(mt.DB, "companies", "change_stream_tokens", &recordingProducer{}, zap.NewNop())
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "db.$cmd.listCollections", mtest.FirstBatch, collectionSpec(bson.D{})),
+			mtest.CreateSuccessResponse(),
+		)
+
+		assert.NoError(t, w.EnsurePreImages(context.Background()))
+		mt.GetStartedEvent()
+		collMod := mt.GetStartedEvent().Command
+		assert.Equal(t, "companies", collMod.Lookup("collMod").StringValue())
+		assert.True(t, collMod.Lookup("changeStreamPreAndPostImages", "enabled").Boolean())
+	})
+
+	mt.Run("fails fast when pre-images cannot be enabled", func(mt *mtest.T) {
+		w := NewWatcher(mt.DB, "companies", "change_stream_tokens", &recordingProducer{}, zap.NewNop())
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "db.$cmd.listCollections", mtest.FirstBatch, collectionSpec(bson.D{})),
+			mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 40415, Message: "unknown field changeStreamPreAndPostImages"}),
+		)
+
+		assert.ErrorIs(t, w.EnsurePreImages(context.Background()), ErrPreImagesDisabled)
+	})
+}
@@ -0,0 +1,354 @@
+package command
+
+import (
+	"company-service/internal/dto"
+	"company-service/internal/messaging/rabbitmq"
+	"company-service/internal/service"
+	"company-service/internal/tenant"
+	"company-service/pkg/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// Tipos de comando aceitos
+const (
+	TypeCreate = "create"
+	TypeUpdate = "update"
+	TypeDelete = "delete"
+)
+
+// Situação do comando na resposta
+const (
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Origem do tenant dos comandos. A fila é uma fronteira de confiança: com TenantFromBody,
+// qualquer sistema com permissão de publicar na fila escreve em qualquer tenant.
+const (
+	TenantFromUserID = "user_id" // usuário AMQP que publicou (propriedade user_id, validada pelo broker)
+	TenantFixed      = "fixed"   // um único tenant configurado para a fila
+	TenantFromBody   = "body"    // tenant_id do corpo; só para publicadores confiáveis
+)
+
+// CodeInvalidCommand é o código das respostas de comandos malformados (JSON inválido, tipo
+// desconhecido, ID ausente ou inválido)
+const CodeInvalidCommand = "INVALID_COMMAND"
+
+// CodeTenantNotAllowed é o código das respostas de comandos para um tenant diferente do
+// permitido ao publicador
+const CodeTenantNotAllowed = "TENANT_NOT_ALLOWED"
+
+// ErrInvalidCommand indica um comando que não pode ser executado como foi enviado
+var ErrInvalidCommand = errors.New("invalid command")
+
+// ErrTenantNotAllowed indica um tenant_id que não corresponde à origem configurada do tenant
+var ErrTenantNotAllowed = errors.New("tenant not allowed")
+
+// Command é uma operação de escrita recebida pela fila de comandos. Company segue o formato do
+// corpo das requisições REST de criação e atualização.
+type Command struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	TenantID  string          `json:"tenant_id"`
+	CompanyID string          `json:"company_id"`
+	Company   json.RawMessage `json:"company"`
+
+	// UserID é o usuário AMQP que publicou o comando, usado como tenant com TenantFromUserID
+	UserID string `json:"-"`
+}
+
+// Reply é o resultado de um comando, publicado na fila reply_to da mensagem ou, nas falhas de
+// comandos sem reply_to, na fila de erros. Code é o código do ServiceError (ou
+// INVALID_COMMAND) e Company traz a empresa criada ou atualizada.
+type Reply struct {
+	CommandID string               `json:"command_id"`
+	Type      string               `json:"type"`
+	Status    string               `json:"status"`
+	CompanyID string               `json:"company_id,omitempty"`
+	Company   *dto.CompanyResponse `json:"company,omitempty"`
+	Code      string               `json:"code,omitempty"`
+	Error     string               `json:"error,omitempty"`
+}
+
+// Processor executa os comandos pelo mesmo CompanyService usado pela API REST
+type Processor struct {
+	service       service.CompanyService
+	defaultTenant string
+	tenantSource  string
+	tenant        string
+	store         Store
+	logger        *zap.Logger
+}
+
+// Option configura recursos opcionais do processador
+type Option func(*Processor)
+
+// WithTenantSource define de onde vem o tenant dos comandos (padrão: TenantFromUserID); tenant
+// é o tenant usado com TenantFixed
+func WithTenantSource(source, tenant string) Option {
+	return func(p *Processor) {
+		p.tenantSource = source
+		p.tenant = tenant
+	}
+}
+
+// WithStore torna os comandos com ID idempotentes, registrando suas respostas no store
+func WithStore(store Store) Option {
+	return func(p *Processor) {
+		p.store = store
+	}
+}
+
+// CheckTenantSource valida a origem do tenant antes de criar o processador
+func CheckTenantSource(source, tenant string) error {
+	switch source {
+	case TenantFromUserID, TenantFromBody:
+		return nil
+	case TenantFixed:
+		if tenant == "" {
+			return errors.New("a tenant is required when commands use a fixed tenant")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown command tenant source %q, expected user_id, fixed or body", source)
+	}
+}
+
+// NewProcessor cria o processador de comandos; defaultTenant é usado nos comandos sem
+// tenant_id quando o tenant vem do corpo, como o cabeçalho de tenant na API
+func NewProcessor(svc service.CompanyService, defaultTenant string, logger *zap.Logger, opts ...Option) *Processor {
+	p := &Processor{
+		service:       svc,
+		defaultTenant: defaultTenant,
+		tenantSource:  TenantFromUserID,
+		logger:        logger,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Handle executa o comando. Falhas de validação e de regra de negócio resultam em uma resposta
+// com status failed; falhas temporárias (ex.: banco indisponível) retornam erro para que o
+// comando seja reprocessado. Com store, um comando já concluído retorna a resposta gravada sem
+// ser executado de novo.
+func (p *Processor) Handle(ctx context.Context, cmd Command) (*Reply, error) {
+	reply := &Reply{CommandID: cmd.ID, Type: cmd.Type, CompanyID: cmd.CompanyID}
+
+	tenantID, err := p.resolveTenant(cmd)
+	if err != nil {
+		return p.failure(cmd, reply, err)
+	}
+	ctx = tenant.WithTenant(ctx, tenantID)
+
+	tracked := p.store != nil && cmd.ID != ""
+	if tracked {
+		stored, err := p.store.Claim(ctx, tenantID, cmd.ID)
+		if err != nil {
+			p.logger.Warn("Failed to claim command, will retry",
+				zap.String("command_id", cmd.ID),
+				zap.Error(err))
+			return nil, err
+		}
+		if stored != nil {
+			p.logger.Info("Command already processed, returning stored reply",
+				zap.String("command_id", cmd.ID),
+				zap.String("status", stored.Status))
+			return stored, nil
+		}
+	}
+
+	reply, err = p.execute(ctx, cmd, reply)
+	if !tracked {
+		return reply, err
+	}
+	if err != nil {
+		if releaseErr := p.store.Release(ctx, tenantID, cmd.ID); releaseErr != nil {
+			p.logger.Error("Failed to release command claim",
+				zap.String("command_id", cmd.ID),
+				zap.Error(releaseErr))
+		}
+		return nil, err
+	}
+	// A resposta já foi produzida: uma falha ao gravá-la não desfaz o comando. A reserva expira
+	// e uma reentrega executa de novo (na criação, o CNPJ duplicado é recusado).
+	if err := p.store.Complete(ctx, tenantID, cmd.ID, reply); err != nil {
+		p.logger.Error("Failed to store command reply",
+			zap.String("command_id", cmd.ID),
+			zap.Error(err))
+	}
+	return reply, nil
+}
+
+// resolveTenant determina o tenant do comando pela origem configurada. Um tenant_id no corpo
+// diferente do tenant da origem é recusado.
+func (p *Processor) resolveTenant(cmd Command) (string, error) {
+	var tenantID string
+	switch p.tenantSource {
+	case TenantFromBody:
+		if cmd.TenantID != "" {
+			return cmd.TenantID, nil
+		}
+		return p.defaultTenant, nil
+	case TenantFixed:
+		tenantID = p.tenant
+	default:
+		if cmd.UserID == "" {
+			return "", fmt.Errorf("%w: the user_id message property is required", ErrTenantNotAllowed)
+		}
+		tenantID = cmd.UserID
+	}
+	if cmd.TenantID != "" && cmd.TenantID != tenantID {
+		return "", fmt.Errorf("%w: tenant_id %s does not match the publisher tenant", ErrTenantNotAllowed, cmd.TenantID)
+	}
+	return tenantID, nil
+}
+
+// execute executa o comando no service e monta a resposta
+func (p *Processor) execute(ctx context.Context, cmd Command, reply *Reply) (*Reply, error) {
+	var err error
+	switch cmd.Type {
+	case TypeCreate:
+		err = p.create(ctx, cmd, reply)
+	case TypeUpdate:
+		err = p.update(ctx, cmd, reply)
+	case TypeDelete:
+		err = p.delete(ctx, cmd)
+	default:
+		err = fmt.Errorf("%w: unknown type %s, expected create, update or delete", ErrInvalidCommand, cmd.Type)
+	}
+
+	if err != nil {
+		return p.failure(cmd, reply, err)
+	}
+
+	reply.Status = StatusSucceeded
+	p.logger.Info("Command executed",
+		zap.String("command_id", cmd.ID),
+		zap.String("type", cmd.Type),
+		zap.String("company_id", reply.CompanyID))
+	return reply, nil
+}
+
+func (p *Processor) create(ctx context.Context, cmd Command, reply *Reply) error {
+	var req dto.CreateCompanyRequest
+	if err := decodeCompany(cmd, &req); err != nil {
+		return err
+	}
+
+	company := dto.ToDomainCompanyCreate(&req)
+	if err := p.service.CreateCompany(ctx, company); err != nil {
+		return err
+	}
+	reply.CompanyID = company.ID
+	reply.Company = dto.FromDomainCompany(company)
+	return nil
+}
+
+func (p *Processor) update(ctx context.Context, cmd Command, reply *Reply) error {
+	if err := validateCompanyID(cmd.CompanyID); err != nil {
+		return err
+	}
+	var req dto.UpdateCompanyRequest
+	if err := decodeCompany(cmd, &req); err != nil {
+		return err
+	}
+
+	company, err := p.service.UpdateCompany(ctx, dto.ToDomainCompanyUpdate(&req, cmd.CompanyID))
+	if err != nil {
+		return err
+	}
+	reply.Company = dto.FromDomainCompany(company)
+	return nil
+}
+
+func (p *Processor) delete(ctx context.Context, cmd Command) error {
+	if err := validateCompanyID(cmd.CompanyID); err != nil {
+		return err
+	}
+	return p.service.DeleteCompany(ctx, cmd.CompanyID)
+}
+
+// failure converte o erro em uma resposta de falha ou, se temporário, o devolve
+func (p *Processor) failure(cmd Command, reply *Reply, err error) (*Reply, error) {
+	var serviceErr *service.ServiceError
+	switch {
+	case errors.Is(err, ErrInvalidCommand):
+		reply.Code = CodeInvalidCommand
+	case errors.Is(err, ErrTenantNotAllowed):
+		reply.Code = CodeTenantNotAllowed
+	case errors.As(err, &serviceErr) && serviceErr.Code != "REPOSITORY_ERROR":
+		reply.Code = serviceErr.Code
+	default:
+		p.logger.Error("Command failed, will retry",
+			zap.String("command_id", cmd.ID),
+			zap.String("type", cmd.Type),
+			zap.Error(err))
+		return nil, err
+	}
+
+	p.logger.Warn("Command rejected",
+		zap.String("command_id", cmd.ID),
+		zap.String("type", cmd.Type),
+		zap.String("code", reply.Code),
+		zap.Error(err))
+	reply.Status = StatusFailed
+	reply.Error = err.Error()
+	return reply, nil
+}
+
+// HandleMessage adapta o processador ao consumidor do RabbitMQ. O ID e o tipo do comando, se
+// ausentes no corpo, vêm das propriedades message_id e type da mensagem; o usuário vem da
+// propriedade user_id.
+func (p *Processor) HandleMessage(ctx context.Context, msg rabbitmq.Message) (*rabbitmq.Reply, error) {
+	var cmd Command
+	var reply *Reply
+	if err := json.Unmarshal(msg.Body, &cmd); err != nil {
+		reply, _ = p.failure(Command{ID: msg.ID, Type: msg.Type}, &Reply{CommandID: msg.ID, Type: msg.Type},
+			fmt.Errorf("%w: invalid JSON: %v", ErrInvalidCommand, err))
+	} else {
+		if cmd.ID == "" {
+			cmd.ID = msg.ID
+		}
+		if cmd.Type == "" {
+			cmd.Type = msg.Type
+		}
+		cmd.UserID = msg.UserID
+		if reply, err = p.Handle(ctx, cmd); err != nil {
+			return nil, err
+		}
+	}
+
+	body, err := json.Marshal(reply)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode command reply: %w", err)
+	}
+	return &rabbitmq.Reply{
+		Type:   "company.command." + reply.Status,
+		Body:   body,
+		Failed: reply.Status == StatusFailed,
+	}, nil
+}
+
+func decodeCompany(cmd Command, v interface{}) error {
+	if len(cmd.Company) == 0 {
+		return fmt.Errorf("%w: company is required", ErrInvalidCommand)
+	}
+	if err := json.Unmarshal(cmd.Company, v); err != nil {
+		return fmt.Errorf("%w: invalid company: %v", ErrInvalidCommand, err)
+	}
+	return nil
+}
+
+func validateCompanyID(id string) error {
+	if !utils.IsValidObjectID(id) {
+		return fmt.Errorf("%w: invalid company_id", ErrInvalidCommand)
+	}
+	return nil
+}
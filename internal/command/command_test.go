@@ -0,0 +1,303 @@
+package command
+
+import (
+	"company-service/internal/domain"
+	"company-service/internal/messaging/rabbitmq"
+	"company-service/internal/service"
+	"company-service/internal/tenant"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const companyID = "507f1f77bcf86cd799439011"
+
+// fakeService registra o tenant de cada operação e devolve o erro configurado
+type fakeService struct {
+	service.CompanyService
+	err     error
+	tenants []string
+	deleted []string
+}
+
+func (f *fakeService) record(ctx context.Context) {
+	tenantID, _ := tenant.FromContext(ctx)
+	f.tenants = append(f.tenants, tenantID)
+}
+
+func (f *fakeService) CreateCompany(ctx context.Context, company *domain.Company) error {
+	f.record(ctx)
+	if f.err != nil {
+		return f.err
+	}
+	company.ID = companyID
+	company.TenantID, _ = tenant.FromContext(ctx)
+	return nil
+}
+
+func (f *fakeService) UpdateCompany(ctx context.Context, company *domain.Company) (*domain.Company, error) {
+	f.record(ctx)
+	if f.err != nil {
+		return nil, f.err
+	}
+	return company, nil
+}
+
+func (f *fakeService) DeleteCompany(ctx context.Context, id string) error {
+	f.record(ctx)
+	if f.err != nil {
+		return f.err
+	}
+	f.deleted = append(f.deleted, id)
+	return nil
+}
+
+const companyJSON = `{"cnpj":"11222333000181","fantasy_name":"Acme","corporate_name":"Acme LTDA","address":"Rua A","employee_count":10,"required_min_pwd_employee_count":1}`
+
+func TestProcessor_Create(t *testing.T) {
+	svc := &fakeService{}
+	p := NewProcessor(svc, "default", zap.NewNop(), WithTenantSource(TenantFromBody, ""))
+
+	reply, err := p.Handle(context.Background(), Command{ID: "c1", Type: TypeCreate, TenantID: "acme", Company: json.RawMessage(companyJSON)})
+	require.NoError(t, err)
+	assert.Equal(t, StatusSucceeded, reply.Status)
+	assert.Equal(t, "c1", reply.CommandID)
+	assert.Equal(t, companyID, reply.CompanyID)
+	require.NotNil(t, reply.Company)
+	assert.Equal(t, "11222333000181", reply.Company.CNPJ)
+	assert.Equal(t, []string{"acme"}, svc.tenants)
+}
+
+func TestProcessor_UsesDefaultTenant(t *testing.T) {
+	svc := &fakeService{}
+	p := NewProcessor(svc, "default", zap.NewNop(), WithTenantSource(TenantFromBody, ""))
+
+	reply, err := p.Handle(context.Background(), Command{Type: TypeDelete, CompanyID: companyID})
+	require.NoError(t, err)
+	assert.Equal(t, StatusSucceeded, reply.Status)
+	assert.Equal(t, []string{"default"}, svc.tenants)
+	assert.Equal(t, []string{companyID}, svc.deleted)
+}
+
+func TestProcessor_InvalidCommands(t *testing.T) {
+	tests := []struct {
+		name string
+		cmd  Command
+	}{
+		{"unknown type", Command{Type: "upsert"}},
+		{"missing company", Command{Type: TypeCreate}},
+		{"malformed company", Command{Type: TypeCreate, Company: json.RawMessage(`{"cnpj":1}`)}},
+		{"invalid id", Command{Type: TypeUpdate, CompanyID: "123", Company: json.RawMessage(companyJSON)}},
+		{"missing id", Command{Type: TypeDelete}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &fakeService{}
+			reply, err := NewProcessor(svc, "default", zap.NewNop(), WithTenantSource(TenantFixed, "acme")).Handle(context.Background(), tt.cmd)
+			require.NoError(t, err)
+			assert.Equal(t, StatusFailed, reply.Status)
+			assert.Equal(t, CodeInvalidCommand, reply.Code)
+			assert.NotEmpty(t, reply.Error)
+			assert.Empty(t, svc.tenants, "comandos inválidos não chegam ao serviço")
+		})
+	}
+}
+
+func TestProcessor_ServiceErrors(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		code      string
+		temporary bool
+	}{
+		{"validation", service.NewServiceError(service.ErrInvalidCompanyData, "dados da empresa inválidos", "VALIDATION_ERROR"), "VALIDATION_ERROR", false},
+		{"conflict", service.NewServiceError(service.ErrCNPJAlreadyExists, "CNPJ já cadastrado", "CNPJ_CONFLICT"), "CNPJ_CONFLICT", false},
+		{"not found", service.NewServiceError(service.ErrCompanyNotFound, "empresa não encontrada", "NOT_FOUND"), "NOT_FOUND", false},
+		{"repository", service.NewServiceError(errors.New("timeout"), "erro ao criar empresa", "REPOSITORY_ERROR"), "", true},
+		{"unexpected", errors.New("boom"), "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProcessor(&fakeService{err: tt.err}, "default", zap.NewNop(), WithTenantSource(TenantFixed, "acme"))
+			reply, err := p.Handle(context.Background(), Command{Type: TypeCreate, Company: json.RawMessage(companyJSON)})
+			if tt.temporary {
+				assert.ErrorIs(t, err, tt.err)
+				assert.Nil(t, reply)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, StatusFailed, reply.Status)
+			assert.Equal(t, tt.code, reply.Code)
+		})
+	}
+}
+
+func TestProcessor_HandleMessage(t *testing.T) {
+	svc := &fakeService{}
+	p := NewProcessor(svc, "default", zap.NewNop())
+
+	// Tipo e ID vêm das propriedades da mensagem quando ausentes no corpo; o tenant, do user_id
+	reply, err := p.HandleMessage(context.Background(), rabbitmq.Message{
+		ID:     "m1",
+		Type:   TypeDelete,
+		UserID: "acme",
+		Body:   []byte(`{"company_id":"` + companyID + `"}`),
+	})
+	require.NoError(t, err)
+	assert.False(t, reply.Failed)
+	assert.Equal(t, "company.command.succeeded", reply.Type)
+	var body Reply
+	require.NoError(t, json.Unmarshal(reply.Body, &body))
+	assert.Equal(t, Reply{CommandID: "m1", Type: TypeDelete, Status: StatusSucceeded, CompanyID: companyID}, body)
+	assert.Equal(t, []string{"acme"}, svc.tenants)
+
+	// JSON inválido é confirmado com uma resposta de falha, sem reprocessamento
+	reply, err = p.HandleMessage(context.Background(), rabbitmq.Message{ID: "m2", Body: []byte(`{`)})
+	require.NoError(t, err)
+	assert.True(t, reply.Failed)
+	assert.Equal(t, "company.command.failed", reply.Type)
+	require.NoError(t, json.Unmarshal(reply.Body, &body))
+	assert.Equal(t, "m2", body.CommandID)
+	assert.Equal(t, CodeInvalidCommand, body.Code)
+
+	// Falhas temporárias devolvem o erro ao consumidor
+	p = NewProcessor(&fakeService{err: errors.New("boom")}, "default", zap.NewNop())
+	_, err = p.HandleMessage(context.Background(), rabbitmq.Message{UserID: "acme", Body: []byte(`{"type":"delete","company_id":"` + companyID + `"}`)})
+	assert.Error(t, err)
+}
+
+func TestProcessor_TenantSources(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		tenant string
+		cmd    Command
+		want   string // tenant usado; vazio quando o comando é recusado
+	}{
+		{"user_id", TenantFromUserID, "", Command{UserID: "acme"}, "acme"},
+		{"user_id with matching tenant_id", TenantFromUserID, "", Command{UserID: "acme", TenantID: "acme"}, "acme"},
+		{"user_id with other tenant_id", TenantFromUserID, "", Command{UserID: "acme", TenantID: "globex"}, ""},
+		{"missing user_id", TenantFromUserID, "", Command{TenantID: "acme"}, ""},
+		{"fixed", TenantFixed, "acme", Command{UserID: "publisher"}, "acme"},
+		{"fixed with other tenant_id", TenantFixed, "acme", Command{TenantID: "globex"}, ""},
+		{"body", TenantFromBody, "", Command{UserID: "publisher", TenantID: "globex"}, "globex"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &fakeService{}
+			p := NewProcessor(svc, "default", zap.NewNop(), WithTenantSource(tt.source, tt.tenant))
+			cmd := tt.cmd
+			cmd.Type, cmd.CompanyID = TypeDelete, companyID
+
+			reply, err := p.Handle(context.Background(), cmd)
+			require.NoError(t, err)
+			if tt.want == "" {
+				assert.Equal(t, StatusFailed, reply.Status)
+				assert.Equal(t, CodeTenantNotAllowed, reply.Code)
+				assert.Empty(t, svc.tenants, "comandos de outro tenant não chegam ao serviço")
+				return
+			}
+			assert.Equal(t, StatusSucceeded, reply.Status)
+			assert.Equal(t, []string{tt.want}, svc.tenants)
+		})
+	}
+}
+
+func TestCheckTenantSource(t *testing.T) {
+	assert.NoError(t, CheckTenantSource(TenantFromUserID, ""))
+	assert.NoError(t, CheckTenantSource(TenantFromBody, ""))
+	assert.NoError(t, CheckTenantSource(TenantFixed, "acme"))
+	assert.Error(t, CheckTenantSource(TenantFixed, ""))
+	assert.Error(t, CheckTenantSource("header", ""))
+}
+
+// memoryStore é um Store em memória que também registra as reservas desfeitas
+type memoryStore struct {
+	replies  map[string]*Reply
+	claimed  map[string]bool
+	released []string
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{replies: map[string]*Reply{}, claimed: map[string]bool{}}
+}
+
+func (s *memoryStore) Claim(ctx context.Context, tenantID, id string) (*Reply, error) {
+	key := recordID(tenantID, id)
+	if reply, ok := s.replies[key]; ok {
+		return reply, nil
+	}
+	if s.claimed[key] {
+		return nil, ErrCommandInProgress
+	}
+	s.claimed[key] = true
+	return nil, nil
+}
+
+func (s *memoryStore) Complete(ctx context.Context, tenantID, id string, reply *Reply) error {
+	s.replies[recordID(tenantID, id)] = reply
+	return nil
+}
+
+func (s *memoryStore) Release(ctx context.Context, tenantID, id string) error {
+	delete(s.claimed, recordID(tenantID, id))
+	s.released = append(s.released, id)
+	return nil
+}
+
+func TestProcessor_RedeliveredCommandReturnsStoredReply(t *testing.T) {
+	svc := &fakeService{}
+	store := newMemoryStore()
+	p := NewProcessor(svc, "default", zap.NewNop(), WithStore(store))
+	cmd := Command{ID: "c1", Type: TypeCreate, UserID: "acme", Company: json.RawMessage(companyJSON)}
+
+	first, err := p.Handle(context.Background(), cmd)
+	require.NoError(t, err)
+	second, err := p.Handle(context.Background(), cmd)
+	require.NoError(t, err)
+
+	assert.Equal(t, first, second)
+	assert.Equal(t, StatusSucceeded, second.Status)
+	assert.Equal(t, []string{"acme"}, svc.tenants, "a reentrega não cria a empresa de novo")
+
+	// O registro é por tenant: o mesmo ID em outro tenant é outro comando
+	_, err = p.Handle(context.Background(), Command{ID: "c1", Type: TypeCreate, UserID: "globex", Company: json.RawMessage(companyJSON)})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"acme", "globex"}, svc.tenants)
+}
+
+func TestProcessor_TemporaryFailureReleasesClaim(t *testing.T) {
+	svc := &fakeService{err: errors.New("mongo unavailable")}
+	store := newMemoryStore()
+	p := NewProcessor(svc, "default", zap.NewNop(), WithStore(store))
+	cmd := Command{ID: "c1", Type: TypeCreate, UserID: "acme", Company: json.RawMessage(companyJSON)}
+
+	_, err := p.Handle(context.Background(), cmd)
+	require.Error(t, err)
+	assert.Equal(t, []string{"c1"}, store.released)
+
+	svc.err = nil
+	reply, err := p.Handle(context.Background(), cmd)
+	require.NoError(t, err)
+	assert.Equal(t, StatusSucceeded, reply.Status)
+	assert.Len(t, svc.tenants, 2)
+}
+
+func TestProcessor_CommandInProgressIsRetried(t *testing.T) {
+	svc := &fakeService{}
+	store := newMemoryStore()
+	store.claimed[recordID("acme", "c1")] = true
+	p := NewProcessor(svc, "default", zap.NewNop(), WithStore(store))
+
+	_, err := p.Handle(context.Background(), Command{ID: "c1", Type: TypeDelete, CompanyID: companyID, UserID: "acme"})
+	assert.ErrorIs(t, err, ErrCommandInProgress)
+	assert.Empty(t, svc.tenants)
+}
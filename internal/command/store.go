@@ -0,0 +1,154 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrCommandInProgress indica um comando reservado por outra execução ainda em andamento; é
+// uma falha temporária e a mensagem é reprocessada depois
+var ErrCommandInProgress = errors.New("command is already being processed")
+
+// claimTTL é o prazo de uma reserva: passado esse tempo sem conclusão, a execução é
+// considerada interrompida (ex.: réplica encerrada) e o comando pode ser reservado de novo
+const claimTTL = 30 * time.Second
+
+// Situação do registro do comando
+const (
+	recordProcessing = "processing"
+	recordCompleted  = "completed"
+)
+
+// Store registra os comandos processados, tornando a execução idempotente: uma mensagem
+// entregue de novo (at-least-once) recebe a resposta gravada em vez de executar o comando
+// outra vez. Os registros são identificados pelo tenant e pelo ID do comando.
+type Store interface {
+	// Claim reserva o comando para execução. Retorna a resposta gravada se ele já foi
+	// concluído e ErrCommandInProgress se outra execução recente o reservou.
+	Claim(ctx context.Context, tenantID, id string) (*Reply, error)
+	// Complete grava a resposta final do comando reservado
+	Complete(ctx context.Context, tenantID, id string, reply *Reply) error
+	// Release desfaz a reserva após uma falha temporária, para que a nova tentativa execute
+	Release(ctx context.Context, tenantID, id string) error
+}
+
+type mongoStore struct {
+	collection *mongo.Collection
+	timeout    time.Duration
+}
+
+// NewMongoStore registra os comandos processados na coleção informada
+func NewMongoStore(db *mongo.Database, collection string, timeout time.Duration) Store {
+	return &mongoStore{collection: db.Collection(collection), timeout: timeout}
+}
+
+// EnsureIndexes cria o índice TTL que remove os registros após retention. A unicidade vem do
+// _id (tenant e ID do comando). Uma mensagem reentregue depois de retention é executada de novo.
+func EnsureIndexes(ctx context.Context, db *mongo.Database, collection string, retention time.Duration) error {
+	if retention <= 0 {
+		return nil
+	}
+	_, err := db.Collection(collection).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "created_at", Value: 1}},
+		Options: options.Index().SetName("ttl_created_at").SetExpireAfterSeconds(int32(retention.Seconds())),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create command indexes: %w", err)
+	}
+	return nil
+}
+
+// record é o registro gravado; Reply guarda a resposta em JSON, no formato publicado
+type record struct {
+	ID        string    `bson:"_id"`
+	TenantID  string    `bson:"tenant_id"`
+	CommandID string    `bson:"command_id"`
+	Status    string    `bson:"status"`
+	Reply     []byte    `bson:"reply,omitempty"`
+	ClaimedAt time.Time `bson:"claimed_at"`
+	CreatedAt time.Time `bson:"created_at"`
+}
+
+func recordID(tenantID, id string) string {
+	return tenantID + ":" + id
+}
+
+func (s *mongoStore) Claim(ctx context.Context, tenantID, id string) (*Reply, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	now := time.Now().UTC()
+	_, err := s.collection.InsertOne(ctx, record{
+		ID:        recordID(tenantID, id),
+		TenantID:  tenantID,
+		CommandID: id,
+		Status:    recordProcessing,
+		ClaimedAt: now,
+		CreatedAt: now,
+	})
+	if err == nil {
+		return nil, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return nil, fmt.Errorf("failed to claim command %s: %w", id, err)
+	}
+
+	var existing record
+	if err := s.collection.FindOne(ctx, bson.M{"_id": recordID(tenantID, id)}).Decode(&existing); err != nil {
+		if err == mongo.ErrNoDocuments {
+			// Reserva desfeita entre as duas operações
+			return nil, ErrCommandInProgress
+		}
+		return nil, fmt.Errorf("failed to load command %s: %w", id, err)
+	}
+	if existing.Status == recordCompleted {
+		var reply Reply
+		if err := json.Unmarshal(existing.Reply, &reply); err != nil {
+			return nil, fmt.Errorf("failed to decode reply of command %s: %w", id, err)
+		}
+		return &reply, nil
+	}
+	if now.Sub(existing.ClaimedAt) < claimTTL {
+		return nil, ErrCommandInProgress
+	}
+
+	// Reserva abandonada: assume a execução se nenhuma outra o fez antes
+	result, err := s.collection.UpdateOne(ctx,
+		bson.M{"_id": existing.ID, "status": recordProcessing, "claimed_at": existing.ClaimedAt},
+		bson.M{"$set": bson.M{"claimed_at": now}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim command %s: %w", id, err)
+	}
+	if result.ModifiedCount == 0 {
+		return nil, ErrCommandInProgress
+	}
+	return nil, nil
+}
+
+func (s *mongoStore) Complete(ctx context.Context, tenantID, id string, reply *Reply) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	body, err := json.Marshal(reply)
+	if err != nil {
+		return fmt.Errorf("failed to encode reply of command %s: %w", id, err)
+	}
+	_, err = s.collection.UpdateOne(ctx, bson.M{"_id": recordID(tenantID, id)},
+		bson.M{"$set": bson.M{"status": recordCompleted, "reply": body}})
+	return err
+}
+
+func (s *mongoStore) Release(ctx context.Context, tenantID, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	_, err := s.collection.DeleteOne(ctx, bson.M{"_id": recordID(tenantID, id), "status": recordProcessing})
+	return err
+}
@@ -0,0 +1,120 @@
+package command
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func duplicateKeyReply() bson.D {
+	return mtest.CreateWriteErrorsResponse(mtest.WriteError{Index: 0, Code: 11000, Message: "E11000 duplicate key error"})
+}
+
+func storedRecord(status string, claimedAt time.Time, reply *Reply) bson.D {
+	doc := bson.D{
+		{Key: "_id", Value: "acme:c1"},
+		{Key: "tenant_id", Value: "acme"},
+		{Key: "command_id", Value: "c1"},
+		{Key: "status", Value: status},
+		{Key: "claimed_at", Value: claimedAt},
+		{Key: "created_at", Value: claimedAt},
+	}
+	if reply != nil {
+		body, _ := json.Marshal(reply)
+		doc = append(doc, bson.E{Key: "reply", Value: body})
+	}
+	return doc
+}
+
+func TestMongoStore_Claim(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("new command", func(mt *mtest.T) {
+		store := NewMongoStore(mt.DB, "processed_commands", time.Second)
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+		reply, err := store.Claim(context.Background(), "acme", "c1")
+		require.NoError(t, err)
+		assert.Nil(t, reply)
+
+		doc := mt.GetStartedEvent().Command.Lookup("documents").Array().Index(0).Value().Document()
+		assert.Equal(t, "acme:c1", doc.Lookup("_id").StringValue())
+		assert.Equal(t, recordProcessing, doc.Lookup("status").StringValue())
+	})
+
+	mt.Run("completed command returns stored reply", func(mt *mtest.T) {
+		store := NewMongoStore(mt.DB, "processed_commands", time.Second)
+		stored := &Reply{CommandID: "c1", Type: TypeCreate, Status: StatusSucceeded, CompanyID: companyID}
+		mt.AddMockResponses(
+			duplicateKeyReply(),
+			mtest.CreateCursorResponse(0, "db.processed_commands", mtest.FirstBatch, storedRecord(recordCompleted, time.Now(), stored)),
+		)
+
+		reply, err := store.Claim(context.Background(), "acme", "c1")
+		require.NoError(t, err)
+		assert.Equal(t, stored, reply)
+	})
+
+	mt.Run("recent claim is in progress", func(mt *mtest.T) {
+		store := NewMongoStore(mt.DB, "processed_commands", time.Second)
+		mt.AddMockResponses(
+			duplicateKeyReply(),
+			mtest.CreateCursorResponse(0, "db.processed_commands", mtest.FirstBatch, storedRecord(recordProcessing, time.Now(), nil)),
+		)
+
+		_, err := store.Claim(context.Background(), "acme", "c1")
+		assert.ErrorIs(t, err, ErrCommandInProgress)
+	})
+
+	mt.Run("abandoned claim is taken over", func(mt *mtest.T) {
+		store := NewMongoStore(mt.DB, "processed_commands", time.Second)
+		mt.AddMockResponses(
+			duplicateKeyReply(),
+			mtest.CreateCursorResponse(0, "db.processed_commands", mtest.FirstBatch, storedRecord(recordProcessing, time.Now().Add(-time.Minute), nil)),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+		)
+
+		reply, err := store.Claim(context.Background(), "acme", "c1")
+		require.NoError(t, err)
+		assert.Nil(t, reply)
+	})
+
+	mt.Run("abandoned claim taken by another replica", func(mt *mtest.T) {
+		store := NewMongoStore(mt.DB, "processed_commands", time.Second)
+		mt.AddMockResponses(
+			duplicateKeyReply(),
+			mtest.CreateCursorResponse(0, "db.processed_commands", mtest.FirstBatch, storedRecord(recordProcessing, time.Now().Add(-time.Minute), nil)),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}),
+		)
+
+		_, err := store.Claim(context.Background(), "acme", "c1")
+		assert.ErrorIs(t, err, ErrCommandInProgress)
+	})
+}
+
+func TestMongoStore_CompleteStoresReply(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("complete", func(mt *mtest.T) {
+		store := NewMongoStore(mt.DB, "processed_commands", time.Second)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		reply := &Reply{CommandID: "c1", Type: TypeCreate, Status: StatusFailed, Code: "CNPJ_CONFLICT"}
+		require.NoError(t, store.Complete(context.Background(), "acme", "c1", reply))
+
+		update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(t, "acme:c1", update.Lookup("q", "_id").StringValue())
+		set := update.Lookup("u", "$set").Document()
+		assert.Equal(t, recordCompleted, set.Lookup("status").StringValue())
+		_, body := set.Lookup("reply").Binary()
+		var stored Reply
+		require.NoError(t, json.Unmarshal(body, &stored))
+		assert.Equal(t, *reply, stored)
+	})
+}
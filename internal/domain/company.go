@@ -9,6 +9,7 @@ import (
 
 type Company struct {
 	ID                          string    `bson:"_id,omitempty" json:"id"`
+	TenantID                    string    `bson:"tenant_id" json:"tenant_id"`
 	CNPJ                        string    `bson:"cnpj" json:"cnpj"`
 	FantasyName                 string    `bson:"fantasy_name" json:"fantasy_name"`
 	CorporateName               string    `bson:"corporate_name" json:"corporate_name"`
@@ -17,6 +18,14 @@ type Company struct {
 	RequiredMinPWDEmployeeCount int       `bson:"required_min_pwd_employee_count" json:"required_min_pwd_employee_count"`
 	CreatedAt                   time.Time `bson:"created_at" json:"created_at"`
 	UpdatedAt                   time.Time `bson:"updated_at" json:"updated_at"`
+
+	// Sequence é incrementada a cada escrita da empresa e publicada nos eventos, permitindo aos
+	// consumidores descartar duplicatas e detectar eventos perdidos; zero em registros anteriores a ela
+	Sequence int64 `bson:"sequence,omitempty" json:"sequence,omitempty"`
+
+	// Campos de busca normalizados (sem acentos e em minúsculas), mantidos pelos hooks
+	SearchName    string `bson:"search_name,omitempty" json:"-"`
+	SearchAddress string `bson:"search_address,omitempty" json:"-"`
 }
 
 func (c *Company) Validate() error {
@@ -149,9 +158,18 @@ func (c *Company) BeforeCreate() {
 		c.CreatedAt = now
 	}
 	c.UpdatedAt = now
+	c.Sequence = 1
+	c.RefreshSearchFields()
 }
 
 // BeforeUpdate hook para ser chamado antes de atualizar
 func (c *Company) BeforeUpdate() {
 	c.UpdatedAt = time.Now()
+	c.RefreshSearchFields()
+}
+
+// RefreshSearchFields recalcula os campos de busca normalizados a partir dos nomes e do endereço
+func (c *Company) RefreshSearchFields() {
+	c.SearchName = utils.FoldText(c.FantasyName + " " + c.CorporateName)
+	c.SearchAddress = utils.FoldText(c.Address)
 }
@@ -257,3 +257,19 @@ func TestGivenCompany_WhenPWDEqualsEmployeeCount_ThenShouldReturnNoError(t *test
 	}
 	assert.NoError(t, company.Validate())
 }
+
+func TestCompany_BeforeCreate_SetsNormalizedSearchFields(t *testing.T) {
+	// Given
+	company := &Company{
+		FantasyName:   "São João",
+		CorporateName: "São João Comércio LTDA",
+		Address:       "Avenida Paulista, 1000 - São Paulo/SP",
+	}
+
+	// When
+	company.BeforeCreate()
+
+	// Then
+	assert.Equal(t, "sao joao sao joao comercio ltda", company.SearchName)
+	assert.Equal(t, "avenida paulista 1000 sao paulo sp", company.SearchAddress)
+}
@@ -28,6 +28,7 @@ type UpdateCompanyRequest struct {
 // CompanyResponse represents the response containing company details.
 type CompanyResponse struct {
 	ID                          string    `json:"id"`
+	TenantID                    string    `json:"tenant_id"`
 	CNPJ                        string    `json:"cnpj"`
 	FantasyName                 string    `json:"fantasy_name"`
 	CorporateName               string    `json:"corporate_name"`
@@ -38,6 +39,31 @@ type CompanyResponse struct {
 	UpdatedAt                   time.Time `json:"updated_at"`
 }
 
+// BatchCompaniesRequest represents a batch of companies to create or upsert.
+// Mode is "create" (default) or "upsert" (create or update by CNPJ).
+type BatchCompaniesRequest struct {
+	Mode      string                 `json:"mode"`
+	Companies []CreateCompanyRequest `json:"companies" validate:"required"`
+}
+
+// BatchItemResponse represents the outcome of a single item of a batch.
+type BatchItemResponse struct {
+	Index  int    `json:"index"`
+	Status string `json:"status"`
+	ID     string `json:"id,omitempty"`
+	CNPJ   string `json:"cnpj"`
+	Code   string `json:"code,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// BatchCompaniesResponse represents the per-item results of a batch.
+type BatchCompaniesResponse struct {
+	Created int                 `json:"created"`
+	Updated int                 `json:"updated"`
+	Failed  int                 `json:"failed"`
+	Results []BatchItemResponse `json:"results"`
+}
+
 // ToDomainCompany converts CreateCompanyRequest to domain.Company.
 func ToDomainCompanyCreate(req *CreateCompanyRequest) *domain.Company {
 	return &domain.Company{
@@ -66,6 +92,7 @@ func ToDomainCompanyUpdate(req *UpdateCompanyRequest, id string) *domain.Company
 func FromDomainCompany(company *domain.Company) *CompanyResponse {
 	return &CompanyResponse{
 		ID:                          company.ID,
+		TenantID:                    company.TenantID,
 		CNPJ:                        company.CNPJ,
 		FantasyName:                 company.FantasyName,
 		CorporateName:               company.CorporateName,
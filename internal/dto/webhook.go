@@ -0,0 +1,53 @@
+package dto
+
+import (
+	"company-service/internal/messaging"
+	"company-service/internal/webhook"
+	"time"
+)
+
+// CreateWebhookRequest represents the request to subscribe a URL to company events.
+// Secret is generated when omitted.
+type CreateWebhookRequest struct {
+	URL        string                `json:"url" validate:"required"`
+	EventTypes []messaging.EventType `json:"event_types"`
+	Secret     string                `json:"secret,omitempty"`
+}
+
+// UpdateWebhookRequest represents a partial update of a webhook subscription.
+// Setting Active to true re-enables a disabled subscription.
+type UpdateWebhookRequest struct {
+	URL        *string               `json:"url,omitempty"`
+	EventTypes []messaging.EventType `json:"event_types,omitempty"`
+	Secret     *string               `json:"secret,omitempty"`
+	Active     *bool                 `json:"active,omitempty"`
+}
+
+// WebhookResponse represents a webhook subscription. Secret is only returned on creation.
+type WebhookResponse struct {
+	ID                  string                `json:"id"`
+	URL                 string                `json:"url"`
+	EventTypes          []messaging.EventType `json:"event_types"`
+	Secret              string                `json:"secret,omitempty"`
+	Active              bool                  `json:"active"`
+	ConsecutiveFailures int                   `json:"consecutive_failures"`
+	DisabledReason      string                `json:"disabled_reason,omitempty"`
+	DisabledAt          *time.Time            `json:"disabled_at,omitempty"`
+	CreatedAt           time.Time             `json:"created_at"`
+	UpdatedAt           time.Time             `json:"updated_at"`
+}
+
+// FromWebhookSubscription converts a subscription to its response, without the secret.
+func FromWebhookSubscription(subscription *webhook.Subscription) WebhookResponse {
+	return WebhookResponse{
+		ID:                  subscription.ID,
+		URL:                 subscription.URL,
+		EventTypes:          subscription.EventTypes,
+		Active:              subscription.Active,
+		ConsecutiveFailures: subscription.ConsecutiveFailures,
+		DisabledReason:      subscription.DisabledReason,
+		DisabledAt:          subscription.DisabledAt,
+		CreatedAt:           subscription.CreatedAt,
+		UpdatedAt:           subscription.UpdatedAt,
+	}
+}
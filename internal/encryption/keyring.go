@@ -0,0 +1,128 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+)
+
+var (
+	// ErrUnknownKey indica um dado cifrado com uma chave ausente do keyring
+	ErrUnknownKey = errors.New("chave de criptografia desconhecida")
+	// ErrInvalidCiphertext indica um dado cifrado corrompido ou adulterado
+	ErrInvalidCiphertext = errors.New("dado criptografado inválido")
+)
+
+// keyFile é o formato do arquivo de chaves:
+//
+//	{"active": "2024-01", "keys": {"2024-01": "<base64>"}, "index_key": "<base64>"}
+//
+// As chaves de dados têm 32 bytes (AES-256). Chaves antigas devem permanecer no arquivo
+// até que o re-encrypt migre todos os documentos para a chave ativa.
+type keyFile struct {
+	Active   string            `json:"active"`
+	Keys     map[string]string `json:"keys"`
+	IndexKey string            `json:"index_key"`
+}
+
+// Keyring guarda as chaves de dados identificadas por ID e a chave do índice determinístico.
+// Novos dados são sempre cifrados com a chave ativa; qualquer chave do keyring decifra.
+type Keyring struct {
+	active   string
+	aeads    map[string]cipher.AEAD
+	indexKey []byte
+}
+
+// LoadKeyring lê o keyring do arquivo informado
+func LoadKeyring(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	return ParseKeyring(data)
+}
+
+// ParseKeyring interpreta o conteúdo de um arquivo de chaves
+func ParseKeyring(data []byte) (*Keyring, error) {
+	var file keyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid key file: %w", err)
+	}
+	if _, ok := file.Keys[file.Active]; !ok {
+		return nil, fmt.Errorf("invalid key file: active key %q not found", file.Active)
+	}
+
+	k := &Keyring{active: file.Active, aeads: make(map[string]cipher.AEAD, len(file.Keys))}
+	for id, encoded := range file.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("invalid key file: key %q must be 32 bytes in base64", id)
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		k.aeads[id] = aead
+	}
+
+	indexKey, err := base64.StdEncoding.DecodeString(file.IndexKey)
+	if err != nil || len(indexKey) < 32 {
+		return nil, errors.New("invalid key file: index_key must have at least 32 bytes in base64")
+	}
+	k.indexKey = indexKey
+	return k, nil
+}
+
+// ActiveKeyID retorna o ID da chave usada para cifrar novos dados
+func (k *Keyring) ActiveKeyID() string {
+	return k.active
+}
+
+// Encrypt cifra o texto com a chave ativa. O contexto (ex.: nome do campo) é autenticado
+// junto, impedindo que um valor cifrado seja copiado para outro campo.
+// O resultado é nonce || ciphertext.
+func (k *Keyring) Encrypt(plaintext, context []byte) (keyID string, data []byte, err error) {
+	aead := k.aeads[k.active]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, err
+	}
+	return k.active, aead.Seal(nonce, nonce, plaintext, context), nil
+}
+
+// Decrypt decifra um valor produzido por Encrypt com a chave de ID keyID
+func (k *Keyring) Decrypt(keyID string, data, context []byte) ([]byte, error) {
+	aead, ok := k.aeads[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+	if len(data) < aead.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, context)
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+	return plaintext, nil
+}
+
+// BlindIndex calcula o HMAC-SHA256 determinístico de um valor, permitindo consultas por
+// igualdade sem armazenar o valor em claro
+func (k *Keyring) BlindIndex(value string) string {
+	mac := hmac.New(sha256.New, k.indexKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,108 @@
+package encryption
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func key(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(rune(b)), 32)))
+}
+
+func keyFileFor(active string, ids ...string) []byte {
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = `"` + id + `": "` + key(byte('a'+i)) + `"`
+	}
+	return []byte(`{"active": "` + active + `", "keys": {` + strings.Join(keys, ",") + `}, "index_key": "` + key('z') + `"}`)
+}
+
+func TestKeyring_EncryptDecrypt(t *testing.T) {
+	k, err := ParseKeyring(keyFileFor("k1", "k1"))
+	require.NoError(t, err)
+
+	keyID, data, err := k.Encrypt([]byte("Rua A, 100"), []byte("address"))
+	require.NoError(t, err)
+	assert.Equal(t, "k1", keyID)
+	assert.NotContains(t, string(data), "Rua A")
+
+	plaintext, err := k.Decrypt(keyID, data, []byte("address"))
+	require.NoError(t, err)
+	assert.Equal(t, "Rua A, 100", string(plaintext))
+
+	// O mesmo valor cifrado duas vezes gera resultados diferentes (nonce aleatório)
+	_, again, err := k.Encrypt([]byte("Rua A, 100"), []byte("address"))
+	require.NoError(t, err)
+	assert.NotEqual(t, data, again)
+}
+
+func TestKeyring_DecryptRejectsOtherContextAndTampering(t *testing.T) {
+	k, err := ParseKeyring(keyFileFor("k1", "k1"))
+	require.NoError(t, err)
+
+	keyID, data, err := k.Encrypt([]byte("42"), []byte("employee_count"))
+	require.NoError(t, err)
+
+	_, err = k.Decrypt(keyID, data, []byte("address"))
+	assert.ErrorIs(t, err, ErrInvalidCiphertext)
+
+	data[len(data)-1] ^= 0xff
+	_, err = k.Decrypt(keyID, data, []byte("employee_count"))
+	assert.ErrorIs(t, err, ErrInvalidCiphertext)
+
+	_, err = k.Decrypt(keyID, []byte{1, 2}, []byte("employee_count"))
+	assert.ErrorIs(t, err, ErrInvalidCiphertext)
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	old, err := ParseKeyring(keyFileFor("k1", "k1"))
+	require.NoError(t, err)
+	keyID, data, err := old.Encrypt([]byte("segredo"), nil)
+	require.NoError(t, err)
+
+	rotated, err := ParseKeyring(keyFileFor("k2", "k1", "k2"))
+	require.NoError(t, err)
+	assert.Equal(t, "k2", rotated.ActiveKeyID())
+
+	plaintext, err := rotated.Decrypt(keyID, data, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "segredo", string(plaintext))
+
+	newID, _, err := rotated.Encrypt([]byte("segredo"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, "k2", newID)
+
+	_, err = rotated.Decrypt("k9", data, nil)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestKeyring_BlindIndex(t *testing.T) {
+	k1, err := ParseKeyring(keyFileFor("k1", "k1"))
+	require.NoError(t, err)
+	k2, err := ParseKeyring(keyFileFor("k2", "k1", "k2"))
+	require.NoError(t, err)
+
+	// Determinístico e independente da chave de dados ativa
+	assert.Equal(t, k1.BlindIndex("11222333000181"), k2.BlindIndex("11222333000181"))
+	assert.NotEqual(t, k1.BlindIndex("11222333000181"), k1.BlindIndex("11222333000182"))
+	assert.NotContains(t, k1.BlindIndex("11222333000181"), "11222333000181")
+}
+
+func TestParseKeyring_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"json":          `{`,
+		"active":        `{"active": "k2", "keys": {"k1": "` + key('a') + `"}, "index_key": "` + key('z') + `"}`,
+		"key size":      `{"active": "k1", "keys": {"k1": "c2hvcnQ="}, "index_key": "` + key('z') + `"}`,
+		"missing index": `{"active": "k1", "keys": {"k1": "` + key('a') + `"}}`,
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseKeyring([]byte(data))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"company-service/internal/repository/cache"
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+type CacheHandler struct {
+	cache  *cache.Repository
+	logger *zap.Logger
+}
+
+func NewCacheHandler(cache *cache.Repository, logger *zap.Logger) *CacheHandler {
+	return &CacheHandler{
+		cache:  cache,
+		logger: logger,
+	}
+}
+
+// StatsHandler retorna as estatísticas de acertos e falhas do cache de leitura
+func (h *CacheHandler) StatsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.cache.Stats()); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
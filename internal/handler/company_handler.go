@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"company-service/internal/domain"
 	"company-service/internal/dto"
+	"company-service/internal/repository"
 	"company-service/internal/service"
 	"company-service/pkg/utils"
 	"encoding/json"
@@ -52,6 +54,66 @@ func (h *CompanyHandler) CreateCompanyHandler(w http.ResponseWriter, r *http.Req
 	}
 }
 
+// BatchCompaniesHandler lida com a criação (ou upsert por CNPJ) de empresas em lote
+func (h *CompanyHandler) BatchCompaniesHandler(w http.ResponseWriter, r *http.Request) {
+	var req dto.BatchCompaniesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Failed to decode request body", zap.Error(err))
+		http.Error(w, `{"error": "Invalid JSON format"}`, http.StatusBadRequest)
+		return
+	}
+
+	var upsert bool
+	switch req.Mode {
+	case "", "create":
+	case "upsert":
+		upsert = true
+	default:
+		http.Error(w, `{"error": "Invalid batch mode, expected create or upsert"}`, http.StatusBadRequest)
+		return
+	}
+
+	h.logger.Info("Received request to batch companies",
+		zap.Int("count", len(req.Companies)),
+		zap.Bool("upsert", upsert))
+
+	companies := make([]*domain.Company, len(req.Companies))
+	for i := range req.Companies {
+		companies[i] = dto.ToDomainCompanyCreate(&req.Companies[i])
+	}
+
+	results, err := h.service.BatchCompanies(r.Context(), companies, upsert)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	response := dto.BatchCompaniesResponse{Results: make([]dto.BatchItemResponse, len(results))}
+	for i, result := range results {
+		item := dto.BatchItemResponse{Index: i, Status: string(result.Status), CNPJ: result.Company.CNPJ}
+		switch result.Status {
+		case repository.BulkCreated:
+			response.Created++
+			item.ID = result.Company.ID
+		case repository.BulkUpdated:
+			response.Updated++
+			item.ID = result.Company.ID
+		default:
+			response.Failed++
+			item.Error = result.Err.Error()
+			if serviceErr, ok := result.Err.(*service.ServiceError); ok {
+				item.Code = serviceErr.Code
+			}
+		}
+		response.Results[i] = item
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
 // GetCompanyHandler lida com a busca de uma empresa por ID
 func (h *CompanyHandler) GetCompanyHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -139,7 +201,7 @@ func (h *CompanyHandler) DeleteCompanyHandler(w http.ResponseWriter, r *http.Req
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// ListCompaniesHandler lida com a listagem de empresas com paginação
+// ListCompaniesHandler lida com a listagem de empresas com filtros, ordenação e paginação
 func (h *CompanyHandler) ListCompaniesHandler(w http.ResponseWriter, r *http.Request) {
 	h.logger.Info("Received request to list companies")
 
@@ -154,7 +216,14 @@ func (h *CompanyHandler) ListCompaniesHandler(w http.ResponseWriter, r *http.Req
 		limit = 20
 	}
 
-	companies, err := h.service.ListCompanies(r.Context(), page, limit)
+	filter, err := parseCompanyFilter(r.URL.Query())
+	if err != nil {
+		h.logger.Warn("Invalid list filter", zap.Error(err))
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+
+	companies, err := h.service.ListCompanies(r.Context(), filter, page, limit)
 	if err != nil {
 		h.handleServiceError(w, err)
 		return
@@ -173,16 +242,119 @@ func (h *CompanyHandler) ListCompaniesHandler(w http.ResponseWriter, r *http.Req
 	}
 }
 
+// ListAllCompaniesHandler lida com a listagem administrativa de empresas de todos os tenants.
+// O parâmetro tenant restringe a listagem a um tenant específico.
+func (h *CompanyHandler) ListAllCompaniesHandler(w http.ResponseWriter, r *http.Request) {
+	h.logger.Info("Received request to list companies across tenants")
+
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	if page < 1 {
+		page = 1
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	filter, err := parseCompanyFilter(r.URL.Query())
+	if err != nil {
+		h.logger.Warn("Invalid list filter", zap.Error(err))
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+	filter.TenantID = r.URL.Query().Get("tenant")
+
+	companies, err := h.service.ListAllCompanies(r.Context(), filter, page, limit)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"page":      page,
+		"limit":     limit,
+		"tenant":    filter.TenantID,
+		"companies": companies,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+// SearchCompaniesHandler lida com a busca textual de empresas por nome ou endereço
+func (h *CompanyHandler) SearchCompaniesHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("q")
+	h.logger.Info("Received request to search companies", zap.String("query", query))
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	companies, err := h.service.SearchCompanies(r.Context(), query, limit)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"query":     query,
+		"limit":     limit,
+		"companies": companies,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+// AutocompleteCompaniesHandler lida com as sugestões de empresas por prefixo de nome ou CNPJ
+func (h *CompanyHandler) AutocompleteCompaniesHandler(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+
+	suggestions, err := h.service.AutocompleteCompanies(r.Context(), prefix, limit)
+	if err != nil {
+		h.handleServiceError(w, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"prefix":      prefix,
+		"limit":       limit,
+		"suggestions": suggestions,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
 // handleServiceError trata os erros do service layer e retorna respostas HTTP apropriadas
 func (h *CompanyHandler) handleServiceError(w http.ResponseWriter, err error) {
 	if serviceErr, ok := err.(*service.ServiceError); ok {
 		switch serviceErr.Code {
-		case "VALIDATION_ERROR", "CNPJ_CONFLICT":
+		case "VALIDATION_ERROR", "CNPJ_CONFLICT", "TENANT_REQUIRED":
 			h.logger.Warn("Validation error", zap.Error(err))
 			http.Error(w, `{"error": "`+serviceErr.Error()+`"}`, http.StatusBadRequest)
 		case "NOT_FOUND":
 			h.logger.Warn("Resource not found", zap.Error(err))
 			http.Error(w, `{"error": "`+serviceErr.Error()+`"}`, http.StatusNotFound)
+		case "FORBIDDEN":
+			h.logger.Warn("Forbidden operation", zap.Error(err))
+			http.Error(w, `{"error": "`+serviceErr.Error()+`"}`, http.StatusForbidden)
+		case "UNAVAILABLE":
+			h.logger.Error("Service unavailable", zap.Error(err))
+			http.Error(w, `{"error": "`+serviceErr.Error()+`"}`, http.StatusServiceUnavailable)
 		default:
 			h.logger.Error("Service error", zap.Error(err))
 			http.Error(w, `{"error": "Internal server error"}`, http.StatusInternalServerError)
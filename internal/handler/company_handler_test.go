@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"company-service/internal/domain"
+	"company-service/internal/dto"
+	"company-service/internal/repository"
+	"company-service/internal/service"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeCompanyService implementa apenas BatchCompanies; os demais métodos não são usados
+type fakeCompanyService struct {
+	service.CompanyService
+	batch func(companies []*domain.Company, upsert bool) ([]repository.BulkResult, error)
+
+	received []*domain.Company
+	upsert   bool
+}
+
+func (s *fakeCompanyService) BatchCompanies(ctx context.Context, companies []*domain.Company, upsert bool) ([]repository.BulkResult, error) {
+	s.received, s.upsert = companies, upsert
+	return s.batch(companies, upsert)
+}
+
+func postBatch(t *testing.T, svc service.CompanyService, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	h := NewCompanyHandler(svc, zap.NewNop())
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/companies/batch", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.BatchCompaniesHandler(rec, req)
+	return rec
+}
+
+func TestBatchCompaniesHandler_MapsEveryItem(t *testing.T) {
+	svc := &fakeCompanyService{batch: func(companies []*domain.Company, upsert bool) ([]repository.BulkResult, error) {
+		companies[0].ID = "id-1"
+		companies[1].ID = "id-2"
+		return []repository.BulkResult{
+			{Status: repository.BulkCreated, Company: companies[0]},
+			{Status: repository.BulkUpdated, Company: companies[1]},
+			{Status: repository.BulkFailed, Company: companies[2],
+				Err: service.NewServiceError(repository.ErrDuplicateCNPJ, "CNPJ 11222333000181 repetido no lote", "CNPJ_CONFLICT")},
+			{Status: repository.BulkFailed, Company: companies[3], Err: errors.New("falha inesperada")},
+		}, nil
+	}}
+	body := `{"mode": "upsert", "companies": [
+		{"cnpj": "11222333000181", "fantasy_name": "Nova"},
+		{"cnpj": "11444777000161", "fantasy_name": "Atualizada"},
+		{"cnpj": "11222333000181", "fantasy_name": "Repetida"},
+		{"cnpj": "47960950000121", "fantasy_name": "Com Falha"}
+	]}`
+
+	rec := postBatch(t, svc, body)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.True(t, svc.upsert)
+	require.Len(t, svc.received, 4)
+	assert.Equal(t, "Repetida", svc.received[2].FantasyName)
+
+	var resp dto.BatchCompaniesResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, 1, resp.Created)
+	assert.Equal(t, 1, resp.Updated)
+	assert.Equal(t, 2, resp.Failed)
+	assert.Equal(t, []dto.BatchItemResponse{
+		{Index: 0, Status: "created", ID: "id-1", CNPJ: "11222333000181"},
+		{Index: 1, Status: "updated", ID: "id-2", CNPJ: "11444777000161"},
+		{Index: 2, Status: "failed", CNPJ: "11222333000181", Code: "CNPJ_CONFLICT", Error: "CNPJ 11222333000181 repetido no lote"},
+		{Index: 3, Status: "failed", CNPJ: "47960950000121", Error: "falha inesperada"},
+	}, resp.Results)
+}
+
+func TestBatchCompaniesHandler_DefaultModeIsCreate(t *testing.T) {
+	svc := &fakeCompanyService{batch: func(companies []*domain.Company, upsert bool) ([]repository.BulkResult, error) {
+		return []repository.BulkResult{{Status: repository.BulkCreated, Company: companies[0]}}, nil
+	}}
+
+	rec := postBatch(t, svc, `{"companies": [{"cnpj": "11222333000181"}]}`)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.False(t, svc.upsert)
+}
+
+func TestBatchCompaniesHandler_RejectsRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		err      error
+		wantCode int
+	}{
+		{name: "invalid JSON", body: `{"companies": [`, wantCode: http.StatusBadRequest},
+		{name: "invalid mode", body: `{"mode": "replace", "companies": []}`, wantCode: http.StatusBadRequest},
+		{
+			name:     "batch too large",
+			body:     `{"companies": []}`,
+			err:      service.NewServiceError(nil, "lote excede o limite de 1000 empresas", "VALIDATION_ERROR"),
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "tenant required",
+			body:     `{"companies": []}`,
+			err:      service.NewServiceError(nil, "tenant obrigatório", "TENANT_REQUIRED"),
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "repository failure",
+			body:     `{"companies": []}`,
+			err:      service.NewServiceError(errors.New("timeout"), "", "REPOSITORY_ERROR"),
+			wantCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called := false
+			svc := &fakeCompanyService{batch: func(companies []*domain.Company, upsert bool) ([]repository.BulkResult, error) {
+				called = true
+				return nil, tt.err
+			}}
+
+			rec := postBatch(t, svc, tt.body)
+
+			assert.Equal(t, tt.wantCode, rec.Code)
+			assert.Equal(t, tt.err != nil, called)
+		})
+	}
+}
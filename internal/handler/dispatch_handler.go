@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"company-service/internal/messaging/dispatch"
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+type DispatchHandler struct {
+	dispatcher *dispatch.Dispatcher
+	logger     *zap.Logger
+}
+
+func NewDispatchHandler(dispatcher *dispatch.Dispatcher, logger *zap.Logger) *DispatchHandler {
+	return &DispatchHandler{
+		dispatcher: dispatcher,
+		logger:     logger,
+	}
+}
+
+// StatsHandler retorna a ocupação da fila e os contadores do dispatcher de eventos
+func (h *DispatchHandler) StatsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(h.dispatcher.Stats()); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
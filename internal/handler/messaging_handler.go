@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"company-service/internal/messaging"
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+type MessagingHandler struct {
+	producer messaging.MessageProducer
+	logger   *zap.Logger
+}
+
+func NewMessagingHandler(producer messaging.MessageProducer, logger *zap.Logger) *MessagingHandler {
+	return &MessagingHandler{
+		producer: producer,
+		logger:   logger,
+	}
+}
+
+type messagingHealthResponse struct {
+	Healthy bool        `json:"healthy"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+// StatsHandler retorna as estatísticas de publicação: confirmações, rejeições,
+// mensagens sem rota e latência das confirmações do broker
+func (h *MessagingHandler) StatsHandler(w http.ResponseWriter, r *http.Request) {
+	reporter, ok := h.producer.(messaging.StatsReporter)
+	if !ok {
+		http.Error(w, `{"error": "Publisher statistics not available"}`, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(reporter.Stats()); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+// HealthHandler informa se o broker está disponível para publicação, com os detalhes
+// fornecidos pelo produtor; responde 503 quando indisponível
+func (h *MessagingHandler) HealthHandler(w http.ResponseWriter, r *http.Request) {
+	var response messagingHealthResponse
+	switch checker := h.producer.(type) {
+	case messaging.HealthReporter:
+		response = messagingHealthResponse{Healthy: checker.Healthy(), Details: checker.HealthDetails()}
+	case messaging.HealthChecker:
+		response = messagingHealthResponse{Healthy: checker.Healthy()}
+	default:
+		http.Error(w, `{"error": "Broker health not available"}`, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !response.Healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"company-service/internal/repository"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// parseCompanyFilter converte os parâmetros de query da listagem em um filtro tipado.
+//
+// Parâmetros aceitos: cnpj (prefixo), name (substring), min_employees, max_employees,
+// created_from, created_to, updated_from, updated_to (RFC3339 ou AAAA-MM-DD),
+// requires_pwd (true/false) e sort (ex.: "-employee_count,fantasy_name").
+func parseCompanyFilter(query url.Values) (repository.CompanyFilter, error) {
+	var filter repository.CompanyFilter
+	var err error
+
+	filter.CNPJPrefix = query.Get("cnpj")
+	filter.Name = query.Get("name")
+
+	if filter.MinEmployeeCount, err = parseIntParam(query, "min_employees"); err != nil {
+		return filter, err
+	}
+	if filter.MaxEmployeeCount, err = parseIntParam(query, "max_employees"); err != nil {
+		return filter, err
+	}
+	if filter.CreatedFrom, err = parseTimeParam(query, "created_from", false); err != nil {
+		return filter, err
+	}
+	if filter.CreatedTo, err = parseTimeParam(query, "created_to", true); err != nil {
+		return filter, err
+	}
+	if filter.UpdatedFrom, err = parseTimeParam(query, "updated_from", false); err != nil {
+		return filter, err
+	}
+	if filter.UpdatedTo, err = parseTimeParam(query, "updated_to", true); err != nil {
+		return filter, err
+	}
+
+	if raw := query.Get("requires_pwd"); raw != "" {
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return filter, fmt.Errorf("parâmetro requires_pwd inválido: %s", raw)
+		}
+		filter.RequiresPWD = &value
+	}
+
+	if filter.Sort, err = repository.ParseSort(query.Get("sort")); err != nil {
+		return filter, err
+	}
+
+	return filter, filter.Validate()
+}
+
+func parseIntParam(query url.Values, name string) (*int, error) {
+	raw := query.Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parâmetro %s inválido: %s", name, raw)
+	}
+	return &value, nil
+}
+
+// parseTimeParam aceita RFC3339 ou apenas a data; quando endOfDay é verdadeiro,
+// uma data sem horário cobre o dia inteiro.
+func parseTimeParam(query url.Values, name string, endOfDay bool) (*time.Time, error) {
+	raw := query.Get(name)
+	if raw == "" {
+		return nil, nil
+	}
+	if value, err := time.Parse(time.RFC3339, raw); err == nil {
+		return &value, nil
+	}
+	value, err := time.Parse(time.DateOnly, raw)
+	if err != nil {
+		return nil, fmt.Errorf("parâmetro %s inválido: %s", name, raw)
+	}
+	if endOfDay {
+		value = value.Add(24*time.Hour - time.Nanosecond)
+	}
+	return &value, nil
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"company-service/internal/replay"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+type ReplayHandler struct {
+	replayer         *replay.Replayer
+	defaultRateLimit float64
+	logger           *zap.Logger
+}
+
+// NewReplayHandler cria o handler do replay de eventos; defaultRateLimit é o limite de eventos
+// por segundo usado quando a requisição não informa rate_limit
+func NewReplayHandler(replayer *replay.Replayer, defaultRateLimit float64, logger *zap.Logger) *ReplayHandler {
+	return &ReplayHandler{
+		replayer:         replayer,
+		defaultRateLimit: defaultRateLimit,
+		logger:           logger,
+	}
+}
+
+type replayRequest struct {
+	IDs          []string   `json:"ids"`
+	CNPJs        []string   `json:"cnpjs"`
+	UpdatedSince *time.Time `json:"updated_since"`
+	TenantID     string     `json:"tenant_id"`
+	EventType    string     `json:"event_type"` // created ou updated (padrão)
+	RateLimit    *float64   `json:"rate_limit"` // eventos por segundo; 0 não limita
+	DryRun       bool       `json:"dry_run"`
+}
+
+// StartHandler inicia o replay dos eventos das empresas selecionadas em segundo plano e
+// responde 202 com o ID da execução; com dry_run apenas retorna a quantidade selecionada
+func (h *ReplayHandler) StartHandler(w http.ResponseWriter, r *http.Request) {
+	var req replayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Failed to decode request body", zap.Error(err))
+		http.Error(w, `{"error": "Invalid JSON format"}`, http.StatusBadRequest)
+		return
+	}
+
+	eventType, err := replay.ParseEventType(req.EventType)
+	if err != nil {
+		http.Error(w, `{"error": "Invalid event_type, expected created or updated"}`, http.StatusBadRequest)
+		return
+	}
+	opts := replay.Options{EventType: eventType, RateLimit: h.defaultRateLimit}
+	if req.RateLimit != nil {
+		if *req.RateLimit < 0 {
+			http.Error(w, `{"error": "Invalid rate_limit"}`, http.StatusBadRequest)
+			return
+		}
+		opts.RateLimit = *req.RateLimit
+	}
+	filter := replay.Filter{IDs: req.IDs, CNPJs: req.CNPJs, UpdatedSince: req.UpdatedSince, TenantID: req.TenantID}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if req.DryRun {
+		matched, err := h.replayer.Count(r.Context(), filter)
+		if err != nil {
+			h.logger.Error("Failed to count companies for replay", zap.Error(err))
+			http.Error(w, `{"error": "Internal server error"}`, http.StatusInternalServerError)
+			return
+		}
+		if err := json.NewEncoder(w).Encode(map[string]interface{}{"dry_run": true, "matched": matched}); err != nil {
+			h.logger.Error("Failed to encode response", zap.Error(err))
+		}
+		return
+	}
+
+	run, err := h.replayer.Start(filter, opts)
+	if err != nil {
+		h.handleReplayError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(run); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+// ListHandler retorna os replays recentes, do mais novo para o mais antigo
+func (h *ReplayHandler) ListHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"replays": h.replayer.Runs()}); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+// GetHandler retorna o estado e a contagem de um replay
+func (h *ReplayHandler) GetHandler(w http.ResponseWriter, r *http.Request) {
+	run, err := h.replayer.Get(mux.Vars(r)["id"])
+	if err != nil {
+		h.handleReplayError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(run); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+// CancelHandler interrompe um replay em andamento
+func (h *ReplayHandler) CancelHandler(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	if err := h.replayer.Cancel(id); err != nil {
+		h.handleReplayError(w, err)
+		return
+	}
+
+	h.logger.Warn("Event replay canceled", zap.String("replay_id", id))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *ReplayHandler) handleReplayError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, replay.ErrInvalidEventType):
+		http.Error(w, `{"error": "Invalid event_type, expected created or updated"}`, http.StatusBadRequest)
+	case errors.Is(err, replay.ErrAlreadyRunning):
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusConflict)
+	case errors.Is(err, replay.ErrRunNotFound):
+		http.Error(w, `{"error": "Replay not found"}`, http.StatusNotFound)
+	default:
+		h.logger.Error("Event replay error", zap.Error(err))
+		http.Error(w, `{"error": "Internal server error"}`, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"company-service/internal/schema"
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// schemaContentType é o media type dos documentos JSON Schema
+const schemaContentType = "application/schema+json"
+
+type SchemaHandler struct {
+	docs   []schema.Document
+	logger *zap.Logger
+}
+
+// NewSchemaHandler gera os schemas publicados uma única vez, na inicialização
+func NewSchemaHandler(logger *zap.Logger) *SchemaHandler {
+	return &SchemaHandler{
+		docs:   schema.All(),
+		logger: logger,
+	}
+}
+
+type schemaEntry struct {
+	Name  string `json:"name"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+// ListHandler lista os schemas publicados dos eventos e dos payloads da API
+func (h *SchemaHandler) ListHandler(w http.ResponseWriter, r *http.Request) {
+	entries := make([]schemaEntry, len(h.docs))
+	for i, doc := range h.docs {
+		entries[i] = schemaEntry{Name: doc.Name, Title: doc.Schema.Title, URL: doc.Path()}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+// GetHandler retorna um schema pelo grupo e nome (ex.: /schemas/events/br.company.created.v1.json)
+func (h *SchemaHandler) GetHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	doc, ok := h.find(vars["group"] + "/" + vars["name"])
+	if !ok {
+		http.Error(w, `{"error": "Schema not found"}`, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", schemaContentType)
+	if err := json.NewEncoder(w).Encode(doc.Schema); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+func (h *SchemaHandler) find(name string) (schema.Document, bool) {
+	for _, doc := range h.docs {
+		if doc.Name == name || doc.Path() == "/schemas/"+name {
+			return doc, true
+		}
+	}
+	return schema.Document{}, false
+}
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"company-service/internal/messaging/spool"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+type SpoolHandler struct {
+	spool  *spool.Spool
+	logger *zap.Logger
+}
+
+func NewSpoolHandler(spool *spool.Spool, logger *zap.Logger) *SpoolHandler {
+	return &SpoolHandler{
+		spool:  spool,
+		logger: logger,
+	}
+}
+
+type spoolResponse struct {
+	Pending int           `json:"pending"`
+	Entries []spool.Entry `json:"entries"`
+}
+
+// ListHandler retorna os eventos pendentes no spool, na ordem de reentrega; "limit" restringe
+// a quantidade de eventos retornados
+func (h *SpoolHandler) ListHandler(w http.ResponseWriter, r *http.Request) {
+	entries := h.spool.Pending()
+	response := spoolResponse{Pending: len(entries), Entries: entries}
+
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 0 {
+			http.Error(w, `{"error": "Invalid limit"}`, http.StatusBadRequest)
+			return
+		}
+		if limit < len(entries) {
+			response.Entries = entries[:limit]
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+// PurgeHandler descarta todos os eventos pendentes no spool
+func (h *SpoolHandler) PurgeHandler(w http.ResponseWriter, r *http.Request) {
+	purged, err := h.spool.Purge()
+	if err != nil {
+		h.logger.Error("Failed to purge spool", zap.Error(err))
+		http.Error(w, `{"error": "Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Warn("Spool purged", zap.Int("purged", purged))
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]int{"purged": purged}); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+// DiscardHandler descarta um evento do spool pela sequência
+func (h *SpoolHandler) DiscardHandler(w http.ResponseWriter, r *http.Request) {
+	seq, err := strconv.ParseUint(mux.Vars(r)["seq"], 10, 64)
+	if err != nil {
+		http.Error(w, `{"error": "Invalid sequence"}`, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.spool.Ack(seq); err != nil {
+		if errors.Is(err, spool.ErrNotFound) {
+			http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusNotFound)
+			return
+		}
+		h.logger.Error("Failed to discard spool entry", zap.Uint64("seq", seq), zap.Error(err))
+		http.Error(w, `{"error": "Internal server error"}`, http.StatusInternalServerError)
+		return
+	}
+
+	h.logger.Warn("Spool entry discarded", zap.Uint64("seq", seq))
+	w.WriteHeader(http.StatusNoContent)
+}
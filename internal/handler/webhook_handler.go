@@ -0,0 +1,284 @@
+package handler
+
+import (
+	"company-service/internal/dto"
+	"company-service/internal/tenant"
+	"company-service/internal/webhook"
+	"company-service/pkg/utils"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+type WebhookHandler struct {
+	store  webhook.Store
+	logger *zap.Logger
+}
+
+func NewWebhookHandler(store webhook.Store, logger *zap.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		store:  store,
+		logger: logger,
+	}
+}
+
+// CreateHandler cadastra um webhook para o tenant da requisição. O segredo usado nas
+// assinaturas das entregas só é retornado nesta resposta.
+func (h *WebhookHandler) CreateHandler(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := h.requireTenant(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Failed to decode request body", zap.Error(err))
+		http.Error(w, `{"error": "Invalid JSON format"}`, http.StatusBadRequest)
+		return
+	}
+
+	subscription, err := webhook.NewSubscription(tenantID, req.URL, req.EventTypes, req.Secret)
+	if err != nil {
+		h.handleWebhookError(w, err)
+		return
+	}
+	if err := h.store.CreateSubscription(r.Context(), subscription); err != nil {
+		h.handleWebhookError(w, err)
+		return
+	}
+
+	h.logger.Info("Webhook created",
+		zap.String("id", subscription.ID),
+		zap.String("tenant_id", tenantID),
+		zap.String("url", subscription.URL))
+
+	response := dto.FromWebhookSubscription(subscription)
+	response.Secret = subscription.Secret
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+// ListHandler lista os webhooks do tenant
+func (h *WebhookHandler) ListHandler(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := h.requireTenant(w, r)
+	if !ok {
+		return
+	}
+
+	subscriptions, err := h.store.ListSubscriptions(r.Context(), tenantID)
+	if err != nil {
+		h.handleWebhookError(w, err)
+		return
+	}
+
+	webhooks := make([]dto.WebhookResponse, len(subscriptions))
+	for i, subscription := range subscriptions {
+		webhooks[i] = dto.FromWebhookSubscription(subscription)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"webhooks": webhooks}); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+// GetHandler retorna um webhook do tenant
+func (h *WebhookHandler) GetHandler(w http.ResponseWriter, r *http.Request) {
+	subscription, ok := h.loadSubscription(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(dto.FromWebhookSubscription(subscription)); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+// UpdateHandler altera a URL, os tipos de evento, o segredo ou o estado do webhook. Reativar
+// um webhook desativado zera o contador de falhas consecutivas.
+func (h *WebhookHandler) UpdateHandler(w http.ResponseWriter, r *http.Request) {
+	subscription, ok := h.loadSubscription(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.UpdateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.logger.Error("Failed to decode request body", zap.Error(err))
+		http.Error(w, `{"error": "Invalid JSON format"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.URL != nil {
+		subscription.URL = *req.URL
+	}
+	if req.EventTypes != nil {
+		subscription.EventTypes = req.EventTypes
+	}
+	if req.Secret != nil {
+		subscription.Secret = *req.Secret
+	}
+	if req.Active != nil && *req.Active != subscription.Active {
+		subscription.Active = *req.Active
+		if subscription.Active {
+			subscription.ConsecutiveFailures = 0
+			subscription.DisabledReason = ""
+			subscription.DisabledAt = nil
+		} else {
+			now := time.Now().UTC()
+			subscription.DisabledReason = "desativado pelo usuário"
+			subscription.DisabledAt = &now
+		}
+	}
+	subscription.UpdatedAt = time.Now().UTC()
+
+	if err := subscription.Validate(); err != nil {
+		h.handleWebhookError(w, err)
+		return
+	}
+	if err := h.store.UpdateSubscription(r.Context(), subscription); err != nil {
+		h.handleWebhookError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(dto.FromWebhookSubscription(subscription)); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+// DeleteHandler remove o webhook; o log de entregas é mantido até expirar
+func (h *WebhookHandler) DeleteHandler(w http.ResponseWriter, r *http.Request) {
+	tenantID, ok := h.requireTenant(w, r)
+	if !ok {
+		return
+	}
+	id, ok := webhookID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.store.DeleteSubscription(r.Context(), tenantID, id); err != nil {
+		h.handleWebhookError(w, err)
+		return
+	}
+
+	h.logger.Info("Webhook deleted", zap.String("id", id), zap.String("tenant_id", tenantID))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveriesHandler retorna o log de entregas do webhook, das mais recentes para as mais
+// antigas; "limit" restringe a quantidade (padrão 50, máximo 500)
+func (h *WebhookHandler) ListDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	subscription, ok := h.loadSubscription(w, r)
+	if !ok {
+		return
+	}
+
+	limit := 50
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 || parsed > 500 {
+			http.Error(w, `{"error": "Invalid limit, expected 1 to 500"}`, http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	deliveries, err := h.store.ListDeliveries(r.Context(), subscription.TenantID, subscription.ID, limit)
+	if err != nil {
+		h.handleWebhookError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]interface{}{"limit": limit, "deliveries": deliveries}); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+// GetDeliveryHandler retorna uma entrega do webhook com todas as tentativas
+func (h *WebhookHandler) GetDeliveryHandler(w http.ResponseWriter, r *http.Request) {
+	subscription, ok := h.loadSubscription(w, r)
+	if !ok {
+		return
+	}
+
+	deliveryID := mux.Vars(r)["delivery_id"]
+	if !utils.IsValidObjectID(deliveryID) {
+		http.Error(w, `{"error": "Invalid delivery ID format"}`, http.StatusBadRequest)
+		return
+	}
+
+	delivery, err := h.store.GetDelivery(r.Context(), subscription.TenantID, subscription.ID, deliveryID)
+	if err != nil {
+		h.handleWebhookError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(delivery); err != nil {
+		h.logger.Error("Failed to encode response", zap.Error(err))
+	}
+}
+
+// loadSubscription busca o webhook {id} do tenant da requisição, respondendo o erro se falhar
+func (h *WebhookHandler) loadSubscription(w http.ResponseWriter, r *http.Request) (*webhook.Subscription, bool) {
+	tenantID, ok := h.requireTenant(w, r)
+	if !ok {
+		return nil, false
+	}
+	id, ok := webhookID(w, r)
+	if !ok {
+		return nil, false
+	}
+
+	subscription, err := h.store.GetSubscription(r.Context(), tenantID, id)
+	if err != nil {
+		h.handleWebhookError(w, err)
+		return nil, false
+	}
+	return subscription, true
+}
+
+func (h *WebhookHandler) requireTenant(w http.ResponseWriter, r *http.Request) (string, bool) {
+	tenantID, ok := tenant.FromContext(r.Context())
+	if !ok {
+		http.Error(w, `{"error": "Tenant not specified"}`, http.StatusBadRequest)
+		return "", false
+	}
+	return tenantID, true
+}
+
+func webhookID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	id := mux.Vars(r)["id"]
+	if !utils.IsValidObjectID(id) {
+		http.Error(w, `{"error": "Invalid webhook ID format"}`, http.StatusBadRequest)
+		return "", false
+	}
+	return id, true
+}
+
+func (h *WebhookHandler) handleWebhookError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, webhook.ErrInvalidSubscription):
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+	case errors.Is(err, webhook.ErrSubscriptionNotFound):
+		http.Error(w, `{"error": "Webhook not found"}`, http.StatusNotFound)
+	case errors.Is(err, webhook.ErrDeliveryNotFound):
+		http.Error(w, `{"error": "Delivery not found"}`, http.StatusNotFound)
+	default:
+		h.logger.Error("Webhook store error", zap.Error(err))
+		http.Error(w, `{"error": "Internal server error"}`, http.StatusInternalServerError)
+	}
+}
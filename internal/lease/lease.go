@@ -0,0 +1,192 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// Collection é a coleção padrão das leases
+const Collection = "leases"
+
+var (
+	// ErrHeld indica que a lease pertence a outra instância
+	ErrHeld = errors.New("lease is held by another instance")
+	// ErrLost indica que a lease expirou ou foi obtida por outra instância enquanto era mantida
+	ErrLost = errors.New("lease lost")
+)
+
+// Lease é um lock distribuído baseado em um documento único com expiração. Se a instância
+// que a detém morrer, a lease expira após ttl; enquanto é usada, a validade é estendida por Renew.
+type Lease struct {
+	collection *mongo.Collection
+	name       string
+	owner      string
+	ttl        time.Duration
+}
+
+// New cria a lease name na coleção informada, identificada por um owner único desta instância
+func New(db *mongo.Database, collectionName, name string, ttl time.Duration) *Lease {
+	host, _ := os.Hostname()
+	return &Lease{
+		collection: db.Collection(collectionName),
+		name:       name,
+		owner:      fmt.Sprintf("%s-%d-%d", host, os.Getpid(), time.Now().UnixNano()),
+		ttl:        ttl,
+	}
+}
+
+// Owner identifica esta instância como detentora da lease
+func (l *Lease) Owner() string {
+	return l.owner
+}
+
+// TTL é a validade da lease a cada aquisição ou renovação
+func (l *Lease) TTL() time.Duration {
+	return l.ttl
+}
+
+// Acquire obtém a lease se estiver livre ou expirada. Com a lease ativa em outra
+// instância, o filtro não casa e o upsert falha com chave duplicada.
+func (l *Lease) Acquire(ctx context.Context) error {
+	now := time.Now()
+	filter := bson.M{
+		"_id": l.name,
+		"$or": bson.A{
+			bson.M{"expires_at": bson.M{"$lt": now}},
+			bson.M{"owner": l.owner},
+		},
+	}
+	update := bson.M{"$set": bson.M{
+		"owner":      l.owner,
+		"locked_at":  now,
+		"expires_at": now.Add(l.ttl),
+	}}
+
+	_, err := l.collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrHeld
+	}
+	if err != nil {
+		return fmt.Errorf("failed to acquire lease %s: %w", l.name, err)
+	}
+	return nil
+}
+
+// Renew estende a validade da lease, desde que ainda pertença a esta instância
+func (l *Lease) Renew(ctx context.Context) error {
+	result, err := l.collection.UpdateOne(ctx,
+		bson.M{"_id": l.name, "owner": l.owner},
+		bson.M{"$set": bson.M{"expires_at": time.Now().Add(l.ttl)}})
+	if err != nil {
+		return fmt.Errorf("failed to renew lease %s: %w", l.name, err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrLost
+	}
+	return nil
+}
+
+// Release libera a lease somente se ainda pertencer a esta instância
+func (l *Lease) Release(ctx context.Context) error {
+	_, err := l.collection.DeleteOne(ctx, bson.M{"_id": l.name, "owner": l.owner})
+	return err
+}
+
+// store são as operações da lease usadas pela eleição de líder
+type store interface {
+	Acquire(ctx context.Context) error
+	Renew(ctx context.Context) error
+	Release(ctx context.Context) error
+}
+
+// Leader executa uma tarefa em apenas uma das instâncias, a detentora da lease
+type Leader struct {
+	lease    store
+	interval time.Duration // intervalo entre tentativas de aquisição e entre renovações
+	logger   *zap.Logger
+}
+
+// NewLeader cria a eleição de líder sobre a lease; tentativas e renovações ocorrem a cada ttl/3
+func NewLeader(lease *Lease, logger *zap.Logger) *Leader {
+	return &Leader{lease: lease, interval: lease.TTL() / 3, logger: logger}
+}
+
+// Run tenta obter a lease até o contexto ser cancelado e, enquanto a detém, executa fn. A lease
+// é renovada em segundo plano; se a renovação falhar, o contexto de fn é cancelado com ErrLost
+// antes que a lease expire e outra instância assuma. Quando fn termina, a lease é liberada e a
+// instância volta a disputá-la.
+func (l *Leader) Run(ctx context.Context, fn func(ctx context.Context) error) error {
+	for {
+		err := l.lease.Acquire(ctx)
+		switch {
+		case err == nil:
+			l.lead(ctx, fn)
+		case !errors.Is(err, ErrHeld):
+			l.logger.Warn("Failed to acquire lease", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(l.interval):
+		}
+	}
+}
+
+func (l *Leader) lead(ctx context.Context, fn func(ctx context.Context) error) {
+	l.logger.Info("Lease acquired, running as leader")
+
+	leaderCtx, cancel := context.WithCancelCause(ctx)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		l.heartbeat(leaderCtx, cancel)
+	}()
+
+	err := fn(leaderCtx)
+	cancel(nil)
+	<-stopped
+
+	// A perda da lease já foi registrada pelo heartbeat
+	if err != nil && ctx.Err() == nil && !errors.Is(context.Cause(leaderCtx), ErrLost) {
+		l.logger.Error("Leader task stopped", zap.Error(err))
+	}
+
+	if err := l.lease.Release(context.Background()); err != nil {
+		l.logger.Warn("Failed to release lease", zap.Error(err))
+	}
+}
+
+// heartbeat renova a lease a cada interval até o contexto ser cancelado
+func (l *Leader) heartbeat(ctx context.Context, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := l.lease.Renew(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			l.logger.Error("Failed to renew lease, stepping down", zap.Error(err))
+			if !errors.Is(err, ErrLost) {
+				err = fmt.Errorf("%w: %w", ErrLost, err)
+			}
+			cancel(err)
+			return
+		}
+	}
+}
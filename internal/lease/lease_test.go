@@ -0,0 +1,132 @@
+package lease
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"go.uber.org/zap"
+)
+
+func TestLease_AcquireAndRenew(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("held by another instance", func(mt *mtest.T) {
+		l := New(mt.DB, Collection, "job", time.Minute)
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Code: 11000, Message: "duplicate key"}))
+
+		assert.ErrorIs(t, l.Acquire(context.Background()), ErrHeld)
+	})
+
+	mt.Run("renew extends the lease", func(mt *mtest.T) {
+		l := New(mt.DB, Collection, "job", time.Minute)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		assert.NoError(t, l.Renew(context.Background()))
+
+		update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(t, "job", update.Lookup("q", "_id").StringValue())
+		assert.Equal(t, l.Owner(), update.Lookup("q", "owner").StringValue())
+	})
+
+	mt.Run("renew after being taken over", func(mt *mtest.T) {
+		l := New(mt.DB, Collection, "job", time.Minute)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+
+		assert.ErrorIs(t, l.Renew(context.Background()), ErrLost)
+	})
+}
+
+// fakeStore simula a lease; held indica que outra instância a detém
+type fakeStore struct {
+	mu       sync.Mutex
+	held     bool
+	renewErr error
+	acquired atomic.Int32
+	renewed  atomic.Int32
+	released atomic.Int32
+}
+
+func (s *fakeStore) Acquire(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.held {
+		return ErrHeld
+	}
+	s.acquired.Add(1)
+	return nil
+}
+
+func (s *fakeStore) Renew(ctx context.Context) error {
+	s.renewed.Add(1)
+	return s.renewErr
+}
+
+func (s *fakeStore) Release(ctx context.Context) error {
+	s.released.Add(1)
+	return nil
+}
+
+func (s *fakeStore) setHeld(held bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.held = held
+}
+
+func TestLeader_Run_WaitsForLeaseAndRenewsWhileLeading(t *testing.T) {
+	store := &fakeStore{held: true}
+	leader := &Leader{lease: store, interval: 5 * time.Millisecond, logger: zap.NewNop()}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	running := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- leader.Run(ctx, func(ctx context.Context) error {
+			close(running)
+			<-ctx.Done()
+			return ctx.Err()
+		})
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	select {
+	case <-running:
+		t.Fatal("fn não deve executar enquanto outra instância detém a lease")
+	default:
+	}
+
+	store.setHeld(false)
+	<-running
+	assert.Eventually(t, func() bool { return store.renewed.Load() >= 2 }, time.Second, time.Millisecond)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	assert.Equal(t, int32(1), store.acquired.Load())
+	assert.Equal(t, int32(1), store.released.Load())
+}
+
+func TestLeader_Run_StepsDownWhenLeaseIsLost(t *testing.T) {
+	store := &fakeStore{renewErr: errors.New("timeout")}
+	leader := &Leader{lease: store, interval: 5 * time.Millisecond, logger: zap.NewNop()}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	causes := make(chan error, 10)
+	go leader.Run(ctx, func(ctx context.Context) error {
+		<-ctx.Done()
+		causes <- context.Cause(ctx)
+		return ctx.Err()
+	})
+
+	assert.ErrorIs(t, <-causes, ErrLost)
+	// A instância volta a disputar a lease e, obtendo-a novamente, reassume
+	assert.Eventually(t, func() bool { return store.acquired.Load() >= 2 }, time.Second, time.Millisecond)
+	assert.GreaterOrEqual(t, store.released.Load(), int32(1))
+}
@@ -0,0 +1,126 @@
+package broker
+
+import (
+	"company-service/internal/config"
+	"company-service/internal/messaging"
+	"company-service/internal/messaging/kafka"
+	"company-service/internal/messaging/nats"
+	"company-service/internal/messaging/rabbitmq"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// NewProducer cria o produtor de eventos do broker configurado em MESSAGING_DRIVER
+func NewProducer(cfg *config.Config, logger *zap.Logger) (messaging.MessageProducer, error) {
+	switch cfg.MessagingDriver {
+	case "kafka":
+		return newKafkaProducer(cfg, logger)
+	case "nats":
+		return newNATSProducer(cfg, logger)
+	case "rabbitmq", "":
+		return newRabbitMQProducer(cfg, logger)
+	default:
+		return nil, fmt.Errorf("invalid MESSAGING_DRIVER: %s", cfg.MessagingDriver)
+	}
+}
+
+// newRabbitMQProducer usa a fila única QUEUE_NAME (modo legado, padrão) ou, com
+// RABBITMQ_TOPOLOGY=topic, um exchange topic com routing keys por evento e UF
+func newRabbitMQProducer(cfg *config.Config, logger *zap.Logger) (messaging.MessageProducer, error) {
+	var topology rabbitmq.Topology
+	switch cfg.RabbitMQTopology {
+	case "", "legacy":
+		topology = rabbitmq.LegacyTopology(cfg.QueueName)
+	case "topic":
+		bindings, err := rabbitmq.ParseBindings(cfg.RabbitMQQueues)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RABBITMQ_QUEUES: %w", err)
+		}
+		topology = rabbitmq.TopicTopology(cfg.RabbitMQExchange, bindings)
+	default:
+		return nil, fmt.Errorf("invalid RABBITMQ_TOPOLOGY: %s", cfg.RabbitMQTopology)
+	}
+
+	// Dead-lettering: mensagens rejeitadas, expiradas ou excedentes vão para o parking lot
+	if cfg.RabbitMQDLX != "" {
+		topology.DeadLetter = rabbitmq.DeadLetter{
+			Exchange:   cfg.RabbitMQDLX,
+			Queue:      cfg.RabbitMQParkingQueue,
+			MessageTTL: config.ParseDuration(cfg.RabbitMQMessageTTL, 0),
+			MaxLength:  cfg.RabbitMQQueueMaxLength,
+		}
+	}
+
+	producer, err := rabbitmq.NewProducer(rabbitmq.Config{
+		URI:               cfg.RabbitMQURI,
+		Topology:          topology,
+		ConfirmTimeout:    config.ParseDuration(cfg.RabbitMQConfirmTimeout, 5*time.Second),
+		ReconnectDelay:    config.ParseDuration(cfg.RabbitMQReconnectDelay, 1*time.Second),
+		MaxReconnectDelay: config.ParseDuration(cfg.RabbitMQReconnectMaxDelay, 30*time.Second),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create RabbitMQ producer: %w", err)
+	}
+
+	logger.Info("RabbitMQ producer initialized",
+		zap.String("topology", cfg.RabbitMQTopology),
+		zap.String("exchange", topology.Exchange),
+		zap.String("dead_letter_exchange", topology.DeadLetter.Exchange))
+	return producer, nil
+}
+
+// newKafkaProducer publica no tópico KAFKA_TOPIC com o ID da empresa como chave
+func newKafkaProducer(cfg *config.Config, logger *zap.Logger) (messaging.MessageProducer, error) {
+	var brokers []string
+	for _, broker := range strings.Split(cfg.KafkaBrokers, ",") {
+		if broker = strings.TrimSpace(broker); broker != "" {
+			brokers = append(brokers, broker)
+		}
+	}
+
+	producer, err := kafka.NewProducer(kafka.Config{
+		Brokers:     brokers,
+		Topic:       cfg.KafkaTopic,
+		ClientID:    cfg.KafkaClientID,
+		Version:     cfg.KafkaVersion,
+		Compression: cfg.KafkaCompression,
+		Idempotent:  cfg.KafkaIdempotent,
+		Timeout:     config.ParseDuration(cfg.KafkaTimeout, 10*time.Second),
+		HealthTTL:   config.ParseDuration(cfg.KafkaHealthTTL, 15*time.Second),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kafka producer: %w", err)
+	}
+
+	logger.Info("Kafka producer initialized",
+		zap.Strings("brokers", brokers),
+		zap.String("topic", cfg.KafkaTopic),
+		zap.String("compression", cfg.KafkaCompression),
+		zap.Bool("idempotent", cfg.KafkaIdempotent))
+	return producer, nil
+}
+
+// newNATSProducer publica no JetStream, nos subjects <NATS_SUBJECT_PREFIX>.<evento>
+func newNATSProducer(cfg *config.Config, logger *zap.Logger) (messaging.MessageProducer, error) {
+	producer, err := nats.NewProducer(nats.Config{
+		URL:             cfg.NatsURL,
+		Stream:          cfg.NatsStream,
+		SubjectPrefix:   cfg.NatsSubjectPrefix,
+		Replicas:        cfg.NatsReplicas,
+		MaxAge:          config.ParseDuration(cfg.NatsMaxAge, 0),
+		DuplicateWindow: config.ParseDuration(cfg.NatsDuplicateWindow, 2*time.Minute),
+		Timeout:         config.ParseDuration(cfg.NatsTimeout, 5*time.Second),
+		UpdateStream:    cfg.NatsUpdateStream,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create NATS JetStream producer: %w", err)
+	}
+
+	logger.Info("NATS JetStream producer initialized",
+		zap.String("stream", cfg.NatsStream),
+		zap.String("subjects", cfg.NatsSubjectPrefix+".>"))
+	return producer, nil
+}
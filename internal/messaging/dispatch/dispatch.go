@@ -0,0 +1,432 @@
+package dispatch
+
+import (
+	"company-service/internal/domain"
+	"company-service/internal/messaging"
+	"company-service/internal/messaging/spool"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// OverflowPolicy define o que acontece com um evento quando a fila está cheia
+type OverflowPolicy string
+
+const (
+	// OverflowBlock aguarda espaço na fila (ou o cancelamento do contexto de quem publica)
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowDrop descarta o evento, contabilizado em Stats.Dropped
+	OverflowDrop OverflowPolicy = "drop"
+	// OverflowSpill grava o evento no spool, de onde é reenviado quando o broker se recupera. Se
+	// a empresa ainda tem eventos na fila ou em envio, o evento aguarda espaço como em
+	// OverflowBlock, pois no spool ficaria à frente deles.
+	OverflowSpill OverflowPolicy = "spill"
+)
+
+var (
+	// ErrClosed indica um evento recebido após o Shutdown
+	ErrClosed = errors.New("event dispatcher closed")
+	// ErrDropped indica um evento descartado por falta de espaço na fila
+	ErrDropped = errors.New("event dispatcher queue is full, event dropped")
+)
+
+// Config define a fila, os workers e as novas tentativas do dispatcher
+type Config struct {
+	QueueSize      int            // eventos aguardando envio, divididos entre os workers
+	Workers        int            // envios simultâneos
+	MaxAttempts    int            // tentativas por evento, incluindo a primeira
+	InitialBackoff time.Duration  // espera antes da segunda tentativa, dobrada a cada falha
+	MaxBackoff     time.Duration  // limite da espera entre tentativas
+	Overflow       OverflowPolicy // vazio usa spill com spool e block sem
+	Spool          *spool.Spool   // recebe os eventos que esgotam as tentativas; opcional
+}
+
+func (c Config) withDefaults() Config {
+	if c.Workers <= 0 {
+		c.Workers = 4
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = 10000
+	}
+	if c.QueueSize < c.Workers {
+		c.QueueSize = c.Workers
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 3
+	}
+	if c.InitialBackoff <= 0 {
+		c.InitialBackoff = 1 * time.Second
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = 30 * time.Second
+	}
+	if c.MaxBackoff < c.InitialBackoff {
+		c.MaxBackoff = c.InitialBackoff
+	}
+	if c.Overflow == "" {
+		c.Overflow = OverflowBlock
+		if c.Spool != nil {
+			c.Overflow = OverflowSpill
+		}
+	}
+	return c
+}
+
+// Stats são os contadores do dispatcher, expostos na rota administrativa
+type Stats struct {
+	Overflow  OverflowPolicy `json:"overflow"`
+	Queued    int            `json:"queued"` // eventos aguardando envio
+	QueueSize int            `json:"queue_size"`
+	Accepted  uint64         `json:"accepted"`  // eventos aceitos na fila
+	Delivered uint64         `json:"delivered"` // eventos publicados
+	Retried   uint64         `json:"retried"`   // tentativas com falha seguidas de nova tentativa
+	Failed    uint64         `json:"failed"`    // eventos que esgotaram as tentativas
+	Dropped   uint64         `json:"dropped"`   // eventos descartados (fila cheia ou encerramento, sem spool)
+	Spilled   uint64         `json:"spilled"`   // eventos gravados no spool
+}
+
+// job é um evento aguardando envio. O contexto preserva os valores de quem publicou, mas não o
+// cancelamento.
+type job struct {
+	ctx       context.Context
+	eventType messaging.EventType
+	previous  *domain.Company
+	company   *domain.Company
+}
+
+// Dispatcher publica os eventos das escritas em segundo plano, com uma fila limitada e um pool
+// de workers. Os eventos de uma empresa vão sempre para o mesmo worker, preservando a ordem. As
+// novas tentativas usam espera exponencial com jitter; eventos que esgotam as tentativas vão
+// para o spool, quando configurado.
+type Dispatcher struct {
+	producer messaging.MessageProducer
+	logger   *zap.Logger
+	cfg      Config
+	queues   []chan job
+
+	// ctx é cancelado quando o prazo do Shutdown expira, interrompendo esperas e envios
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.RWMutex
+	closed  bool
+	closing chan struct{} // fechado no início do Shutdown, libera quem aguarda espaço na fila
+	workers sync.WaitGroup
+
+	// inFlight conta os eventos de cada empresa na fila ou em envio
+	inFlightMu sync.Mutex
+	inFlight   map[string]int
+
+	accepted, delivered, retried, failed, dropped, spilled atomic.Uint64
+}
+
+// New inicia os workers do dispatcher
+func New(producer messaging.MessageProducer, logger *zap.Logger, cfg Config) (*Dispatcher, error) {
+	cfg = cfg.withDefaults()
+	switch cfg.Overflow {
+	case OverflowBlock, OverflowDrop:
+	case OverflowSpill:
+		if cfg.Spool == nil {
+			return nil, errors.New("overflow policy spill requires a spool")
+		}
+	default:
+		return nil, fmt.Errorf("invalid overflow policy %s, expected block, drop or spill", cfg.Overflow)
+	}
+
+	// Cada worker tem sua parte da fila
+	cfg.QueueSize -= cfg.QueueSize % cfg.Workers
+
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		producer: producer,
+		logger:   logger,
+		cfg:      cfg,
+		queues:   make([]chan job, cfg.Workers),
+		ctx:      ctx,
+		cancel:   cancel,
+		closing:  make(chan struct{}),
+		inFlight: map[string]int{},
+	}
+	for i := range d.queues {
+		queue := make(chan job, cfg.QueueSize/cfg.Workers)
+		d.queues[i] = queue
+
+		d.workers.Add(1)
+		go func() {
+			defer d.workers.Done()
+			for j := range queue {
+				d.deliver(j)
+				d.track(j.company.ID, -1)
+			}
+		}()
+	}
+	return d, nil
+}
+
+// Dispatch agenda o envio do evento. Com a fila cheia, segue a política de overflow: aguarda
+// (até o cancelamento de ctx), descarta ou grava no spool; com spill, os eventos de uma empresa
+// que ainda tem eventos na fila aguardam espaço, mantendo a ordem. Retorna erro se o evento não
+// foi aceito nem gravado no spool.
+func (d *Dispatcher) Dispatch(ctx context.Context, eventType messaging.EventType, previous, company *domain.Company) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return ErrClosed
+	}
+
+	j := job{ctx: context.WithoutCancel(ctx), eventType: eventType, previous: previous, company: company}
+	queue := d.queues[shard(company.ID, len(d.queues))]
+
+	if d.enqueue(queue, j) {
+		return nil
+	}
+
+	switch d.cfg.Overflow {
+	case OverflowSpill:
+		// Gravar no spool um evento cuja empresa ainda tem eventos na fila o colocaria à frente
+		// deles; nesse caso ele aguarda espaço na fila, atrás dos anteriores
+		if !d.queued(company.ID) {
+			return d.spill(j, ErrDropped)
+		}
+		return d.wait(ctx, queue, j)
+	case OverflowBlock:
+		return d.wait(ctx, queue, j)
+	default:
+		d.dropped.Add(1)
+		return ErrDropped
+	}
+}
+
+// enqueue coloca o evento na fila se houver espaço, contabilizando-o para a empresa
+func (d *Dispatcher) enqueue(queue chan job, j job) bool {
+	d.track(j.company.ID, 1)
+	select {
+	case queue <- j:
+		d.accepted.Add(1)
+		return true
+	default:
+		d.track(j.company.ID, -1)
+		return false
+	}
+}
+
+// wait aguarda espaço na fila até o cancelamento de ctx. No encerramento, o evento vai para o
+// spool (se houver) ou é descartado.
+func (d *Dispatcher) wait(ctx context.Context, queue chan job, j job) error {
+	d.track(j.company.ID, 1)
+	select {
+	case queue <- j:
+		d.accepted.Add(1)
+		return nil
+	case <-ctx.Done():
+		d.track(j.company.ID, -1)
+		d.dropped.Add(1)
+		return fmt.Errorf("%w: %w", ErrDropped, ctx.Err())
+	case <-d.closing:
+		d.track(j.company.ID, -1)
+		if d.cfg.Spool != nil {
+			return d.spill(j, ErrClosed)
+		}
+		d.dropped.Add(1)
+		return ErrClosed
+	}
+}
+
+// track ajusta a contagem de eventos da empresa na fila ou em envio
+func (d *Dispatcher) track(companyID string, delta int) {
+	d.inFlightMu.Lock()
+	defer d.inFlightMu.Unlock()
+	d.inFlight[companyID] += delta
+	if d.inFlight[companyID] <= 0 {
+		delete(d.inFlight, companyID)
+	}
+}
+
+// queued informa se a empresa tem eventos na fila ou em envio
+func (d *Dispatcher) queued(companyID string) bool {
+	d.inFlightMu.Lock()
+	defer d.inFlightMu.Unlock()
+	return d.inFlight[companyID] > 0
+}
+
+// deliver publica o evento com novas tentativas. Se a empresa já tem eventos no spool, o novo
+// evento vai direto para o fim da fila dela, preservando a ordem.
+func (d *Dispatcher) deliver(j job) {
+	operation := "company_" + j.eventType.Operation()
+	if d.cfg.Spool != nil && d.cfg.Spool.HasPending(j.company.ID) {
+		d.spill(j, nil)
+		return
+	}
+
+	ctx, cancel := mergeCancel(j.ctx, d.ctx)
+	defer cancel()
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = messaging.Send(ctx, d.producer, j.eventType, j.previous, j.company); err == nil {
+			d.delivered.Add(1)
+			d.logger.Info("Mensagem enviada com sucesso",
+				zap.String("operation", operation),
+				zap.String("company_id", j.company.ID))
+			return
+		}
+		if attempt >= d.cfg.MaxAttempts || d.ctx.Err() != nil {
+			break
+		}
+
+		d.retried.Add(1)
+		wait := d.backoff(attempt)
+		d.logger.Warn("Falha ao enviar mensagem, tentando novamente",
+			zap.String("operation", operation),
+			zap.String("company_id", j.company.ID),
+			zap.Int("attempt", attempt),
+			zap.Duration("backoff", wait),
+			zap.Error(err))
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-d.ctx.Done():
+		case <-timer.C:
+		}
+		timer.Stop()
+	}
+
+	if d.ctx.Err() != nil {
+		d.logger.Warn("Envio interrompido pelo encerramento",
+			zap.String("operation", operation),
+			zap.String("company_id", j.company.ID),
+			zap.Error(err))
+	} else {
+		d.failed.Add(1)
+		d.logger.Error("Falha ao enviar mensagem após todas as tentativas",
+			zap.String("operation", operation),
+			zap.String("company_id", j.company.ID),
+			zap.Error(err))
+	}
+	d.abandon(j, err)
+}
+
+// abandon grava no spool (se houver) ou descarta um evento que não foi publicado
+func (d *Dispatcher) abandon(j job, cause error) {
+	if d.cfg.Spool != nil {
+		d.spill(j, cause)
+		return
+	}
+	d.dropped.Add(1)
+	d.logger.Error("Evento descartado",
+		zap.String("type", string(j.eventType)),
+		zap.String("company_id", j.company.ID),
+		zap.Error(cause))
+}
+
+// spill grava o evento no spool para reentrega posterior
+func (d *Dispatcher) spill(j job, cause error) error {
+	entry, err := d.cfg.Spool.Append(j.eventType, j.previous, j.company, cause)
+	if err != nil {
+		d.dropped.Add(1)
+		d.logger.Error("Falha ao gravar evento no spool",
+			zap.String("type", string(j.eventType)),
+			zap.String("company_id", j.company.ID),
+			zap.Error(err))
+		return fmt.Errorf("failed to spool event: %w", err)
+	}
+	d.spilled.Add(1)
+	d.logger.Warn("Evento gravado no spool para reentrega",
+		zap.Uint64("seq", entry.Seq),
+		zap.String("type", string(j.eventType)),
+		zap.String("company_id", j.company.ID))
+	return nil
+}
+
+// backoff retorna a espera antes da próxima tentativa: metade da espera exponencial mais uma
+// parte aleatória da outra metade, para que eventos que falharam juntos não sejam reenviados
+// juntos
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	wait := d.cfg.InitialBackoff
+	for i := 1; i < attempt && wait < d.cfg.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > d.cfg.MaxBackoff {
+		wait = d.cfg.MaxBackoff
+	}
+	half := wait / 2
+	return half + time.Duration(rand.Int63n(int64(wait-half)+1))
+}
+
+// Stats retorna os contadores do dispatcher
+func (d *Dispatcher) Stats() Stats {
+	queued := 0
+	for _, queue := range d.queues {
+		queued += len(queue)
+	}
+	return Stats{
+		Overflow:  d.cfg.Overflow,
+		Queued:    queued,
+		QueueSize: d.cfg.QueueSize,
+		Accepted:  d.accepted.Load(),
+		Delivered: d.delivered.Load(),
+		Retried:   d.retried.Load(),
+		Failed:    d.failed.Load(),
+		Dropped:   d.dropped.Load(),
+		Spilled:   d.spilled.Load(),
+	}
+}
+
+// Shutdown para de aceitar eventos e aguarda o envio dos que estão na fila até o fim de ctx.
+// Depois disso, as novas tentativas e os envios em andamento são interrompidos, e os eventos
+// restantes vão para o spool (se houver) ou são descartados.
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	select {
+	case <-d.closing:
+		return nil
+	default:
+		close(d.closing)
+	}
+
+	d.mu.Lock()
+	d.closed = true
+	for _, queue := range d.queues {
+		close(queue)
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		d.cancel()
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		<-done
+		return fmt.Errorf("event dispatcher did not drain in time: %w", ctx.Err())
+	}
+}
+
+// shard escolhe o worker da empresa
+func shard(companyID string, workers int) int {
+	h := fnv.New32a()
+	h.Write([]byte(companyID))
+	return int(h.Sum32() % uint32(workers))
+}
+
+// mergeCancel retorna um contexto com os valores de ctx, cancelado junto com stop
+func mergeCancel(ctx, stop context.Context) (context.Context, context.CancelFunc) {
+	merged, cancel := context.WithCancel(ctx)
+	unregister := context.AfterFunc(stop, cancel)
+	return merged, func() {
+		unregister()
+		cancel()
+	}
+}
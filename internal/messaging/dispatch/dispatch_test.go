@@ -0,0 +1,365 @@
+package dispatch
+
+import (
+	"company-service/internal/domain"
+	"company-service/internal/messaging"
+	"company-service/internal/messaging/spool"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeProducer registra os eventos recebidos; fail decide o erro de cada envio e block, se
+// informado, segura os envios até ser fechado ou até o cancelamento do contexto
+type fakeProducer struct {
+	mu      sync.Mutex
+	sent    []string
+	replays int
+	calls   int
+	fail    func(call int) error
+	block   chan struct{}
+}
+
+func (f *fakeProducer) send(ctx context.Context, eventType messaging.EventType, company *domain.Company) error {
+	if f.block != nil {
+		select {
+		case <-f.block:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.fail != nil {
+		if err := f.fail(f.calls); err != nil {
+			return err
+		}
+	}
+	f.sent = append(f.sent, eventType.Operation()+":"+company.ID)
+	if messaging.IsReplay(ctx) {
+		f.replays++
+	}
+	return nil
+}
+
+func (f *fakeProducer) SendCompanyCreated(ctx context.Context, company *domain.Company) error {
+	return f.send(ctx, messaging.CompanyCreated, company)
+}
+
+func (f *fakeProducer) SendCompanyUpdated(ctx context.Context, previous, company *domain.Company) error {
+	return f.send(ctx, messaging.CompanyUpdated, company)
+}
+
+func (f *fakeProducer) SendCompanyDeleted(ctx context.Context, company *domain.Company) error {
+	return f.send(ctx, messaging.CompanyDeleted, company)
+}
+
+func (f *fakeProducer) Close() error { return nil }
+
+func (f *fakeProducer) events() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.sent...)
+}
+
+func fastConfig() Config {
+	return Config{QueueSize: 10, Workers: 1, MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+}
+
+func company(id string) *domain.Company {
+	return &domain.Company{ID: id, TenantID: "acme"}
+}
+
+func shutdown(t *testing.T, d *Dispatcher) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, d.Shutdown(ctx))
+}
+
+func openSpool(t *testing.T) *spool.Spool {
+	t.Helper()
+	s, err := spool.Open(t.TempDir(), 1<<20)
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestDispatcher_DeliversEventsOfACompanyInOrder(t *testing.T) {
+	producer := &fakeProducer{}
+	cfg := fastConfig()
+	cfg.Workers = 4
+	d, err := New(producer, zap.NewNop(), cfg)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, d.Dispatch(ctx, messaging.CompanyCreated, nil, company("a")))
+	require.NoError(t, d.Dispatch(ctx, messaging.CompanyUpdated, company("a"), company("a")))
+	require.NoError(t, d.Dispatch(ctx, messaging.CompanyDeleted, nil, company("a")))
+	shutdown(t, d)
+
+	assert.Equal(t, []string{"created:a", "updated:a", "deleted:a"}, producer.events())
+	stats := d.Stats()
+	assert.Equal(t, uint64(3), stats.Accepted)
+	assert.Equal(t, uint64(3), stats.Delivered)
+	assert.Equal(t, 8, stats.QueueSize, "a fila é dividida igualmente entre os workers")
+	assert.ErrorIs(t, d.Dispatch(ctx, messaging.CompanyCreated, nil, company("b")), ErrClosed)
+}
+
+func TestDispatcher_RetriesWithBackoff(t *testing.T) {
+	producer := &fakeProducer{fail: func(call int) error {
+		if call < 3 {
+			return errors.New("broker unavailable")
+		}
+		return nil
+	}}
+	d, err := New(producer, zap.NewNop(), fastConfig())
+	require.NoError(t, err)
+
+	require.NoError(t, d.Dispatch(context.Background(), messaging.CompanyCreated, nil, company("a")))
+	shutdown(t, d)
+
+	assert.Equal(t, []string{"created:a"}, producer.events())
+	assert.Equal(t, uint64(2), d.Stats().Retried)
+	assert.Equal(t, uint64(1), d.Stats().Delivered)
+}
+
+func TestDispatcher_BackoffIsCappedAndJittered(t *testing.T) {
+	d, err := New(&fakeProducer{}, zap.NewNop(), Config{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second})
+	require.NoError(t, err)
+	defer shutdown(t, d)
+
+	for range 100 {
+		wait := d.backoff(1)
+		assert.GreaterOrEqual(t, wait, 50*time.Millisecond)
+		assert.LessOrEqual(t, wait, 100*time.Millisecond)
+
+		wait = d.backoff(10)
+		assert.GreaterOrEqual(t, wait, 500*time.Millisecond)
+		assert.LessOrEqual(t, wait, time.Second)
+	}
+}
+
+func TestDispatcher_ExhaustedEventsAreSpilledOrDropped(t *testing.T) {
+	down := func(int) error { return errors.New("broker down") }
+
+	t.Run("without spool", func(t *testing.T) {
+		d, err := New(&fakeProducer{fail: down}, zap.NewNop(), fastConfig())
+		require.NoError(t, err)
+		require.NoError(t, d.Dispatch(context.Background(), messaging.CompanyCreated, nil, company("a")))
+		shutdown(t, d)
+
+		assert.Equal(t, uint64(1), d.Stats().Failed)
+		assert.Equal(t, uint64(1), d.Stats().Dropped)
+	})
+
+	t.Run("with spool", func(t *testing.T) {
+		eventSpool := openSpool(t)
+		cfg := fastConfig()
+		cfg.Spool = eventSpool
+		d, err := New(&fakeProducer{fail: down}, zap.NewNop(), cfg)
+		require.NoError(t, err)
+		require.NoError(t, d.Dispatch(context.Background(), messaging.CompanyCreated, nil, company("a")))
+		require.NoError(t, d.Dispatch(context.Background(), messaging.CompanyUpdated, company("a"), company("a")))
+		shutdown(t, d)
+
+		// O segundo evento da empresa entra no spool atrás do primeiro, sem novas tentativas
+		assert.Equal(t, uint64(1), d.Stats().Failed)
+		assert.Equal(t, uint64(2), d.Stats().Spilled)
+		pending := eventSpool.Pending()
+		require.Len(t, pending, 2)
+		assert.Equal(t, messaging.CompanyCreated, pending[0].Type)
+		assert.Equal(t, messaging.CompanyUpdated, pending[1].Type)
+	})
+}
+
+func TestDispatcher_Overflow(t *testing.T) {
+	cfg := fastConfig()
+	cfg.QueueSize = 1
+
+	// fill ocupa o worker com um evento em envio e a fila com outro
+	fill := func(t *testing.T, d *Dispatcher) {
+		require.NoError(t, d.Dispatch(context.Background(), messaging.CompanyCreated, nil, company("a")))
+		require.Eventually(t, func() bool { return d.Stats().Queued == 0 }, time.Second, time.Millisecond)
+		require.NoError(t, d.Dispatch(context.Background(), messaging.CompanyCreated, nil, company("b")))
+	}
+
+	t.Run("drop", func(t *testing.T) {
+		producer := &fakeProducer{block: make(chan struct{})}
+		cfg := cfg
+		cfg.Overflow = OverflowDrop
+		d, err := New(producer, zap.NewNop(), cfg)
+		require.NoError(t, err)
+		fill(t, d)
+
+		assert.ErrorIs(t, d.Dispatch(context.Background(), messaging.CompanyCreated, nil, company("c")), ErrDropped)
+		assert.Equal(t, uint64(1), d.Stats().Dropped)
+
+		close(producer.block)
+		shutdown(t, d)
+		assert.Equal(t, []string{"created:a", "created:b"}, producer.events())
+	})
+
+	t.Run("spill", func(t *testing.T) {
+		producer := &fakeProducer{block: make(chan struct{})}
+		eventSpool := openSpool(t)
+		cfg := cfg
+		cfg.Spool = eventSpool
+		d, err := New(producer, zap.NewNop(), cfg)
+		require.NoError(t, err)
+		assert.Equal(t, OverflowSpill, d.Stats().Overflow, "com spool, o padrão é spill")
+		fill(t, d)
+
+		require.NoError(t, d.Dispatch(context.Background(), messaging.CompanyCreated, nil, company("c")))
+		assert.Equal(t, uint64(1), d.Stats().Spilled)
+		pending := eventSpool.Pending()
+		require.Len(t, pending, 1)
+		assert.Equal(t, "c", pending[0].Company.ID)
+
+		close(producer.block)
+		shutdown(t, d)
+	})
+
+	t.Run("block", func(t *testing.T) {
+		producer := &fakeProducer{block: make(chan struct{})}
+		d, err := New(producer, zap.NewNop(), cfg)
+		require.NoError(t, err)
+		assert.Equal(t, OverflowBlock, d.Stats().Overflow, "sem spool, o padrão é block")
+		fill(t, d)
+
+		// Com o contexto cancelado, a espera por espaço é interrompida
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		err = d.Dispatch(ctx, messaging.CompanyCreated, nil, company("c"))
+		assert.ErrorIs(t, err, ErrDropped)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		// Com espaço liberado, o evento é aceito
+		accepted := make(chan error, 1)
+		go func() {
+			accepted <- d.Dispatch(context.Background(), messaging.CompanyCreated, nil, company("d"))
+		}()
+		close(producer.block)
+		require.NoError(t, <-accepted)
+
+		shutdown(t, d)
+		assert.Equal(t, []string{"created:a", "created:b", "created:d"}, producer.events())
+		assert.Equal(t, uint64(1), d.Stats().Dropped)
+	})
+}
+
+func TestDispatcher_SpillKeepsCompanyOrder(t *testing.T) {
+	producer := &fakeProducer{block: make(chan struct{})}
+	eventSpool := openSpool(t)
+	cfg := fastConfig()
+	cfg.QueueSize = 1
+	cfg.Spool = eventSpool
+	d, err := New(producer, zap.NewNop(), cfg)
+	require.NoError(t, err)
+
+	// a1 em envio e a2 na fila
+	ctx := context.Background()
+	require.NoError(t, d.Dispatch(ctx, messaging.CompanyCreated, nil, company("a")))
+	require.Eventually(t, func() bool { return d.Stats().Queued == 0 }, time.Second, time.Millisecond)
+	require.NoError(t, d.Dispatch(ctx, messaging.CompanyUpdated, company("a"), company("a")))
+
+	// Outra empresa, sem eventos na fila, vai para o spool
+	require.NoError(t, d.Dispatch(ctx, messaging.CompanyCreated, nil, company("b")))
+	assert.True(t, eventSpool.HasPending("b"))
+
+	// a3 não pode passar à frente de a1 e a2 no spool: aguarda espaço na fila
+	accepted := make(chan error, 1)
+	go func() {
+		accepted <- d.Dispatch(ctx, messaging.CompanyDeleted, nil, company("a"))
+	}()
+	select {
+	case err := <-accepted:
+		t.Fatalf("evento aceito antes de haver espaço na fila: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	assert.False(t, eventSpool.HasPending("a"))
+
+	close(producer.block)
+	require.NoError(t, <-accepted)
+	shutdown(t, d)
+
+	assert.Equal(t, []string{"created:a", "updated:a", "deleted:a"}, producer.events())
+	assert.Equal(t, uint64(1), d.Stats().Spilled)
+}
+
+func TestDispatcher_ShutdownReleasesBlockedPublishers(t *testing.T) {
+	producer := &fakeProducer{block: make(chan struct{})}
+	cfg := fastConfig()
+	cfg.QueueSize = 1
+	d, err := New(producer, zap.NewNop(), cfg)
+	require.NoError(t, err)
+
+	require.NoError(t, d.Dispatch(context.Background(), messaging.CompanyCreated, nil, company("a")))
+	require.Eventually(t, func() bool { return d.Stats().Queued == 0 }, time.Second, time.Millisecond)
+	require.NoError(t, d.Dispatch(context.Background(), messaging.CompanyCreated, nil, company("b")))
+
+	blocked := make(chan error, 1)
+	go func() {
+		blocked <- d.Dispatch(context.Background(), messaging.CompanyCreated, nil, company("c"))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.Error(t, d.Shutdown(ctx))
+	assert.ErrorIs(t, <-blocked, ErrClosed)
+}
+
+func TestDispatcher_ShutdownAbandonsEventsAfterDeadline(t *testing.T) {
+	eventSpool := openSpool(t)
+	producer := &fakeProducer{fail: func(int) error { return errors.New("broker down") }}
+	cfg := fastConfig()
+	cfg.MaxAttempts = 1000
+	cfg.InitialBackoff = time.Hour
+	cfg.MaxBackoff = time.Hour
+	cfg.Spool = eventSpool
+	d, err := New(producer, zap.NewNop(), cfg)
+	require.NoError(t, err)
+
+	require.NoError(t, d.Dispatch(context.Background(), messaging.CompanyCreated, nil, company("a")))
+	require.NoError(t, d.Dispatch(context.Background(), messaging.CompanyCreated, nil, company("b")))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	assert.ErrorIs(t, d.Shutdown(ctx), context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// O evento em nova tentativa e o que aguardava na fila vão para o spool
+	assert.Equal(t, uint64(2), d.Stats().Spilled)
+	assert.Zero(t, d.Stats().Failed)
+	assert.Equal(t, 2, eventSpool.Len())
+}
+
+func TestDispatcher_PreservesReplayMarkerButNotCancellation(t *testing.T) {
+	producer := &fakeProducer{}
+	d, err := New(producer, zap.NewNop(), fastConfig())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(messaging.WithReplay(context.Background()))
+	require.NoError(t, d.Dispatch(ctx, messaging.CompanyCreated, nil, company("a")))
+	cancel()
+	shutdown(t, d)
+
+	assert.Equal(t, []string{"created:a"}, producer.events())
+	assert.Equal(t, 1, producer.replays)
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	_, err := New(&fakeProducer{}, zap.NewNop(), Config{Overflow: "wait"})
+	assert.Error(t, err)
+
+	_, err = New(&fakeProducer{}, zap.NewNop(), Config{Overflow: OverflowSpill})
+	assert.Error(t, err, "spill exige um spool")
+}
@@ -0,0 +1,205 @@
+package messaging
+
+import (
+	"company-service/internal/domain"
+	"crypto/rand"
+	"crypto/sha1"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Atributos fixos dos eventos publicados (CloudEvents 1.0, modo estruturado em JSON)
+const (
+	SpecVersion     = "1.0"
+	EventSource     = "/company-service"
+	DataContentType = "application/json"
+
+	// ContentType é o content type de um CloudEvent estruturado
+	ContentType = "application/cloudevents+json"
+
+	// SchemaVersion é a versão do schema de CompanyEventData; muda a cada alteração
+	// incompatível dos dados, junto com o sufixo do tipo do evento
+	SchemaVersion = 1
+)
+
+// EventType é o tipo do evento, no formato br.company.<operação>.v<versão>
+type EventType string
+
+const (
+	CompanyCreated EventType = "br.company.created.v1"
+	CompanyUpdated EventType = "br.company.updated.v1"
+	CompanyDeleted EventType = "br.company.deleted.v1"
+)
+
+// EventTypes lista os tipos de evento publicados
+var EventTypes = []EventType{CompanyCreated, CompanyUpdated, CompanyDeleted}
+
+// Valid informa se o tipo é um dos eventos publicados
+func (t EventType) Valid() bool {
+	for _, known := range EventTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Operation retorna a operação do tipo do evento (created, updated ou deleted)
+func (t EventType) Operation() string {
+	parts := strings.Split(string(t), ".")
+	if len(parts) != 4 {
+		return ""
+	}
+	return parts[2]
+}
+
+// CompanyEvent é o envelope CloudEvents 1.0 dos eventos de empresa
+type CompanyEvent struct {
+	SpecVersion     string           `json:"specversion"`
+	ID              string           `json:"id"`
+	Source          string           `json:"source"`
+	Type            EventType        `json:"type"`
+	Subject         string           `json:"subject"`
+	Time            time.Time        `json:"time"`
+	DataContentType string           `json:"datacontenttype"`
+	TenantID        string           `json:"tenantid,omitempty"` // extensão com o tenant da empresa
+	Replay          bool             `json:"replay,omitempty"`   // extensão que marca eventos republicados por replay
+	Sequence        int64            `json:"sequence,omitempty"` // extensão com a posição do evento entre os eventos da empresa
+	Data            CompanyEventData `json:"data"`
+}
+
+// CompanyEventData é o snapshot completo da empresa no momento do evento. Em atualizações,
+// Previous traz o snapshot anterior e ChangedFields os campos alterados (omitido se nenhum).
+type CompanyEventData struct {
+	SchemaVersion               int       `json:"schema_version"`
+	ID                          string    `json:"id"`
+	TenantID                    string    `json:"tenant_id"`
+	CNPJ                        string    `json:"cnpj"`
+	FantasyName                 string    `json:"fantasy_name"`
+	CorporateName               string    `json:"corporate_name"`
+	Address                     string    `json:"address"`
+	EmployeeCount               int       `json:"employee_count"`
+	RequiredMinPWDEmployeeCount int       `json:"required_min_pwd_employee_count"`
+	CreatedAt                   time.Time `json:"created_at"`
+	UpdatedAt                   time.Time `json:"updated_at"`
+
+	Previous      *CompanyEventData `json:"previous,omitempty"`
+	ChangedFields []string          `json:"changed_fields,omitempty"`
+}
+
+// NewCompanyEvent cria o evento do tipo informado com o snapshot da empresa. A sequência é a da
+// empresa; na remoção, que não grava o documento, é a seguinte à última escrita.
+func NewCompanyEvent(eventType EventType, company *domain.Company) CompanyEvent {
+	sequence := company.Sequence
+	if eventType == CompanyDeleted && sequence > 0 {
+		sequence++
+	}
+	return CompanyEvent{
+		SpecVersion:     SpecVersion,
+		ID:              EventID(eventType, company.ID, sequence),
+		Sequence:        sequence,
+		Source:          EventSource,
+		Type:            eventType,
+		Subject:         company.ID,
+		Time:            time.Now().UTC(),
+		DataContentType: DataContentType,
+		TenantID:        company.TenantID,
+		Data:            NewCompanyEventData(company),
+	}
+}
+
+// MarkReplay marca o evento como republicado por um replay. O replay é uma nova publicação do
+// estado atual: mantém a sequência, mas recebe um ID novo para não ser descartado como duplicata
+// do evento original (ex.: pelo Nats-Msg-Id).
+func (e *CompanyEvent) MarkReplay() {
+	e.Replay = true
+	e.ID = newEventID()
+}
+
+// NewCompanyUpdatedEvent cria o evento de atualização com os snapshots anterior e atual e a
+// lista de campos alterados; sem o estado anterior (previous nil) traz apenas o snapshot atual
+func NewCompanyUpdatedEvent(previous, company *domain.Company) CompanyEvent {
+	event := NewCompanyEvent(CompanyUpdated, company)
+	if previous != nil {
+		before := NewCompanyEventData(previous)
+		event.Data.Previous = &before
+		event.Data.ChangedFields = ChangedFields(before, event.Data)
+	}
+	return event
+}
+
+// ChangedFields lista, pelos nomes JSON, os campos de negócio que diferem entre os snapshots.
+// Identificadores e datas de controle não entram na comparação.
+func ChangedFields(before, after CompanyEventData) []string {
+	var changed []string
+	for _, field := range []struct {
+		name    string
+		changed bool
+	}{
+		{"cnpj", before.CNPJ != after.CNPJ},
+		{"fantasy_name", before.FantasyName != after.FantasyName},
+		{"corporate_name", before.CorporateName != after.CorporateName},
+		{"address", before.Address != after.Address},
+		{"employee_count", before.EmployeeCount != after.EmployeeCount},
+		{"required_min_pwd_employee_count", before.RequiredMinPWDEmployeeCount != after.RequiredMinPWDEmployeeCount},
+	} {
+		if field.changed {
+			changed = append(changed, field.name)
+		}
+	}
+	return changed
+}
+
+// NewCompanyEventData converte a empresa no snapshot publicado nos eventos
+func NewCompanyEventData(company *domain.Company) CompanyEventData {
+	return CompanyEventData{
+		SchemaVersion:               SchemaVersion,
+		ID:                          company.ID,
+		TenantID:                    company.TenantID,
+		CNPJ:                        company.CNPJ,
+		FantasyName:                 company.FantasyName,
+		CorporateName:               company.CorporateName,
+		Address:                     company.Address,
+		EmployeeCount:               company.EmployeeCount,
+		RequiredMinPWDEmployeeCount: company.RequiredMinPWDEmployeeCount,
+		CreatedAt:                   company.CreatedAt,
+		UpdatedAt:                   company.UpdatedAt,
+	}
+}
+
+// eventNamespace é o namespace dos IDs de evento derivados da sequência (UUID v5)
+var eventNamespace = [16]byte{0x6b, 0x2f, 0x4e, 0x1a, 0x93, 0xd5, 0x4c, 0x0e, 0xa8, 0x71, 0x3c, 0x52, 0x0f, 0x9e, 0xb6, 0x24}
+
+// EventID retorna o ID do evento da empresa com a sequência informada: um UUID v5 do tipo, da
+// empresa e da sequência, o mesmo em todas as tentativas, na reentrega do spool e em todos os
+// destinos. Sem sequência (empresas gravadas antes dela), o ID é aleatório.
+func EventID(eventType EventType, companyID string, sequence int64) string {
+	if sequence <= 0 {
+		return newEventID()
+	}
+	h := sha1.New()
+	h.Write(eventNamespace[:])
+	fmt.Fprintf(h, "%s/%s/%d", eventType, companyID, sequence)
+
+	var b [16]byte
+	copy(b[:], h.Sum(nil))
+	b[6] = (b[6] & 0x0f) | 0x50 // versão 5
+	b[8] = (b[8] & 0x3f) | 0x80 // variante RFC 4122
+	return formatUUID(b)
+}
+
+// newEventID gera um UUID v4 para identificar o evento
+func newEventID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(fmt.Sprintf("failed to generate event id: %v", err))
+	}
+	b[6] = (b[6] & 0x0f) | 0x40 // versão 4
+	b[8] = (b[8] & 0x3f) | 0x80 // variante RFC 4122
+	return formatUUID(b)
+}
+
+func formatUUID(b [16]byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,165 @@
+package messaging
+
+import (
+	"company-service/internal/domain"
+	"encoding/json"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCompanyEvent_CloudEventsEnvelope(t *testing.T) {
+	createdAt := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	company := &domain.Company{
+		ID:                          "665f1c2e8b3e4a0001a1b2c3",
+		TenantID:                    "acme",
+		CNPJ:                        "11444777000161",
+		FantasyName:                 "Empresa Teste",
+		CorporateName:               "Empresa Teste LTDA",
+		Address:                     "Rua A, 100",
+		EmployeeCount:               150,
+		RequiredMinPWDEmployeeCount: 3,
+		CreatedAt:                   createdAt,
+		UpdatedAt:                   createdAt,
+	}
+
+	event := NewCompanyEvent(CompanyCreated, company)
+
+	body, err := json.Marshal(event)
+	require.NoError(t, err)
+
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(body, &decoded))
+
+	assert.Equal(t, "1.0", decoded["specversion"])
+	assert.Equal(t, "br.company.created.v1", decoded["type"])
+	assert.Equal(t, EventSource, decoded["source"])
+	assert.Equal(t, company.ID, decoded["subject"])
+	assert.Equal(t, "application/json", decoded["datacontenttype"])
+	assert.Equal(t, "acme", decoded["tenantid"])
+	assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), decoded["id"])
+
+	_, err = time.Parse(time.RFC3339, decoded["time"].(string))
+	assert.NoError(t, err)
+
+	data := decoded["data"].(map[string]interface{})
+	assert.Equal(t, float64(SchemaVersion), data["schema_version"])
+	assert.Equal(t, "11444777000161", data["cnpj"])
+	assert.Equal(t, "Empresa Teste", data["fantasy_name"])
+	assert.Equal(t, float64(150), data["employee_count"])
+	assert.Equal(t, "2024-05-10T12:00:00Z", data["created_at"])
+}
+
+func TestNewCompanyEvent_UniqueIDs(t *testing.T) {
+	company := &domain.Company{ID: "1"}
+
+	first := NewCompanyEvent(CompanyDeleted, company)
+	second := NewCompanyEvent(CompanyDeleted, company)
+
+	assert.NotEqual(t, first.ID, second.ID)
+	assert.Equal(t, CompanyDeleted, first.Type)
+}
+
+func TestNewCompanyEvent_SequenceDerivesStableID(t *testing.T) {
+	company := &domain.Company{ID: "665f1c2e8b3e4a0001a1b2c3", Sequence: 4}
+
+	first := NewCompanyEvent(CompanyUpdated, company)
+	retry := NewCompanyEvent(CompanyUpdated, company)
+	assert.Equal(t, first.ID, retry.ID, "o mesmo evento tem o mesmo ID em todas as tentativas")
+	assert.Equal(t, int64(4), first.Sequence)
+	assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-5[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), first.ID)
+
+	company.Sequence = 5
+	assert.NotEqual(t, first.ID, NewCompanyEvent(CompanyUpdated, company).ID)
+}
+
+func TestNewCompanyEvent_DeletedUsesNextSequence(t *testing.T) {
+	event := NewCompanyEvent(CompanyDeleted, &domain.Company{ID: "1", Sequence: 7})
+	assert.Equal(t, int64(8), event.Sequence)
+	assert.Equal(t, EventID(CompanyDeleted, "1", 8), event.ID)
+
+	body, err := json.Marshal(NewCompanyEvent(CompanyDeleted, &domain.Company{ID: "1"}))
+	require.NoError(t, err)
+	assert.NotContains(t, string(body), `"sequence"`, "empresas sem sequência não publicam a extensão")
+}
+
+func TestCompanyEvent_MarkReplayKeepsSequenceWithNewID(t *testing.T) {
+	original := NewCompanyEvent(CompanyUpdated, &domain.Company{ID: "1", Sequence: 3})
+
+	replayed := original
+	replayed.MarkReplay()
+	assert.True(t, replayed.Replay)
+	assert.NotEqual(t, original.ID, replayed.ID)
+	assert.Equal(t, original.Sequence, replayed.Sequence)
+}
+
+func TestEventType_Operation(t *testing.T) {
+	assert.Equal(t, "created", CompanyCreated.Operation())
+	assert.Equal(t, "updated", CompanyUpdated.Operation())
+	assert.Equal(t, "deleted", CompanyDeleted.Operation())
+	assert.Equal(t, "", EventType("invalid").Operation())
+}
+
+func TestNewCompanyUpdatedEvent_CarriesPreviousStateAndChangedFields(t *testing.T) {
+	previous := &domain.Company{ID: "1", TenantID: "acme", CNPJ: "11444777000161", FantasyName: "Antiga", EmployeeCount: 90}
+	company := &domain.Company{ID: "1", TenantID: "acme", CNPJ: "11444777000161", FantasyName: "Nova", EmployeeCount: 120,
+		UpdatedAt: time.Now()}
+
+	event := NewCompanyUpdatedEvent(previous, company)
+
+	body, err := json.Marshal(event)
+	require.NoError(t, err)
+
+	var decoded struct {
+		Type EventType `json:"type"`
+		Data struct {
+			FantasyName   string   `json:"fantasy_name"`
+			EmployeeCount int      `json:"employee_count"`
+			ChangedFields []string `json:"changed_fields"`
+			Previous      struct {
+				FantasyName   string `json:"fantasy_name"`
+				EmployeeCount int    `json:"employee_count"`
+			} `json:"previous"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(body, &decoded))
+
+	assert.Equal(t, CompanyUpdated, decoded.Type)
+	assert.Equal(t, "Nova", decoded.Data.FantasyName)
+	assert.Equal(t, 120, decoded.Data.EmployeeCount)
+	assert.Equal(t, "Antiga", decoded.Data.Previous.FantasyName)
+	assert.Equal(t, 90, decoded.Data.Previous.EmployeeCount)
+	assert.Equal(t, []string{"fantasy_name", "employee_count"}, decoded.Data.ChangedFields)
+}
+
+func TestNewCompanyUpdatedEvent_WithoutPreviousState(t *testing.T) {
+	event := NewCompanyUpdatedEvent(nil, &domain.Company{ID: "1"})
+
+	body, err := json.Marshal(event)
+	require.NoError(t, err)
+
+	assert.NotContains(t, string(body), `"previous"`)
+	assert.NotContains(t, string(body), `"changed_fields"`)
+}
+
+func TestChangedFields_IgnoresControlFields(t *testing.T) {
+	before := NewCompanyEventData(&domain.Company{ID: "1", CNPJ: "11444777000161", UpdatedAt: time.Now()})
+	after := NewCompanyEventData(&domain.Company{ID: "1", CNPJ: "11444777000161", UpdatedAt: time.Now().Add(time.Minute)})
+
+	assert.Empty(t, ChangedFields(before, after))
+
+	after.Address = "Rua B, 200"
+	after.RequiredMinPWDEmployeeCount = 2
+	assert.Equal(t, []string{"address", "required_min_pwd_employee_count"}, ChangedFields(before, after))
+}
+
+func TestEventType_Valid(t *testing.T) {
+	for _, eventType := range EventTypes {
+		assert.True(t, eventType.Valid())
+	}
+	assert.False(t, EventType("br.company.merged.v1").Valid())
+	assert.False(t, EventType("").Valid())
+}
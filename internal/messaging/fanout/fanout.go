@@ -0,0 +1,378 @@
+package fanout
+
+import (
+	"company-service/internal/domain"
+	"company-service/internal/messaging"
+	"company-service/internal/messaging/spool"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrClosed indica um envio após o Close
+var ErrClosed = errors.New("fan-out producer closed")
+
+// Policy define a fila e as novas tentativas de um destino
+type Policy struct {
+	QueueSize      int           // eventos aguardando envio; excedentes vão para o spool ou são descartados
+	MaxAttempts    int           // tentativas por evento, incluindo a primeira
+	InitialBackoff time.Duration // espera antes da segunda tentativa, dobrada a cada falha
+	MaxBackoff     time.Duration // limite da espera entre tentativas
+}
+
+func (p Policy) withDefaults() Policy {
+	if p.QueueSize <= 0 {
+		p.QueueSize = 10000
+	}
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 5
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = 1 * time.Second
+	}
+	if p.MaxBackoff < p.InitialBackoff {
+		p.MaxBackoff = p.InitialBackoff
+	}
+	return p
+}
+
+// Sink é um destino dos eventos. Spool, se informado, recebe os eventos que esgotam as
+// tentativas ou não cabem na fila; sem ele, esses eventos são descartados.
+type Sink struct {
+	Name     string
+	Producer messaging.MessageProducer
+	Policy   Policy
+	Spool    *spool.Spool
+}
+
+// SinkHealth é o estado de um destino, exposto na rota administrativa
+type SinkHealth struct {
+	Name        string      `json:"name"`
+	Healthy     bool        `json:"healthy"`
+	Queued      int         `json:"queued"`
+	QueueSize   int         `json:"queue_size"`
+	Delivered   uint64      `json:"delivered"` // eventos enviados
+	Retried     uint64      `json:"retried"`   // tentativas com falha seguidas de nova tentativa
+	Failed      uint64      `json:"failed"`    // eventos que esgotaram as tentativas
+	Dropped     uint64      `json:"dropped"`   // eventos descartados (fila cheia ou encerramento)
+	Spooled     uint64      `json:"spooled"`   // eventos gravados no spool
+	LastError   string      `json:"last_error,omitempty"`
+	LastErrorAt *time.Time  `json:"last_error_at,omitempty"`
+	Details     interface{} `json:"details,omitempty"` // estado informado pelo próprio produtor
+}
+
+// job é um evento aguardando envio a um destino. O contexto preserva os valores do envio
+// original (ex.: marcação de replay), mas não o cancelamento.
+type job struct {
+	ctx       context.Context
+	eventType messaging.EventType
+	previous  *domain.Company
+	company   *domain.Company
+}
+
+type sink struct {
+	Sink
+	queue chan job
+
+	mu      sync.Mutex
+	health  SinkHealth
+	failing bool // a última entrega esgotou as tentativas ou foi descartada
+}
+
+// Producer distribui cada evento para vários destinos (ex.: broker, webhooks e auditoria). Cada
+// destino tem sua fila, um worker e sua política de novas tentativas: um destino lento ou
+// indisponível não atrasa os demais nem quem publica, e os eventos de cada destino são enviados
+// na ordem em que foram recebidos.
+type Producer struct {
+	sinks        []*sink
+	logger       *zap.Logger
+	drainTimeout time.Duration
+
+	mu      sync.RWMutex
+	closed  bool
+	stop    chan struct{}
+	workers sync.WaitGroup
+}
+
+// NewProducer inicia os workers dos destinos; drainTimeout limita a espera do Close pelo
+// esvaziamento das filas
+func NewProducer(logger *zap.Logger, drainTimeout time.Duration, sinks ...Sink) *Producer {
+	if drainTimeout <= 0 {
+		drainTimeout = 10 * time.Second
+	}
+	p := &Producer{
+		logger:       logger,
+		drainTimeout: drainTimeout,
+		stop:         make(chan struct{}),
+	}
+	for _, cfg := range sinks {
+		cfg.Policy = cfg.Policy.withDefaults()
+		s := &sink{
+			Sink:   cfg,
+			queue:  make(chan job, cfg.Policy.QueueSize),
+			health: SinkHealth{Name: cfg.Name, QueueSize: cfg.Policy.QueueSize},
+		}
+		p.sinks = append(p.sinks, s)
+
+		p.workers.Add(1)
+		go func() {
+			defer p.workers.Done()
+			for j := range s.queue {
+				p.deliver(s, j)
+			}
+		}()
+	}
+	return p
+}
+
+func (p *Producer) SendCompanyCreated(ctx context.Context, company *domain.Company) error {
+	return p.publish(ctx, messaging.CompanyCreated, nil, company)
+}
+
+func (p *Producer) SendCompanyUpdated(ctx context.Context, previous, company *domain.Company) error {
+	return p.publish(ctx, messaging.CompanyUpdated, previous, company)
+}
+
+func (p *Producer) SendCompanyDeleted(ctx context.Context, company *domain.Company) error {
+	return p.publish(ctx, messaging.CompanyDeleted, nil, company)
+}
+
+// publish coloca o evento na fila de cada destino sem bloquear. Um destino com a fila cheia não
+// impede a entrega aos demais, por isso o envio só falha após o Close.
+func (p *Producer) publish(ctx context.Context, eventType messaging.EventType, previous, company *domain.Company) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return ErrClosed
+	}
+
+	j := job{ctx: context.WithoutCancel(ctx), eventType: eventType, previous: previous, company: company}
+	for _, s := range p.sinks {
+		select {
+		case s.queue <- j:
+		default:
+			p.abandon(s, j, errors.New("sink queue is full"))
+		}
+	}
+	return nil
+}
+
+// deliver envia o evento ao destino com novas tentativas e espera exponencial. Se a empresa já
+// tem eventos no spool do destino, o novo evento vai direto para o fim da fila dela, preservando
+// a ordem.
+func (p *Producer) deliver(s *sink, j job) {
+	if s.Spool != nil && s.Spool.HasPending(j.company.ID) {
+		p.spool(s, j, nil)
+		return
+	}
+
+	backoff := s.Policy.InitialBackoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		if p.stopped() {
+			p.abandon(s, j, errors.New("fan-out producer closed before delivery"))
+			return
+		}
+
+		if err = messaging.Send(j.ctx, s.Producer, j.eventType, j.previous, j.company); err == nil {
+			s.delivered()
+			return
+		}
+		if attempt >= s.Policy.MaxAttempts {
+			break
+		}
+
+		s.retried(err)
+		p.logger.Warn("Falha ao enviar evento ao destino, tentando novamente",
+			zap.String("sink", s.Name),
+			zap.String("type", string(j.eventType)),
+			zap.String("company_id", j.company.ID),
+			zap.Int("attempt", attempt),
+			zap.Error(err))
+
+		select {
+		case <-p.stop:
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, s.Policy.MaxBackoff)
+	}
+
+	s.failed(err)
+	p.logger.Error("Falha ao enviar evento ao destino após todas as tentativas",
+		zap.String("sink", s.Name),
+		zap.String("type", string(j.eventType)),
+		zap.String("company_id", j.company.ID),
+		zap.Error(err))
+	if s.Spool != nil {
+		p.spool(s, j, err)
+	}
+}
+
+// abandon grava no spool (se houver) ou descarta um evento que não pôde ser enviado
+func (p *Producer) abandon(s *sink, j job, cause error) {
+	if s.Spool != nil {
+		p.spool(s, j, cause)
+		return
+	}
+	s.dropped(cause)
+	p.logger.Error("Evento descartado pelo destino",
+		zap.String("sink", s.Name),
+		zap.String("type", string(j.eventType)),
+		zap.String("company_id", j.company.ID),
+		zap.Error(cause))
+}
+
+func (p *Producer) spool(s *sink, j job, cause error) {
+	entry, err := s.Spool.Append(j.eventType, j.previous, j.company, cause)
+	if err != nil {
+		s.dropped(err)
+		p.logger.Error("Falha ao gravar evento no spool",
+			zap.String("sink", s.Name),
+			zap.String("type", string(j.eventType)),
+			zap.String("company_id", j.company.ID),
+			zap.Error(err))
+		return
+	}
+	s.spooled()
+	p.logger.Warn("Evento gravado no spool para reentrega",
+		zap.String("sink", s.Name),
+		zap.Uint64("seq", entry.Seq),
+		zap.String("type", string(j.eventType)),
+		zap.String("company_id", j.company.ID))
+}
+
+func (p *Producer) stopped() bool {
+	select {
+	case <-p.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// Healthy informa se todos os destinos estão saudáveis
+func (p *Producer) Healthy() bool {
+	for _, health := range p.Health() {
+		if !health.Healthy {
+			return false
+		}
+	}
+	return true
+}
+
+// HealthDetails retorna o estado de cada destino
+func (p *Producer) HealthDetails() interface{} {
+	return p.Health()
+}
+
+// Health retorna o estado de cada destino, na ordem de configuração. Um destino está saudável
+// se o seu produtor informa que está disponível e se o último evento não foi perdido (esgotando
+// as tentativas ou descartado); o estado volta a saudável no próximo envio bem-sucedido.
+func (p *Producer) Health() []SinkHealth {
+	health := make([]SinkHealth, len(p.sinks))
+	for i, s := range p.sinks {
+		s.mu.Lock()
+		health[i] = s.health
+		health[i].Healthy = !s.failing
+		s.mu.Unlock()
+
+		health[i].Queued = len(s.queue)
+		switch producer := s.Producer.(type) {
+		case messaging.HealthReporter:
+			health[i].Healthy = health[i].Healthy && producer.Healthy()
+			health[i].Details = producer.HealthDetails()
+		case messaging.HealthChecker:
+			health[i].Healthy = health[i].Healthy && producer.Healthy()
+		}
+	}
+	return health
+}
+
+// Close para de aceitar eventos e aguarda o envio dos que estão nas filas por até o prazo de
+// drenagem; depois dele, as novas tentativas são interrompidas e os eventos restantes vão para
+// o spool do destino ou são descartados (o que é registrado no log). Os produtores dos destinos
+// não são encerrados.
+func (p *Producer) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.drainTimeout)
+	defer cancel()
+	_ = p.Shutdown(ctx)
+	return nil
+}
+
+// Shutdown é o Close com o prazo de drenagem dado por ctx, para encerramentos que dividem um
+// único prazo entre várias etapas
+func (p *Producer) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	for _, s := range p.sinks {
+		close(s.queue)
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		p.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		p.logger.Warn("Fan-out drain timeout exceeded, abandoning queued events", zap.Error(ctx.Err()))
+		close(p.stop)
+		<-done
+		return fmt.Errorf("fan-out did not drain in time: %w", ctx.Err())
+	}
+}
+
+func (s *sink) delivered() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.health.Delivered++
+	s.failing = false
+}
+
+func (s *sink) retried(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.health.Retried++
+	s.setError(err)
+}
+
+func (s *sink) failed(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.health.Failed++
+	s.failing = true
+	s.setError(err)
+}
+
+func (s *sink) dropped(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.health.Dropped++
+	s.failing = true
+	s.setError(err)
+}
+
+func (s *sink) spooled() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.health.Spooled++
+}
+
+// setError registra o último erro; deve ser chamado com o lock
+func (s *sink) setError(err error) {
+	now := time.Now().UTC()
+	s.health.LastError = err.Error()
+	s.health.LastErrorAt = &now
+}
@@ -0,0 +1,270 @@
+package fanout
+
+import (
+	"company-service/internal/domain"
+	"company-service/internal/messaging"
+	"company-service/internal/messaging/spool"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeProducer registra os eventos recebidos; fail decide o erro de cada envio e block, se
+// informado, segura os envios até ser fechado
+type fakeProducer struct {
+	mu      sync.Mutex
+	sent    []string
+	replays int
+	calls   int
+	fail    func(call int) error
+	block   chan struct{}
+}
+
+func (f *fakeProducer) send(ctx context.Context, eventType messaging.EventType, company *domain.Company) error {
+	if f.block != nil {
+		<-f.block
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.fail != nil {
+		if err := f.fail(f.calls); err != nil {
+			return err
+		}
+	}
+	f.sent = append(f.sent, eventType.Operation()+":"+company.ID)
+	if messaging.IsReplay(ctx) {
+		f.replays++
+	}
+	return nil
+}
+
+func (f *fakeProducer) SendCompanyCreated(ctx context.Context, company *domain.Company) error {
+	return f.send(ctx, messaging.CompanyCreated, company)
+}
+
+func (f *fakeProducer) SendCompanyUpdated(ctx context.Context, previous, company *domain.Company) error {
+	return f.send(ctx, messaging.CompanyUpdated, company)
+}
+
+func (f *fakeProducer) SendCompanyDeleted(ctx context.Context, company *domain.Company) error {
+	return f.send(ctx, messaging.CompanyDeleted, company)
+}
+
+func (f *fakeProducer) Close() error { return nil }
+
+func (f *fakeProducer) events() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.sent...)
+}
+
+func fastPolicy() Policy {
+	return Policy{QueueSize: 10, MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+}
+
+func company(id string) *domain.Company {
+	return &domain.Company{ID: id, TenantID: "acme"}
+}
+
+func healthOf(p *Producer, name string) SinkHealth {
+	for _, health := range p.Health() {
+		if health.Name == name {
+			return health
+		}
+	}
+	return SinkHealth{}
+}
+
+func TestProducer_DeliversToEverySinkInOrder(t *testing.T) {
+	broker, audit := &fakeProducer{}, &fakeProducer{}
+	p := NewProducer(zap.NewNop(), time.Second,
+		Sink{Name: "broker", Producer: broker, Policy: fastPolicy()},
+		Sink{Name: "audit", Producer: audit, Policy: fastPolicy()})
+
+	require.NoError(t, p.SendCompanyCreated(context.Background(), company("a")))
+	require.NoError(t, p.SendCompanyUpdated(context.Background(), company("a"), company("a")))
+	require.NoError(t, p.SendCompanyDeleted(context.Background(), company("a")))
+	require.NoError(t, p.Close())
+
+	expected := []string{"created:a", "updated:a", "deleted:a"}
+	assert.Equal(t, expected, broker.events())
+	assert.Equal(t, expected, audit.events())
+	assert.True(t, p.Healthy())
+	assert.Equal(t, uint64(3), healthOf(p, "audit").Delivered)
+	assert.ErrorIs(t, p.SendCompanyCreated(context.Background(), company("b")), ErrClosed)
+}
+
+func TestProducer_SlowSinkDoesNotBlockOthers(t *testing.T) {
+	slow := &fakeProducer{block: make(chan struct{})}
+	fast := &fakeProducer{}
+	p := NewProducer(zap.NewNop(), time.Second,
+		Sink{Name: "slow", Producer: slow, Policy: fastPolicy()},
+		Sink{Name: "fast", Producer: fast, Policy: fastPolicy()})
+
+	for _, id := range []string{"a", "b", "c"} {
+		require.NoError(t, p.SendCompanyCreated(context.Background(), company(id)))
+	}
+
+	require.Eventually(t, func() bool { return len(fast.events()) == 3 }, time.Second, time.Millisecond)
+	assert.Empty(t, slow.events())
+	assert.Equal(t, 2, healthOf(p, "slow").Queued, "um evento em envio e dois na fila")
+
+	close(slow.block)
+	require.NoError(t, p.Close())
+	assert.Equal(t, []string{"created:a", "created:b", "created:c"}, slow.events())
+}
+
+func TestProducer_RetriesWithSinkPolicy(t *testing.T) {
+	flaky := &fakeProducer{fail: func(call int) error {
+		if call < 3 {
+			return errors.New("temporarily unavailable")
+		}
+		return nil
+	}}
+	p := NewProducer(zap.NewNop(), time.Second, Sink{Name: "webhooks", Producer: flaky, Policy: fastPolicy()})
+
+	require.NoError(t, p.SendCompanyCreated(context.Background(), company("a")))
+	require.NoError(t, p.Close())
+
+	health := healthOf(p, "webhooks")
+	assert.Equal(t, []string{"created:a"}, flaky.events())
+	assert.Equal(t, uint64(2), health.Retried)
+	assert.Equal(t, uint64(1), health.Delivered)
+	assert.True(t, health.Healthy)
+	assert.Equal(t, "temporarily unavailable", health.LastError)
+}
+
+func TestProducer_ExhaustedSinkIsUnhealthyUntilNextSuccess(t *testing.T) {
+	var down sync.Mutex
+	failing := true
+	sinkProducer := &fakeProducer{fail: func(int) error {
+		down.Lock()
+		defer down.Unlock()
+		if failing {
+			return errors.New("audit store down")
+		}
+		return nil
+	}}
+	healthy := &fakeProducer{}
+	p := NewProducer(zap.NewNop(), time.Second,
+		Sink{Name: "broker", Producer: healthy, Policy: fastPolicy()},
+		Sink{Name: "audit", Producer: sinkProducer, Policy: fastPolicy()})
+	defer p.Close()
+
+	require.NoError(t, p.SendCompanyCreated(context.Background(), company("a")))
+	require.Eventually(t, func() bool { return healthOf(p, "audit").Failed == 1 }, time.Second, time.Millisecond)
+
+	assert.False(t, p.Healthy())
+	assert.False(t, healthOf(p, "audit").Healthy)
+	assert.True(t, healthOf(p, "broker").Healthy)
+	assert.Equal(t, []string{"created:a"}, healthy.events())
+
+	down.Lock()
+	failing = false
+	down.Unlock()
+	require.NoError(t, p.SendCompanyCreated(context.Background(), company("b")))
+	require.Eventually(t, func() bool { return healthOf(p, "audit").Healthy }, time.Second, time.Millisecond)
+	assert.Equal(t, []string{"created:b"}, sinkProducer.events())
+}
+
+func TestProducer_FullQueueDropsOrSpools(t *testing.T) {
+	eventSpool, err := spool.Open(t.TempDir(), 1<<20)
+	require.NoError(t, err)
+	defer eventSpool.Close()
+
+	dropping := &fakeProducer{block: make(chan struct{})}
+	spooling := &fakeProducer{block: make(chan struct{})}
+	policy := fastPolicy()
+	policy.QueueSize = 1
+	p := NewProducer(zap.NewNop(), time.Second,
+		Sink{Name: "webhooks", Producer: dropping, Policy: policy},
+		Sink{Name: "broker", Producer: spooling, Policy: policy, Spool: eventSpool})
+
+	// O primeiro evento fica em envio, o segundo na fila e o terceiro não cabe
+	require.NoError(t, p.SendCompanyCreated(context.Background(), company("a")))
+	require.Eventually(t, func() bool { return healthOf(p, "broker").Queued == 0 }, time.Second, time.Millisecond)
+	require.Eventually(t, func() bool { return healthOf(p, "webhooks").Queued == 0 }, time.Second, time.Millisecond)
+	require.NoError(t, p.SendCompanyCreated(context.Background(), company("b")))
+	require.NoError(t, p.SendCompanyCreated(context.Background(), company("c")))
+
+	assert.Equal(t, uint64(1), healthOf(p, "webhooks").Dropped)
+	assert.False(t, healthOf(p, "webhooks").Healthy)
+	assert.Equal(t, uint64(1), healthOf(p, "broker").Spooled)
+	pending := eventSpool.Pending()
+	require.Len(t, pending, 1)
+	assert.Equal(t, "c", pending[0].Company.ID)
+
+	close(dropping.block)
+	close(spooling.block)
+	require.NoError(t, p.Close())
+	assert.Equal(t, []string{"created:a", "created:b"}, dropping.events())
+	assert.Equal(t, []string{"created:a", "created:b"}, spooling.events())
+}
+
+func TestProducer_KeepsOrderBehindSpooledEvents(t *testing.T) {
+	eventSpool, err := spool.Open(t.TempDir(), 1<<20)
+	require.NoError(t, err)
+	defer eventSpool.Close()
+
+	broker := &fakeProducer{fail: func(call int) error {
+		if call <= 3 {
+			return errors.New("broker down")
+		}
+		return nil
+	}}
+	p := NewProducer(zap.NewNop(), time.Second, Sink{Name: "broker", Producer: broker, Policy: fastPolicy(), Spool: eventSpool})
+
+	require.NoError(t, p.SendCompanyCreated(context.Background(), company("a")))
+	require.NoError(t, p.SendCompanyUpdated(context.Background(), company("a"), company("a")))
+	require.NoError(t, p.SendCompanyCreated(context.Background(), company("b")))
+	require.NoError(t, p.Close())
+
+	// O evento de "a" que esgotou as tentativas vai para o spool, e o seguinte da mesma empresa
+	// entra atrás dele; a empresa "b" não é afetada
+	var spooled []string
+	for _, entry := range eventSpool.Pending() {
+		spooled = append(spooled, entry.Type.Operation()+":"+entry.Company.ID)
+	}
+	assert.Equal(t, []string{"created:a", "updated:a"}, spooled)
+	assert.Equal(t, []string{"created:b"}, broker.events())
+	assert.Equal(t, uint64(2), healthOf(p, "broker").Spooled)
+}
+
+func TestProducer_PreservesReplayMarkerButNotCancellation(t *testing.T) {
+	sinkProducer := &fakeProducer{}
+	p := NewProducer(zap.NewNop(), time.Second, Sink{Name: "broker", Producer: sinkProducer, Policy: fastPolicy()})
+
+	ctx, cancel := context.WithCancel(messaging.WithReplay(context.Background()))
+	require.NoError(t, p.SendCompanyCreated(ctx, company("a")))
+	cancel()
+	require.NoError(t, p.Close())
+
+	assert.Equal(t, []string{"created:a"}, sinkProducer.events())
+	assert.Equal(t, 1, sinkProducer.replays)
+}
+
+func TestProducer_CloseAbandonsEventsAfterDrainTimeout(t *testing.T) {
+	down := &fakeProducer{fail: func(int) error { return errors.New("down") }}
+	policy := fastPolicy()
+	policy.MaxAttempts = 1000
+	policy.InitialBackoff = time.Hour
+	policy.MaxBackoff = time.Hour
+	p := NewProducer(zap.NewNop(), 20*time.Millisecond, Sink{Name: "audit", Producer: down, Policy: policy})
+
+	require.NoError(t, p.SendCompanyCreated(context.Background(), company("a")))
+	require.NoError(t, p.SendCompanyCreated(context.Background(), company("b")))
+
+	start := time.Now()
+	require.NoError(t, p.Close())
+	assert.Less(t, time.Since(start), time.Second)
+
+	// O evento em nova tentativa e o que aguardava na fila são descartados
+	assert.Equal(t, uint64(2), healthOf(p, "audit").Dropped)
+}
@@ -0,0 +1,339 @@
+package kafka
+
+import (
+	"company-service/internal/domain"
+	"company-service/internal/messaging"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+)
+
+// Config define a conexão e as garantias de entrega do produtor Kafka
+type Config struct {
+	Brokers     []string
+	Topic       string
+	ClientID    string
+	Version     string        // versão do protocolo (ex.: "2.8.0"); vazio usa o padrão do sarama
+	Compression string        // none, gzip, snappy, lz4 ou zstd
+	Idempotent  bool          // produtor idempotente: sem duplicatas nas novas tentativas internas
+	Timeout     time.Duration // prazo para a confirmação de todas as réplicas em sincronia
+	HealthTTL   time.Duration // validade do estado das partições; 0 usa defaultHealthTTL
+}
+
+// defaultHealthTTL evita que cada verificação de saúde consulte os metadados no cluster
+const defaultHealthTTL = 15 * time.Second
+
+// PartitionHealth é o estado de uma partição do tópico de eventos
+type PartitionHealth struct {
+	Partition int32   `json:"partition"`
+	Leader    int32   `json:"leader"` // -1 sem líder
+	Replicas  []int32 `json:"replicas"`
+	ISR       []int32 `json:"isr"`
+	Available bool    `json:"available"`
+}
+
+// Health é o estado do tópico de eventos: saudável quando todas as partições têm líder, pois
+// cada empresa é sempre publicada na mesma partição
+type Health struct {
+	Topic      string            `json:"topic"`
+	Healthy    bool              `json:"healthy"`
+	Partitions []PartitionHealth `json:"partitions"`
+	Error      string            `json:"error,omitempty"`
+	CheckedAt  time.Time         `json:"checked_at"`
+}
+
+type kafkaProducer struct {
+	client   sarama.Client
+	producer sarama.AsyncProducer
+	topic    string
+	metrics  messaging.PublisherMetrics
+	done     sync.WaitGroup // rotinas que repassam os resultados do produtor
+
+	healthMu  sync.Mutex
+	health    Health
+	healthTTL time.Duration
+}
+
+// sendResult é o resultado da entrega de uma mensagem, repassado a quem a publicou
+type sendResult struct {
+	partition int32
+	offset    int64
+	err       error
+}
+
+// NewProducer conecta ao cluster e cria o produtor. As mensagens usam o ID da empresa como
+// chave, de modo que os eventos de uma empresa ficam na mesma partição e mantêm a ordem.
+func NewProducer(cfg Config) (messaging.MessageProducer, error) {
+	return newProducer(cfg)
+}
+
+func newProducer(cfg Config) (*kafkaProducer, error) {
+	saramaConfig, err := newSaramaConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := sarama.NewClient(cfg.Brokers, saramaConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Kafka: %w", err)
+	}
+	producer, err := sarama.NewAsyncProducerFromClient(client)
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to create Kafka producer: %w", err)
+	}
+
+	log.Printf("Connected to Kafka (topic: %q, brokers: %d, idempotent: %t, compression: %s)",
+		cfg.Topic, len(client.Brokers()), cfg.Idempotent, saramaConfig.Producer.Compression)
+
+	healthTTL := cfg.HealthTTL
+	if healthTTL <= 0 {
+		healthTTL = defaultHealthTTL
+	}
+	p := &kafkaProducer{
+		client:    client,
+		producer:  producer,
+		topic:     cfg.Topic,
+		healthTTL: healthTTL,
+	}
+	p.done.Add(2)
+	go p.forwardSuccesses()
+	go p.forwardErrors()
+	return p, nil
+}
+
+// forwardSuccesses entrega a confirmação de cada mensagem a quem a publicou
+func (p *kafkaProducer) forwardSuccesses() {
+	defer p.done.Done()
+	for message := range p.producer.Successes() {
+		message.Metadata.(chan sendResult) <- sendResult{partition: message.Partition, offset: message.Offset}
+	}
+}
+
+// forwardErrors entrega a falha de cada mensagem a quem a publicou
+func (p *kafkaProducer) forwardErrors() {
+	defer p.done.Done()
+	for failure := range p.producer.Errors() {
+		failure.Msg.Metadata.(chan sendResult) <- sendResult{err: failure.Err}
+	}
+}
+
+// newSaramaConfig traduz a configuração para o sarama
+func newSaramaConfig(cfg Config) (*sarama.Config, error) {
+	if len(cfg.Brokers) == 0 {
+		return nil, fmt.Errorf("no Kafka brokers configured")
+	}
+	if cfg.Topic == "" {
+		return nil, fmt.Errorf("no Kafka topic configured")
+	}
+
+	c := sarama.NewConfig()
+	if cfg.ClientID != "" {
+		c.ClientID = cfg.ClientID
+	}
+	if cfg.Version != "" {
+		version, err := sarama.ParseKafkaVersion(cfg.Version)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Kafka version %q: %w", cfg.Version, err)
+		}
+		c.Version = version
+	}
+
+	codec, err := compressionCodec(cfg.Compression)
+	if err != nil {
+		return nil, err
+	}
+	c.Producer.Compression = codec
+
+	// Chave = ID da empresa; o particionador por hash mantém cada empresa na mesma partição
+	c.Producer.Partitioner = sarama.NewHashPartitioner
+	c.Producer.RequiredAcks = sarama.WaitForAll
+	c.Producer.Return.Successes = true
+	c.Producer.Return.Errors = true
+	if cfg.Timeout > 0 {
+		c.Producer.Timeout = cfg.Timeout
+	}
+
+	// O produtor idempotente exige acks de todas as réplicas, novas tentativas e uma única
+	// requisição em andamento por conexão para não reordenar mensagens
+	if cfg.Idempotent {
+		c.Producer.Idempotent = true
+		c.Net.MaxOpenRequests = 1
+		if c.Producer.Retry.Max < 1 {
+			c.Producer.Retry.Max = 1
+		}
+	}
+
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid Kafka configuration: %w", err)
+	}
+	return c, nil
+}
+
+func compressionCodec(name string) (sarama.CompressionCodec, error) {
+	switch strings.ToLower(name) {
+	case "", "none":
+		return sarama.CompressionNone, nil
+	case "gzip":
+		return sarama.CompressionGZIP, nil
+	case "snappy":
+		return sarama.CompressionSnappy, nil
+	case "lz4":
+		return sarama.CompressionLZ4, nil
+	case "zstd":
+		return sarama.CompressionZSTD, nil
+	default:
+		return sarama.CompressionNone, fmt.Errorf("unknown Kafka compression %q", name)
+	}
+}
+
+func (p *kafkaProducer) SendCompanyCreated(ctx context.Context, company *domain.Company) error {
+	return p.sendEvent(ctx, messaging.NewCompanyEvent(messaging.CompanyCreated, company))
+}
+
+func (p *kafkaProducer) SendCompanyUpdated(ctx context.Context, previous, company *domain.Company) error {
+	return p.sendEvent(ctx, messaging.NewCompanyUpdatedEvent(previous, company))
+}
+
+func (p *kafkaProducer) SendCompanyDeleted(ctx context.Context, company *domain.Company) error {
+	return p.sendEvent(ctx, messaging.NewCompanyEvent(messaging.CompanyDeleted, company))
+}
+
+func (p *kafkaProducer) sendEvent(ctx context.Context, event messaging.CompanyEvent) error {
+	if messaging.IsReplay(ctx) {
+		event.MarkReplay()
+	}
+	_, _, err := p.publish(ctx, event)
+	return err
+}
+
+// publish envia o evento como CloudEvent estruturado, com o ID da empresa como chave, e
+// aguarda a confirmação das réplicas em sincronia. Retorna a partição e o offset gravados.
+// Com o contexto cancelado durante a espera, retorna o erro do contexto; a mensagem já
+// entregue ao produtor ainda pode ser gravada, e a nova tentativa gera uma duplicata com o
+// mesmo ce_id.
+func (p *kafkaProducer) publish(ctx context.Context, event messaging.CompanyEvent) (int32, int64, error) {
+	if err := ctx.Err(); err != nil {
+		p.metrics.Failed()
+		return 0, 0, err
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	message := &sarama.ProducerMessage{
+		Topic: p.topic,
+		Key:   sarama.StringEncoder(event.Subject),
+		Value: sarama.ByteEncoder(body),
+		Headers: []sarama.RecordHeader{
+			{Key: []byte("content-type"), Value: []byte(messaging.ContentType)},
+			{Key: []byte("ce_id"), Value: []byte(event.ID)},
+			{Key: []byte("ce_type"), Value: []byte(event.Type)},
+		},
+		Timestamp: event.Time,
+		Metadata:  make(chan sendResult, 1),
+	}
+	if event.Replay {
+		message.Headers = append(message.Headers, sarama.RecordHeader{Key: []byte(messaging.ReplayHeader), Value: []byte("true")})
+	}
+
+	start := time.Now()
+	p.metrics.Published()
+	select {
+	case p.producer.Input() <- message:
+	case <-ctx.Done():
+		p.metrics.Failed()
+		return 0, 0, ctx.Err()
+	}
+
+	var result sendResult
+	select {
+	case result = <-message.Metadata.(chan sendResult):
+	case <-ctx.Done():
+		p.metrics.Failed()
+		return 0, 0, fmt.Errorf("gave up waiting for confirmation of event %s: %w", event.ID, ctx.Err())
+	}
+	if result.err != nil {
+		p.metrics.Failed()
+		return 0, 0, fmt.Errorf("failed to publish event %s: %w", event.ID, result.err)
+	}
+	p.metrics.Confirmed(true, time.Since(start))
+
+	log.Printf("Event sent: %s %s (company %s, partition %d, offset %d)", event.Type, event.ID, event.Subject, result.partition, result.offset)
+	return result.partition, result.offset, nil
+}
+
+// Stats retorna as estatísticas de publicação, incluindo a latência das confirmações
+func (p *kafkaProducer) Stats() messaging.PublisherStats {
+	return p.metrics.Stats()
+}
+
+// Health informa o líder e as réplicas de cada partição. Os metadados são atualizados no
+// cluster no máximo uma vez a cada healthTTL; nesse intervalo as verificações, inclusive as
+// concorrentes, usam o último estado obtido.
+func (p *kafkaProducer) Health() Health {
+	p.healthMu.Lock()
+	defer p.healthMu.Unlock()
+
+	if p.health.CheckedAt.IsZero() || time.Since(p.health.CheckedAt) >= p.healthTTL {
+		p.health = p.checkHealth()
+	}
+	return p.health
+}
+
+// checkHealth atualiza os metadados do tópico no cluster e monta o estado das partições
+func (p *kafkaProducer) checkHealth() Health {
+	health := Health{Topic: p.topic, CheckedAt: time.Now()}
+
+	if err := p.client.RefreshMetadata(p.topic); err != nil {
+		health.Error = err.Error()
+		return health
+	}
+	partitions, err := p.client.Partitions(p.topic)
+	if err != nil {
+		health.Error = err.Error()
+		return health
+	}
+
+	health.Healthy = len(partitions) > 0
+	for _, partition := range partitions {
+		state := PartitionHealth{Partition: partition, Leader: -1}
+		state.Replicas, _ = p.client.Replicas(p.topic, partition)
+		state.ISR, _ = p.client.InSyncReplicas(p.topic, partition)
+		if leader, err := p.client.Leader(p.topic, partition); err == nil {
+			state.Leader = leader.ID()
+			state.Available = true
+		}
+		if !state.Available {
+			health.Healthy = false
+		}
+		health.Partitions = append(health.Partitions, state)
+	}
+	return health
+}
+
+// Healthy informa se todas as partições do tópico têm líder disponível
+func (p *kafkaProducer) Healthy() bool {
+	return p.Health().Healthy
+}
+
+// HealthDetails retorna o estado das partições para a rota administrativa
+func (p *kafkaProducer) HealthDetails() interface{} {
+	return p.Health()
+}
+
+// Close envia as mensagens pendentes, aguarda seus resultados e fecha o produtor e a conexão
+// com o cluster
+func (p *kafkaProducer) Close() error {
+	p.producer.AsyncClose()
+	p.done.Wait()
+	return p.client.Close()
+}
@@ -0,0 +1,235 @@
+package kafka
+
+import (
+	"company-service/internal/domain"
+	"company-service/internal/messaging"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testTopic = "company.events"
+
+func testCompany(id string) *domain.Company {
+	return &domain.Company{
+		ID:          id,
+		TenantID:    "acme",
+		CNPJ:        "11444777000161",
+		FantasyName: "Empresa " + id,
+		Address:     "Rua Teste, 123, Centro - São Paulo/SP",
+	}
+}
+
+// newTestBroker sobe um broker Kafka em processo com o tópico de eventos; leaders define o
+// líder de cada partição (-1 = sem líder)
+func newTestBroker(t *testing.T, produce *sarama.MockProduceResponse, leaders ...int32) *sarama.MockBroker {
+	t.Helper()
+	broker := sarama.NewMockBroker(t, 1)
+	t.Cleanup(broker.Close)
+
+	metadata := sarama.NewMockMetadataResponse(t).SetBroker(broker.Addr(), broker.BrokerID())
+	for partition, leader := range leaders {
+		metadata.SetLeader(testTopic, int32(partition), leader)
+	}
+	if produce == nil {
+		produce = sarama.NewMockProduceResponse(t)
+	}
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"ApiVersionsRequest":    sarama.NewMockApiVersionsResponse(t),
+		"MetadataRequest":       metadata,
+		"InitProducerIDRequest": sarama.NewMockInitProducerIDResponse(t).SetProducerID(1000),
+		"ProduceRequest":        produce,
+	})
+	return broker
+}
+
+func newTestProducer(t *testing.T, broker *sarama.MockBroker, cfg Config) *kafkaProducer {
+	t.Helper()
+	cfg.Brokers = []string{broker.Addr()}
+	cfg.Topic = testTopic
+	producer, err := newProducer(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { producer.Close() })
+	return producer
+}
+
+func TestProducer_PublishesKeyedCloudEvent(t *testing.T) {
+	broker := newTestBroker(t, nil, 1, 1, 1)
+	producer := newTestProducer(t, broker, Config{Idempotent: true, Compression: "snappy"})
+
+	require.NoError(t, producer.SendCompanyCreated(context.Background(), testCompany("a")))
+
+	stats := producer.Stats()
+	assert.Equal(t, uint64(1), stats.Published)
+	assert.Equal(t, uint64(1), stats.Confirmed)
+	assert.Equal(t, uint64(0), stats.Failed)
+
+	var produceRequests int
+	for _, request := range broker.History() {
+		if _, ok := request.Request.(*sarama.ProduceRequest); ok {
+			produceRequests++
+		}
+	}
+	assert.Equal(t, 1, produceRequests)
+}
+
+func TestProducer_SameCompanyAlwaysGoesToSamePartition(t *testing.T) {
+	broker := newTestBroker(t, nil, 1, 1, 1, 1, 1, 1, 1, 1)
+	producer := newTestProducer(t, broker, Config{})
+
+	ctx := context.Background()
+	first, _, err := producer.publish(ctx, messaging.NewCompanyEvent(messaging.CompanyCreated, testCompany("a")))
+	require.NoError(t, err)
+
+	previous := testCompany("a")
+	updated := testCompany("a")
+	updated.FantasyName = "Outro nome"
+	for _, event := range []messaging.CompanyEvent{
+		messaging.NewCompanyUpdatedEvent(previous, updated),
+		messaging.NewCompanyEvent(messaging.CompanyDeleted, updated),
+	} {
+		partition, _, err := producer.publish(ctx, event)
+		require.NoError(t, err)
+		assert.Equal(t, first, partition, "eventos da mesma empresa mantêm a partição e a ordem")
+	}
+
+	partitions := map[int32]bool{}
+	for _, id := range []string{"b", "c", "d", "e", "f", "g", "h", "i"} {
+		partition, _, err := producer.publish(ctx, messaging.NewCompanyEvent(messaging.CompanyCreated, testCompany(id)))
+		require.NoError(t, err)
+		partitions[partition] = true
+	}
+	assert.Greater(t, len(partitions), 1, "empresas diferentes são distribuídas entre as partições")
+}
+
+func TestProducer_ProduceErrorIsReturnedAndCounted(t *testing.T) {
+	produce := sarama.NewMockProduceResponse(t).SetError(testTopic, 0, sarama.ErrMessageSizeTooLarge)
+	broker := newTestBroker(t, produce, 1)
+	producer := newTestProducer(t, broker, Config{})
+
+	err := producer.SendCompanyDeleted(context.Background(), testCompany("a"))
+	assert.ErrorIs(t, err, sarama.ErrMessageSizeTooLarge)
+
+	stats := producer.Stats()
+	assert.Equal(t, uint64(1), stats.Published)
+	assert.Equal(t, uint64(0), stats.Confirmed)
+	assert.Equal(t, uint64(1), stats.Failed)
+}
+
+func TestProducer_CanceledContextIsNotPublished(t *testing.T) {
+	broker := newTestBroker(t, nil, 1)
+	producer := newTestProducer(t, broker, Config{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, producer.SendCompanyCreated(ctx, testCompany("a")), context.Canceled)
+	assert.Equal(t, uint64(0), producer.Stats().Published)
+}
+
+func TestProducer_HealthReportsPartitionsWithoutLeader(t *testing.T) {
+	broker := newTestBroker(t, nil, 1, -1)
+	producer := newTestProducer(t, broker, Config{})
+
+	health := producer.Health()
+	assert.False(t, health.Healthy)
+	assert.False(t, producer.Healthy())
+	require.Len(t, health.Partitions, 2)
+
+	byPartition := map[int32]PartitionHealth{}
+	for _, partition := range health.Partitions {
+		byPartition[partition.Partition] = partition
+	}
+	assert.True(t, byPartition[0].Available)
+	assert.Equal(t, int32(1), byPartition[0].Leader)
+	assert.False(t, byPartition[1].Available)
+	assert.Equal(t, int32(-1), byPartition[1].Leader)
+
+	body, err := json.Marshal(producer.HealthDetails())
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"topic":"company.events"`)
+}
+
+func TestProducer_HealthyWhenEveryPartitionHasLeader(t *testing.T) {
+	broker := newTestBroker(t, nil, 1, 1)
+	producer := newTestProducer(t, broker, Config{})
+
+	health := producer.Health()
+	assert.True(t, health.Healthy)
+	assert.Empty(t, health.Error)
+	assert.Len(t, health.Partitions, 2)
+}
+
+// metadataRequests conta as consultas de metadados recebidas pelo broker
+func metadataRequests(broker *sarama.MockBroker) int {
+	var count int
+	for _, request := range broker.History() {
+		if _, ok := request.Request.(*sarama.MetadataRequest); ok {
+			count++
+		}
+	}
+	return count
+}
+
+func TestProducer_HealthIsCachedForTTL(t *testing.T) {
+	broker := newTestBroker(t, nil, 1, 1)
+	producer := newTestProducer(t, broker, Config{HealthTTL: 50 * time.Millisecond})
+
+	first := producer.Health()
+	requests := metadataRequests(broker)
+	for i := 0; i < 5; i++ {
+		assert.True(t, producer.Healthy())
+	}
+	assert.Equal(t, requests, metadataRequests(broker), "dentro do TTL as verificações não consultam o cluster")
+	assert.Equal(t, first.CheckedAt, producer.Health().CheckedAt)
+
+	time.Sleep(60 * time.Millisecond)
+	assert.True(t, producer.Healthy())
+	assert.Greater(t, metadataRequests(broker), requests, "após o TTL os metadados são atualizados")
+}
+
+func TestProducer_SendHonorsContextDeadline(t *testing.T) {
+	broker := newTestBroker(t, nil, 1)
+	producer := newTestProducer(t, broker, Config{})
+	broker.SetLatency(time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := producer.SendCompanyCreated(ctx, testCompany("a"))
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 500*time.Millisecond, "não aguarda a confirmação após o prazo")
+	assert.Equal(t, uint64(1), producer.Stats().Failed)
+}
+
+func TestNewSaramaConfig(t *testing.T) {
+	base := Config{Brokers: []string{"localhost:9092"}, Topic: testTopic}
+
+	idempotent := base
+	idempotent.Idempotent = true
+	idempotent.Compression = "zstd"
+	c, err := newSaramaConfig(idempotent)
+	require.NoError(t, err)
+	assert.True(t, c.Producer.Idempotent)
+	assert.Equal(t, sarama.WaitForAll, c.Producer.RequiredAcks)
+	assert.Equal(t, 1, c.Net.MaxOpenRequests)
+	assert.Equal(t, sarama.CompressionZSTD, c.Producer.Compression)
+
+	invalid := base
+	invalid.Compression = "brotli"
+	_, err = newSaramaConfig(invalid)
+	assert.Error(t, err)
+
+	invalid = base
+	invalid.Version = "not-a-version"
+	_, err = newSaramaConfig(invalid)
+	assert.Error(t, err)
+
+	_, err = newSaramaConfig(Config{Topic: testTopic})
+	assert.Error(t, err, "sem brokers")
+}
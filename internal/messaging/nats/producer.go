@@ -0,0 +1,252 @@
+package nats
+
+import (
+	"company-service/internal/domain"
+	"company-service/internal/messaging"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	natsio "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Config define a conexão e o stream JetStream dos eventos
+type Config struct {
+	URL             string
+	Stream          string        // nome do stream criado na inicialização
+	SubjectPrefix   string        // eventos publicados em <prefixo>.<evento>, ex.: companies.created
+	Replicas        int           // réplicas do stream (1 em servidor único)
+	MaxAge          time.Duration // retenção das mensagens; 0 mantém indefinidamente
+	DuplicateWindow time.Duration // janela de de-duplicação pelo cabeçalho Nats-Msg-Id
+	Timeout         time.Duration // prazo para o ack do JetStream
+	UpdateStream    bool          // aplica a configuração acima a um stream já existente
+}
+
+// Health é o estado da conexão e do stream de eventos
+type Health struct {
+	Connected bool   `json:"connected"`
+	Server    string `json:"server,omitempty"`
+	Stream    string `json:"stream"`
+	Messages  uint64 `json:"messages"`
+	LastSeq   uint64 `json:"last_seq"`
+	Error     string `json:"error,omitempty"`
+}
+
+type natsProducer struct {
+	conn          *natsio.Conn
+	js            jetstream.JetStream
+	stream        string
+	subjectPrefix string
+	timeout       time.Duration
+	metrics       messaging.PublisherMetrics
+}
+
+// NewProducer conecta ao servidor, cria o stream dos eventos se não existir e cria o produtor.
+// Cada mensagem leva o ID do evento no cabeçalho Nats-Msg-Id, de modo que o JetStream descarta
+// as publicações repetidas dentro da janela de de-duplicação.
+func NewProducer(cfg Config) (messaging.MessageProducer, error) {
+	return newProducer(cfg)
+}
+
+func newProducer(cfg Config) (*natsProducer, error) {
+	if cfg.Stream == "" {
+		return nil, fmt.Errorf("no JetStream stream configured")
+	}
+	if cfg.SubjectPrefix == "" {
+		cfg.SubjectPrefix = "companies"
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+
+	// O cliente reconecta sozinho; as publicações falham enquanto não houver conexão
+	conn, err := natsio.Connect(cfg.URL,
+		natsio.Name("company-service"),
+		natsio.MaxReconnects(-1),
+		natsio.DisconnectErrHandler(func(_ *natsio.Conn, err error) {
+			log.Printf("NATS connection lost: %v", err)
+		}),
+		natsio.ReconnectHandler(func(conn *natsio.Conn) {
+			log.Printf("Reconnected to NATS (%s)", conn.ConnectedUrl())
+		}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+	if err := ensureStream(ctx, js, cfg); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	log.Printf("Connected to NATS JetStream (stream: %q, subjects: %s.>)", cfg.Stream, cfg.SubjectPrefix)
+
+	return &natsProducer{
+		conn:          conn,
+		js:            js,
+		stream:        cfg.Stream,
+		subjectPrefix: cfg.SubjectPrefix,
+		timeout:       cfg.Timeout,
+	}, nil
+}
+
+// ensureStream cria o stream que recebe todos os subjects de eventos. Um stream existente é
+// usado como está, já que pode ter sido ajustado pelos operadores (réplicas, retenção, limites);
+// sua configuração só é substituída com UpdateStream.
+func ensureStream(ctx context.Context, js jetstream.JetStream, cfg Config) error {
+	replicas := cfg.Replicas
+	if replicas < 1 {
+		replicas = 1
+	}
+	streamConfig := jetstream.StreamConfig{
+		Name:       cfg.Stream,
+		Subjects:   []string{cfg.SubjectPrefix + ".>"},
+		Storage:    jetstream.FileStorage,
+		Retention:  jetstream.LimitsPolicy,
+		Replicas:   replicas,
+		MaxAge:     cfg.MaxAge,
+		Duplicates: cfg.DuplicateWindow,
+	}
+
+	if cfg.UpdateStream {
+		if _, err := js.CreateOrUpdateStream(ctx, streamConfig); err != nil {
+			return fmt.Errorf("failed to create or update stream %s: %w", cfg.Stream, err)
+		}
+		return nil
+	}
+
+	_, err := js.CreateStream(ctx, streamConfig)
+	if errors.Is(err, jetstream.ErrStreamNameAlreadyInUse) {
+		if _, err := js.Stream(ctx, cfg.Stream); err != nil {
+			return fmt.Errorf("failed to look up stream %s: %w", cfg.Stream, err)
+		}
+		log.Printf("Using existing JetStream stream %q without changing its configuration", cfg.Stream)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create stream %s: %w", cfg.Stream, err)
+	}
+	return nil
+}
+
+// subject retorna o subject do evento: <prefixo>.<operação>, ex.: companies.updated
+func (p *natsProducer) subject(eventType messaging.EventType) string {
+	return p.subjectPrefix + "." + eventType.Operation()
+}
+
+func (p *natsProducer) SendCompanyCreated(ctx context.Context, company *domain.Company) error {
+	return p.sendEvent(ctx, messaging.NewCompanyEvent(messaging.CompanyCreated, company))
+}
+
+func (p *natsProducer) SendCompanyUpdated(ctx context.Context, previous, company *domain.Company) error {
+	return p.sendEvent(ctx, messaging.NewCompanyUpdatedEvent(previous, company))
+}
+
+func (p *natsProducer) SendCompanyDeleted(ctx context.Context, company *domain.Company) error {
+	return p.sendEvent(ctx, messaging.NewCompanyEvent(messaging.CompanyDeleted, company))
+}
+
+func (p *natsProducer) sendEvent(ctx context.Context, event messaging.CompanyEvent) error {
+	if messaging.IsReplay(ctx) {
+		event.MarkReplay()
+	}
+	_, err := p.publish(ctx, event)
+	return err
+}
+
+// publish envia o evento como CloudEvent estruturado e aguarda o ack do JetStream. Uma
+// publicação repetida do mesmo evento é confirmada pelo servidor como duplicata, sem ser
+// gravada novamente.
+func (p *natsProducer) publish(ctx context.Context, event messaging.CompanyEvent) (*jetstream.PubAck, error) {
+	if err := ctx.Err(); err != nil {
+		p.metrics.Failed()
+		return nil, err
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	msg := natsio.NewMsg(p.subject(event.Type))
+	msg.Data = body
+	msg.Header.Set("Content-Type", messaging.ContentType)
+	msg.Header.Set("Ce-Type", string(event.Type))
+	msg.Header.Set("Ce-Subject", event.Subject)
+	if event.Replay {
+		msg.Header.Set(messaging.ReplayHeader, "true")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	start := time.Now()
+	p.metrics.Published()
+	ack, err := p.js.PublishMsg(ctx, msg, jetstream.WithMsgID(event.ID), jetstream.WithExpectStream(p.stream))
+	if err != nil {
+		p.metrics.Failed()
+		return nil, fmt.Errorf("failed to publish event %s: %w", event.ID, err)
+	}
+	p.metrics.Confirmed(true, time.Since(start))
+
+	if ack.Duplicate {
+		log.Printf("Event already in stream: %s %s (seq %d)", event.Type, event.ID, ack.Sequence)
+	} else {
+		log.Printf("Event sent: %s %s (subject %s, seq %d)", event.Type, event.ID, msg.Subject, ack.Sequence)
+	}
+	return ack, nil
+}
+
+// Stats retorna as estatísticas de publicação, incluindo a latência dos acks
+func (p *natsProducer) Stats() messaging.PublisherStats {
+	return p.metrics.Stats()
+}
+
+// Health consulta o stream de eventos no servidor
+func (p *natsProducer) Health() Health {
+	health := Health{Connected: p.conn.IsConnected(), Server: p.conn.ConnectedUrlRedacted(), Stream: p.stream}
+	if !health.Connected {
+		health.Error = p.conn.Status().String()
+		return health
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+	stream, err := p.js.Stream(ctx, p.stream)
+	if err != nil {
+		health.Error = err.Error()
+		return health
+	}
+	info := stream.CachedInfo()
+	health.Messages = info.State.Msgs
+	health.LastSeq = info.State.LastSeq
+	return health
+}
+
+// Healthy informa se há conexão com o servidor
+func (p *natsProducer) Healthy() bool {
+	return p.conn.IsConnected()
+}
+
+// HealthDetails retorna o estado da conexão e do stream para a rota administrativa
+func (p *natsProducer) HealthDetails() interface{} {
+	return p.Health()
+}
+
+// Close fecha a conexão; as publicações são síncronas, então não há mensagens pendentes
+func (p *natsProducer) Close() error {
+	p.conn.Close()
+	return nil
+}
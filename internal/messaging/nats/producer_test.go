@@ -0,0 +1,195 @@
+package nats
+
+import (
+	"company-service/internal/domain"
+	"company-service/internal/messaging"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testStream = "COMPANIES"
+
+func testCompany(id string) *domain.Company {
+	return &domain.Company{
+		ID:          id,
+		TenantID:    "acme",
+		CNPJ:        "11444777000161",
+		FantasyName: "Empresa " + id,
+		Address:     "Rua Teste, 123, Centro - São Paulo/SP",
+	}
+}
+
+// runServer sobe um nats-server com JetStream em processo, numa porta livre
+func runServer(t *testing.T) *server.Server {
+	t.Helper()
+	ns, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	require.NoError(t, err)
+	go ns.Start()
+	require.True(t, ns.ReadyForConnections(5*time.Second), "nats-server não iniciou")
+	t.Cleanup(ns.Shutdown)
+	return ns
+}
+
+func testConfig(ns *server.Server) Config {
+	return Config{
+		URL:             ns.ClientURL(),
+		Stream:          testStream,
+		SubjectPrefix:   "companies",
+		DuplicateWindow: time.Minute,
+		Timeout:         time.Second,
+	}
+}
+
+func newTestProducer(t *testing.T, cfg Config) *natsProducer {
+	t.Helper()
+	producer, err := newProducer(cfg)
+	require.NoError(t, err)
+	t.Cleanup(func() { producer.Close() })
+	return producer
+}
+
+func streamInfo(t *testing.T, producer *natsProducer) *jetstream.StreamInfo {
+	t.Helper()
+	stream, err := producer.js.Stream(context.Background(), testStream)
+	require.NoError(t, err)
+	info, err := stream.Info(context.Background(), jetstream.WithSubjectFilter(">"))
+	require.NoError(t, err)
+	return info
+}
+
+func TestProducer_CreatesStreamAtStartup(t *testing.T) {
+	ns := runServer(t)
+	cfg := testConfig(ns)
+	producer := newTestProducer(t, cfg)
+
+	info := streamInfo(t, producer)
+	assert.Equal(t, []string{"companies.>"}, info.Config.Subjects)
+	assert.Equal(t, time.Minute, info.Config.Duplicates)
+	assert.Equal(t, jetstream.FileStorage, info.Config.Storage)
+
+	// Uma nova instância reaproveita o stream existente sem alterar sua configuração
+	cfg.MaxAge = time.Hour
+	again := newTestProducer(t, cfg)
+	assert.Equal(t, time.Duration(0), streamInfo(t, again).Config.MaxAge)
+
+	// Com UpdateStream a configuração do serviço é aplicada
+	cfg.UpdateStream = true
+	updated := newTestProducer(t, cfg)
+	assert.Equal(t, time.Hour, streamInfo(t, updated).Config.MaxAge)
+}
+
+func TestProducer_PublishesToEventSubjects(t *testing.T) {
+	producer := newTestProducer(t, testConfig(runServer(t)))
+	ctx := context.Background()
+
+	previous := testCompany("a")
+	updated := testCompany("a")
+	updated.FantasyName = "Outro nome"
+	require.NoError(t, producer.SendCompanyCreated(ctx, previous))
+	require.NoError(t, producer.SendCompanyUpdated(ctx, previous, updated))
+	require.NoError(t, producer.SendCompanyDeleted(ctx, updated))
+
+	info := streamInfo(t, producer)
+	assert.Equal(t, uint64(3), info.State.Msgs)
+	assert.Equal(t, map[string]uint64{
+		"companies.created": 1,
+		"companies.updated": 1,
+		"companies.deleted": 1,
+	}, info.State.Subjects)
+
+	stream, err := producer.js.Stream(ctx, testStream)
+	require.NoError(t, err)
+	msg, err := stream.GetMsg(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, messaging.ContentType, msg.Header.Get("Content-Type"))
+	assert.Equal(t, string(messaging.CompanyUpdated), msg.Header.Get("Ce-Type"))
+	assert.NotEmpty(t, msg.Header.Get(jetstream.MsgIDHeader))
+
+	var event messaging.CompanyEvent
+	require.NoError(t, json.Unmarshal(msg.Data, &event))
+	assert.Equal(t, msg.Header.Get(jetstream.MsgIDHeader), event.ID)
+	assert.Equal(t, []string{"fantasy_name"}, event.Data.ChangedFields)
+
+	stats := producer.Stats()
+	assert.Equal(t, uint64(3), stats.Published)
+	assert.Equal(t, uint64(3), stats.Confirmed)
+}
+
+func TestProducer_RepublishedEventIsDeduplicated(t *testing.T) {
+	producer := newTestProducer(t, testConfig(runServer(t)))
+	ctx := context.Background()
+
+	event := messaging.NewCompanyEvent(messaging.CompanyCreated, testCompany("a"))
+	first, err := producer.publish(ctx, event)
+	require.NoError(t, err)
+	assert.False(t, first.Duplicate)
+
+	again, err := producer.publish(ctx, event)
+	require.NoError(t, err)
+	assert.True(t, again.Duplicate)
+	assert.Equal(t, first.Sequence, again.Sequence)
+
+	assert.Equal(t, uint64(1), streamInfo(t, producer).State.Msgs)
+}
+
+func TestProducer_MarksReplayedEvents(t *testing.T) {
+	producer := newTestProducer(t, testConfig(runServer(t)))
+	ctx := context.Background()
+
+	require.NoError(t, producer.SendCompanyCreated(ctx, testCompany("a")))
+	require.NoError(t, producer.SendCompanyUpdated(messaging.WithReplay(ctx), nil, testCompany("a")))
+
+	stream, err := producer.js.Stream(ctx, testStream)
+	require.NoError(t, err)
+
+	original, err := stream.GetMsg(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, original.Header.Get(messaging.ReplayHeader))
+
+	replayed, err := stream.GetMsg(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "true", replayed.Header.Get(messaging.ReplayHeader))
+
+	var event messaging.CompanyEvent
+	require.NoError(t, json.Unmarshal(replayed.Data, &event))
+	assert.True(t, event.Replay)
+}
+
+func TestProducer_HealthFollowsConnection(t *testing.T) {
+	ns := runServer(t)
+	producer := newTestProducer(t, testConfig(ns))
+
+	require.NoError(t, producer.SendCompanyCreated(context.Background(), testCompany("a")))
+	health := producer.Health()
+	assert.True(t, producer.Healthy())
+	assert.True(t, health.Connected)
+	assert.Equal(t, uint64(1), health.Messages)
+	assert.Empty(t, health.Error)
+
+	ns.Shutdown()
+	require.Eventually(t, func() bool { return !producer.Healthy() }, 5*time.Second, 10*time.Millisecond)
+	assert.NotEmpty(t, producer.Health().Error)
+
+	err := producer.SendCompanyDeleted(context.Background(), testCompany("a"))
+	assert.Error(t, err)
+	assert.Equal(t, uint64(1), producer.Stats().Failed)
+}
+
+func TestNewProducer_RequiresStream(t *testing.T) {
+	_, err := newProducer(Config{URL: "nats://127.0.0.1:1"})
+	assert.Error(t, err)
+}
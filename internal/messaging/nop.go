@@ -0,0 +1,22 @@
+package messaging
+
+import (
+	"company-service/internal/domain"
+	"context"
+)
+
+// nopProducer descarta as mensagens; usado quando os eventos são gerados por outra fonte
+// (ex.: change stream do MongoDB) e o serviço não deve publicá-los diretamente
+type nopProducer struct{}
+
+// NewNopProducer cria um MessageProducer que não publica nada
+func NewNopProducer() MessageProducer {
+	return nopProducer{}
+}
+
+func (nopProducer) SendCompanyCreated(ctx context.Context, company *domain.Company) error { return nil }
+func (nopProducer) SendCompanyUpdated(ctx context.Context, previous, company *domain.Company) error {
+	return nil
+}
+func (nopProducer) SendCompanyDeleted(ctx context.Context, company *domain.Company) error { return nil }
+func (nopProducer) Close() error                                                          { return nil }
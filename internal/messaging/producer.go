@@ -3,12 +3,40 @@ package messaging
 import (
 	"company-service/internal/domain"
 	"context"
+	"fmt"
 )
 
 // MessageProducer define a produção de mensagens para eventos relacionados à empresa
 type MessageProducer interface {
 	SendCompanyCreated(ctx context.Context, company *domain.Company) error
-	SendCompanyUpdated(ctx context.Context, company *domain.Company) error
+	SendCompanyUpdated(ctx context.Context, previous, company *domain.Company) error
 	SendCompanyDeleted(ctx context.Context, company *domain.Company) error
 	Close() error
 }
+
+// HealthChecker é implementado pelos produtores que sabem informar se o broker está disponível
+type HealthChecker interface {
+	Healthy() bool
+}
+
+// HealthReporter é implementado pelos produtores que detalham o estado do broker
+// (ex.: conexão, partições e líderes) para a rota administrativa
+type HealthReporter interface {
+	HealthChecker
+	HealthDetails() interface{}
+}
+
+// Send publica o evento do tipo informado pelo método correspondente do produtor; previous é o
+// estado anterior da empresa, usado apenas em atualizações
+func Send(ctx context.Context, producer MessageProducer, eventType EventType, previous, company *domain.Company) error {
+	switch eventType {
+	case CompanyCreated:
+		return producer.SendCompanyCreated(ctx, company)
+	case CompanyUpdated:
+		return producer.SendCompanyUpdated(ctx, previous, company)
+	case CompanyDeleted:
+		return producer.SendCompanyDeleted(ctx, company)
+	default:
+		return fmt.Errorf("tipo de evento desconhecido: %s", eventType)
+	}
+}
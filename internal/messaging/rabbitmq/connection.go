@@ -0,0 +1,299 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ErrNotConnected indica uma publicação enquanto a conexão com o broker está sendo restabelecida
+var ErrNotConnected = errors.New("sem conexão com o RabbitMQ")
+
+// errManagerClosed indica uma operação após o Close do produtor
+var errManagerClosed = errors.New("conexão com o RabbitMQ encerrada")
+
+// amqpConnection é o subconjunto de *amqp.Connection usado pelo produtor
+type amqpConnection interface {
+	Channel() (amqpChannel, error)
+	NotifyClose(receiver chan *amqp.Error) chan *amqp.Error
+	Close() error
+}
+
+// amqpChannel é o subconjunto de *amqp.Channel usado pelo produtor, pelo consumidor e pelo
+// parking lot
+type amqpChannel interface {
+	ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error
+	QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error)
+	QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error
+	Confirm(noWait bool) error
+	NotifyReturn(receiver chan amqp.Return) chan amqp.Return
+	NotifyClose(receiver chan *amqp.Error) chan *amqp.Error
+	PublishWithDeferredConfirmWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) (confirmation, error)
+	Get(queue string, autoAck bool) (amqp.Delivery, bool, error)
+	Qos(prefetchCount, prefetchSize int, global bool) error
+	Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error)
+	Close() error
+}
+
+// confirmation é a confirmação pendente (ack/nack) de uma publicação
+type confirmation interface {
+	WaitContext(ctx context.Context) (bool, error)
+}
+
+// dialer abre uma conexão com o broker
+type dialer func(uri string) (amqpConnection, error)
+
+// dialAMQP abre uma conexão real com o broker
+func dialAMQP(uri string) (amqpConnection, error) {
+	conn, err := amqp.Dial(uri)
+	if err != nil {
+		return nil, err
+	}
+	return realConnection{conn}, nil
+}
+
+type realConnection struct{ *amqp.Connection }
+
+func (c realConnection) Channel() (amqpChannel, error) {
+	ch, err := c.Connection.Channel()
+	if err != nil {
+		return nil, err
+	}
+	return realChannel{ch}, nil
+}
+
+type realChannel struct{ *amqp.Channel }
+
+func (c realChannel) PublishWithDeferredConfirmWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) (confirmation, error) {
+	confirm, err := c.Channel.PublishWithDeferredConfirmWithContext(ctx, exchange, key, mandatory, immediate, msg)
+	if err != nil {
+		return nil, err
+	}
+	return confirm, nil
+}
+
+// session é um canal pronto para publicação e as devoluções (basic.return) associadas
+type session struct {
+	channel amqpChannel
+	returns *returnTracker
+}
+
+// connectionManager mantém a conexão com o broker. Ao receber NotifyClose da conexão ou do
+// canal, reconecta com backoff exponencial, declara novamente a topologia e troca a sessão
+// atual; publicadores concorrentes sempre obtêm a sessão vigente por current().
+type connectionManager struct {
+	uri      string
+	dial     dialer
+	topology Topology
+	delay    time.Duration
+	maxDelay time.Duration
+
+	mu      sync.RWMutex
+	conn    amqpConnection
+	session *session
+	closed  bool
+
+	done    chan struct{}
+	stopped chan struct{}
+}
+
+// newConnectionManager conecta ao broker e passa a vigiar a conexão. A primeira conexão é
+// síncrona para que erros de configuração apareçam na inicialização.
+func newConnectionManager(cfg Config, dial dialer) (*connectionManager, error) {
+	m := &connectionManager{
+		uri:      cfg.URI,
+		dial:     dial,
+		topology: cfg.Topology,
+		delay:    cfg.ReconnectDelay,
+		maxDelay: cfg.MaxReconnectDelay,
+		done:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+
+	closed, err := m.connect()
+	if err != nil {
+		return nil, err
+	}
+	go m.watch(closed)
+	return m, nil
+}
+
+// connect abre conexão e canal, declara a topologia, habilita publisher confirms e publica a
+// nova sessão. Retorna o canal que sinaliza o fechamento da conexão ou do canal.
+func (m *connectionManager) connect() (<-chan *amqp.Error, error) {
+	conn, err := m.dial(m.uri)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	}
+
+	channel, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open a channel: %w", err)
+	}
+
+	if err := m.topology.declare(channel); err != nil {
+		channel.Close()
+		conn.Close()
+		return nil, err
+	}
+
+	// Publisher confirms: o broker confirma (ack) ou rejeita (nack) cada publicação
+	if err := channel.Confirm(false); err != nil {
+		channel.Close()
+		conn.Close()
+		return nil, fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+
+	closed := make(chan *amqp.Error, 2)
+	conn.NotifyClose(forward(closed))
+	channel.NotifyClose(forward(closed))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		channel.Close()
+		conn.Close()
+		return nil, errManagerClosed
+	}
+	m.conn = conn
+	m.session = &session{
+		channel: channel,
+		returns: newReturnTracker(channel.NotifyReturn(make(chan amqp.Return, 64))),
+	}
+	return closed, nil
+}
+
+// forward cria um receptor para NotifyClose que repassa o primeiro erro (nil em um
+// fechamento normal) para out; o canal informado ao amqp é fechado por ele ao encerrar
+func forward(out chan<- *amqp.Error) chan *amqp.Error {
+	in := make(chan *amqp.Error, 1)
+	go func() {
+		err, ok := <-in
+		if !ok {
+			err = amqp.ErrClosed
+		}
+		select {
+		case out <- err:
+		default:
+		}
+	}()
+	return in
+}
+
+// watch aguarda o fechamento da sessão atual e reconecta até o Close do gerenciador
+func (m *connectionManager) watch(closed <-chan *amqp.Error) {
+	defer close(m.stopped)
+
+	for {
+		select {
+		case <-m.done:
+			return
+		case err := <-closed:
+			log.Printf("RabbitMQ connection lost: %v", err)
+		}
+
+		m.teardown()
+
+		var ok bool
+		if closed, ok = m.reconnect(); !ok {
+			return
+		}
+	}
+}
+
+// reconnect tenta conectar com backoff exponencial; retorna false se o gerenciador foi encerrado
+func (m *connectionManager) reconnect() (<-chan *amqp.Error, bool) {
+	delay := m.delay
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-m.done:
+			return nil, false
+		case <-time.After(delay):
+		}
+
+		closed, err := m.connect()
+		if err == nil {
+			log.Printf("RabbitMQ reconnected after %d attempt(s)", attempt)
+			return closed, true
+		}
+		if errors.Is(err, errManagerClosed) {
+			return nil, false
+		}
+
+		log.Printf("RabbitMQ reconnect attempt %d failed: %v", attempt, err)
+		if delay *= 2; delay > m.maxDelay {
+			delay = m.maxDelay
+		}
+	}
+}
+
+// teardown descarta a sessão atual, fazendo os publicadores receberem ErrNotConnected
+// até a próxima reconexão
+func (m *connectionManager) teardown() {
+	m.mu.Lock()
+	conn, session := m.conn, m.session
+	m.conn, m.session = nil, nil
+	m.mu.Unlock()
+
+	if session != nil {
+		session.channel.Close()
+	}
+	if conn != nil {
+		conn.Close()
+	}
+}
+
+// current retorna a sessão vigente
+func (m *connectionManager) current() (*session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if m.closed {
+		return nil, errManagerClosed
+	}
+	if m.session == nil {
+		return nil, ErrNotConnected
+	}
+	return m.session, nil
+}
+
+// connected informa se há uma sessão pronta para publicação
+func (m *connectionManager) connected() bool {
+	_, err := m.current()
+	return err == nil
+}
+
+// Close interrompe as reconexões e fecha o canal e a conexão
+func (m *connectionManager) Close() error {
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil
+	}
+	m.closed = true
+	conn, session := m.conn, m.session
+	m.conn, m.session = nil, nil
+	m.mu.Unlock()
+
+	close(m.done)
+	<-m.stopped
+
+	var errs []error
+	if session != nil {
+		if err := session.channel.Close(); err != nil && !errors.Is(err, amqp.ErrClosed) {
+			errs = append(errs, err)
+		}
+	}
+	if conn != nil {
+		if err := conn.Close(); err != nil && !errors.Is(err, amqp.ErrClosed) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
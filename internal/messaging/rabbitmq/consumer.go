@@ -0,0 +1,388 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ConsumerConfig define a fila consumida, a fila de erros e o paralelismo do consumidor
+type ConsumerConfig struct {
+	URI               string
+	Queue             string        // fila consumida
+	ErrorQueue        string        // respostas de falha de mensagens sem reply_to; vazio descarta
+	Workers           int           // mensagens processadas simultaneamente (prefetch)
+	RetryDelay        time.Duration // espera antes de devolver à fila uma mensagem com falha temporária
+	MaxRetries        int           // novas tentativas após falhas temporárias; esgotadas, a mensagem vai para a fila de erros
+	ConfirmTimeout    time.Duration // prazo para o ack da publicação de cada resposta
+	ReconnectDelay    time.Duration // espera inicial entre tentativas de reconexão
+	MaxReconnectDelay time.Duration // limite do backoff exponencial de reconexão
+}
+
+// Message é uma mensagem consumida
+type Message struct {
+	ID            string
+	Type          string
+	Body          []byte
+	ReplyTo       string
+	CorrelationID string
+	UserID        string // propriedade user_id, validada pelo broker contra o usuário da conexão
+	Redelivered   bool
+	Retries       int // novas tentativas já feitas após falhas temporárias
+}
+
+// RetryHeader conta as novas tentativas de uma mensagem. Filas clássicas não contam as
+// devoluções (Nack com requeue), então a mensagem é republicada com o contador incrementado.
+const RetryHeader = "x-retry-count"
+
+// ErrorHeader traz, nas mensagens enviadas à fila de erros por esgotar as tentativas, o último
+// erro do handler
+const ErrorHeader = "x-last-error"
+
+// Reply é a resposta a uma mensagem, publicada na fila reply_to da mensagem ou, nas falhas de
+// mensagens sem reply_to, na fila de erros
+type Reply struct {
+	Type   string
+	Body   []byte
+	Failed bool
+}
+
+// MessageHandler processa uma mensagem. Sem erro, a resposta (se houver) é publicada e a
+// mensagem é confirmada (ack); um erro indica falha temporária e a mensagem volta para a fila
+// após RetryDelay, até MaxRetries vezes.
+type MessageHandler func(ctx context.Context, msg Message) (*Reply, error)
+
+// Consumer consome uma fila com um pool de workers, reconectando automaticamente quando a
+// conexão cai. A entrega é at-least-once: mensagens sem ack quando o canal fecha voltam para
+// a fila e são processadas de novo.
+type Consumer struct {
+	conn       *connectionManager
+	queue      string
+	errorQueue string
+	workers    int
+	retryDelay time.Duration
+	maxRetries int
+	timeout    time.Duration
+	handler    MessageHandler
+}
+
+// NewConsumer conecta ao broker e declara a fila consumida e a fila de erros
+func NewConsumer(cfg ConsumerConfig, handler MessageHandler) (*Consumer, error) {
+	return newConsumer(cfg, handler, dialAMQP)
+}
+
+func newConsumer(cfg ConsumerConfig, handler MessageHandler, dial dialer) (*Consumer, error) {
+	if cfg.Queue == "" {
+		return nil, errors.New("consumer queue is required")
+	}
+	if cfg.Workers <= 0 {
+		cfg.Workers = 1
+	}
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = 5 * time.Second
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = 5
+	}
+	if cfg.ConfirmTimeout <= 0 {
+		cfg.ConfirmTimeout = 5 * time.Second
+	}
+	if cfg.ReconnectDelay <= 0 {
+		cfg.ReconnectDelay = 1 * time.Second
+	}
+	if cfg.MaxReconnectDelay < cfg.ReconnectDelay {
+		cfg.MaxReconnectDelay = 30 * time.Second
+	}
+
+	queues := []QueueBinding{{Queue: cfg.Queue}}
+	if cfg.ErrorQueue != "" {
+		queues = append(queues, QueueBinding{Queue: cfg.ErrorQueue})
+	}
+	conn, err := newConnectionManager(Config{
+		URI:               cfg.URI,
+		Topology:          Topology{Queues: queues},
+		ReconnectDelay:    cfg.ReconnectDelay,
+		MaxReconnectDelay: cfg.MaxReconnectDelay,
+	}, dial)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("Connected to RabbitMQ to consume queue %q (workers: %d)", cfg.Queue, cfg.Workers)
+
+	return &Consumer{
+		conn:       conn,
+		queue:      cfg.Queue,
+		errorQueue: cfg.ErrorQueue,
+		workers:    cfg.Workers,
+		retryDelay: cfg.RetryDelay,
+		maxRetries: cfg.MaxRetries,
+		timeout:    cfg.ConfirmTimeout,
+		handler:    handler,
+	}, nil
+}
+
+// Run consome a fila até o contexto ser cancelado. No encerramento, nenhuma mensagem nova é
+// iniciada e Run retorna após as mensagens em processamento serem concluídas; as recebidas e
+// ainda não iniciadas voltam para a fila no Close.
+func (c *Consumer) Run(ctx context.Context) {
+	for {
+		session, err := c.conn.current()
+		if errors.Is(err, errManagerClosed) {
+			return
+		}
+		if err == nil {
+			if err = c.consume(ctx, session); err == nil {
+				return
+			}
+			log.Printf("RabbitMQ consumer of %q interrupted: %v", c.queue, err)
+		}
+
+		// Aguarda a reconexão do gerenciador
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(c.conn.delay):
+		}
+	}
+}
+
+// consume processa as entregas da sessão com o pool de workers. Retorna nil quando o contexto
+// é cancelado e um erro quando o canal de entregas é fechado pela queda da sessão.
+func (c *Consumer) consume(ctx context.Context, session *session) error {
+	if err := session.channel.Qos(c.workers, 0, false); err != nil {
+		return fmt.Errorf("failed to set prefetch: %w", err)
+	}
+	deliveries, err := session.channel.Consume(c.queue, "", false, false, false, false, nil)
+	if err != nil {
+		return fmt.Errorf("failed to consume queue %s: %w", c.queue, err)
+	}
+
+	// As mensagens iniciadas são concluídas mesmo após o cancelamento
+	handlerCtx := context.WithoutCancel(ctx)
+	lost := make(chan struct{})
+	var once sync.Once
+	var workers sync.WaitGroup
+	for i := 0; i < c.workers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case d, ok := <-deliveries:
+					if !ok {
+						once.Do(func() { close(lost) })
+						return
+					}
+					if ctx.Err() != nil {
+						return
+					}
+					c.process(ctx, handlerCtx, session, d)
+				}
+			}
+		}()
+	}
+	workers.Wait()
+
+	select {
+	case <-lost:
+		if ctx.Err() == nil {
+			return ErrNotConnected
+		}
+	default:
+	}
+	return nil
+}
+
+// process executa o handler e confirma a mensagem após publicar a resposta; falhas temporárias
+// e falhas ao publicar a resposta devolvem a mensagem para a fila
+func (c *Consumer) process(ctx, handlerCtx context.Context, session *session, d amqp.Delivery) {
+	reply, err := c.handler(handlerCtx, Message{
+		ID:            d.MessageId,
+		Type:          d.Type,
+		Body:          d.Body,
+		ReplyTo:       d.ReplyTo,
+		CorrelationID: d.CorrelationId,
+		UserID:        d.UserId,
+		Redelivered:   d.Redelivered,
+		Retries:       retries(d),
+	})
+	if err != nil {
+		c.retry(ctx, handlerCtx, session, d, err)
+		return
+	}
+
+	if queue := c.replyQueue(d, reply); queue != "" {
+		if err := c.publishReply(handlerCtx, session, queue, d, reply); err != nil {
+			log.Printf("Failed to publish reply of message %s to %q, retrying: %v", d.MessageId, queue, err)
+			c.retry(ctx, handlerCtx, session, d, err)
+			return
+		}
+	}
+
+	if err := d.Ack(false); err != nil {
+		log.Printf("Failed to ack message %s from %q: %v", d.MessageId, c.queue, err)
+	}
+}
+
+// replyQueue escolhe a fila da resposta: reply_to da mensagem ou, para falhas, a fila de erros
+func (c *Consumer) replyQueue(d amqp.Delivery, reply *Reply) string {
+	if reply == nil {
+		return ""
+	}
+	if d.ReplyTo != "" {
+		return d.ReplyTo
+	}
+	if reply.Failed {
+		return c.errorQueue
+	}
+	return ""
+}
+
+// publishReply publica a resposta pelo exchange padrão e aguarda o ack do broker. O
+// correlation_id é o da mensagem ou, sem ele, o message_id.
+func (c *Consumer) publishReply(ctx context.Context, session *session, queue string, d amqp.Delivery, reply *Reply) error {
+	correlationID := d.CorrelationId
+	if correlationID == "" {
+		correlationID = d.MessageId
+	}
+
+	return c.publish(ctx, session, queue, amqp.Publishing{
+		ContentType:   "application/json",
+		CorrelationId: correlationID,
+		Type:          reply.Type,
+		Timestamp:     time.Now().UTC(),
+		Body:          reply.Body,
+		DeliveryMode:  amqp.Persistent,
+	})
+}
+
+// retry trata uma falha temporária. Após RetryDelay (ou imediatamente no encerramento), a
+// mensagem é republicada no fim da fila com RetryHeader incrementado e a original é confirmada;
+// esgotadas as MaxRetries tentativas, ela vai para a fila de erros com o último erro em
+// ErrorHeader, de modo que uma falha persistente não prende a mensagem em um laço infinito.
+// Se a republicação falhar, a mensagem volta para a fila sem incrementar o contador.
+func (c *Consumer) retry(ctx, handlerCtx context.Context, session *session, d amqp.Delivery, cause error) {
+	attempt := retries(d) + 1
+	if attempt > c.maxRetries {
+		c.discard(handlerCtx, session, d, cause)
+		return
+	}
+	log.Printf("Message %s from %q failed, retrying in %s (%d/%d): %v", d.MessageId, c.queue, c.retryDelay, attempt, c.maxRetries, cause)
+
+	timer := time.NewTimer(c.retryDelay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+
+	message := republished(d)
+	message.Headers[RetryHeader] = int32(attempt)
+	if err := c.publish(handlerCtx, session, c.queue, message); err != nil {
+		log.Printf("Failed to republish message %s to %q, requeueing: %v", d.MessageId, c.queue, err)
+		if err := d.Nack(false, true); err != nil {
+			log.Printf("Failed to requeue message %s from %q: %v", d.MessageId, c.queue, err)
+		}
+		return
+	}
+	if err := d.Ack(false); err != nil {
+		log.Printf("Failed to ack message %s from %q: %v", d.MessageId, c.queue, err)
+	}
+}
+
+// discard envia à fila de erros a mensagem que esgotou as tentativas. Sem fila de erros, a
+// mensagem é rejeitada sem requeue (e segue para o dead-letter exchange da fila, se houver).
+func (c *Consumer) discard(ctx context.Context, session *session, d amqp.Delivery, cause error) {
+	log.Printf("Message %s from %q failed after %d retries, moving to %q: %v", d.MessageId, c.queue, c.maxRetries, c.errorQueue, cause)
+	if c.errorQueue == "" {
+		if err := d.Nack(false, false); err != nil {
+			log.Printf("Failed to reject message %s from %q: %v", d.MessageId, c.queue, err)
+		}
+		return
+	}
+
+	message := republished(d)
+	message.Headers[ErrorHeader] = cause.Error()
+	if err := c.publish(ctx, session, c.errorQueue, message); err != nil {
+		log.Printf("Failed to move message %s to %q, requeueing: %v", d.MessageId, c.errorQueue, err)
+		if err := d.Nack(false, true); err != nil {
+			log.Printf("Failed to requeue message %s from %q: %v", d.MessageId, c.queue, err)
+		}
+		return
+	}
+	if err := d.Ack(false); err != nil {
+		log.Printf("Failed to ack message %s from %q: %v", d.MessageId, c.queue, err)
+	}
+}
+
+// publish publica a mensagem pelo exchange padrão e aguarda o ack do broker
+func (c *Consumer) publish(ctx context.Context, session *session, queue string, message amqp.Publishing) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	confirm, err := session.channel.PublishWithDeferredConfirmWithContext(ctx, "", queue, false, false, message)
+	if err != nil {
+		return err
+	}
+	acked, err := confirm.WaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrConfirmTimeout, err)
+	}
+	if !acked {
+		return ErrNacked
+	}
+	return nil
+}
+
+// republished copia a entrega para uma nova publicação, preservando as propriedades usadas pelo
+// handler e pela resposta
+func republished(d amqp.Delivery) amqp.Publishing {
+	headers := amqp.Table{}
+	for key, value := range d.Headers {
+		headers[key] = value
+	}
+	return amqp.Publishing{
+		Headers:       headers,
+		ContentType:   d.ContentType,
+		DeliveryMode:  amqp.Persistent,
+		CorrelationId: d.CorrelationId,
+		ReplyTo:       d.ReplyTo,
+		MessageId:     d.MessageId,
+		Timestamp:     d.Timestamp,
+		Type:          d.Type,
+		UserId:        d.UserId,
+		AppId:         d.AppId,
+		Body:          d.Body,
+	}
+}
+
+// retries lê o contador de novas tentativas da mensagem
+func retries(d amqp.Delivery) int {
+	switch count := d.Headers[RetryHeader].(type) {
+	case int32:
+		return int(count)
+	case int64:
+		return int(count)
+	case int:
+		return count
+	}
+	return 0
+}
+
+// Connected informa se há uma sessão ativa com o broker
+func (c *Consumer) Connected() bool {
+	return c.conn.connected()
+}
+
+// Close encerra a conexão; as mensagens recebidas e ainda sem ack voltam para a fila
+func (c *Consumer) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,197 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testConsumerConfig() ConsumerConfig {
+	return ConsumerConfig{
+		URI:               "amqp://fake",
+		Queue:             "company.commands",
+		ErrorQueue:        "company.commands.errors",
+		Workers:           2,
+		RetryDelay:        5 * time.Millisecond,
+		ConfirmTimeout:    200 * time.Millisecond,
+		ReconnectDelay:    5 * time.Millisecond,
+		MaxReconnectDelay: 20 * time.Millisecond,
+	}
+}
+
+// startConsumer cria o consumidor e o executa até o fim do teste; o cancel retornado inicia o
+// encerramento e o canal done fecha quando Run retorna
+func startConsumer(t *testing.T, broker *fakeBroker, handler MessageHandler) (*Consumer, context.CancelFunc, <-chan struct{}) {
+	t.Helper()
+	consumer, err := newConsumer(testConsumerConfig(), handler, broker.dial)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		consumer.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+		consumer.Close()
+	})
+	return consumer, cancel, done
+}
+
+func TestConsumer_AcksHandledMessages(t *testing.T) {
+	broker := newFakeBroker()
+	var handled sync.Map
+	startConsumer(t, broker, func(ctx context.Context, msg Message) (*Reply, error) {
+		handled.Store(msg.ID, string(msg.Body))
+		return nil, nil
+	})
+
+	broker.enqueue("company.commands", amqp.Publishing{MessageId: "m1", Body: []byte(`{"type":"delete"}`)})
+	broker.enqueue("company.commands", amqp.Publishing{MessageId: "m2"})
+
+	require.Eventually(t, func() bool {
+		_, ok := handled.Load("m2")
+		return ok && len(broker.queued("company.commands")) == 0
+	}, time.Second, time.Millisecond)
+	body, _ := handled.Load("m1")
+	assert.Equal(t, `{"type":"delete"}`, body)
+	assert.Empty(t, broker.queuedMessages("company.commands.errors"))
+}
+
+func TestConsumer_FailureRepliesGoToErrorQueue(t *testing.T) {
+	broker := newFakeBroker()
+	startConsumer(t, broker, func(ctx context.Context, msg Message) (*Reply, error) {
+		return &Reply{Type: "company.command.failed", Body: []byte(`{"code":"VALIDATION_ERROR"}`), Failed: true}, nil
+	})
+
+	broker.enqueue("company.commands", amqp.Publishing{MessageId: "m1"})
+
+	require.Eventually(t, func() bool { return len(broker.queuedMessages("company.commands.errors")) == 1 }, time.Second, time.Millisecond)
+	reply := broker.queuedMessages("company.commands.errors")[0]
+	assert.Equal(t, "m1", reply.CorrelationId)
+	assert.Equal(t, "company.command.failed", reply.Type)
+	assert.JSONEq(t, `{"code":"VALIDATION_ERROR"}`, string(reply.Body))
+	assert.Eventually(t, func() bool { return len(broker.queued("company.commands")) == 0 }, time.Second, time.Millisecond)
+}
+
+func TestConsumer_RepliesToReplyTo(t *testing.T) {
+	broker := newFakeBroker()
+	broker.set(func(b *fakeBroker) { b.queues["client.replies"] = 1 })
+	startConsumer(t, broker, func(ctx context.Context, msg Message) (*Reply, error) {
+		return &Reply{Body: []byte(`{"status":"succeeded"}`)}, nil
+	})
+
+	broker.enqueue("company.commands", amqp.Publishing{MessageId: "m1", ReplyTo: "client.replies", CorrelationId: "req-7"})
+
+	require.Eventually(t, func() bool { return len(broker.queuedMessages("client.replies")) == 1 }, time.Second, time.Millisecond)
+	assert.Equal(t, "req-7", broker.queuedMessages("client.replies")[0].CorrelationId)
+	assert.Empty(t, broker.queuedMessages("company.commands.errors"))
+}
+
+func TestConsumer_TemporaryFailureIsRequeued(t *testing.T) {
+	broker := newFakeBroker()
+	var attempts atomic.Int32
+	startConsumer(t, broker, func(ctx context.Context, msg Message) (*Reply, error) {
+		if attempts.Add(1) < 3 {
+			return nil, errors.New("mongo unavailable")
+		}
+		return nil, nil
+	})
+
+	broker.enqueue("company.commands", amqp.Publishing{MessageId: "m1"})
+
+	require.Eventually(t, func() bool {
+		return attempts.Load() == 3 && len(broker.queued("company.commands")) == 0
+	}, time.Second, time.Millisecond)
+}
+
+func TestConsumer_ExhaustedRetriesGoToErrorQueue(t *testing.T) {
+	broker := newFakeBroker()
+	var seen []int
+	var mu sync.Mutex
+	startConsumer(t, broker, func(ctx context.Context, msg Message) (*Reply, error) {
+		mu.Lock()
+		seen = append(seen, msg.Retries)
+		mu.Unlock()
+		assert.Equal(t, "tenant-a", msg.UserID)
+		return nil, errors.New("mongo unavailable")
+	})
+
+	broker.enqueue("company.commands", amqp.Publishing{MessageId: "m1", UserId: "tenant-a", ReplyTo: "client.replies", Body: []byte(`{"type":"create"}`)})
+
+	require.Eventually(t, func() bool { return len(broker.queuedMessages("company.commands.errors")) == 1 }, time.Second, time.Millisecond)
+	dead := broker.queuedMessages("company.commands.errors")[0]
+	assert.Equal(t, "m1", dead.MessageId)
+	assert.Equal(t, "client.replies", dead.ReplyTo)
+	assert.Equal(t, `{"type":"create"}`, string(dead.Body))
+	assert.Equal(t, int32(5), dead.Headers[RetryHeader])
+	assert.Equal(t, "mongo unavailable", dead.Headers[ErrorHeader])
+	assert.Eventually(t, func() bool { return len(broker.queued("company.commands")) == 0 }, time.Second, time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []int{0, 1, 2, 3, 4, 5}, seen)
+}
+
+func TestConsumer_ShutdownWaitsForInFlightMessages(t *testing.T) {
+	broker := newFakeBroker()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var finished atomic.Bool
+	_, cancel, done := startConsumer(t, broker, func(ctx context.Context, msg Message) (*Reply, error) {
+		close(started)
+		<-release
+		finished.Store(ctx.Err() == nil)
+		return nil, nil
+	})
+
+	broker.enqueue("company.commands", amqp.Publishing{MessageId: "m1"})
+	<-started
+	cancel()
+
+	select {
+	case <-done:
+		t.Fatal("Run returned before the in-flight message finished")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	<-done
+	assert.True(t, finished.Load(), "o handler conclui com um contexto não cancelado")
+	assert.Empty(t, broker.queued("company.commands"), "a mensagem em processamento foi confirmada")
+}
+
+func TestConsumer_ResumesAfterReconnect(t *testing.T) {
+	broker := newFakeBroker()
+	var handled atomic.Int32
+	consumer, _, _ := startConsumer(t, broker, func(ctx context.Context, msg Message) (*Reply, error) {
+		handled.Add(1)
+		return nil, nil
+	})
+
+	broker.enqueue("company.commands", amqp.Publishing{MessageId: "m1"})
+	require.Eventually(t, func() bool { return handled.Load() == 1 }, time.Second, time.Millisecond)
+
+	broker.restart()
+	require.Eventually(t, consumer.Connected, time.Second, time.Millisecond)
+
+	broker.enqueue("company.commands", amqp.Publishing{MessageId: "m2"})
+	require.Eventually(t, func() bool { return handled.Load() == 2 }, time.Second, time.Millisecond)
+
+	_, _, _, queues, _ := broker.snapshot()
+	assert.Equal(t, 2, queues["company.commands"], "filas declaradas novamente na reconexão")
+}
+
+func TestNewConsumer_RequiresQueue(t *testing.T) {
+	_, err := newConsumer(ConsumerConfig{}, nil, newFakeBroker().dial)
+	assert.Error(t, err)
+}
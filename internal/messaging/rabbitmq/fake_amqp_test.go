@@ -0,0 +1,489 @@
+package rabbitmq
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// fakeBroker é um broker AMQP em memória: registra declarações e publicações e permite
+// simular quedas de conexão, falhas de dial, nacks, mensagens sem rota e confirmações perdidas
+type fakeBroker struct {
+	mu           sync.Mutex
+	dials        int
+	dialFailures int // quantidade de dials seguintes que falham
+	conns        []*fakeConnection
+
+	exchanges map[string]int // quantidade de declarações por exchange
+	queues    map[string]int
+	queueArgs map[string]amqp.Table
+	bindings  map[string]int // "fila|routing key|exchange"
+	published []amqp.Publishing
+
+	// Mensagens nas filas, lidas com basic.get; publicações no exchange padrão entram na fila
+	// da routing key
+	messages map[string][]fakeMessage
+	nextMsg  int
+
+	unroutable    bool          // devolve as mensagens publicadas (basic.return) antes do ack
+	unroutableKey string        // devolve apenas as mensagens com esta routing key
+	nack          bool          // confirma com nack
+	noConfirms    bool          // nunca confirma
+	confirmGate   chan struct{} // se definido, as confirmações só chegam quando ele é fechado
+}
+
+func newFakeBroker() *fakeBroker {
+	return &fakeBroker{
+		exchanges: map[string]int{},
+		queues:    map[string]int{},
+		queueArgs: map[string]amqp.Table{},
+		bindings:  map[string]int{},
+		messages:  map[string][]fakeMessage{},
+	}
+}
+
+// fakeMessage é uma mensagem enfileirada; order preserva a posição original ao voltar com nack
+type fakeMessage struct {
+	order int
+	msg   amqp.Publishing
+}
+
+// enqueue coloca a mensagem no fim da fila
+func (b *fakeBroker) enqueue(queue string, msg amqp.Publishing) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.enqueueLocked(queue, msg)
+}
+
+func (b *fakeBroker) enqueueLocked(queue string, msg amqp.Publishing) {
+	b.nextMsg++
+	b.messages[queue] = append(b.messages[queue], fakeMessage{order: b.nextMsg, msg: msg})
+}
+
+// queued retorna os message_ids na fila, em ordem
+func (b *fakeBroker) queued(queue string) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var ids []string
+	for _, m := range b.messages[queue] {
+		ids = append(ids, m.msg.MessageId)
+	}
+	return ids
+}
+
+// queuedMessages retorna as mensagens na fila, em ordem
+func (b *fakeBroker) queuedMessages(queue string) []amqp.Publishing {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var messages []amqp.Publishing
+	for _, m := range b.messages[queue] {
+		messages = append(messages, m.msg)
+	}
+	return messages
+}
+
+func (b *fakeBroker) dial(uri string) (amqpConnection, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.dials++
+	if b.dialFailures > 0 {
+		b.dialFailures--
+		return nil, errors.New("connection refused")
+	}
+	conn := &fakeConnection{broker: b}
+	b.conns = append(b.conns, conn)
+	return conn, nil
+}
+
+// restart derruba todas as conexões abertas, como numa reinicialização do broker
+func (b *fakeBroker) restart() {
+	b.mu.Lock()
+	conns := b.conns
+	b.conns = nil
+	b.mu.Unlock()
+
+	for _, conn := range conns {
+		conn.shutdown(&amqp.Error{Code: amqp.ConnectionForced, Reason: "broker restarted"})
+	}
+}
+
+func (b *fakeBroker) set(fn func(b *fakeBroker)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	fn(b)
+}
+
+func (b *fakeBroker) snapshot() (dials int, published []amqp.Publishing, exchanges, queues, bindings map[string]int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.dials, append([]amqp.Publishing(nil), b.published...), copyCounts(b.exchanges), copyCounts(b.queues), copyCounts(b.bindings)
+}
+
+func copyCounts(m map[string]int) map[string]int {
+	c := make(map[string]int, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+type fakeConnection struct {
+	broker   *fakeBroker
+	mu       sync.Mutex
+	closed   bool
+	notify   []chan *amqp.Error
+	channels []*fakeChannel
+}
+
+func (c *fakeConnection) Channel() (amqpChannel, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, amqp.ErrClosed
+	}
+	ch := &fakeChannel{broker: c.broker, done: make(chan struct{})}
+	c.channels = append(c.channels, ch)
+	return ch, nil
+}
+
+func (c *fakeConnection) NotifyClose(receiver chan *amqp.Error) chan *amqp.Error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		close(receiver)
+		return receiver
+	}
+	c.notify = append(c.notify, receiver)
+	return receiver
+}
+
+func (c *fakeConnection) Close() error {
+	if c.isClosed() {
+		return amqp.ErrClosed
+	}
+	c.shutdown(nil)
+	return nil
+}
+
+func (c *fakeConnection) isClosed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// shutdown fecha a conexão e seus canais; err nil representa um fechamento normal
+func (c *fakeConnection) shutdown(err *amqp.Error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.closed = true
+	notify, channels := c.notify, c.channels
+	c.notify, c.channels = nil, nil
+	c.mu.Unlock()
+
+	for _, ch := range channels {
+		ch.shutdown(err)
+	}
+	for _, receiver := range notify {
+		if err != nil {
+			receiver <- err
+		}
+		close(receiver)
+	}
+}
+
+type fakeChannel struct {
+	broker  *fakeBroker
+	mu      sync.Mutex
+	closed  bool
+	confirm bool
+	notify  []chan *amqp.Error
+	returns []chan amqp.Return
+
+	nextTag  uint64
+	unacked  map[uint64]fakeUnacked
+	prefetch int
+	done     chan struct{} // fechado no shutdown; encerra os consumidores
+}
+
+type fakeUnacked struct {
+	queue   string
+	message fakeMessage
+}
+
+func (ch *fakeChannel) ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error {
+	return ch.record(func(b *fakeBroker) { b.exchanges[name]++ })
+}
+
+func (ch *fakeChannel) QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error) {
+	return amqp.Queue{Name: name}, ch.record(func(b *fakeBroker) {
+		b.queues[name]++
+		b.queueArgs[name] = args
+	})
+}
+
+func (ch *fakeChannel) QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error {
+	return ch.record(func(b *fakeBroker) { b.bindings[name+"|"+key+"|"+exchange]++ })
+}
+
+func (ch *fakeChannel) record(fn func(b *fakeBroker)) error {
+	if ch.isClosed() {
+		return amqp.ErrClosed
+	}
+	ch.broker.set(fn)
+	return nil
+}
+
+func (ch *fakeChannel) Confirm(noWait bool) error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.confirm = true
+	return nil
+}
+
+func (ch *fakeChannel) NotifyReturn(receiver chan amqp.Return) chan amqp.Return {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.returns = append(ch.returns, receiver)
+	return receiver
+}
+
+func (ch *fakeChannel) NotifyClose(receiver chan *amqp.Error) chan *amqp.Error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	if ch.closed {
+		close(receiver)
+		return receiver
+	}
+	ch.notify = append(ch.notify, receiver)
+	return receiver
+}
+
+func (ch *fakeChannel) PublishWithDeferredConfirmWithContext(ctx context.Context, exchange, key string, mandatory, immediate bool, msg amqp.Publishing) (confirmation, error) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	if ch.closed {
+		return nil, amqp.ErrClosed
+	}
+
+	b := ch.broker
+	b.mu.Lock()
+	b.published = append(b.published, msg)
+	if exchange == "" && b.queues[key] > 0 && !b.nack {
+		b.enqueueLocked(key, msg)
+	}
+	unroutable := b.unroutable || (b.unroutableKey != "" && b.unroutableKey == key)
+	nack, noConfirms, gate := b.nack, b.noConfirms, b.confirmGate
+	b.mu.Unlock()
+
+	// Como no broker real, o basic.return chega antes do ack
+	if unroutable && mandatory {
+		for _, receiver := range ch.returns {
+			receiver <- amqp.Return{MessageId: msg.MessageId, ReplyCode: amqp.NoRoute, ReplyText: "NO_ROUTE", RoutingKey: key}
+		}
+	}
+	return fakeConfirmation{ack: !nack, never: noConfirms, gate: gate}, nil
+}
+
+func (ch *fakeChannel) Get(queue string, autoAck bool) (amqp.Delivery, bool, error) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	if ch.closed {
+		return amqp.Delivery{}, false, amqp.ErrClosed
+	}
+
+	b := ch.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.messages[queue]) == 0 {
+		return amqp.Delivery{}, false, nil
+	}
+	message := b.messages[queue][0]
+	b.messages[queue] = b.messages[queue][1:]
+
+	ch.nextTag++
+	if !autoAck {
+		if ch.unacked == nil {
+			ch.unacked = map[uint64]fakeUnacked{}
+		}
+		ch.unacked[ch.nextTag] = fakeUnacked{queue: queue, message: message}
+	}
+
+	msg := message.msg
+	return amqp.Delivery{
+		Acknowledger:  ch,
+		Headers:       msg.Headers,
+		ContentType:   msg.ContentType,
+		MessageId:     msg.MessageId,
+		Type:          msg.Type,
+		Timestamp:     msg.Timestamp,
+		AppId:         msg.AppId,
+		Body:          msg.Body,
+		ReplyTo:       msg.ReplyTo,
+		CorrelationId: msg.CorrelationId,
+		UserId:        msg.UserId,
+		DeliveryTag:   ch.nextTag,
+		MessageCount:  uint32(len(b.messages[queue])),
+	}, true, nil
+}
+
+func (ch *fakeChannel) Qos(prefetchCount, prefetchSize int, global bool) error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	ch.prefetch = prefetchCount
+	return nil
+}
+
+// Consume entrega as mensagens da fila respeitando o prefetch, até o canal ser fechado
+func (ch *fakeChannel) Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan amqp.Delivery, error) {
+	if ch.isClosed() {
+		return nil, amqp.ErrClosed
+	}
+	deliveries := make(chan amqp.Delivery)
+	go func() {
+		defer close(deliveries)
+		for {
+			ch.mu.Lock()
+			full := ch.prefetch > 0 && len(ch.unacked) >= ch.prefetch
+			ch.mu.Unlock()
+
+			var d amqp.Delivery
+			ok := false
+			if !full {
+				var err error
+				if d, ok, err = ch.Get(queue, autoAck); err != nil {
+					return
+				}
+			}
+			if !ok {
+				select {
+				case <-ch.done:
+					return
+				case <-time.After(time.Millisecond):
+				}
+				continue
+			}
+			select {
+			case deliveries <- d:
+			case <-ch.done:
+				return
+			}
+		}
+	}()
+	return deliveries, nil
+}
+
+func (ch *fakeChannel) Ack(tag uint64, multiple bool) error {
+	return ch.settle(tag, false)
+}
+
+func (ch *fakeChannel) Nack(tag uint64, multiple, requeue bool) error {
+	return ch.settle(tag, requeue)
+}
+
+func (ch *fakeChannel) Reject(tag uint64, requeue bool) error {
+	return ch.settle(tag, requeue)
+}
+
+// settle remove a entrega pendente; com requeue ela volta para a posição original na fila
+func (ch *fakeChannel) settle(tag uint64, requeue bool) error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	pending, ok := ch.unacked[tag]
+	if !ok {
+		return errors.New("unknown delivery tag")
+	}
+	delete(ch.unacked, tag)
+	if !requeue {
+		return nil
+	}
+
+	ch.broker.requeue(pending)
+	return nil
+}
+
+// requeue devolve a entrega para a posição original na fila
+func (b *fakeBroker) requeue(pending fakeUnacked) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	queue := append(b.messages[pending.queue], pending.message)
+	sort.Slice(queue, func(i, j int) bool { return queue[i].order < queue[j].order })
+	b.messages[pending.queue] = queue
+}
+
+func (ch *fakeChannel) Close() error {
+	if ch.isClosed() {
+		return amqp.ErrClosed
+	}
+	ch.shutdown(nil)
+	return nil
+}
+
+func (ch *fakeChannel) isClosed() bool {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	return ch.closed
+}
+
+func (ch *fakeChannel) shutdown(err *amqp.Error) {
+	ch.mu.Lock()
+	if ch.closed {
+		ch.mu.Unlock()
+		return
+	}
+	ch.closed = true
+	notify, returns, unacked := ch.notify, ch.returns, ch.unacked
+	ch.notify, ch.returns, ch.unacked = nil, nil, nil
+	close(ch.done)
+	ch.mu.Unlock()
+
+	// Como no broker real, as entregas sem ack voltam para a fila quando o canal fecha
+	for _, pending := range unacked {
+		ch.broker.requeue(pending)
+	}
+
+	for _, receiver := range notify {
+		if err != nil {
+			receiver <- err
+		}
+		close(receiver)
+	}
+	for _, receiver := range returns {
+		close(receiver)
+	}
+}
+
+type fakeConfirmation struct {
+	ack   bool
+	never bool
+	gate  chan struct{}
+}
+
+func (c fakeConfirmation) WaitContext(ctx context.Context) (bool, error) {
+	if c.never {
+		<-ctx.Done()
+		return false, ctx.Err()
+	}
+	if c.gate != nil {
+		select {
+		case <-c.gate:
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+	return c.ack, nil
+}
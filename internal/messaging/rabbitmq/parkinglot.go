@@ -0,0 +1,277 @@
+package rabbitmq
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// ErrMessageNotFound indica uma mensagem inexistente no parking lot
+var ErrMessageNotFound = errors.New("mensagem não encontrada no parking lot")
+
+// DeadLetteredMessage é um evento no parking lot com os dados do dead-lettering (x-death)
+type DeadLetteredMessage struct {
+	MessageID  string          `json:"message_id"`
+	Type       string          `json:"type"`
+	RoutingKey string          `json:"routing_key"`
+	Queue      string          `json:"queue"`  // fila de onde a mensagem saiu
+	Reason     string          `json:"reason"` // rejected, expired ou maxlen
+	Count      int64           `json:"count"`  // quantas vezes foi para o dead-letter a partir dessa fila
+	DeadAt     time.Time       `json:"dead_at"`
+	Body       json.RawMessage `json:"body,omitempty"`
+}
+
+// ParkingLot lista, inspeciona, reenfileira e descarta as mensagens da fila parking lot. As
+// mensagens são lidas com basic.get e mantidas sem ack durante a varredura; as que não são
+// reenfileiradas nem descartadas voltam para a fila com nack ao fim da operação.
+type ParkingLot struct {
+	conn    amqpConnection
+	channel amqpChannel
+	returns chan amqp.Return
+	queue   string
+	timeout time.Duration
+}
+
+// OpenParkingLot conecta ao broker para operar a fila parking lot informada
+func OpenParkingLot(uri, queue string) (*ParkingLot, error) {
+	return openParkingLot(uri, queue, dialAMQP)
+}
+
+func openParkingLot(uri, queue string, dial dialer) (*ParkingLot, error) {
+	conn, err := dial(uri)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
+	}
+	channel, err := conn.Channel()
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open a channel: %w", err)
+	}
+	// O reenfileiramento só remove a mensagem do parking lot após o ack da republicação
+	if err := channel.Confirm(false); err != nil {
+		channel.Close()
+		conn.Close()
+		return nil, fmt.Errorf("failed to enable publisher confirms: %w", err)
+	}
+
+	return &ParkingLot{
+		conn:    conn,
+		channel: channel,
+		returns: channel.NotifyReturn(make(chan amqp.Return, 64)),
+		queue:   queue,
+		timeout: 5 * time.Second,
+	}, nil
+}
+
+// List retorna até limit mensagens do parking lot (0 = todas), sem o corpo
+func (p *ParkingLot) List(limit int) ([]DeadLetteredMessage, error) {
+	var messages []DeadLetteredMessage
+	err := p.scan(func(d amqp.Delivery) (bool, bool, error) {
+		message := deadLettered(d)
+		message.Body = nil
+		messages = append(messages, message)
+		return false, limit > 0 && len(messages) >= limit, nil
+	})
+	return messages, err
+}
+
+// Inspect retorna a mensagem com o message_id informado, incluindo o corpo
+func (p *ParkingLot) Inspect(messageID string) (*DeadLetteredMessage, error) {
+	var found *DeadLetteredMessage
+	err := p.scan(func(d amqp.Delivery) (bool, bool, error) {
+		if d.MessageId != messageID {
+			return false, false, nil
+		}
+		message := deadLettered(d)
+		found = &message
+		return false, true, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%w: %s", ErrMessageNotFound, messageID)
+	}
+	return found, nil
+}
+
+// Requeue republica na fila de origem as mensagens com os message_ids informados (todas, se
+// nenhum for informado) e as remove do parking lot. Retorna a quantidade reenfileirada.
+func (p *ParkingLot) Requeue(ctx context.Context, messageIDs ...string) (int, error) {
+	selected := selector(messageIDs)
+	requeued := 0
+	err := p.scan(func(d amqp.Delivery) (bool, bool, error) {
+		if !selected(d.MessageId) {
+			return false, false, nil
+		}
+		if err := p.republish(ctx, d); err != nil {
+			return false, true, err
+		}
+		requeued++
+		return true, false, nil
+	})
+	return requeued, err
+}
+
+// Discard remove do parking lot as mensagens com os message_ids informados (todas, se nenhum
+// for informado). Retorna a quantidade descartada.
+func (p *ParkingLot) Discard(messageIDs ...string) (int, error) {
+	selected := selector(messageIDs)
+	discarded := 0
+	err := p.scan(func(d amqp.Delivery) (bool, bool, error) {
+		if !selected(d.MessageId) {
+			return false, false, nil
+		}
+		discarded++
+		return true, false, nil
+	})
+	return discarded, err
+}
+
+// Close fecha o canal e a conexão
+func (p *ParkingLot) Close() error {
+	p.channel.Close()
+	return p.conn.Close()
+}
+
+// scan percorre as mensagens presentes no início da varredura. visit informa se a mensagem
+// deve sair do parking lot (ack) e se a varredura termina; as demais voltam para a fila.
+func (p *ParkingLot) scan(visit func(d amqp.Delivery) (remove, stop bool, err error)) error {
+	var held []amqp.Delivery
+	defer func() {
+		for _, d := range held {
+			d.Nack(false, true)
+		}
+	}()
+
+	// Limita a varredura às mensagens existentes no início, ignorando as que chegarem depois
+	remaining := -1
+	for remaining != 0 {
+		d, ok, err := p.channel.Get(p.queue, false)
+		if err != nil {
+			return fmt.Errorf("failed to get message from %s: %w", p.queue, err)
+		}
+		if !ok {
+			return nil
+		}
+		if remaining < 0 {
+			remaining = int(d.MessageCount) + 1
+		}
+		remaining--
+
+		remove, stop, err := visit(d)
+		if remove {
+			if ackErr := d.Ack(false); ackErr != nil {
+				held = append(held, d)
+				return fmt.Errorf("failed to ack message %s: %w", d.MessageId, ackErr)
+			}
+		} else {
+			held = append(held, d)
+		}
+		if err != nil || stop {
+			return err
+		}
+	}
+	return nil
+}
+
+// republish publica a mensagem diretamente na fila de origem pelo exchange padrão, sem os
+// cabeçalhos de dead-lettering, e aguarda a confirmação do broker
+func (p *ParkingLot) republish(ctx context.Context, d amqp.Delivery) error {
+	queue := deathQueue(d.Headers)
+	if queue == "" {
+		return fmt.Errorf("message %s has no x-death origin queue", d.MessageId)
+	}
+
+	headers := amqp.Table{}
+	for key, value := range d.Headers {
+		if key == "x-death" || strings.HasPrefix(key, "x-first-death-") || strings.HasPrefix(key, "x-last-death-") {
+			continue
+		}
+		headers[key] = value
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	confirm, err := p.channel.PublishWithDeferredConfirmWithContext(ctx, "", queue, true, false, amqp.Publishing{
+		Headers:      headers,
+		ContentType:  d.ContentType,
+		MessageId:    d.MessageId,
+		Type:         d.Type,
+		Timestamp:    d.Timestamp,
+		AppId:        d.AppId,
+		Body:         d.Body,
+		DeliveryMode: amqp.Persistent,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to republish message %s: %w", d.MessageId, err)
+	}
+	acked, err := confirm.WaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("%w (message %s): %v", ErrConfirmTimeout, d.MessageId, err)
+	}
+	if !acked {
+		return fmt.Errorf("%w (message %s)", ErrNacked, d.MessageId)
+	}
+	if wasReturned(p.returns, d.MessageId) {
+		return fmt.Errorf("%w (message %s, queue %s)", ErrUnroutable, d.MessageId, queue)
+	}
+	return nil
+}
+
+// selector retorna o filtro de message_ids; sem ids seleciona todas as mensagens
+func selector(messageIDs []string) func(string) bool {
+	if len(messageIDs) == 0 {
+		return func(string) bool { return true }
+	}
+	ids := make(map[string]bool, len(messageIDs))
+	for _, id := range messageIDs {
+		ids[id] = true
+	}
+	return func(id string) bool { return ids[id] }
+}
+
+// deadLettered extrai os dados do dead-lettering da mensagem
+func deadLettered(d amqp.Delivery) DeadLetteredMessage {
+	message := DeadLetteredMessage{
+		MessageID:  d.MessageId,
+		Type:       d.Type,
+		RoutingKey: d.RoutingKey,
+		Body:       json.RawMessage(d.Body),
+	}
+	if death, ok := firstDeath(d.Headers); ok {
+		message.Queue, _ = death["queue"].(string)
+		message.Reason, _ = death["reason"].(string)
+		message.Count, _ = death["count"].(int64)
+		message.DeadAt, _ = death["time"].(time.Time)
+		if keys, ok := death["routing-keys"].([]interface{}); ok && len(keys) > 0 {
+			message.RoutingKey, _ = keys[0].(string)
+		}
+	}
+	return message
+}
+
+// firstDeath retorna a entrada mais recente de x-death (o broker a mantém na primeira posição)
+func firstDeath(headers amqp.Table) (amqp.Table, bool) {
+	deaths, ok := headers["x-death"].([]interface{})
+	if !ok || len(deaths) == 0 {
+		return nil, false
+	}
+	death, ok := deaths[0].(amqp.Table)
+	return death, ok
+}
+
+func deathQueue(headers amqp.Table) string {
+	death, ok := firstDeath(headers)
+	if !ok {
+		return ""
+	}
+	queue, _ := death["queue"].(string)
+	return queue
+}
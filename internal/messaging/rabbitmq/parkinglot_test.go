@@ -0,0 +1,163 @@
+package rabbitmq
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const parkingQueue = "company.events.parking-lot"
+
+func deadLetteredPublishing(id, queue, reason string) amqp.Publishing {
+	return amqp.Publishing{
+		MessageId: id,
+		Type:      "br.company.created.v1",
+		Body:      []byte(`{"id":"` + id + `"}`),
+		Headers: amqp.Table{
+			"x-first-death-queue": queue,
+			"x-death": []interface{}{amqp.Table{
+				"queue":        queue,
+				"reason":       reason,
+				"count":        int64(1),
+				"exchange":     "company.events",
+				"routing-keys": []interface{}{"company.created.sp"},
+				"time":         time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC),
+			}},
+		},
+	}
+}
+
+func newTestParkingLot(t *testing.T, broker *fakeBroker, ids ...string) *ParkingLot {
+	t.Helper()
+	broker.set(func(b *fakeBroker) {
+		b.queues["company.created"] = 1
+		b.queues[parkingQueue] = 1
+	})
+	for _, id := range ids {
+		broker.enqueue(parkingQueue, deadLetteredPublishing(id, "company.created", "rejected"))
+	}
+
+	lot, err := openParkingLot("amqp://fake", parkingQueue, broker.dial)
+	require.NoError(t, err)
+	t.Cleanup(func() { lot.Close() })
+	return lot
+}
+
+func TestTopology_DeclaresDeadLetterExchangeAndQueueArguments(t *testing.T) {
+	broker := newFakeBroker()
+	cfg := testConfig()
+	cfg.Topology.DeadLetter = DeadLetter{
+		Exchange:   "company.events.dlx",
+		Queue:      parkingQueue,
+		MessageTTL: time.Hour,
+		MaxLength:  1000,
+	}
+	producer, err := newProducer(cfg, broker.dial)
+	require.NoError(t, err)
+	defer producer.Close()
+
+	_, _, exchanges, queues, bindings := broker.snapshot()
+	assert.Equal(t, 1, exchanges["company.events.dlx"])
+	assert.Equal(t, 1, queues[parkingQueue])
+	assert.Equal(t, 1, bindings[parkingQueue+"||company.events.dlx"])
+
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	assert.Equal(t, amqp.Table{
+		"x-dead-letter-exchange": "company.events.dlx",
+		"x-message-ttl":          int64(3600000),
+		"x-max-length":           int64(1000),
+		"x-overflow":             "drop-head",
+	}, broker.queueArgs["company.created"])
+	assert.Nil(t, broker.queueArgs[parkingQueue], "o parking lot não tem TTL nem dead-letter")
+}
+
+func TestTopology_WithoutDeadLetter_DeclaresQueuesWithoutArguments(t *testing.T) {
+	broker := newFakeBroker()
+	newTestProducer(t, broker)
+
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	assert.Nil(t, broker.queueArgs["company.created"])
+}
+
+func TestParkingLot_ListKeepsMessagesInOrder(t *testing.T) {
+	broker := newFakeBroker()
+	lot := newTestParkingLot(t, broker, "a", "b", "c")
+
+	messages, err := lot.List(2)
+	require.NoError(t, err)
+	require.Len(t, messages, 2)
+	assert.Equal(t, "a", messages[0].MessageID)
+	assert.Equal(t, "company.created", messages[0].Queue)
+	assert.Equal(t, "rejected", messages[0].Reason)
+	assert.Equal(t, int64(1), messages[0].Count)
+	assert.Equal(t, "company.created.sp", messages[0].RoutingKey)
+	assert.Nil(t, messages[0].Body)
+
+	assert.Equal(t, []string{"a", "b", "c"}, broker.queued(parkingQueue))
+
+	all, err := lot.List(0)
+	require.NoError(t, err)
+	assert.Len(t, all, 3)
+}
+
+func TestParkingLot_Inspect(t *testing.T) {
+	broker := newFakeBroker()
+	lot := newTestParkingLot(t, broker, "a", "b")
+
+	message, err := lot.Inspect("b")
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"id":"b"}`, string(message.Body))
+
+	_, err = lot.Inspect("missing")
+	assert.ErrorIs(t, err, ErrMessageNotFound)
+	assert.Equal(t, []string{"a", "b"}, broker.queued(parkingQueue))
+}
+
+func TestParkingLot_RequeuePublishesToOriginQueue(t *testing.T) {
+	broker := newFakeBroker()
+	lot := newTestParkingLot(t, broker, "a", "b", "c")
+
+	requeued, err := lot.Requeue(context.Background(), "b")
+	require.NoError(t, err)
+	assert.Equal(t, 1, requeued)
+
+	assert.Equal(t, []string{"a", "c"}, broker.queued(parkingQueue))
+	assert.Equal(t, []string{"b"}, broker.queued("company.created"))
+
+	_, published, _, _, _ := broker.snapshot()
+	require.Len(t, published, 1)
+	assert.NotContains(t, published[0].Headers, "x-death")
+	assert.NotContains(t, published[0].Headers, "x-first-death-queue")
+}
+
+func TestParkingLot_RequeueFailureKeepsMessage(t *testing.T) {
+	broker := newFakeBroker()
+	lot := newTestParkingLot(t, broker, "a")
+	broker.set(func(b *fakeBroker) { b.nack = true })
+
+	requeued, err := lot.Requeue(context.Background())
+	assert.ErrorIs(t, err, ErrNacked)
+	assert.Equal(t, 0, requeued)
+	assert.Equal(t, []string{"a"}, broker.queued(parkingQueue))
+}
+
+func TestParkingLot_Discard(t *testing.T) {
+	broker := newFakeBroker()
+	lot := newTestParkingLot(t, broker, "a", "b", "c")
+
+	discarded, err := lot.Discard("a", "c")
+	require.NoError(t, err)
+	assert.Equal(t, 2, discarded)
+	assert.Equal(t, []string{"b"}, broker.queued(parkingQueue))
+
+	discarded, err = lot.Discard()
+	require.NoError(t, err)
+	assert.Equal(t, 1, discarded)
+	assert.Empty(t, broker.queued(parkingQueue))
+}
@@ -5,103 +5,322 @@ import (
 	"company-service/internal/messaging"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 )
 
+// Erros de entrega: a mensagem só é considerada entregue após o ack do broker
+var (
+	ErrNacked         = errors.New("mensagem rejeitada pelo broker (nack)")
+	ErrUnroutable     = errors.New("mensagem sem rota para nenhuma fila")
+	ErrConfirmTimeout = errors.New("tempo esgotado aguardando confirmação do broker")
+)
+
+// Config define a conexão e a topologia do produtor RabbitMQ
+type Config struct {
+	URI               string
+	Topology          Topology
+	ConfirmTimeout    time.Duration // prazo para o ack/nack de cada publicação
+	ReconnectDelay    time.Duration // espera inicial entre tentativas de reconexão
+	MaxReconnectDelay time.Duration // limite do backoff exponencial de reconexão
+}
+
 type rabbitMQProducer struct {
-	conn    *amqp.Connection
-	channel *amqp.Channel
-	queue   string
+	conn     *connectionManager
+	topology Topology
+	timeout  time.Duration
+
+	// mu serializa apenas o envio ao canal; as confirmações são aguardadas fora dele, com
+	// várias publicações pendentes ao mesmo tempo, e os basic.return são atribuídos a cada
+	// mensagem pelo message_id (returnTracker)
+	mu      sync.Mutex
+	metrics messaging.PublisherMetrics
 }
 
-func NewProducer(uri, queueName string) (messaging.MessageProducer, error) {
-	// Create a new RabbitMQ connection
-	conn, err := amqp.Dial(uri)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to RabbitMQ: %w", err)
-	}
+// NewProducer conecta ao broker, declara a topologia e mantém a conexão, reconectando
+// automaticamente quando ela cai
+func NewProducer(cfg Config) (messaging.MessageProducer, error) {
+	return newProducer(cfg, dialAMQP)
+}
 
-	// Create a new channel
-	channel, err := conn.Channel()
-	if err != nil {
-		conn.Close()
-		return nil, fmt.Errorf("failed to open a channel: %w", err)
+func newProducer(cfg Config, dial dialer) (*rabbitMQProducer, error) {
+	if cfg.ConfirmTimeout <= 0 {
+		cfg.ConfirmTimeout = 5 * time.Second
+	}
+	if cfg.ReconnectDelay <= 0 {
+		cfg.ReconnectDelay = 1 * time.Second
+	}
+	if cfg.MaxReconnectDelay < cfg.ReconnectDelay {
+		cfg.MaxReconnectDelay = 30 * time.Second
 	}
 
-	_, err = channel.QueueDeclare(
-		queueName,
-		true,  // durable - sobrevive a reinicialização do broker
-		false, // auto-delete quando não usado
-		false, // exclusive - apenas esta conexão
-		false, // no-wait
-		nil,   // arguments
-	)
+	conn, err := newConnectionManager(cfg, dial)
 	if err != nil {
-		channel.Close()
-		conn.Close()
-		return nil, fmt.Errorf("failed to declare a queue: %w", err)
+		return nil, err
 	}
 
-	log.Printf("Connected to RabbitMQ and declared queue: %s", queueName)
+	log.Printf("Connected to RabbitMQ and declared topology (exchange: %q, queues: %d)", cfg.Topology.Exchange, len(cfg.Topology.Queues))
 
 	return &rabbitMQProducer{
-		conn:    conn,
-		channel: channel,
-		queue:   queueName,
+		conn:     conn,
+		topology: cfg.Topology,
+		timeout:  cfg.ConfirmTimeout,
 	}, nil
 }
 
 func (p *rabbitMQProducer) SendCompanyCreated(ctx context.Context, company *domain.Company) error {
-	return p.sendMessage(ctx, "Cadastro de EMPRESA "+company.FantasyName, company)
+	return p.sendEvent(ctx, messaging.NewCompanyEvent(messaging.CompanyCreated, company))
 }
 
-func (p *rabbitMQProducer) SendCompanyUpdated(ctx context.Context, company *domain.Company) error {
-	return p.sendMessage(ctx, "Edição da EMPRESA "+company.FantasyName, company)
+func (p *rabbitMQProducer) SendCompanyUpdated(ctx context.Context, previous, company *domain.Company) error {
+	return p.sendEvent(ctx, messaging.NewCompanyUpdatedEvent(previous, company))
 }
 
 func (p *rabbitMQProducer) SendCompanyDeleted(ctx context.Context, company *domain.Company) error {
-	return p.sendMessage(ctx, "Exclusão de EMPRESA "+company.FantasyName, company)
+	return p.sendEvent(ctx, messaging.NewCompanyEvent(messaging.CompanyDeleted, company))
 }
 
-func (p *rabbitMQProducer) sendMessage(ctx context.Context, messageTxt string, company *domain.Company) error {
-
-	message := map[string]interface{}{
-		"operation":  messageTxt, // EX: "Cadastro de EMPRESA XXX"
-		"company_id": company.ID,
-		"cnpj":       company.CNPJ,
-		"timestamp":  time.Now().Format(time.RFC3339),
+// sendEvent publica o evento como CloudEvent estruturado; os atributos principais também
+// vão nas propriedades AMQP para permitir roteamento e deduplicação sem ler o corpo.
+// A publicação é mandatory e só tem sucesso após o ack do broker.
+func (p *rabbitMQProducer) sendEvent(ctx context.Context, event messaging.CompanyEvent) error {
+	if messaging.IsReplay(ctx) {
+		event.MarkReplay()
+	}
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
 	}
 
-	body, err := json.Marshal(message)
+	session, err := p.conn.current()
 	if err != nil {
-		return fmt.Errorf("failed to marshal message: %w", err)
+		p.metrics.Failed()
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	var headers amqp.Table
+	if event.Replay {
+		headers = amqp.Table{messaging.ReplayHeader: "true"}
 	}
 
-	err = p.channel.PublishWithContext(
+	start := time.Now()
+	p.mu.Lock()
+	confirm, err := session.channel.PublishWithDeferredConfirmWithContext(
 		ctx,
-		"",      // exchange
-		p.queue, // routing key
-		false,   // mandatory
-		false,   // immediate
+		p.topology.Exchange,
+		p.topology.RoutingKey(event),
+		true,  // mandatory - devolve mensagens sem rota
+		false, // immediate
 		amqp.Publishing{
-			ContentType:  "application/json",
+			Headers:      headers,
+			ContentType:  messaging.ContentType,
+			MessageId:    event.ID,
+			Type:         string(event.Type),
+			Timestamp:    event.Time,
+			AppId:        event.Source,
 			Body:         body,
 			DeliveryMode: amqp.Persistent,
 		},
 	)
-
+	p.mu.Unlock()
 	if err != nil {
+		p.metrics.Failed()
 		return fmt.Errorf("failed to publish message: %w", err)
 	}
+	p.metrics.Published()
+
+	acked, err := confirm.WaitContext(ctx)
+	if err != nil {
+		session.returns.forget(event.ID)
+		p.metrics.TimedOut()
+		return fmt.Errorf("%w (event %s): %v", ErrConfirmTimeout, event.ID, err)
+	}
+	p.metrics.Confirmed(acked, time.Since(start))
 
-	log.Printf("Message sent: %s", messageTxt)
+	returned := session.returns.take(event.ID)
+	if !acked {
+		return fmt.Errorf("%w (event %s)", ErrNacked, event.ID)
+	}
+	if returned {
+		p.metrics.Returned()
+		return fmt.Errorf("%w (event %s, routing key %s)", ErrUnroutable, event.ID, p.topology.RoutingKey(event))
+	}
+
+	log.Printf("Event sent: %s %s (company %s)", event.Type, event.ID, event.Subject)
 	return nil
 }
 
+// wasReturned consome os basic.return pendentes e informa se algum é da mensagem informada,
+// para publicadores com uma mensagem pendente por vez. O broker envia o return antes do ack,
+// então ele já está no canal quando o ack chega.
+func wasReturned(returns <-chan amqp.Return, messageID string) bool {
+	returned := false
+	for {
+		select {
+		case ret, ok := <-returns:
+			if !ok {
+				return returned
+			}
+			if ret.MessageId == messageID {
+				returned = true
+			} else {
+				log.Printf("Late return for message %s: %s", ret.MessageId, ret.ReplyText)
+			}
+		default:
+			return returned
+		}
+	}
+}
+
+// returnTracker atribui os basic.return às publicações pendentes. Uma rotina dedicada drena o
+// canal de returns continuamente e guarda cada um pelo message_id, de modo que o buffer nunca
+// enche (o que bloquearia a entrega dos acks) mesmo com muitas publicações aguardando
+// confirmação. Como o broker envia o return antes do ack da mesma mensagem, ao receber o ack o
+// publicador pede que a rotina processe o que já chegou (flush) e então consulta o registro.
+type returnTracker struct {
+	mu       sync.Mutex
+	returned map[string]time.Time // message_id -> recebimento
+
+	flush   chan chan struct{}
+	stopped chan struct{} // fechado quando o canal de returns é fechado (fim da sessão)
+}
+
+// staleReturn é o tempo após o qual um return não consultado (ex.: publicador que desistiu
+// antes do ack) é descartado
+const staleReturn = time.Minute
+
+func newReturnTracker(returns <-chan amqp.Return) *returnTracker {
+	t := &returnTracker{
+		returned: map[string]time.Time{},
+		flush:    make(chan chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	go t.run(returns)
+	return t
+}
+
+// run guarda os returns recebidos até o fechamento do canal
+func (t *returnTracker) run(returns <-chan amqp.Return) {
+	defer close(t.stopped)
+
+	prune := time.NewTicker(staleReturn)
+	defer prune.Stop()
+
+	for {
+		select {
+		case ret, ok := <-returns:
+			if !ok {
+				return
+			}
+			t.store(ret)
+		case done := <-t.flush:
+			open := t.drain(returns)
+			close(done)
+			if !open {
+				return
+			}
+		case now := <-prune.C:
+			t.prune(now)
+		}
+	}
+}
+
+// drain guarda os returns já disponíveis no canal; retorna false se ele foi fechado
+func (t *returnTracker) drain(returns <-chan amqp.Return) bool {
+	for {
+		select {
+		case ret, ok := <-returns:
+			if !ok {
+				return false
+			}
+			t.store(ret)
+		default:
+			return true
+		}
+	}
+}
+
+func (t *returnTracker) store(ret amqp.Return) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.returned[ret.MessageId] = time.Now()
+}
+
+// sync aguarda a rotina guardar os returns enviados antes da chamada
+func (t *returnTracker) sync() {
+	done := make(chan struct{})
+	select {
+	case t.flush <- done:
+		<-done
+	case <-t.stopped:
+	}
+}
+
+// take informa se a mensagem foi devolvida, consumindo o registro
+func (t *returnTracker) take(messageID string) bool {
+	t.sync()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, returned := t.returned[messageID]
+	delete(t.returned, messageID)
+	return returned
+}
+
+// forget descarta o registro de uma mensagem cujo resultado não será consultado
+func (t *returnTracker) forget(messageID string) {
+	t.sync()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.returned, messageID)
+}
+
+func (t *returnTracker) prune(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for id, at := range t.returned {
+		if now.Sub(at) > staleReturn {
+			log.Printf("Late return for message %s discarded", id)
+			delete(t.returned, id)
+		}
+	}
+}
+
+// Stats retorna as estatísticas de publicação, incluindo a latência das confirmações
+func (p *rabbitMQProducer) Stats() messaging.PublisherStats {
+	return p.metrics.Stats()
+}
+
+// Connected informa se o produtor está conectado ao broker
+func (p *rabbitMQProducer) Connected() bool {
+	return p.conn.connected()
+}
+
+// Healthy informa se o broker está disponível para publicação
+func (p *rabbitMQProducer) Healthy() bool {
+	return p.Connected()
+}
+
+// HealthDetails retorna o estado da conexão e da topologia para a rota administrativa
+func (p *rabbitMQProducer) HealthDetails() interface{} {
+	return map[string]interface{}{
+		"connected": p.Connected(),
+		"exchange":  p.topology.Exchange,
+		"queues":    len(p.topology.Queues),
+	}
+}
+
+// Close interrompe as reconexões e fecha o canal e a conexão com o broker
 func (p *rabbitMQProducer) Close() error {
-	return nil
+	return p.conn.Close()
 }
@@ -0,0 +1,288 @@
+package rabbitmq
+
+import (
+	"company-service/internal/domain"
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testConfig() Config {
+	return Config{
+		URI:               "amqp://fake",
+		Topology:          TopicTopology("company.events", []QueueBinding{{Queue: "company.created", RoutingKeys: []string{"company.created.*"}}}),
+		ConfirmTimeout:    200 * time.Millisecond,
+		ReconnectDelay:    5 * time.Millisecond,
+		MaxReconnectDelay: 20 * time.Millisecond,
+	}
+}
+
+func testCompany() *domain.Company {
+	return &domain.Company{ID: "665f1c2e8b3e4a0001a1b2c3", TenantID: "acme", CNPJ: "11444777000161", Address: "Av. Paulista, 1000 - São Paulo/SP"}
+}
+
+func newTestProducer(t *testing.T, broker *fakeBroker) *rabbitMQProducer {
+	t.Helper()
+	producer, err := newProducer(testConfig(), broker.dial)
+	require.NoError(t, err)
+	t.Cleanup(func() { producer.Close() })
+	return producer
+}
+
+func TestProducer_PublishesWithConfirmAndRoutingKey(t *testing.T) {
+	broker := newFakeBroker()
+	producer := newTestProducer(t, broker)
+
+	require.NoError(t, producer.SendCompanyCreated(context.Background(), testCompany()))
+
+	_, published, exchanges, queues, bindings := broker.snapshot()
+	require.Len(t, published, 1)
+	assert.Equal(t, "br.company.created.v1", published[0].Type)
+	assert.Equal(t, 1, exchanges["company.events"])
+	assert.Equal(t, 1, queues["company.created"])
+	assert.Equal(t, 1, bindings["company.created|company.created.*|company.events"])
+
+	stats := producer.Stats()
+	assert.Equal(t, uint64(1), stats.Confirmed)
+}
+
+func TestProducer_NackAndUnroutableAreFailures(t *testing.T) {
+	broker := newFakeBroker()
+	producer := newTestProducer(t, broker)
+
+	broker.set(func(b *fakeBroker) { b.nack = true })
+	assert.ErrorIs(t, producer.SendCompanyUpdated(context.Background(), nil, testCompany()), ErrNacked)
+
+	broker.set(func(b *fakeBroker) { b.nack, b.unroutable = false, true })
+	assert.ErrorIs(t, producer.SendCompanyUpdated(context.Background(), nil, testCompany()), ErrUnroutable)
+
+	broker.set(func(b *fakeBroker) { b.unroutable, b.noConfirms = false, true })
+	assert.ErrorIs(t, producer.SendCompanyUpdated(context.Background(), nil, testCompany()), ErrConfirmTimeout)
+
+	stats := producer.Stats()
+	assert.Equal(t, uint64(1), stats.Nacked)
+	assert.Equal(t, uint64(1), stats.Returned)
+	assert.Equal(t, uint64(1), stats.TimedOut)
+}
+
+func TestProducer_InitialDialFailure_ReturnsError(t *testing.T) {
+	broker := newFakeBroker()
+	broker.set(func(b *fakeBroker) { b.dialFailures = 1 })
+
+	_, err := newProducer(testConfig(), broker.dial)
+
+	assert.Error(t, err)
+}
+
+func TestProducer_ReconnectsAndRedeclaresTopologyAfterBrokerRestart(t *testing.T) {
+	broker := newFakeBroker()
+	producer := newTestProducer(t, broker)
+
+	// As duas primeiras tentativas de reconexão falham, exercitando o backoff
+	broker.set(func(b *fakeBroker) { b.dialFailures = 2 })
+	broker.restart()
+
+	require.Eventually(t, producer.Connected, time.Second, 5*time.Millisecond)
+	require.NoError(t, producer.SendCompanyDeleted(context.Background(), testCompany()))
+
+	dials, published, exchanges, queues, _ := broker.snapshot()
+	assert.Equal(t, 4, dials)
+	assert.Len(t, published, 1)
+	assert.Equal(t, 2, exchanges["company.events"], "topologia declarada novamente na reconexão")
+	assert.Equal(t, 2, queues["company.created"])
+}
+
+func TestProducer_PublishWhileDisconnected_ReturnsErrNotConnected(t *testing.T) {
+	broker := newFakeBroker()
+	producer := newTestProducer(t, broker)
+
+	broker.set(func(b *fakeBroker) { b.dialFailures = 1000 })
+	broker.restart()
+
+	require.Eventually(t, func() bool { return !producer.Connected() }, time.Second, 5*time.Millisecond)
+	assert.ErrorIs(t, producer.SendCompanyCreated(context.Background(), testCompany()), ErrNotConnected)
+
+	broker.set(func(b *fakeBroker) { b.dialFailures = 0 })
+	require.Eventually(t, producer.Connected, time.Second, 5*time.Millisecond)
+	assert.NoError(t, producer.SendCompanyCreated(context.Background(), testCompany()))
+}
+
+func TestProducer_ConcurrentPublishersDuringRestart(t *testing.T) {
+	broker := newFakeBroker()
+	producer := newTestProducer(t, broker)
+
+	const publishers, perPublisher = 8, 20
+	var wg sync.WaitGroup
+	for i := 0; i < publishers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perPublisher; j++ {
+				// Repete até entregar, como o retry do serviço
+				for producer.SendCompanyCreated(context.Background(), testCompany()) != nil {
+					time.Sleep(time.Millisecond)
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 3; i++ {
+		time.Sleep(5 * time.Millisecond)
+		broker.restart()
+	}
+	wg.Wait()
+
+	assert.Equal(t, uint64(publishers*perPublisher), producer.Stats().Confirmed)
+}
+
+func TestProducer_CloseReleasesConnectionAndStopsReconnecting(t *testing.T) {
+	broker := newFakeBroker()
+	producer, err := newProducer(testConfig(), broker.dial)
+	require.NoError(t, err)
+
+	broker.mu.Lock()
+	conn := broker.conns[0]
+	broker.mu.Unlock()
+
+	require.NoError(t, producer.Close())
+	assert.True(t, conn.isClosed())
+	assert.NoError(t, producer.Close(), "Close é idempotente")
+
+	dials, _, _, _, _ := broker.snapshot()
+	time.Sleep(30 * time.Millisecond)
+	dialsAfter, _, _, _, _ := broker.snapshot()
+	assert.Equal(t, dials, dialsAfter, "não reconecta após o Close")
+
+	assert.Error(t, producer.SendCompanyCreated(context.Background(), testCompany()))
+}
+
+func TestProducer_CloseWhileReconnecting(t *testing.T) {
+	broker := newFakeBroker()
+	producer, err := newProducer(testConfig(), broker.dial)
+	require.NoError(t, err)
+
+	broker.set(func(b *fakeBroker) { b.dialFailures = 1000 })
+	broker.restart()
+	require.Eventually(t, func() bool { return !producer.Connected() }, time.Second, 5*time.Millisecond)
+
+	done := make(chan error)
+	go func() { done <- producer.Close() }()
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("Close não retornou durante a reconexão")
+	}
+}
+
+func TestProducer_PublishesWhileConfirmationsArePending(t *testing.T) {
+	broker := newFakeBroker()
+	producer := newTestProducer(t, broker)
+	gate := make(chan struct{})
+	broker.set(func(b *fakeBroker) { b.confirmGate = gate })
+
+	const publishers = 5
+	errs := make(chan error, publishers)
+	for i := 0; i < publishers; i++ {
+		go func() { errs <- producer.SendCompanyCreated(context.Background(), testCompany()) }()
+	}
+
+	// Todas as mensagens são enviadas enquanto nenhuma confirmação chegou
+	require.Eventually(t, func() bool {
+		_, published, _, _, _ := broker.snapshot()
+		return len(published) == publishers
+	}, time.Second, time.Millisecond)
+
+	close(gate)
+	for i := 0; i < publishers; i++ {
+		assert.NoError(t, <-errs)
+	}
+	assert.Equal(t, uint64(publishers), producer.Stats().Confirmed)
+}
+
+func TestProducer_ConcurrentReturnsAreAttributedByMessage(t *testing.T) {
+	broker := newFakeBroker()
+	producer := newTestProducer(t, broker)
+	broker.set(func(b *fakeBroker) { b.unroutableKey = "company.created.rj" })
+
+	routable := testCompany()
+	unroutable := testCompany()
+	unroutable.Address = "Av. Atlântica, 500 - Rio de Janeiro/RJ"
+
+	const perKind = 20
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	results := map[bool][]error{}
+	for i := 0; i < perKind; i++ {
+		for _, company := range []*domain.Company{routable, unroutable} {
+			wg.Add(1)
+			go func(company *domain.Company) {
+				defer wg.Done()
+				err := producer.SendCompanyCreated(context.Background(), company)
+				mu.Lock()
+				results[company == unroutable] = append(results[company == unroutable], err)
+				mu.Unlock()
+			}(company)
+		}
+	}
+	wg.Wait()
+
+	for _, err := range results[false] {
+		assert.NoError(t, err)
+	}
+	for _, err := range results[true] {
+		assert.ErrorIs(t, err, ErrUnroutable)
+	}
+	assert.Equal(t, uint64(perKind), producer.Stats().Returned)
+}
+
+func TestProducer_ManyPendingReturnsDoNotBlockTheChannel(t *testing.T) {
+	broker := newFakeBroker()
+	producer := newTestProducer(t, broker)
+	gate := make(chan struct{})
+	broker.set(func(b *fakeBroker) {
+		b.unroutable = true
+		b.confirmGate = gate
+	})
+
+	// Mais devoluções pendentes do que o buffer do NotifyReturn (64)
+	const publishers = 150
+	errs := make(chan error, publishers)
+	for i := 0; i < publishers; i++ {
+		company := testCompany()
+		company.ID = fmt.Sprintf("665f1c2e8b3e4a0001a1%04d", i)
+		go func() { errs <- producer.SendCompanyCreated(context.Background(), company) }()
+	}
+
+	require.Eventually(t, func() bool {
+		_, published, _, _, _ := broker.snapshot()
+		return len(published) == publishers
+	}, time.Second, time.Millisecond, "os returns devem ser drenados enquanto as confirmações estão pendentes")
+
+	close(gate)
+	for i := 0; i < publishers; i++ {
+		assert.ErrorIs(t, <-errs, ErrUnroutable)
+	}
+	assert.Equal(t, uint64(publishers), producer.Stats().Returned)
+}
+
+func TestReturnTracker_KeepsReturnsOfOtherMessages(t *testing.T) {
+	returns := make(chan amqp.Return, 4)
+	tracker := newReturnTracker(returns)
+	returns <- amqp.Return{MessageId: "a"}
+	returns <- amqp.Return{MessageId: "b"}
+
+	assert.False(t, tracker.take("c"))
+	assert.True(t, tracker.take("b"), "o return de b foi drenado pela consulta de c")
+	assert.False(t, tracker.take("b"), "o registro é consumido")
+
+	tracker.forget("a")
+	assert.False(t, tracker.take("a"))
+}
@@ -0,0 +1,187 @@
+package rabbitmq
+
+import (
+	"company-service/internal/messaging"
+	"company-service/pkg/utils"
+	"fmt"
+	"strings"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// unknownUF é o segmento de UF da routing key de empresas sem UF reconhecível no endereço
+const unknownUF = "unknown"
+
+// QueueBinding é uma fila durável e os padrões de routing key ligados a ela
+type QueueBinding struct {
+	Queue       string
+	RoutingKeys []string
+}
+
+// Topology descreve onde os eventos são publicados. No modo topic os eventos vão para um
+// exchange do tipo topic com routing keys company.<evento>.<uf>; no modo legado, sem exchange,
+// todos os eventos vão para uma única fila pelo exchange padrão.
+type Topology struct {
+	Exchange   string
+	Queues     []QueueBinding
+	DeadLetter DeadLetter
+}
+
+// DeadLetter configura o dead-lettering das filas de eventos. Mensagens rejeitadas pelos
+// consumidores, expiradas pelo TTL ou descartadas pelo limite de tamanho vão para o exchange
+// fanout Exchange e ficam na fila parking lot Queue até serem reenfileiradas ou descartadas.
+type DeadLetter struct {
+	Exchange   string        // vazio desabilita o dead-lettering
+	Queue      string        // fila parking lot
+	MessageTTL time.Duration // 0 = sem TTL
+	MaxLength  int           // 0 = sem limite de mensagens
+}
+
+// enabled informa se as filas devem ser declaradas com dead-lettering
+func (d DeadLetter) enabled() bool {
+	return d.Exchange != ""
+}
+
+// queueArgs retorna os argumentos x-* das filas de eventos. Com o limite de tamanho as
+// mensagens mais antigas são descartadas (drop-head) e, portanto, vão para o parking lot.
+func (d DeadLetter) queueArgs() amqp.Table {
+	if !d.enabled() {
+		return nil
+	}
+	args := amqp.Table{"x-dead-letter-exchange": d.Exchange}
+	if d.MessageTTL > 0 {
+		args["x-message-ttl"] = d.MessageTTL.Milliseconds()
+	}
+	if d.MaxLength > 0 {
+		args["x-max-length"] = int64(d.MaxLength)
+		args["x-overflow"] = "drop-head"
+	}
+	return args
+}
+
+// LegacyTopology publica todos os eventos na fila informada pelo exchange padrão
+func LegacyTopology(queue string) Topology {
+	return Topology{Queues: []QueueBinding{{Queue: queue}}}
+}
+
+// TopicTopology publica no exchange topic informado, declarando as filas e bindings
+func TopicTopology(exchange string, queues []QueueBinding) Topology {
+	return Topology{Exchange: exchange, Queues: queues}
+}
+
+// ParseBindings interpreta a lista de filas no formato
+// "fila=padrão1,padrão2;outra=padrão", ex.: "company.sp=company.*.sp;company.deleted=company.deleted.*"
+func ParseBindings(spec string) ([]QueueBinding, error) {
+	var bindings []QueueBinding
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		queue, keys, ok := strings.Cut(entry, "=")
+		queue = strings.TrimSpace(queue)
+		if !ok || queue == "" {
+			return nil, fmt.Errorf("invalid queue binding %q: expected queue=pattern", entry)
+		}
+
+		binding := QueueBinding{Queue: queue}
+		for _, key := range strings.Split(keys, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				binding.RoutingKeys = append(binding.RoutingKeys, key)
+			}
+		}
+		if len(binding.RoutingKeys) == 0 {
+			return nil, fmt.Errorf("invalid queue binding %q: no routing keys", entry)
+		}
+		bindings = append(bindings, binding)
+	}
+	return bindings, nil
+}
+
+// legacy informa se a topologia usa o exchange padrão
+func (t Topology) legacy() bool {
+	return t.Exchange == ""
+}
+
+// RoutingKey retorna a routing key do evento: o nome da fila no modo legado ou
+// company.<evento>.<uf> no modo topic, com a UF em minúsculas extraída do endereço
+func (t Topology) RoutingKey(event messaging.CompanyEvent) string {
+	if t.legacy() {
+		if len(t.Queues) == 0 {
+			return ""
+		}
+		return t.Queues[0].Queue
+	}
+
+	uf := strings.ToLower(utils.ExtractUF(event.Data.Address))
+	if uf == "" {
+		uf = unknownUF
+	}
+	return "company." + event.Type.Operation() + "." + uf
+}
+
+// declare cria o exchange, as filas e os bindings. As declarações são idempotentes e
+// executadas a cada inicialização.
+func (t Topology) declare(channel amqpChannel) error {
+	if !t.legacy() {
+		err := channel.ExchangeDeclare(
+			t.Exchange,
+			amqp.ExchangeTopic,
+			true,  // durable
+			false, // auto-delete
+			false, // internal
+			false, // no-wait
+			nil,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to declare exchange %s: %w", t.Exchange, err)
+		}
+	}
+
+	if err := t.DeadLetter.declare(channel); err != nil {
+		return err
+	}
+
+	for _, binding := range t.Queues {
+		_, err := channel.QueueDeclare(
+			binding.Queue,
+			true,  // durable - sobrevive a reinicialização do broker
+			false, // auto-delete quando não usado
+			false, // exclusive - apenas esta conexão
+			false, // no-wait
+			t.DeadLetter.queueArgs(),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to declare queue %s: %w", binding.Queue, err)
+		}
+
+		if t.legacy() {
+			continue
+		}
+		for _, key := range binding.RoutingKeys {
+			if err := channel.QueueBind(binding.Queue, key, t.Exchange, false, nil); err != nil {
+				return fmt.Errorf("failed to bind queue %s to %s: %w", binding.Queue, key, err)
+			}
+		}
+	}
+	return nil
+}
+
+// declare cria o exchange de dead-letter e a fila parking lot ligada a ele
+func (d DeadLetter) declare(channel amqpChannel) error {
+	if !d.enabled() {
+		return nil
+	}
+
+	if err := channel.ExchangeDeclare(d.Exchange, amqp.ExchangeFanout, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare dead-letter exchange %s: %w", d.Exchange, err)
+	}
+	if _, err := channel.QueueDeclare(d.Queue, true, false, false, false, nil); err != nil {
+		return fmt.Errorf("failed to declare parking lot queue %s: %w", d.Queue, err)
+	}
+	if err := channel.QueueBind(d.Queue, "", d.Exchange, false, nil); err != nil {
+		return fmt.Errorf("failed to bind parking lot queue %s: %w", d.Queue, err)
+	}
+	return nil
+}
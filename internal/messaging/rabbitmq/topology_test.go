@@ -0,0 +1,49 @@
+package rabbitmq
+
+import (
+	"company-service/internal/domain"
+	"company-service/internal/messaging"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopology_RoutingKey_TopicUsesEventAndUF(t *testing.T) {
+	topology := TopicTopology("company.events", nil)
+
+	created := messaging.NewCompanyEvent(messaging.CompanyCreated, &domain.Company{Address: "Av. Paulista, 1000 - São Paulo/SP"})
+	deleted := messaging.NewCompanyEvent(messaging.CompanyDeleted, &domain.Company{})
+
+	assert.Equal(t, "company.created.sp", topology.RoutingKey(created))
+	assert.Equal(t, "company.deleted.unknown", topology.RoutingKey(deleted))
+}
+
+func TestTopology_RoutingKey_LegacyUsesQueue(t *testing.T) {
+	topology := LegacyTopology("company_events")
+	event := messaging.NewCompanyEvent(messaging.CompanyUpdated, &domain.Company{Address: "Centro, Curitiba/PR"})
+
+	assert.Equal(t, "", topology.Exchange)
+	assert.Equal(t, "company_events", topology.RoutingKey(event))
+}
+
+func TestParseBindings(t *testing.T) {
+	bindings, err := ParseBindings("company.created=company.created.*; company.sp = company.*.sp, company.*.rj ;")
+
+	require.NoError(t, err)
+	assert.Equal(t, []QueueBinding{
+		{Queue: "company.created", RoutingKeys: []string{"company.created.*"}},
+		{Queue: "company.sp", RoutingKeys: []string{"company.*.sp", "company.*.rj"}},
+	}, bindings)
+}
+
+func TestParseBindings_Invalid(t *testing.T) {
+	for _, spec := range []string{"company.created", "=company.*.sp", "company.sp="} {
+		_, err := ParseBindings(spec)
+		assert.Error(t, err, spec)
+	}
+
+	bindings, err := ParseBindings("")
+	assert.NoError(t, err)
+	assert.Empty(t, bindings)
+}
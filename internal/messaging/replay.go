@@ -0,0 +1,21 @@
+package messaging
+
+import "context"
+
+// ReplayHeader é o cabeçalho (ou propriedade da mensagem) com valor "true" que marca os eventos
+// republicados por um replay, para que os consumidores possam distingui-los dos originais
+const ReplayHeader = "replay"
+
+type replayKey struct{}
+
+// WithReplay marca o contexto como de replay; os produtores publicam os eventos enviados com
+// ele com o marcador ReplayHeader e o atributo replay do CloudEvent
+func WithReplay(ctx context.Context) context.Context {
+	return context.WithValue(ctx, replayKey{}, true)
+}
+
+// IsReplay informa se o contexto foi marcado por WithReplay
+func IsReplay(ctx context.Context) bool {
+	replay, _ := ctx.Value(replayKey{}).(bool)
+	return replay
+}
@@ -0,0 +1,94 @@
+package spool
+
+import (
+	"company-service/internal/messaging"
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Redeliverer republica periodicamente os eventos do spool quando o broker volta a ficar
+// disponível. Os eventos são enviados na ordem em que foram gravados; se o envio de um evento
+// falha, os eventos seguintes da mesma empresa ficam para a próxima rodada, preservando a
+// ordem por empresa.
+type Redeliverer struct {
+	spool    *Spool
+	producer messaging.MessageProducer
+	logger   *zap.Logger
+	interval time.Duration
+}
+
+// NewRedeliverer cria o laço de reentrega do spool
+func NewRedeliverer(spool *Spool, producer messaging.MessageProducer, logger *zap.Logger, interval time.Duration) *Redeliverer {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	return &Redeliverer{spool: spool, producer: producer, logger: logger, interval: interval}
+}
+
+// Run executa o laço de reentrega até o cancelamento do contexto
+func (r *Redeliverer) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		r.Redeliver(ctx)
+	}
+}
+
+// Redeliver executa uma rodada de reentrega e retorna a quantidade de eventos entregues
+func (r *Redeliverer) Redeliver(ctx context.Context) int {
+	if checker, ok := r.producer.(messaging.HealthChecker); ok && !checker.Healthy() {
+		return 0
+	}
+
+	entries := r.spool.Pending()
+	if len(entries) == 0 {
+		return 0
+	}
+
+	delivered := 0
+	blocked := map[string]bool{}
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			break
+		}
+		if blocked[entry.Company.ID] {
+			continue
+		}
+
+		company := entry.Company
+		if err := messaging.Send(ctx, r.producer, entry.Type, entry.Previous, &company); err != nil {
+			blocked[entry.Company.ID] = true
+			r.logger.Warn("Falha ao reenviar evento do spool",
+				zap.Uint64("seq", entry.Seq),
+				zap.String("type", string(entry.Type)),
+				zap.String("company_id", entry.Company.ID),
+				zap.Error(err))
+			continue
+		}
+
+		if err := r.spool.Ack(entry.Seq); err != nil {
+			// O evento já foi publicado; sem o ack ele será reenviado (entrega at-least-once)
+			r.logger.Error("Falha ao remover evento reenviado do spool",
+				zap.Uint64("seq", entry.Seq),
+				zap.Error(err))
+			blocked[entry.Company.ID] = true
+			continue
+		}
+		delivered++
+	}
+
+	if delivered > 0 {
+		r.logger.Info("Eventos do spool reenviados",
+			zap.Int("delivered", delivered),
+			zap.Int("pending", r.spool.Len()))
+	}
+	return delivered
+}
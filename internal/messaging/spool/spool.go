@@ -0,0 +1,423 @@
+package spool
+
+import (
+	"bufio"
+	"company-service/internal/domain"
+	"company-service/internal/encryption"
+	"company-service/internal/messaging"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotFound indica um evento inexistente no spool
+var ErrNotFound = errors.New("evento não encontrado no spool")
+
+const segmentExt = ".seg"
+
+// Entry é um evento que esgotou as tentativas de envio e aguarda nova entrega
+type Entry struct {
+	Seq      uint64              `json:"seq"`
+	Type     messaging.EventType `json:"type"`
+	Company  domain.Company      `json:"company"`
+	Previous *domain.Company     `json:"previous,omitempty"` // estado anterior, em atualizações
+	Error    string              `json:"error,omitempty"`
+	FailedAt time.Time           `json:"failed_at"`
+}
+
+// record é uma linha do segmento: a inclusão de um evento ("add") ou sua remoção ("ack").
+// Com keyring, o evento incluído é gravado cifrado em Sealed no lugar de Entry.
+type record struct {
+	Op     string       `json:"op"`
+	Seq    uint64       `json:"seq"`
+	Entry  *Entry       `json:"entry,omitempty"`
+	Sealed *sealedEntry `json:"sealed,omitempty"`
+}
+
+// sealedEntry é um Entry cifrado: o ID da chave e nonce || ciphertext do JSON do evento
+type sealedEntry struct {
+	KeyID string `json:"k"`
+	Data  []byte `json:"c"`
+}
+
+// sealContext autentica o evento cifrado como conteúdo do spool
+var sealContext = []byte("spool")
+
+// segment é um arquivo append-only do spool; live conta os eventos ainda pendentes nele
+type segment struct {
+	id   uint64
+	path string
+	live int
+}
+
+// Spool guarda em disco os eventos que não puderam ser publicados. Os eventos são gravados em
+// segmentos append-only (JSON por linha) no diretório configurado: cada inclusão e cada remoção
+// é uma nova linha, e um segmento é apagado quando não tem mais eventos pendentes. Ao abrir,
+// os segmentos existentes são relidos e um novo segmento ativo é criado. Os arquivos são
+// acessíveis apenas pelo usuário do serviço e, com WithKeyring, os eventos são cifrados.
+type Spool struct {
+	dir         string
+	maxSegment  int64
+	keyring     *encryption.Keyring
+	mu          sync.Mutex
+	segments    []*segment // em ordem; o último é o ativo
+	active      *os.File
+	activeSize  int64
+	pending     map[uint64]*pendingEntry
+	perCompany  map[string]int
+	nextSeq     uint64
+	nextSegment uint64
+}
+
+type pendingEntry struct {
+	entry   Entry
+	segment *segment
+}
+
+// Option configura recursos opcionais do spool
+type Option func(*Spool)
+
+// WithKeyring cifra os eventos gravados com a chave ativa do keyring. Eventos gravados em claro
+// antes da criptografia continuam legíveis; eventos cifrados exigem o keyring na abertura.
+func WithKeyring(keyring *encryption.Keyring) Option {
+	return func(s *Spool) {
+		s.keyring = keyring
+	}
+}
+
+// Open abre (ou cria) o spool no diretório informado, que deve ser um caminho absoluto.
+// maxSegmentBytes limita o tamanho do segmento ativo antes da rotação.
+func Open(dir string, maxSegmentBytes int64, opts ...Option) (*Spool, error) {
+	if !filepath.IsAbs(dir) {
+		return nil, fmt.Errorf("spool directory must be an absolute path: %q", dir)
+	}
+	if maxSegmentBytes <= 0 {
+		maxSegmentBytes = 16 << 20
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+
+	s := &Spool{
+		dir:         dir,
+		maxSegment:  maxSegmentBytes,
+		pending:     map[uint64]*pendingEntry{},
+		perCompany:  map[string]int{},
+		nextSeq:     1,
+		nextSegment: 1,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	if err := s.rotate(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load relê os segmentos existentes, reconstruindo os eventos pendentes
+func (s *Spool) load() error {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*"+segmentExt))
+	if err != nil {
+		return err
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		var id uint64
+		if _, err := fmt.Sscanf(filepath.Base(path), "%016d"+segmentExt, &id); err != nil {
+			continue
+		}
+		seg := &segment{id: id, path: path}
+		s.segments = append(s.segments, seg)
+		if id >= s.nextSegment {
+			s.nextSegment = id + 1
+		}
+		if err := s.replay(seg); err != nil {
+			return err
+		}
+	}
+
+	s.compact()
+	return nil
+}
+
+func (s *Spool) replay(seg *segment) error {
+	file, err := os.Open(seg.path)
+	if err != nil {
+		return fmt.Errorf("failed to open spool segment: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	for scanner.Scan() {
+		var rec record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			// Linha incompleta de uma gravação interrompida; as anteriores são válidas
+			continue
+		}
+		switch rec.Op {
+		case "add":
+			entry, err := s.open(rec)
+			if err != nil {
+				return fmt.Errorf("failed to read spool segment %s: %w", filepath.Base(seg.path), err)
+			}
+			if entry == nil {
+				continue
+			}
+			s.track(*entry, seg)
+			if rec.Seq >= s.nextSeq {
+				s.nextSeq = rec.Seq + 1
+			}
+		case "ack":
+			s.untrack(rec.Seq)
+		}
+	}
+	return scanner.Err()
+}
+
+func (s *Spool) track(entry Entry, seg *segment) {
+	s.pending[entry.Seq] = &pendingEntry{entry: entry, segment: seg}
+	s.perCompany[entry.Company.ID]++
+	seg.live++
+}
+
+func (s *Spool) untrack(seq uint64) (*pendingEntry, bool) {
+	p, ok := s.pending[seq]
+	if !ok {
+		return nil, false
+	}
+	delete(s.pending, seq)
+	if s.perCompany[p.entry.Company.ID]--; s.perCompany[p.entry.Company.ID] <= 0 {
+		delete(s.perCompany, p.entry.Company.ID)
+	}
+	p.segment.live--
+	return p, true
+}
+
+// rotate fecha o segmento ativo e cria um novo; deve ser chamado com o lock (ou na abertura)
+func (s *Spool) rotate() error {
+	if s.active != nil {
+		if err := s.active.Close(); err != nil {
+			return err
+		}
+		s.active = nil
+	}
+
+	seg := &segment{id: s.nextSegment, path: filepath.Join(s.dir, fmt.Sprintf("%016d%s", s.nextSegment, segmentExt))}
+	file, err := os.OpenFile(seg.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to create spool segment: %w", err)
+	}
+	s.nextSegment++
+	s.segments = append(s.segments, seg)
+	s.active, s.activeSize = file, 0
+	s.compact()
+	return nil
+}
+
+// compact apaga os segmentos mais antigos sem eventos pendentes. Só o prefixo é removido: um
+// segmento pode conter acks de eventos gravados em segmentos anteriores, e apagá-lo antes deles
+// faria esses eventos reaparecerem na próxima abertura.
+func (s *Spool) compact() {
+	for len(s.segments) > 0 && s.segments[0].live == 0 {
+		if s.active != nil && len(s.segments) == 1 {
+			return
+		}
+		os.Remove(s.segments[0].path)
+		s.segments = s.segments[1:]
+	}
+}
+
+// write grava um registro no segmento ativo e sincroniza o arquivo
+func (s *Spool) write(rec record) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if s.activeSize > 0 && s.activeSize+int64(len(line)) > s.maxSegment {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := s.active.Write(line)
+	s.activeSize += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write spool segment: %w", err)
+	}
+	return s.active.Sync()
+}
+
+// Append grava um evento que esgotou as tentativas de envio; previous é o estado anterior da
+// empresa em atualizações
+func (s *Spool) Append(eventType messaging.EventType, previous, company *domain.Company, cause error) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.active == nil {
+		return Entry{}, os.ErrClosed
+	}
+
+	entry := Entry{Seq: s.nextSeq, Type: eventType, Company: *company, Previous: previous, FailedAt: time.Now().UTC()}
+	if cause != nil {
+		entry.Error = cause.Error()
+	}
+	rec, err := s.seal(entry)
+	if err != nil {
+		return Entry{}, err
+	}
+	if err := s.write(rec); err != nil {
+		return Entry{}, err
+	}
+	s.nextSeq++
+	s.track(entry, s.segments[len(s.segments)-1])
+	return entry, nil
+}
+
+// seal monta o registro de inclusão do evento, cifrado quando há keyring
+func (s *Spool) seal(entry Entry) (record, error) {
+	if s.keyring == nil {
+		return record{Op: "add", Seq: entry.Seq, Entry: &entry}, nil
+	}
+	plaintext, err := json.Marshal(entry)
+	if err != nil {
+		return record{}, err
+	}
+	keyID, data, err := s.keyring.Encrypt(plaintext, sealContext)
+	if err != nil {
+		return record{}, fmt.Errorf("failed to encrypt spool entry: %w", err)
+	}
+	return record{Op: "add", Seq: entry.Seq, Sealed: &sealedEntry{KeyID: keyID, Data: data}}, nil
+}
+
+// open extrai o evento de um registro de inclusão, decifrando-o se necessário
+func (s *Spool) open(rec record) (*Entry, error) {
+	if rec.Sealed == nil {
+		return rec.Entry, nil
+	}
+	if s.keyring == nil {
+		return nil, errors.New("spool entry is encrypted but no key file is configured")
+	}
+	plaintext, err := s.keyring.Decrypt(rec.Sealed.KeyID, rec.Sealed.Data, sealContext)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt spool entry %d: %w", rec.Seq, err)
+	}
+	var entry Entry
+	if err := json.Unmarshal(plaintext, &entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// Ack remove um evento entregue (ou descartado) do spool
+func (s *Spool) Ack(seq uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.active == nil {
+		return os.ErrClosed
+	}
+	if _, ok := s.pending[seq]; !ok {
+		return ErrNotFound
+	}
+	if err := s.write(record{Op: "ack", Seq: seq}); err != nil {
+		return err
+	}
+	s.untrack(seq)
+	s.compact()
+	return nil
+}
+
+// Pending retorna os eventos pendentes na ordem em que foram gravados
+func (s *Spool) Pending() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]Entry, 0, len(s.pending))
+	for _, p := range s.pending {
+		entries = append(entries, p.entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Seq < entries[j].Seq })
+	return entries
+}
+
+// Len retorna a quantidade de eventos pendentes
+func (s *Spool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.pending)
+}
+
+// HasPending informa se há eventos pendentes da empresa. Novos eventos dela devem ir para o
+// spool, atrás dos pendentes, para preservar a ordem por empresa.
+func (s *Spool) HasPending(companyID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.perCompany[companyID] > 0
+}
+
+// Purge descarta todos os eventos pendentes, apagando os segmentos
+func (s *Spool) Purge() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.active == nil {
+		return 0, os.ErrClosed
+	}
+	purged := len(s.pending)
+	if err := s.active.Close(); err != nil {
+		return 0, err
+	}
+	s.active = nil
+
+	var errs []error
+	for _, seg := range s.segments {
+		if err := os.Remove(seg.path); err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+	}
+	s.segments = nil
+	s.pending = map[uint64]*pendingEntry{}
+	s.perCompany = map[string]int{}
+
+	if err := s.rotate(); err != nil {
+		errs = append(errs, err)
+	}
+	return purged, errors.Join(errs...)
+}
+
+// Close fecha o segmento ativo; os eventos pendentes são mantidos em disco
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.active == nil {
+		return nil
+	}
+	err := s.active.Close()
+	s.active = nil
+	return err
+}
+
+// segmentFiles lista os arquivos de segmento no diretório (usado nos testes)
+func (s *Spool) segmentFiles() []string {
+	paths, _ := filepath.Glob(filepath.Join(s.dir, "*"+segmentExt))
+	names := make([]string, len(paths))
+	for i, path := range paths {
+		names[i] = strings.TrimPrefix(path, s.dir+string(filepath.Separator))
+	}
+	sort.Strings(names)
+	return names
+}
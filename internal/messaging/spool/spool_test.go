@@ -0,0 +1,291 @@
+package spool
+
+import (
+	"company-service/internal/domain"
+	"company-service/internal/encryption"
+	"company-service/internal/messaging"
+	"context"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func company(id string) *domain.Company {
+	return &domain.Company{ID: id, TenantID: "acme", CNPJ: "11444777000161", FantasyName: "Empresa " + id}
+}
+
+func openSpool(t *testing.T, dir string, maxSegment int64) *Spool {
+	t.Helper()
+	s, err := Open(dir, maxSegment)
+	require.NoError(t, err)
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestSpool_AppendAndReopen_ReplaysPendingEntries(t *testing.T) {
+	dir := t.TempDir()
+	s := openSpool(t, dir, 0)
+
+	first, err := s.Append(messaging.CompanyCreated, nil, company("a"), errors.New("broker down"))
+	require.NoError(t, err)
+	_, err = s.Append(messaging.CompanyUpdated, company("old"), company("a"), nil)
+	require.NoError(t, err)
+	_, err = s.Append(messaging.CompanyCreated, nil, company("b"), nil)
+	require.NoError(t, err)
+	require.NoError(t, s.Ack(first.Seq))
+	require.NoError(t, s.Close())
+
+	reopened := openSpool(t, dir, 0)
+	pending := reopened.Pending()
+	require.Len(t, pending, 2)
+	assert.Equal(t, uint64(2), pending[0].Seq)
+	assert.Equal(t, messaging.CompanyUpdated, pending[0].Type)
+	assert.Equal(t, "Empresa a", pending[0].Company.FantasyName)
+	require.NotNil(t, pending[0].Previous)
+	assert.Equal(t, "Empresa old", pending[0].Previous.FantasyName)
+	assert.Equal(t, uint64(3), pending[1].Seq)
+	assert.True(t, reopened.HasPending("a"))
+	assert.False(t, reopened.HasPending("c"))
+
+	next, err := reopened.Append(messaging.CompanyDeleted, nil, company("c"), nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), next.Seq, "a sequência continua após a reabertura")
+}
+
+func TestSpool_IgnoresTruncatedTrailingLine(t *testing.T) {
+	dir := t.TempDir()
+	s := openSpool(t, dir, 0)
+	_, err := s.Append(messaging.CompanyCreated, nil, company("a"), nil)
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	// Simula uma gravação interrompida no meio da linha
+	files := s.segmentFiles()
+	require.Len(t, files, 1)
+	f, err := os.OpenFile(filepath.Join(dir, files[0]), os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"op":"add","seq":2,"entry":{"se`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	reopened := openSpool(t, dir, 0)
+	assert.Equal(t, 1, reopened.Len())
+}
+
+func TestSpool_RotatesAndRemovesFullyAckedSegments(t *testing.T) {
+	dir := t.TempDir()
+	s := openSpool(t, dir, 256)
+
+	var seqs []uint64
+	for i := 0; i < 6; i++ {
+		entry, err := s.Append(messaging.CompanyCreated, nil, company("a"), nil)
+		require.NoError(t, err)
+		seqs = append(seqs, entry.Seq)
+	}
+	assert.Greater(t, len(s.segmentFiles()), 1, "segmento ativo rotacionado ao atingir o limite")
+
+	for _, seq := range seqs {
+		require.NoError(t, s.Ack(seq))
+	}
+	assert.Len(t, s.segmentFiles(), 1, "só o segmento ativo permanece")
+	require.NoError(t, s.Close())
+
+	assert.Equal(t, 0, openSpool(t, dir, 256).Len())
+}
+
+func TestSpool_AckedEntriesStayAckedAcrossSegments(t *testing.T) {
+	dir := t.TempDir()
+	s := openSpool(t, dir, 256)
+
+	var seqs []uint64
+	for i := 0; i < 6; i++ {
+		entry, err := s.Append(messaging.CompanyCreated, nil, company("a"), nil)
+		require.NoError(t, err)
+		seqs = append(seqs, entry.Seq)
+	}
+	// Remove só os eventos mais recentes; os acks ficam em segmentos posteriores aos eventos
+	for _, seq := range seqs[1:] {
+		require.NoError(t, s.Ack(seq))
+	}
+	require.NoError(t, s.Close())
+
+	pending := openSpool(t, dir, 256).Pending()
+	require.Len(t, pending, 1)
+	assert.Equal(t, seqs[0], pending[0].Seq)
+}
+
+func TestSpool_PurgeAndAckUnknown(t *testing.T) {
+	dir := t.TempDir()
+	s := openSpool(t, dir, 0)
+	for i := 0; i < 3; i++ {
+		_, err := s.Append(messaging.CompanyCreated, nil, company("a"), nil)
+		require.NoError(t, err)
+	}
+
+	purged, err := s.Purge()
+	require.NoError(t, err)
+	assert.Equal(t, 3, purged)
+	assert.Equal(t, 0, s.Len())
+	assert.False(t, s.HasPending("a"))
+	assert.ErrorIs(t, s.Ack(1), ErrNotFound)
+
+	_, err = s.Append(messaging.CompanyCreated, nil, company("b"), nil)
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+	assert.Equal(t, 1, openSpool(t, dir, 0).Len())
+}
+
+// flakyProducer falha os envios das empresas em failing e registra os enviados
+type flakyProducer struct {
+	mu      sync.Mutex
+	failing map[string]bool
+	healthy bool
+	sent    []string
+}
+
+func (p *flakyProducer) send(company *domain.Company, op string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failing[company.ID] {
+		return errors.New("publish failed")
+	}
+	p.sent = append(p.sent, company.ID+":"+op)
+	return nil
+}
+
+func (p *flakyProducer) SendCompanyCreated(ctx context.Context, c *domain.Company) error {
+	return p.send(c, "created")
+}
+func (p *flakyProducer) SendCompanyUpdated(ctx context.Context, previous, c *domain.Company) error {
+	return p.send(c, "updated")
+}
+func (p *flakyProducer) SendCompanyDeleted(ctx context.Context, c *domain.Company) error {
+	return p.send(c, "deleted")
+}
+func (p *flakyProducer) Close() error  { return nil }
+func (p *flakyProducer) Healthy() bool { return p.healthy }
+
+func TestRedeliverer_PreservesPerCompanyOrder(t *testing.T) {
+	s := openSpool(t, t.TempDir(), 0)
+	for _, step := range []struct {
+		id  string
+		typ messaging.EventType
+	}{
+		{"a", messaging.CompanyCreated},
+		{"b", messaging.CompanyCreated},
+		{"a", messaging.CompanyUpdated},
+		{"b", messaging.CompanyDeleted},
+	} {
+		_, err := s.Append(step.typ, nil, company(step.id), nil)
+		require.NoError(t, err)
+	}
+
+	producer := &flakyProducer{healthy: true, failing: map[string]bool{"a": true}}
+	r := NewRedeliverer(s, producer, zap.NewNop(), 0)
+
+	assert.Equal(t, 2, r.Redeliver(context.Background()))
+	assert.Equal(t, []string{"b:created", "b:deleted"}, producer.sent)
+	assert.Equal(t, 2, s.Len(), "eventos de a continuam no spool, na ordem original")
+
+	producer.failing = nil
+	assert.Equal(t, 2, r.Redeliver(context.Background()))
+	assert.Equal(t, []string{"b:created", "b:deleted", "a:created", "a:updated"}, producer.sent)
+	assert.Equal(t, 0, s.Len())
+}
+
+func TestRedeliverer_WaitsForHealthyBroker(t *testing.T) {
+	s := openSpool(t, t.TempDir(), 0)
+	_, err := s.Append(messaging.CompanyCreated, nil, company("a"), nil)
+	require.NoError(t, err)
+
+	producer := &flakyProducer{}
+	r := NewRedeliverer(s, producer, zap.NewNop(), 0)
+
+	assert.Equal(t, 0, r.Redeliver(context.Background()))
+	assert.Empty(t, producer.sent)
+	assert.Equal(t, 1, s.Len())
+}
+
+func testKeyring(t *testing.T) *encryption.Keyring {
+	t.Helper()
+	key := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("a", 32)))
+	keyring, err := encryption.ParseKeyring([]byte(`{"active": "k1", "keys": {"k1": "` + key + `"}, "index_key": "` + key + `"}`))
+	require.NoError(t, err)
+	return keyring
+}
+
+func TestSpool_Open_RejectsRelativeDir(t *testing.T) {
+	_, err := Open("data/spool", 0)
+
+	assert.Error(t, err)
+}
+
+func TestSpool_FilesAreOwnerOnly(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "spool")
+	s := openSpool(t, dir, 0)
+	_, err := s.Append(messaging.CompanyCreated, nil, company("a"), nil)
+	require.NoError(t, err)
+
+	info, err := os.Stat(dir)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o700), info.Mode().Perm())
+
+	segments, err := filepath.Glob(filepath.Join(dir, "*.seg"))
+	require.NoError(t, err)
+	require.NotEmpty(t, segments)
+	for _, segment := range segments {
+		info, err := os.Stat(segment)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	}
+}
+
+func TestSpool_WithKeyring_EncryptsEntries(t *testing.T) {
+	dir := t.TempDir()
+	keyring := testKeyring(t)
+	s, err := Open(dir, 0, WithKeyring(keyring))
+	require.NoError(t, err)
+	_, err = s.Append(messaging.CompanyCreated, nil, company("a"), nil)
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	segments, err := filepath.Glob(filepath.Join(dir, "*.seg"))
+	require.NoError(t, err)
+	for _, segment := range segments {
+		data, err := os.ReadFile(segment)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "Empresa a")
+		assert.NotContains(t, string(data), "11444777000161")
+	}
+
+	reopened, err := Open(dir, 0, WithKeyring(keyring))
+	require.NoError(t, err)
+	defer reopened.Close()
+	pending := reopened.Pending()
+	require.Len(t, pending, 1)
+	assert.Equal(t, "Empresa a", pending[0].Company.FantasyName)
+
+	_, err = Open(dir, 0)
+	assert.Error(t, err, "eventos cifrados exigem o keyring")
+}
+
+func TestSpool_WithKeyring_ReadsPlaintextEntries(t *testing.T) {
+	dir := t.TempDir()
+	s := openSpool(t, dir, 0)
+	_, err := s.Append(messaging.CompanyCreated, nil, company("a"), nil)
+	require.NoError(t, err)
+	require.NoError(t, s.Close())
+
+	reopened, err := Open(dir, 0, WithKeyring(testKeyring(t)))
+	require.NoError(t, err)
+	defer reopened.Close()
+	assert.Len(t, reopened.Pending(), 1)
+}
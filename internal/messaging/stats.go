@@ -0,0 +1,93 @@
+package messaging
+
+import (
+	"sync"
+	"time"
+)
+
+// PublisherStats são as estatísticas acumuladas de publicação de um MessageProducer
+type PublisherStats struct {
+	Published      uint64       `json:"published"` // publicações enviadas ao broker
+	Confirmed      uint64       `json:"confirmed"` // confirmadas pelo broker (ack)
+	Nacked         uint64       `json:"nacked"`    // rejeitadas pelo broker (nack)
+	Returned       uint64       `json:"returned"`  // devolvidas por falta de rota
+	TimedOut       uint64       `json:"timed_out"` // sem confirmação dentro do prazo
+	Failed         uint64       `json:"failed"`    // falhas ao publicar
+	ConfirmLatency LatencyStats `json:"confirm_latency"`
+}
+
+// LatencyStats resume a latência entre a publicação e a confirmação do broker
+type LatencyStats struct {
+	LastMs float64 `json:"last_ms"`
+	AvgMs  float64 `json:"avg_ms"`
+	MaxMs  float64 `json:"max_ms"`
+}
+
+// StatsReporter é implementado pelos produtores que expõem estatísticas de publicação
+type StatsReporter interface {
+	Stats() PublisherStats
+}
+
+// PublisherMetrics acumula as estatísticas de publicação; seguro para uso concorrente
+type PublisherMetrics struct {
+	mu      sync.Mutex
+	stats   PublisherStats
+	total   time.Duration
+	samples uint64
+}
+
+// Published registra uma publicação enviada ao broker
+func (m *PublisherMetrics) Published() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats.Published++
+}
+
+// Confirmed registra a confirmação (ack ou nack) de uma publicação e sua latência
+func (m *PublisherMetrics) Confirmed(acked bool, latency time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if acked {
+		m.stats.Confirmed++
+	} else {
+		m.stats.Nacked++
+	}
+
+	m.samples++
+	m.total += latency
+	ms := float64(latency) / float64(time.Millisecond)
+	m.stats.ConfirmLatency.LastMs = ms
+	if ms > m.stats.ConfirmLatency.MaxMs {
+		m.stats.ConfirmLatency.MaxMs = ms
+	}
+	m.stats.ConfirmLatency.AvgMs = float64(m.total) / float64(m.samples) / float64(time.Millisecond)
+}
+
+// Returned registra uma publicação devolvida por falta de rota
+func (m *PublisherMetrics) Returned() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats.Returned++
+}
+
+// TimedOut registra uma publicação sem confirmação dentro do prazo
+func (m *PublisherMetrics) TimedOut() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats.TimedOut++
+}
+
+// Failed registra uma falha ao publicar
+func (m *PublisherMetrics) Failed() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stats.Failed++
+}
+
+// Stats retorna uma cópia das estatísticas acumuladas
+func (m *PublisherMetrics) Stats() PublisherStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stats
+}
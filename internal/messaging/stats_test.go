@@ -0,0 +1,38 @@
+package messaging
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublisherMetrics_ConfirmLatency(t *testing.T) {
+	var m PublisherMetrics
+
+	m.Published()
+	m.Confirmed(true, 10*time.Millisecond)
+	m.Published()
+	m.Confirmed(false, 30*time.Millisecond)
+	m.Published()
+	m.TimedOut()
+	m.Returned()
+	m.Failed()
+
+	stats := m.Stats()
+	assert.Equal(t, uint64(3), stats.Published)
+	assert.Equal(t, uint64(1), stats.Confirmed)
+	assert.Equal(t, uint64(1), stats.Nacked)
+	assert.Equal(t, uint64(1), stats.TimedOut)
+	assert.Equal(t, uint64(1), stats.Returned)
+	assert.Equal(t, uint64(1), stats.Failed)
+	assert.Equal(t, 30.0, stats.ConfirmLatency.LastMs)
+	assert.Equal(t, 20.0, stats.ConfirmLatency.AvgMs)
+	assert.Equal(t, 30.0, stats.ConfirmLatency.MaxMs)
+}
+
+func TestPublisherMetrics_Empty(t *testing.T) {
+	var m PublisherMetrics
+
+	assert.Equal(t, PublisherStats{}, m.Stats())
+}
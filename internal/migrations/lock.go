@@ -0,0 +1,58 @@
+package migrations
+
+import (
+	"company-service/internal/lease"
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	lockCollection = "schema_migrations_lock"
+	lockID         = "migrations"
+)
+
+var (
+	// ErrLocked indica que outra instância está executando migrações
+	ErrLocked = errors.New("migrations are locked by another instance")
+	// ErrLockLost indica que o lock expirou ou foi obtido por outra instância durante a execução
+	ErrLockLost = errors.New("migration lock lost")
+)
+
+// locker é o lock distribuído que impede a execução simultânea de migrações
+type locker interface {
+	Acquire(ctx context.Context) error
+	Renew(ctx context.Context) error
+	Release(ctx context.Context) error
+}
+
+// lock é o lock das migrações, uma lease única em schema_migrations_lock. Se a instância que
+// detém o lock morrer, ele expira após ttl; enquanto as migrações rodam, a validade é estendida
+// por Renew.
+type lock struct {
+	*lease.Lease
+}
+
+func newLock(db *mongo.Database, ttl time.Duration) *lock {
+	return &lock{Lease: lease.New(db, lockCollection, lockID, ttl)}
+}
+
+// Acquire obtém o lock se estiver livre ou expirado
+func (l *lock) Acquire(ctx context.Context) error {
+	err := l.Lease.Acquire(ctx)
+	if errors.Is(err, lease.ErrHeld) {
+		return ErrLocked
+	}
+	return err
+}
+
+// Renew estende a validade do lock, desde que ainda pertença a esta instância
+func (l *lock) Renew(ctx context.Context) error {
+	err := l.Lease.Renew(ctx)
+	if errors.Is(err, lease.ErrLost) {
+		return ErrLockLost
+	}
+	return err
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestLock_AcquireAndRenew(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("lock held by another instance", func(mt *mtest.T) {
+		l := newLock(mt.DB, time.Minute)
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{Code: 11000, Message: "duplicate key"}))
+
+		assert.ErrorIs(t, l.Acquire(context.Background()), ErrLocked)
+	})
+
+	mt.Run("renew extends the lock", func(mt *mtest.T) {
+		l := newLock(mt.DB, time.Minute)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}))
+
+		assert.NoError(t, l.Renew(context.Background()))
+
+		update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(t, l.Owner(), update.Lookup("q", "owner").StringValue())
+	})
+
+	mt.Run("renew after the lock was taken over", func(mt *mtest.T) {
+		l := newLock(mt.DB, time.Minute)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}, bson.E{Key: "nModified", Value: 0}))
+
+		assert.ErrorIs(t, l.Renew(context.Background()), ErrLockLost)
+	})
+}
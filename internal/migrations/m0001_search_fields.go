@@ -0,0 +1,76 @@
+package migrations
+
+import (
+	"company-service/internal/encryption"
+	"company-service/internal/repository/mongorepo"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// backfillSearchFields preenche os campos normalizados de busca (search_name e search_address)
+// em documentos criados antes da busca textual. Os documentos são lidos e os campos gravados pelo
+// codec do repositório: com keyring, os campos cifrados são decifrados e o endereço é gravado
+// apenas como tokens de busca, nunca em claro.
+func backfillSearchFields(collection string, keyring *encryption.Keyring) Migration {
+	decode := mongorepo.NewDocumentDecoder(keyring)
+	searchFields := mongorepo.NewSearchFieldsEncoder(keyring)
+	missing := bson.M{"search_name": bson.M{"$exists": false}}
+	present := bson.M{"search_name": bson.M{"$exists": true}}
+
+	return Migration{
+		Version: 1,
+		Name:    "backfill_search_fields",
+		Up: Step{
+			Affected: func(ctx context.Context, db *mongo.Database) (int64, error) {
+				return db.Collection(collection).CountDocuments(ctx, missing)
+			},
+			Apply: func(ctx context.Context, db *mongo.Database) (int64, error) {
+				coll := db.Collection(collection)
+				cursor, err := coll.Find(ctx, missing)
+				if err != nil {
+					return 0, err
+				}
+				defer cursor.Close(ctx)
+
+				var updated int64
+				for cursor.Next(ctx) {
+					company, err := decode(cursor.Current)
+					if err != nil {
+						return updated, err
+					}
+					objectID, err := primitive.ObjectIDFromHex(company.ID)
+					if err != nil {
+						return updated, err
+					}
+					fields, err := searchFields(company)
+					if err != nil {
+						return updated, err
+					}
+
+					_, err = coll.UpdateOne(ctx, bson.M{"_id": objectID}, bson.M{"$set": fields})
+					if err != nil {
+						return updated, err
+					}
+					updated++
+				}
+				return updated, cursor.Err()
+			},
+		},
+		Down: &Step{
+			Affected: func(ctx context.Context, db *mongo.Database) (int64, error) {
+				return db.Collection(collection).CountDocuments(ctx, present)
+			},
+			Apply: func(ctx context.Context, db *mongo.Database) (int64, error) {
+				result, err := db.Collection(collection).UpdateMany(ctx, present,
+					bson.M{"$unset": bson.M{"search_name": "", "search_address": ""}})
+				if err != nil {
+					return 0, err
+				}
+				return result.ModifiedCount, nil
+			},
+		},
+	}
+}
@@ -0,0 +1,86 @@
+package migrations
+
+import (
+	"company-service/internal/encryption"
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func testKeyring(t *testing.T) *encryption.Keyring {
+	t.Helper()
+	key := func(c string) string { return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(c, 32))) }
+	keyring, err := encryption.ParseKeyring([]byte(`{"active": "k1", "keys": {"k1": "` + key("a") + `"}, "index_key": "` + key("z") + `"}`))
+	require.NoError(t, err)
+	return keyring
+}
+
+// sealed cifra o valor como o repositório grava os campos sensíveis ({k, c})
+func sealed(t *testing.T, keyring *encryption.Keyring, field string, value interface{}) bson.M {
+	t.Helper()
+	plaintext, err := bson.Marshal(bson.D{{Key: "v", Value: value}})
+	require.NoError(t, err)
+	keyID, data, err := keyring.Encrypt(plaintext, []byte(field))
+	require.NoError(t, err)
+	return bson.M{"k": keyID, "c": data}
+}
+
+func TestBackfillSearchFields_WithEncryption(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("decrypts and writes address tokens", func(mt *mtest.T) {
+		keyring := testKeyring(t)
+		id := primitive.NewObjectID()
+		doc := bson.D{
+			{Key: "_id", Value: id},
+			{Key: "tenant_id", Value: "acme"},
+			{Key: "cnpj", Value: sealed(t, keyring, "cnpj", "11444777000161")},
+			{Key: "fantasy_name", Value: "Padaria Central"},
+			{Key: "corporate_name", Value: "Central Alimentos LTDA"},
+			{Key: "address", Value: sealed(t, keyring, "address", "Rua das Flores, 100")},
+			{Key: "employee_count", Value: sealed(t, keyring, "employee_count", int32(10))},
+			{Key: "required_min_pwd_employee_count", Value: sealed(t, keyring, "required_min_pwd_employee_count", int32(1))},
+		}
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, "db.companies", mtest.FirstBatch, doc),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}, bson.E{Key: "nModified", Value: 1}),
+		)
+
+		updated, err := backfillSearchFields("companies", keyring).Up.Apply(context.Background(), mt.DB)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), updated)
+
+		mt.GetStartedEvent() // find
+		update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(t, id, update.Lookup("q", "_id").ObjectID())
+		set := update.Lookup("u", "$set").Document()
+		assert.Equal(t, "padaria central central alimentos ltda", set.Lookup("search_name").StringValue())
+
+		address := set.Lookup("search_address").StringValue()
+		assert.NotContains(t, address, "flores", "o endereço não é gravado em claro")
+		tokens := strings.Fields(address)
+		require.Len(t, tokens, 4)
+		for _, token := range tokens {
+			assert.Len(t, token, 16)
+		}
+	})
+
+	mt.Run("fails on sealed fields without the keyring", func(mt *mtest.T) {
+		keyring := testKeyring(t)
+		doc := bson.D{
+			{Key: "_id", Value: primitive.NewObjectID()},
+			{Key: "address", Value: sealed(t, keyring, "address", "Rua das Flores, 100")},
+		}
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, "db.companies", mtest.FirstBatch, doc))
+
+		_, err := backfillSearchFields("companies", nil).Up.Apply(context.Background(), mt.DB)
+		assert.Error(t, err)
+	})
+}
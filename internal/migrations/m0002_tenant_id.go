@@ -0,0 +1,53 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrTenantRequired indica documentos sem tenant a migrar sem DEFAULT_TENANT configurado
+var ErrTenantRequired = errors.New("DEFAULT_TENANT is required to backfill documents without tenant")
+
+// backfillTenantID atribui DEFAULT_TENANT, o mesmo tenant usado pela API em requisições sem
+// tenant, aos documentos criados antes da multi-tenancy. Sem DEFAULT_TENANT a migração só é
+// aplicada se não houver documentos a migrar, já que eles ficariam em um tenant que a API não
+// atende. Não há Down: após a migração não é possível distinguir documentos legados de
+// documentos criados no tenant padrão.
+func backfillTenantID(collection, defaultTenant string) Migration {
+	missing := bson.M{"$or": bson.A{
+		bson.M{"tenant_id": bson.M{"$exists": false}},
+		bson.M{"tenant_id": ""},
+	}}
+
+	return Migration{
+		Version: 2,
+		Name:    "backfill_tenant_id",
+		Up: Step{
+			Affected: func(ctx context.Context, db *mongo.Database) (int64, error) {
+				return db.Collection(collection).CountDocuments(ctx, missing)
+			},
+			Apply: func(ctx context.Context, db *mongo.Database) (int64, error) {
+				if defaultTenant == "" {
+					count, err := db.Collection(collection).CountDocuments(ctx, missing)
+					if err != nil {
+						return 0, err
+					}
+					if count > 0 {
+						return 0, fmt.Errorf("%w: %d documents", ErrTenantRequired, count)
+					}
+					return 0, nil
+				}
+				result, err := db.Collection(collection).UpdateMany(ctx, missing,
+					bson.M{"$set": bson.M{"tenant_id": defaultTenant}})
+				if err != nil {
+					return 0, err
+				}
+				return result.ModifiedCount, nil
+			},
+		},
+	}
+}
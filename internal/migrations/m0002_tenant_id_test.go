@@ -0,0 +1,51 @@
+package migrations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+// countReply é a resposta do CountDocuments (aggregate com $group) com o total informado
+func countReply(mt *mtest.T, n int64) bson.D {
+	return mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, bson.D{{Key: "n", Value: n}})
+}
+
+func TestBackfillTenantID(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("assigns the configured tenant", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateSuccessResponse(
+			bson.E{Key: "n", Value: 2}, bson.E{Key: "nModified", Value: 2}))
+
+		affected, err := backfillTenantID("companies", "acme").Up.Apply(context.Background(), mt.DB)
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), affected)
+		update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(0).Value().Document()
+		assert.Equal(t, "acme", update.Lookup("u", "$set", "tenant_id").StringValue())
+	})
+
+	mt.Run("refuses legacy documents without DEFAULT_TENANT", func(mt *mtest.T) {
+		mt.AddMockResponses(countReply(mt, 3))
+
+		_, err := backfillTenantID("companies", "").Up.Apply(context.Background(), mt.DB)
+
+		assert.ErrorIs(t, err, ErrTenantRequired)
+		assert.Equal(t, "aggregate", mt.GetStartedEvent().CommandName)
+		assert.Nil(t, mt.GetStartedEvent(), "nenhum documento deve ser alterado")
+	})
+
+	mt.Run("applies without DEFAULT_TENANT when nothing is missing", func(mt *mtest.T) {
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch))
+
+		affected, err := backfillTenantID("companies", "").Up.Apply(context.Background(), mt.DB)
+
+		require.NoError(t, err)
+		assert.Zero(t, affected)
+	})
+}
@@ -0,0 +1,33 @@
+package migrations
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// backfillSequence inicia em 1 a sequência dos documentos criados antes dela, de modo que a
+// primeira escrita após a migração publique o evento com a sequência 2. Sem Down: a sequência
+// é ignorada por versões anteriores do serviço.
+func backfillSequence(collection string) Migration {
+	missing := bson.M{"sequence": bson.M{"$exists": false}}
+
+	return Migration{
+		Version: 3,
+		Name:    "backfill_sequence",
+		Up: Step{
+			Affected: func(ctx context.Context, db *mongo.Database) (int64, error) {
+				return db.Collection(collection).CountDocuments(ctx, missing)
+			},
+			Apply: func(ctx context.Context, db *mongo.Database) (int64, error) {
+				result, err := db.Collection(collection).UpdateMany(ctx, missing,
+					bson.M{"$set": bson.M{"sequence": int64(1)}})
+				if err != nil {
+					return 0, err
+				}
+				return result.ModifiedCount, nil
+			},
+		},
+	}
+}
@@ -0,0 +1,318 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// Nome da coleção que registra as migrações aplicadas
+const migrationsCollection = "schema_migrations"
+
+var (
+	ErrDuplicateVersion = errors.New("duplicate migration version")
+	ErrIrreversible     = errors.New("migration has no down step")
+)
+
+// Step é um passo (up ou down) de uma migração
+type Step struct {
+	// Affected conta os documentos que o passo alteraria; usado no modo dry-run
+	Affected func(ctx context.Context, db *mongo.Database) (int64, error)
+	// Apply executa o passo e retorna a quantidade de documentos alterados
+	Apply func(ctx context.Context, db *mongo.Database) (int64, error)
+}
+
+// Migration é uma alteração versionada do schema das coleções
+type Migration struct {
+	Version int
+	Name    string
+	Up      Step
+	Down    *Step // nil para migrações irreversíveis
+}
+
+// Record é o documento salvo em schema_migrations para cada migração aplicada
+type Record struct {
+	Version   int       `bson:"_id" json:"version"`
+	Name      string    `bson:"name" json:"name"`
+	AppliedAt time.Time `bson:"applied_at" json:"applied_at"`
+}
+
+// Result descreve a execução (ou simulação) de um passo
+type Result struct {
+	Version   int
+	Name      string
+	Direction string
+	Affected  int64
+	DryRun    bool
+}
+
+// recordStore guarda o registro das migrações aplicadas
+type recordStore interface {
+	Applied(ctx context.Context) ([]Record, error)
+	Insert(ctx context.Context, record Record) error
+	Delete(ctx context.Context, version int) error
+}
+
+// Migrator aplica e reverte migrações em ordem, registrando o estado em schema_migrations
+type Migrator struct {
+	db            *mongo.Database
+	migrations    []Migration
+	records       recordStore
+	lock          locker
+	renewInterval time.Duration
+	logger        *zap.Logger
+}
+
+// NewMigrator cria um Migrator com as migrações ordenadas por versão
+func NewMigrator(db *mongo.Database, migrations []Migration, logger *zap.Logger) (*Migrator, error) {
+	sorted, err := sortMigrations(migrations)
+	if err != nil {
+		return nil, err
+	}
+	ttl := 5 * time.Minute
+	return &Migrator{
+		db:            db,
+		migrations:    sorted,
+		records:       &mongoRecords{collection: db.Collection(migrationsCollection)},
+		lock:          newLock(db, ttl),
+		renewInterval: ttl / 3,
+		logger:        logger,
+	}, nil
+}
+
+// Applied retorna as migrações já aplicadas, em ordem de versão
+func (m *Migrator) Applied(ctx context.Context) ([]Record, error) {
+	return m.records.Applied(ctx)
+}
+
+// Up aplica as migrações pendentes até a versão target (0 aplica todas).
+// Em dry-run nada é alterado e apenas a quantidade de documentos afetados é reportada.
+func (m *Migrator) Up(ctx context.Context, target int, dryRun bool) ([]Result, error) {
+	ctx, release, err := m.acquire(ctx, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	applied, err := m.appliedSet(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	for _, mig := range m.migrations {
+		if applied[mig.Version] || (target > 0 && mig.Version > target) {
+			continue
+		}
+
+		result, err := m.run(ctx, mig, mig.Up, "up", dryRun)
+		if err != nil {
+			return results, err
+		}
+		if !dryRun {
+			record := Record{Version: mig.Version, Name: mig.Name, AppliedAt: time.Now()}
+			if err := m.records.Insert(ctx, record); err != nil {
+				return results, fmt.Errorf("failed to record migration %d: %w", mig.Version, abortCause(ctx, err))
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Down reverte as últimas steps migrações aplicadas, da mais recente para a mais antiga
+func (m *Migrator) Down(ctx context.Context, steps int, dryRun bool) ([]Result, error) {
+	ctx, release, err := m.acquire(ctx, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	applied, err := m.appliedSet(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Result
+	for i := len(m.migrations) - 1; i >= 0 && len(results) < steps; i-- {
+		mig := m.migrations[i]
+		if !applied[mig.Version] {
+			continue
+		}
+		if mig.Down == nil {
+			return results, fmt.Errorf("%w: %d_%s", ErrIrreversible, mig.Version, mig.Name)
+		}
+
+		result, err := m.run(ctx, mig, *mig.Down, "down", dryRun)
+		if err != nil {
+			return results, err
+		}
+		if !dryRun {
+			if err := m.records.Delete(ctx, mig.Version); err != nil {
+				return results, fmt.Errorf("failed to unrecord migration %d: %w", mig.Version, abortCause(ctx, err))
+			}
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func (m *Migrator) run(ctx context.Context, mig Migration, step Step, direction string, dryRun bool) (Result, error) {
+	result := Result{Version: mig.Version, Name: mig.Name, Direction: direction, DryRun: dryRun}
+
+	fn := step.Apply
+	if dryRun {
+		fn = step.Affected
+	}
+	if fn == nil {
+		return result, nil
+	}
+
+	// Com o lock perdido, nenhum passo é iniciado
+	if err := context.Cause(ctx); err != nil {
+		return result, fmt.Errorf("migration %d_%s (%s) aborted: %w", mig.Version, mig.Name, direction, err)
+	}
+
+	affected, err := fn(ctx, m.db)
+	if err != nil {
+		return result, fmt.Errorf("migration %d_%s (%s) failed: %w", mig.Version, mig.Name, direction, abortCause(ctx, err))
+	}
+	result.Affected = affected
+
+	m.logger.Info("Migration step executed",
+		zap.Int("version", mig.Version),
+		zap.String("name", mig.Name),
+		zap.String("direction", direction),
+		zap.Int64("affected", affected),
+		zap.Bool("dry_run", dryRun))
+
+	return result, nil
+}
+
+// acquire obtém o lock distribuído e o renova em segundo plano até a liberação. O contexto
+// retornado é cancelado se a renovação falhar, interrompendo a migração antes que o lock expire
+// e outra instância o obtenha. Em dry-run nenhuma escrita é feita e o lock é dispensado.
+func (m *Migrator) acquire(ctx context.Context, dryRun bool) (context.Context, func(), error) {
+	if dryRun {
+		return ctx, func() {}, nil
+	}
+	if err := m.lock.Acquire(ctx); err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		m.heartbeat(ctx, cancel)
+	}()
+
+	return ctx, func() {
+		cancel(nil)
+		<-stopped
+		if err := m.lock.Release(context.Background()); err != nil {
+			m.logger.Warn("Failed to release migration lock", zap.Error(err))
+		}
+	}, nil
+}
+
+// heartbeat renova o lock a cada renewInterval até o contexto ser cancelado
+func (m *Migrator) heartbeat(ctx context.Context, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(m.renewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := m.lock.Renew(ctx); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			m.logger.Error("Failed to renew migration lock, aborting migrations", zap.Error(err))
+			if !errors.Is(err, ErrLockLost) {
+				err = fmt.Errorf("%w: %w", ErrLockLost, err)
+			}
+			cancel(err)
+			return
+		}
+	}
+}
+
+// abortCause retorna o motivo do cancelamento do contexto (ex.: lock perdido) no lugar do erro
+// causado por ele
+func abortCause(ctx context.Context, err error) error {
+	if cause := context.Cause(ctx); cause != nil {
+		return cause
+	}
+	return err
+}
+
+func (m *Migrator) appliedSet(ctx context.Context) (map[int]bool, error) {
+	records, err := m.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[int]bool, len(records))
+	for _, r := range records {
+		set[r.Version] = true
+	}
+	return set, nil
+}
+
+// sortMigrations ordena por versão e rejeita versões duplicadas ou não positivas
+func sortMigrations(migrations []Migration) ([]Migration, error) {
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	for i, mig := range sorted {
+		if mig.Version <= 0 {
+			return nil, fmt.Errorf("invalid migration version %d (%s)", mig.Version, mig.Name)
+		}
+		if i > 0 && sorted[i-1].Version == mig.Version {
+			return nil, fmt.Errorf("%w: %d", ErrDuplicateVersion, mig.Version)
+		}
+	}
+	return sorted, nil
+}
+
+// mongoRecords guarda as migrações aplicadas em schema_migrations, com a versão como _id
+type mongoRecords struct {
+	collection *mongo.Collection
+}
+
+func (r *mongoRecords) Applied(ctx context.Context) ([]Record, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load applied migrations: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var records []Record
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, fmt.Errorf("failed to decode applied migrations: %w", err)
+	}
+	return records, nil
+}
+
+func (r *mongoRecords) Insert(ctx context.Context, record Record) error {
+	_, err := r.collection.InsertOne(ctx, record)
+	return err
+}
+
+func (r *mongoRecords) Delete(ctx context.Context, version int) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": version})
+	return err
+}
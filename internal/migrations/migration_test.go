@@ -0,0 +1,271 @@
+package migrations
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.uber.org/zap"
+)
+
+func TestSortMigrations_OrdersByVersion(t *testing.T) {
+	sorted, err := sortMigrations([]Migration{
+		{Version: 3, Name: "c"},
+		{Version: 1, Name: "a"},
+		{Version: 2, Name: "b"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, []string{sorted[0].Name, sorted[1].Name, sorted[2].Name})
+}
+
+func TestSortMigrations_DuplicateVersion_ReturnsError(t *testing.T) {
+	_, err := sortMigrations([]Migration{{Version: 1, Name: "a"}, {Version: 1, Name: "b"}})
+	assert.ErrorIs(t, err, ErrDuplicateVersion)
+}
+
+func TestSortMigrations_NonPositiveVersion_ReturnsError(t *testing.T) {
+	_, err := sortMigrations([]Migration{{Version: 0, Name: "zero"}})
+	assert.Error(t, err)
+}
+
+func TestAll_RegisteredMigrationsAreValid(t *testing.T) {
+	_, err := sortMigrations(All("companies", "", nil))
+	assert.NoError(t, err)
+}
+
+// fakeRecords guarda as migrações aplicadas em memória
+type fakeRecords struct {
+	mu      sync.Mutex
+	applied map[int]Record
+}
+
+func (r *fakeRecords) Applied(ctx context.Context) ([]Record, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var records []Record
+	for _, record := range r.applied {
+		records = append(records, record)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Version < records[j].Version })
+	return records, nil
+}
+
+func (r *fakeRecords) Insert(ctx context.Context, record Record) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.applied[record.Version] = record
+	return nil
+}
+
+func (r *fakeRecords) Delete(ctx context.Context, version int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.applied, version)
+	return nil
+}
+
+func (r *fakeRecords) versions() []int {
+	records, _ := r.Applied(context.Background())
+	versions := []int{}
+	for _, record := range records {
+		versions = append(versions, record.Version)
+	}
+	return versions
+}
+
+// fakeLock simula o lock distribuído; renewErr faz as renovações falharem
+type fakeLock struct {
+	acquireErr error
+	renewErr   error
+	acquired   atomic.Int32
+	renewed    atomic.Int32
+	released   atomic.Int32
+}
+
+func (l *fakeLock) Acquire(ctx context.Context) error {
+	if l.acquireErr != nil {
+		return l.acquireErr
+	}
+	l.acquired.Add(1)
+	return nil
+}
+
+func (l *fakeLock) Renew(ctx context.Context) error {
+	l.renewed.Add(1)
+	return l.renewErr
+}
+
+func (l *fakeLock) Release(ctx context.Context) error {
+	l.released.Add(1)
+	return nil
+}
+
+// recorder registra a ordem em que os passos foram executados
+type recorder struct {
+	mu    sync.Mutex
+	steps []string
+}
+
+func (r *recorder) step(name string, affected int64) Step {
+	return Step{
+		Affected: func(ctx context.Context, db *mongo.Database) (int64, error) {
+			r.add("count " + name)
+			return affected, nil
+		},
+		Apply: func(ctx context.Context, db *mongo.Database) (int64, error) {
+			r.add(name)
+			return affected, nil
+		},
+	}
+}
+
+func (r *recorder) add(step string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.steps = append(r.steps, step)
+}
+
+func newTestMigrator(t *testing.T, migrations []Migration, applied ...int) (*Migrator, *fakeRecords, *fakeLock) {
+	sorted, err := sortMigrations(migrations)
+	require.NoError(t, err)
+
+	records := &fakeRecords{applied: map[int]Record{}}
+	for _, version := range applied {
+		records.applied[version] = Record{Version: version}
+	}
+	lock := &fakeLock{}
+	return &Migrator{
+		migrations:    sorted,
+		records:       records,
+		lock:          lock,
+		renewInterval: time.Minute,
+		logger:        zap.NewNop(),
+	}, records, lock
+}
+
+func reversible(r *recorder, version int, name string) Migration {
+	down := r.step("down "+name, 1)
+	return Migration{Version: version, Name: name, Up: r.step("up "+name, 2), Down: &down}
+}
+
+func TestMigrator_Up_AppliesPendingInVersionOrder(t *testing.T) {
+	r := &recorder{}
+	migrator, records, lock := newTestMigrator(t, []Migration{
+		reversible(r, 3, "c"), reversible(r, 1, "a"), reversible(r, 2, "b"), reversible(r, 4, "d"),
+	}, 1)
+
+	results, err := migrator.Up(context.Background(), 3, false)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"up b", "up c"}, r.steps, "aplicadas são puladas e target limita a versão")
+	assert.Equal(t, []int{1, 2, 3}, records.versions())
+	assert.Equal(t, []Result{
+		{Version: 2, Name: "b", Direction: "up", Affected: 2},
+		{Version: 3, Name: "c", Direction: "up", Affected: 2},
+	}, results)
+	assert.Equal(t, int32(1), lock.acquired.Load())
+	assert.Equal(t, int32(1), lock.released.Load())
+}
+
+func TestMigrator_Down_RevertsMostRecentFirst(t *testing.T) {
+	r := &recorder{}
+	migrator, records, _ := newTestMigrator(t, []Migration{
+		reversible(r, 1, "a"), reversible(r, 2, "b"), reversible(r, 3, "c"),
+	}, 1, 2, 3)
+
+	results, err := migrator.Down(context.Background(), 2, false)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"down c", "down b"}, r.steps)
+	assert.Equal(t, []int{1}, records.versions())
+	assert.Len(t, results, 2)
+}
+
+func TestMigrator_Down_IrreversibleMigration_ReturnsError(t *testing.T) {
+	r := &recorder{}
+	migrator, records, _ := newTestMigrator(t, []Migration{
+		{Version: 1, Name: "a", Up: r.step("up a", 1)},
+		reversible(r, 2, "b"),
+	}, 1, 2)
+
+	results, err := migrator.Down(context.Background(), 2, false)
+
+	assert.ErrorIs(t, err, ErrIrreversible)
+	assert.Len(t, results, 1, "a migração reversível mais recente é desfeita antes do erro")
+	assert.Equal(t, []int{1}, records.versions())
+}
+
+func TestMigrator_DryRun_CountsWithoutApplyingOrLocking(t *testing.T) {
+	r := &recorder{}
+	migrator, records, lock := newTestMigrator(t, []Migration{reversible(r, 1, "a"), reversible(r, 2, "b")}, 1)
+
+	results, err := migrator.Up(context.Background(), 0, true)
+	require.NoError(t, err)
+	assert.Equal(t, []Result{{Version: 2, Name: "b", Direction: "up", Affected: 2, DryRun: true}}, results)
+
+	_, err = migrator.Down(context.Background(), 1, true)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"count up b", "count down a"}, r.steps)
+	assert.Equal(t, []int{1}, records.versions())
+	assert.Zero(t, lock.acquired.Load())
+}
+
+func TestMigrator_Locked_RunsNothing(t *testing.T) {
+	r := &recorder{}
+	migrator, records, lock := newTestMigrator(t, []Migration{reversible(r, 1, "a")})
+	lock.acquireErr = ErrLocked
+
+	_, err := migrator.Up(context.Background(), 0, false)
+
+	assert.ErrorIs(t, err, ErrLocked)
+	assert.Empty(t, r.steps)
+	assert.Empty(t, records.versions())
+	assert.Zero(t, lock.released.Load())
+}
+
+func TestMigrator_LockLost_AbortsRunningMigration(t *testing.T) {
+	migrator, records, lock := newTestMigrator(t, []Migration{
+		{Version: 1, Name: "slow", Up: Step{Apply: func(ctx context.Context, db *mongo.Database) (int64, error) {
+			// Simula uma migração mais longa que o TTL, interrompida pelo cancelamento
+			<-ctx.Done()
+			return 0, ctx.Err()
+		}}},
+		{Version: 2, Name: "next", Up: Step{Apply: func(ctx context.Context, db *mongo.Database) (int64, error) {
+			t.Error("nenhuma migração deve ser iniciada após a perda do lock")
+			return 0, nil
+		}}},
+	})
+	migrator.renewInterval = 10 * time.Millisecond
+	lock.renewErr = ErrLockLost
+
+	_, err := migrator.Up(context.Background(), 0, false)
+
+	assert.ErrorIs(t, err, ErrLockLost)
+	assert.Empty(t, records.versions())
+	assert.Equal(t, int32(1), lock.renewed.Load())
+	assert.Equal(t, int32(1), lock.released.Load())
+}
+
+func TestMigrator_Heartbeat_RenewsWhileRunning(t *testing.T) {
+	migrator, records, lock := newTestMigrator(t, []Migration{
+		{Version: 1, Name: "slow", Up: Step{Apply: func(ctx context.Context, db *mongo.Database) (int64, error) {
+			time.Sleep(50 * time.Millisecond)
+			return 1, nil
+		}}},
+	})
+	migrator.renewInterval = 5 * time.Millisecond
+
+	_, err := migrator.Up(context.Background(), 0, false)
+
+	require.NoError(t, err)
+	assert.Equal(t, []int{1}, records.versions())
+	assert.Greater(t, lock.renewed.Load(), int32(1))
+}
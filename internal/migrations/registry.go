@@ -0,0 +1,15 @@
+package migrations
+
+import "company-service/internal/encryption"
+
+// All retorna as migrações registradas. Novas migrações devem ser adicionadas ao final
+// com a próxima versão disponível; versões já publicadas nunca devem ser alteradas.
+// defaultTenant é o tenant atribuído a documentos anteriores à multi-tenancy; keyring, o mesmo
+// do repositório, ou nil sem criptografia.
+func All(companiesCollection, defaultTenant string, keyring *encryption.Keyring) []Migration {
+	return []Migration{
+		backfillSearchFields(companiesCollection, keyring),
+		backfillTenantID(companiesCollection, defaultTenant),
+		backfillSequence(companiesCollection),
+	}
+}
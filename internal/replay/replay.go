@@ -0,0 +1,346 @@
+package replay
+
+import (
+	"company-service/internal/domain"
+	"company-service/internal/messaging"
+	"company-service/internal/repository"
+	"company-service/internal/tenant"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+const (
+	// pageSize é a quantidade de empresas lidas por consulta ao repositório
+	pageSize = 100
+	// maxFailedIDs limita os IDs com falha guardados no resultado
+	maxFailedIDs = 100
+	// maxConsecutiveFailures interrompe o replay quando o produtor falha seguidamente,
+	// em geral por indisponibilidade do broker
+	maxConsecutiveFailures = 10
+	// maxRuns limita as execuções mantidas para consulta
+	maxRuns = 50
+)
+
+// Erros do replay
+var (
+	ErrInvalidEventType = errors.New("tipo de evento de replay inválido, use created ou updated")
+	ErrAlreadyRunning   = errors.New("já existe um replay em andamento")
+	ErrRunNotFound      = errors.New("replay não encontrado")
+)
+
+// Filter seleciona as empresas cujos eventos são republicados. Critérios vazios não são
+// aplicados; sem nenhum critério, todas as empresas de todos os tenants são selecionadas.
+type Filter struct {
+	IDs          []string   `json:"ids,omitempty"`
+	CNPJs        []string   `json:"cnpjs,omitempty"`
+	UpdatedSince *time.Time `json:"updated_since,omitempty"`
+	TenantID     string     `json:"tenant_id,omitempty"`
+}
+
+// companyFilter converte o filtro na consulta do repositório, em ordem de criação para que a
+// paginação não seja afetada por atualizações feitas durante o replay
+func (f Filter) companyFilter() repository.CompanyFilter {
+	filter := repository.CompanyFilter{
+		UpdatedFrom: f.UpdatedSince,
+		TenantID:    f.TenantID,
+		Sort:        []repository.SortField{{Field: "created_at"}},
+	}
+	if len(f.IDs) > 0 {
+		filter.IDs = f.IDs
+	}
+	if len(f.CNPJs) > 0 {
+		filter.CNPJs = f.CNPJs
+	}
+	return filter
+}
+
+// Options define como os eventos são republicados
+type Options struct {
+	// EventType é o tipo dos eventos gerados: CompanyUpdated (padrão), com o snapshot atual e
+	// sem estado anterior, ou CompanyCreated. Empresas excluídas não estão mais armazenadas e
+	// não podem ser republicadas.
+	EventType messaging.EventType `json:"event_type"`
+	// RateLimit limita os eventos publicados por segundo; 0 não limita
+	RateLimit float64 `json:"rate_limit"`
+	// DryRun apenas conta as empresas selecionadas, sem publicar
+	DryRun bool `json:"dry_run"`
+}
+
+// ParseEventType aceita o tipo completo (br.company.updated.v1) ou a operação (updated);
+// vazio seleciona CompanyUpdated
+func ParseEventType(value string) (messaging.EventType, error) {
+	if value == "" {
+		return messaging.CompanyUpdated, nil
+	}
+	for _, eventType := range []messaging.EventType{messaging.CompanyCreated, messaging.CompanyUpdated} {
+		if value == string(eventType) || value == eventType.Operation() {
+			return eventType, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrInvalidEventType, value)
+}
+
+// Result contabiliza um replay
+type Result struct {
+	Matched   int64    `json:"matched"`
+	Published int64    `json:"published"`
+	Failed    int64    `json:"failed"`
+	FailedIDs []string `json:"failed_ids,omitempty"` // primeiras empresas cuja publicação falhou
+}
+
+// Replayer regenera os eventos a partir do estado armazenado das empresas e os publica pelo
+// produtor com a marcação de replay (messaging.WithReplay)
+type Replayer struct {
+	repo     repository.CompanyRepository
+	producer messaging.MessageProducer
+	logger   *zap.Logger
+
+	mu   sync.Mutex
+	runs []*Run // da mais antiga para a mais recente
+}
+
+func NewReplayer(repo repository.CompanyRepository, producer messaging.MessageProducer, logger *zap.Logger) *Replayer {
+	return &Replayer{repo: repo, producer: producer, logger: logger}
+}
+
+// Count retorna a quantidade de empresas selecionadas pelo filtro
+func (r *Replayer) Count(ctx context.Context, filter Filter) (int64, error) {
+	count, err := r.repo.Count(tenant.WithAllTenants(ctx), filter.companyFilter())
+	if err != nil {
+		return 0, fmt.Errorf("failed to count companies for replay: %w", err)
+	}
+	return count, nil
+}
+
+// Replay republica os eventos das empresas selecionadas e retorna a contagem. Falhas de
+// publicação são contabilizadas sem interromper o replay, exceto quando se repetem
+// seguidamente. Com DryRun apenas conta as empresas.
+func (r *Replayer) Replay(ctx context.Context, filter Filter, opts Options) (Result, error) {
+	return r.replay(ctx, filter, opts, func(Result) {})
+}
+
+func (r *Replayer) replay(ctx context.Context, filter Filter, opts Options, progress func(Result)) (Result, error) {
+	var result Result
+	eventType, err := ParseEventType(string(opts.EventType))
+	if err != nil {
+		return result, err
+	}
+
+	result.Matched, err = r.Count(ctx, filter)
+	if err != nil || opts.DryRun {
+		return result, err
+	}
+	progress(result)
+
+	var throttle <-chan time.Time
+	if opts.RateLimit > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / opts.RateLimit))
+		defer ticker.Stop()
+		throttle = ticker.C
+	}
+
+	listCtx := tenant.WithAllTenants(ctx)
+	publishCtx := messaging.WithReplay(ctx)
+	companyFilter := filter.companyFilter()
+	consecutiveFailures := 0
+
+	for page := 1; ; page++ {
+		companies, err := r.repo.List(listCtx, companyFilter, page, pageSize)
+		if err != nil {
+			return result, fmt.Errorf("failed to load companies for replay: %w", err)
+		}
+
+		for _, company := range companies {
+			if throttle != nil {
+				select {
+				case <-ctx.Done():
+					return result, ctx.Err()
+				case <-throttle:
+				}
+			}
+			if err := ctx.Err(); err != nil {
+				return result, err
+			}
+
+			if err := r.publish(publishCtx, eventType, company); err != nil {
+				result.Failed++
+				if len(result.FailedIDs) < maxFailedIDs {
+					result.FailedIDs = append(result.FailedIDs, company.ID)
+				}
+				r.logger.Warn("Failed to replay company event",
+					zap.String("company_id", company.ID),
+					zap.String("tenant_id", company.TenantID),
+					zap.Error(err))
+
+				consecutiveFailures++
+				if consecutiveFailures >= maxConsecutiveFailures {
+					progress(result)
+					return result, fmt.Errorf("replay aborted after %d consecutive failures: %w", consecutiveFailures, err)
+				}
+			} else {
+				result.Published++
+				consecutiveFailures = 0
+			}
+			progress(result)
+		}
+
+		if len(companies) < pageSize {
+			return result, nil
+		}
+	}
+}
+
+func (r *Replayer) publish(ctx context.Context, eventType messaging.EventType, company *domain.Company) error {
+	if eventType == messaging.CompanyCreated {
+		return r.producer.SendCompanyCreated(ctx, company)
+	}
+	return r.producer.SendCompanyUpdated(ctx, nil, company)
+}
+
+// Status é o estado de uma execução em segundo plano
+type Status string
+
+const (
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+	StatusFailed    Status = "failed"
+	StatusCanceled  Status = "canceled"
+)
+
+// Run é um replay executado em segundo plano
+type Run struct {
+	ID         string     `json:"id"`
+	Status     Status     `json:"status"`
+	Filter     Filter     `json:"filter"`
+	Options    Options    `json:"options"`
+	Result     Result     `json:"result"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+
+	cancel context.CancelFunc
+}
+
+// Start inicia o replay em segundo plano, desvinculado da requisição que o solicitou, e
+// retorna o estado inicial. Apenas um replay é executado por vez.
+func (r *Replayer) Start(filter Filter, opts Options) (Run, error) {
+	eventType, err := ParseEventType(string(opts.EventType))
+	if err != nil {
+		return Run{}, err
+	}
+	opts.EventType = eventType
+	opts.DryRun = false
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, run := range r.runs {
+		if run.Status == StatusRunning {
+			return Run{}, fmt.Errorf("%w: %s", ErrAlreadyRunning, run.ID)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	run := &Run{
+		ID:        primitive.NewObjectID().Hex(),
+		Status:    StatusRunning,
+		Filter:    filter,
+		Options:   opts,
+		StartedAt: time.Now().UTC(),
+		cancel:    cancel,
+	}
+	r.runs = append(r.runs, run)
+	if len(r.runs) > maxRuns {
+		r.runs = r.runs[len(r.runs)-maxRuns:]
+	}
+
+	r.logger.Info("Event replay started",
+		zap.String("replay_id", run.ID),
+		zap.String("event_type", string(opts.EventType)),
+		zap.Float64("rate_limit", opts.RateLimit))
+
+	go r.execute(ctx, run)
+	return run.snapshot(), nil
+}
+
+func (r *Replayer) execute(ctx context.Context, run *Run) {
+	defer run.cancel()
+
+	result, err := r.replay(ctx, run.Filter, run.Options, func(progress Result) {
+		r.mu.Lock()
+		run.Result = progress
+		r.mu.Unlock()
+	})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now().UTC()
+	run.Result = result
+	run.FinishedAt = &now
+	switch {
+	case errors.Is(err, context.Canceled):
+		run.Status = StatusCanceled
+	case err != nil:
+		run.Status = StatusFailed
+		run.Error = err.Error()
+	default:
+		run.Status = StatusCompleted
+	}
+
+	r.logger.Info("Event replay finished",
+		zap.String("replay_id", run.ID),
+		zap.String("status", string(run.Status)),
+		zap.Int64("matched", result.Matched),
+		zap.Int64("published", result.Published),
+		zap.Int64("failed", result.Failed),
+		zap.Error(err))
+}
+
+// Get retorna o estado de uma execução
+func (r *Replayer) Get(id string) (Run, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, run := range r.runs {
+		if run.ID == id {
+			return run.snapshot(), nil
+		}
+	}
+	return Run{}, ErrRunNotFound
+}
+
+// Runs retorna as execuções mais recentes, da mais nova para a mais antiga
+func (r *Replayer) Runs() []Run {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	runs := make([]Run, 0, len(r.runs))
+	for i := len(r.runs) - 1; i >= 0; i-- {
+		runs = append(runs, r.runs[i].snapshot())
+	}
+	return runs
+}
+
+// Cancel interrompe uma execução em andamento; os eventos já publicados não são desfeitos
+func (r *Replayer) Cancel(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, run := range r.runs {
+		if run.ID == id {
+			run.cancel()
+			return nil
+		}
+	}
+	return ErrRunNotFound
+}
+
+// snapshot copia a execução para leitura fora do lock; deve ser chamado com r.mu travado
+func (run *Run) snapshot() Run {
+	copied := *run
+	copied.Result.FailedIDs = append([]string(nil), run.Result.FailedIDs...)
+	copied.cancel = nil
+	return copied
+}
@@ -0,0 +1,285 @@
+package replay
+
+import (
+	"company-service/internal/domain"
+	"company-service/internal/messaging"
+	"company-service/internal/repository"
+	"company-service/internal/tenant"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeRepository aplica em memória os critérios usados pelo replay
+type fakeRepository struct {
+	repository.CompanyRepository
+	companies []*domain.Company
+}
+
+func (f *fakeRepository) matching(ctx context.Context, filter repository.CompanyFilter) ([]*domain.Company, error) {
+	if !tenant.IsAllTenants(ctx) {
+		return nil, tenant.ErrMissingTenant
+	}
+	var matched []*domain.Company
+	for _, company := range f.companies {
+		if filter.IDs != nil && !contains(filter.IDs, company.ID) {
+			continue
+		}
+		if filter.CNPJs != nil && !contains(filter.CNPJs, company.CNPJ) {
+			continue
+		}
+		if filter.UpdatedFrom != nil && company.UpdatedAt.Before(*filter.UpdatedFrom) {
+			continue
+		}
+		if filter.TenantID != "" && company.TenantID != filter.TenantID {
+			continue
+		}
+		matched = append(matched, company)
+	}
+	return matched, nil
+}
+
+func (f *fakeRepository) List(ctx context.Context, filter repository.CompanyFilter, page, limit int) ([]*domain.Company, error) {
+	matched, err := f.matching(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	start := (page - 1) * limit
+	if start >= len(matched) {
+		return nil, nil
+	}
+	return matched[start:min(start+limit, len(matched))], nil
+}
+
+func (f *fakeRepository) Count(ctx context.Context, filter repository.CompanyFilter) (int64, error) {
+	matched, err := f.matching(ctx, filter)
+	return int64(len(matched)), err
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// recordingProducer registra os eventos publicados e se o contexto estava marcado como replay
+type recordingProducer struct {
+	mu      sync.Mutex
+	sent    []string
+	replays int
+	fail    func(company *domain.Company) error
+	block   chan struct{}
+}
+
+func (p *recordingProducer) SendCompanyCreated(ctx context.Context, company *domain.Company) error {
+	return p.record(ctx, "created", company)
+}
+
+func (p *recordingProducer) SendCompanyUpdated(ctx context.Context, previous, company *domain.Company) error {
+	return p.record(ctx, "updated", company)
+}
+
+func (p *recordingProducer) SendCompanyDeleted(ctx context.Context, company *domain.Company) error {
+	return p.record(ctx, "deleted", company)
+}
+
+func (p *recordingProducer) Close() error { return nil }
+
+func (p *recordingProducer) record(ctx context.Context, operation string, company *domain.Company) error {
+	if p.block != nil {
+		select {
+		case <-p.block:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if p.fail != nil {
+		if err := p.fail(company); err != nil {
+			return err
+		}
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sent = append(p.sent, operation+":"+company.ID)
+	if messaging.IsReplay(ctx) {
+		p.replays++
+	}
+	return nil
+}
+
+func (p *recordingProducer) published() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.sent...)
+}
+
+var base = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newFakeRepository(count int) *fakeRepository {
+	repo := &fakeRepository{}
+	for i := 0; i < count; i++ {
+		tenantID := "acme"
+		if i%2 == 1 {
+			tenantID = "globex"
+		}
+		repo.companies = append(repo.companies, &domain.Company{
+			ID:        fmt.Sprintf("c%03d", i),
+			TenantID:  tenantID,
+			CNPJ:      fmt.Sprintf("%014d", i),
+			UpdatedAt: base.Add(time.Duration(i) * time.Hour),
+		})
+	}
+	return repo
+}
+
+func TestReplayer_ReplaysAllPagesWithReplayMarker(t *testing.T) {
+	producer := &recordingProducer{}
+	replayer := NewReplayer(newFakeRepository(pageSize+5), producer, zap.NewNop())
+
+	result, err := replayer.Replay(context.Background(), Filter{}, Options{})
+
+	require.NoError(t, err)
+	assert.Equal(t, Result{Matched: pageSize + 5, Published: pageSize + 5}, result)
+	sent := producer.published()
+	require.Len(t, sent, pageSize+5)
+	assert.Equal(t, "updated:c000", sent[0])
+	assert.Equal(t, "updated:c104", sent[pageSize+4])
+	assert.Equal(t, pageSize+5, producer.replays)
+}
+
+func TestReplayer_Filters(t *testing.T) {
+	since := base.Add(6 * time.Hour)
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"ids", Filter{IDs: []string{"c001", "c003"}}, []string{"created:c001", "created:c003"}},
+		{"cnpjs", Filter{CNPJs: []string{"00000000000002"}}, []string{"created:c002"}},
+		{"updated since", Filter{UpdatedSince: &since}, []string{"created:c006", "created:c007"}},
+		{"tenant", Filter{TenantID: "globex", UpdatedSince: &since}, []string{"created:c007"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			producer := &recordingProducer{}
+			replayer := NewReplayer(newFakeRepository(8), producer, zap.NewNop())
+
+			result, err := replayer.Replay(context.Background(), tt.filter, Options{EventType: "created"})
+
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(tt.want)), result.Matched)
+			assert.Equal(t, tt.want, producer.published())
+		})
+	}
+}
+
+func TestReplayer_DryRunOnlyCounts(t *testing.T) {
+	producer := &recordingProducer{}
+	replayer := NewReplayer(newFakeRepository(8), producer, zap.NewNop())
+
+	result, err := replayer.Replay(context.Background(), Filter{TenantID: "acme"}, Options{DryRun: true})
+
+	require.NoError(t, err)
+	assert.Equal(t, Result{Matched: 4}, result)
+	assert.Empty(t, producer.published())
+}
+
+func TestReplayer_RateLimit(t *testing.T) {
+	replayer := NewReplayer(newFakeRepository(5), &recordingProducer{}, zap.NewNop())
+
+	start := time.Now()
+	result, err := replayer.Replay(context.Background(), Filter{}, Options{RateLimit: 100})
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), result.Published)
+	assert.GreaterOrEqual(t, time.Since(start), 45*time.Millisecond)
+}
+
+func TestReplayer_CountsFailuresAndAbortsWhenTheyRepeat(t *testing.T) {
+	producer := &recordingProducer{fail: func(company *domain.Company) error {
+		if company.ID == "c002" {
+			return errors.New("nack")
+		}
+		return nil
+	}}
+	replayer := NewReplayer(newFakeRepository(5), producer, zap.NewNop())
+
+	result, err := replayer.Replay(context.Background(), Filter{}, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, Result{Matched: 5, Published: 4, Failed: 1, FailedIDs: []string{"c002"}}, result)
+
+	producer.fail = func(*domain.Company) error { return errors.New("broker down") }
+	replayer = NewReplayer(newFakeRepository(maxConsecutiveFailures+5), producer, zap.NewNop())
+	result, err = replayer.Replay(context.Background(), Filter{}, Options{})
+	assert.ErrorContains(t, err, "consecutive failures")
+	assert.Equal(t, int64(0), result.Published)
+	assert.Equal(t, int64(maxConsecutiveFailures), result.Failed)
+}
+
+func TestReplayer_RejectsDeletedEventType(t *testing.T) {
+	replayer := NewReplayer(newFakeRepository(1), &recordingProducer{}, zap.NewNop())
+
+	_, err := replayer.Replay(context.Background(), Filter{}, Options{EventType: messaging.CompanyDeleted})
+	assert.ErrorIs(t, err, ErrInvalidEventType)
+
+	eventType, err := ParseEventType("br.company.created.v1")
+	require.NoError(t, err)
+	assert.Equal(t, messaging.CompanyCreated, eventType)
+}
+
+func TestReplayer_StartRunsInBackgroundAndCanBeCanceled(t *testing.T) {
+	producer := &recordingProducer{block: make(chan struct{})}
+	replayer := NewReplayer(newFakeRepository(3), producer, zap.NewNop())
+
+	run, err := replayer.Start(Filter{}, Options{})
+	require.NoError(t, err)
+	assert.Equal(t, StatusRunning, run.Status)
+	assert.Equal(t, messaging.CompanyUpdated, run.Options.EventType)
+
+	_, err = replayer.Start(Filter{}, Options{})
+	assert.ErrorIs(t, err, ErrAlreadyRunning)
+
+	producer.block <- struct{}{}
+	require.Eventually(t, func() bool {
+		current, _ := replayer.Get(run.ID)
+		return current.Result.Published == 1
+	}, time.Second, time.Millisecond)
+
+	require.NoError(t, replayer.Cancel(run.ID))
+	require.Eventually(t, func() bool {
+		current, _ := replayer.Get(run.ID)
+		return current.Status == StatusCanceled
+	}, time.Second, time.Millisecond)
+
+	runs := replayer.Runs()
+	require.Len(t, runs, 1)
+	assert.Equal(t, int64(3), runs[0].Result.Matched)
+	assert.NotNil(t, runs[0].FinishedAt)
+
+	_, err = replayer.Get("desconhecido")
+	assert.ErrorIs(t, err, ErrRunNotFound)
+}
+
+func TestReplayer_StartCompletes(t *testing.T) {
+	replayer := NewReplayer(newFakeRepository(3), &recordingProducer{}, zap.NewNop())
+
+	run, err := replayer.Start(Filter{}, Options{DryRun: true})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		current, _ := replayer.Get(run.ID)
+		return current.Status == StatusCompleted
+	}, time.Second, time.Millisecond)
+	current, _ := replayer.Get(run.ID)
+	assert.Equal(t, Result{Matched: 3, Published: 3}, current.Result)
+}
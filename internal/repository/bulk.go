@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"company-service/internal/domain"
+	"errors"
+)
+
+// ErrDuplicateCNPJ indica que o CNPJ já está cadastrado (criação em lote)
+var ErrDuplicateCNPJ = errors.New("CNPJ já cadastrado")
+
+// BulkStatus é o resultado de um item em uma operação em lote
+type BulkStatus string
+
+const (
+	BulkCreated BulkStatus = "created"
+	BulkUpdated BulkStatus = "updated"
+	BulkFailed  BulkStatus = "failed"
+)
+
+// BulkResult descreve o resultado de um item, na mesma posição da entrada
+type BulkResult struct {
+	Status   BulkStatus
+	Company  *domain.Company
+	Previous *domain.Company // estado anterior de itens atualizados, quando conhecido
+	Err      error
+}
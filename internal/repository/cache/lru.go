@@ -0,0 +1,159 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// entry é um item do LRU; value nil representa um cache negativo (registro inexistente)
+type entry struct {
+	key       string
+	value     interface{}
+	expiresAt time.Time
+}
+
+// lru é um cache LRU limitado por tamanho, com expiração por item. Seguro para uso concorrente.
+type lru struct {
+	mu       sync.Mutex
+	capacity int
+	items    map[string]*list.Element
+	order    *list.List // frente = mais recentemente usado
+	now      func() time.Time
+
+	// generation muda a cada remoção; setIf usa-a para não gravar valores lidos antes de uma
+	// invalidação concorrente
+	generation uint64
+	evictions  uint64
+}
+
+func newLRU(capacity int) *lru {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &lru{
+		capacity: capacity,
+		items:    make(map[string]*list.Element, capacity),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+// get retorna o valor e se a chave estava presente e válida
+func (c *lru) get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*entry)
+	if c.now().After(e.expiresAt) {
+		c.removeElement(el)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return e.value, true
+}
+
+// set insere ou substitui uma chave, removendo o item menos usado se a capacidade for excedida
+func (c *lru) set(key string, value interface{}, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.setLocked(key, value, ttl)
+}
+
+// currentGeneration retorna a geração atual, a ser informada em setIf
+func (c *lru) currentGeneration() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.generation
+}
+
+// setIf grava as chaves somente se nenhuma remoção ocorreu desde a geração informada
+func (c *lru) setIf(generation uint64, keys []string, value interface{}, ttl time.Duration) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generation != generation {
+		return false
+	}
+	for _, key := range keys {
+		c.setLocked(key, value, ttl)
+	}
+	return true
+}
+
+func (c *lru) setLocked(key string, value interface{}, ttl time.Duration) {
+	expiresAt := c.now().Add(ttl)
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*entry)
+		e.value, e.expiresAt = value, expiresAt
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&entry{key: key, value: value, expiresAt: expiresAt})
+	for c.order.Len() > c.capacity {
+		c.removeElement(c.order.Back())
+		c.evictions++
+	}
+}
+
+func (c *lru) delete(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	for _, key := range keys {
+		if el, ok := c.items[key]; ok {
+			c.removeElement(el)
+		}
+	}
+}
+
+// deleteFunc remove as chaves cujo valor satisfaz match
+func (c *lru) deleteFunc(match func(value interface{}) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	for el := c.order.Front(); el != nil; {
+		next := el.Next()
+		if match(el.Value.(*entry).value) {
+			c.removeElement(el)
+		}
+		el = next
+	}
+}
+
+func (c *lru) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	c.items = make(map[string]*list.Element, c.capacity)
+	c.order.Init()
+}
+
+func (c *lru) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+func (c *lru) evicted() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.evictions
+}
+
+func (c *lru) removeElement(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.items, el.Value.(*entry).key)
+}
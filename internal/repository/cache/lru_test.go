@@ -0,0 +1,44 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := newLRU(2)
+	c.set("a", 1, time.Minute)
+	c.set("b", 2, time.Minute)
+	c.get("a") // "b" passa a ser o menos usado
+	c.set("c", 3, time.Minute)
+
+	_, ok := c.get("b")
+	assert.False(t, ok)
+	_, ok = c.get("a")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1), c.evicted())
+}
+
+func TestLRU_ExpiredEntry_IsMiss(t *testing.T) {
+	now := time.Now()
+	c := newLRU(10)
+	c.now = func() time.Time { return now }
+	c.set("a", 1, time.Second)
+
+	c.now = func() time.Time { return now.Add(2 * time.Second) }
+	_, ok := c.get("a")
+
+	assert.False(t, ok)
+	assert.Equal(t, 0, c.len())
+}
+
+func TestLRU_NilValue_IsCachedAsNegative(t *testing.T) {
+	c := newLRU(10)
+	c.set("missing", nil, time.Minute)
+
+	value, ok := c.get("missing")
+	assert.True(t, ok)
+	assert.Nil(t, value)
+}
@@ -0,0 +1,258 @@
+package cache
+
+import (
+	"company-service/internal/domain"
+	"company-service/internal/repository"
+	"company-service/internal/tenant"
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Config define os parâmetros do cache de leitura
+type Config struct {
+	Size        int           // quantidade máxima de chaves (LRU)
+	TTL         time.Duration // validade de registros encontrados
+	NegativeTTL time.Duration // validade de registros inexistentes (cache negativo)
+}
+
+// Stats são as estatísticas acumuladas do cache
+type Stats struct {
+	Hits         uint64  `json:"hits"`
+	NegativeHits uint64  `json:"negative_hits"`
+	Misses       uint64  `json:"misses"`
+	Evictions    uint64  `json:"evictions"`
+	Size         int     `json:"size"`
+	HitRatio     float64 `json:"hit_ratio"`
+}
+
+// Repository é um decorator de repository.CompanyRepository que mantém em cache as leituras
+// por ID e por CNPJ. Escritas feitas por esta instância invalidam as chaves afetadas; escritas
+// de outras instâncias são refletidas após o TTL, ou imediatamente quando recebidas pelo
+// change feed e repassadas a Invalidate/InvalidateID.
+type Repository struct {
+	next  repository.CompanyRepository
+	cache *lru
+	cfg   Config
+
+	hits         atomic.Uint64
+	negativeHits atomic.Uint64
+	misses       atomic.Uint64
+}
+
+var _ repository.CompanyRepository = (*Repository)(nil)
+
+// NewRepository cria o decorator de cache sobre o repositório informado
+func NewRepository(next repository.CompanyRepository, cfg Config) *Repository {
+	if cfg.TTL <= 0 {
+		cfg.TTL = 30 * time.Second
+	}
+	if cfg.NegativeTTL <= 0 {
+		cfg.NegativeTTL = 5 * time.Second
+	}
+	return &Repository{next: next, cache: newLRU(cfg.Size), cfg: cfg}
+}
+
+// As chaves incluem o tenant do contexto ("*" para contextos com acesso a todos os tenants),
+// evitando que uma empresa em cache seja vista por outro tenant
+func idKey(scope, id string) string     { return scope + "|id:" + id }
+func cnpjKey(scope, cnpj string) string { return scope + "|cnpj:" + cnpj }
+
+// cacheScope retorna o escopo de cache do contexto; contextos sem tenant não usam o cache
+func cacheScope(ctx context.Context) (string, bool) {
+	if tenant.IsAllTenants(ctx) {
+		return "*", true
+	}
+	return tenant.FromContext(ctx)
+}
+
+// GetByID busca no cache e, em caso de ausência, no repositório decorado
+func (r *Repository) GetByID(ctx context.Context, id string) (*domain.Company, error) {
+	return r.readThrough(ctx, func(scope string) string { return idKey(scope, id) }, func() (*domain.Company, error) {
+		return r.next.GetByID(ctx, id)
+	})
+}
+
+// GetByCNPJ busca no cache e, em caso de ausência, no repositório decorado
+func (r *Repository) GetByCNPJ(ctx context.Context, cnpj string) (*domain.Company, error) {
+	return r.readThrough(ctx, func(scope string) string { return cnpjKey(scope, cnpj) }, func() (*domain.Company, error) {
+		return r.next.GetByCNPJ(ctx, cnpj)
+	})
+}
+
+// Create persiste a empresa e descarta entradas negativas do CNPJ
+func (r *Repository) Create(ctx context.Context, company *domain.Company) error {
+	if err := r.next.Create(ctx, company); err != nil {
+		return err
+	}
+	r.invalidate(company.TenantID, company.ID, company.CNPJ)
+	return nil
+}
+
+// Update persiste a empresa e invalida as chaves do ID, do CNPJ anterior e do novo CNPJ
+func (r *Repository) Update(ctx context.Context, company *domain.Company) (*domain.Company, *domain.Company, error) {
+	scope, _ := cacheScope(ctx)
+	stale := r.keysFor(scope, company.ID)
+	updated, previous, err := r.next.Update(ctx, company)
+	r.cache.delete(stale...)
+	if previous != nil {
+		r.invalidate(previous.TenantID, previous.ID, previous.CNPJ)
+	}
+	if updated != nil {
+		r.invalidate(updated.TenantID, updated.ID, updated.CNPJ)
+	}
+	return updated, previous, err
+}
+
+// Delete remove a empresa e invalida suas chaves, inclusive as do documento removido
+func (r *Repository) Delete(ctx context.Context, id string) (*domain.Company, error) {
+	scope, _ := cacheScope(ctx)
+	stale := r.keysFor(scope, id)
+	deleted, err := r.next.Delete(ctx, id)
+	r.cache.delete(stale...)
+	if deleted != nil {
+		r.invalidate(deleted.TenantID, deleted.ID, deleted.CNPJ)
+	}
+	return deleted, err
+}
+
+// CreateMany persiste em lote e descarta entradas negativas dos CNPJs
+func (r *Repository) CreateMany(ctx context.Context, companies []*domain.Company) ([]repository.BulkResult, error) {
+	results, err := r.next.CreateMany(ctx, companies)
+	r.invalidateBulk(companies, results)
+	return results, err
+}
+
+// UpsertManyByCNPJ persiste em lote e invalida as chaves de cada empresa afetada
+func (r *Repository) UpsertManyByCNPJ(ctx context.Context, companies []*domain.Company) ([]repository.BulkResult, error) {
+	results, err := r.next.UpsertManyByCNPJ(ctx, companies)
+	r.invalidateBulk(companies, results)
+	return results, err
+}
+
+func (r *Repository) invalidateBulk(companies []*domain.Company, results []repository.BulkResult) {
+	for _, company := range companies {
+		r.invalidate(company.TenantID, company.ID, company.CNPJ)
+	}
+	for _, result := range results {
+		if result.Company != nil {
+			r.invalidate(result.Company.TenantID, result.Company.ID, result.Company.CNPJ)
+		}
+	}
+}
+
+// List não é cacheado
+func (r *Repository) List(ctx context.Context, filter repository.CompanyFilter, page, limit int) ([]*domain.Company, error) {
+	return r.next.List(ctx, filter, page, limit)
+}
+
+// Count não é cacheado
+func (r *Repository) Count(ctx context.Context, filter repository.CompanyFilter) (int64, error) {
+	return r.next.Count(ctx, filter)
+}
+
+// Search não é cacheado
+func (r *Repository) Search(ctx context.Context, query string, limit int) ([]*domain.Company, error) {
+	return r.next.Search(ctx, query, limit)
+}
+
+// Invalidate descarta as chaves de uma empresa; útil para reagir a eventos de outras instâncias
+func (r *Repository) Invalidate(tenantID, id, cnpj string) {
+	r.invalidate(tenantID, id, cnpj)
+}
+
+// InvalidateID descarta as entradas da empresa em todos os escopos, para alterações em que
+// apenas o ID é conhecido (ex.: exclusões recebidas pelo change feed)
+func (r *Repository) InvalidateID(id string) {
+	r.cache.deleteFunc(func(value interface{}) bool {
+		company, ok := value.(*domain.Company)
+		return ok && company.ID == id
+	})
+}
+
+// invalidate remove as chaves da empresa no escopo do tenant e no escopo global ("*"),
+// incluindo o CNPJ da versão em cache, que pode ser diferente do atual
+func (r *Repository) invalidate(tenantID, id, cnpj string) {
+	var keys []string
+	for _, scope := range []string{tenantID, "*"} {
+		if scope == "" {
+			continue
+		}
+		if id != "" {
+			keys = append(keys, r.keysFor(scope, id)...)
+		}
+		if cnpj != "" {
+			keys = append(keys, cnpjKey(scope, cnpj))
+		}
+	}
+	r.cache.delete(keys...)
+}
+
+// Purge esvazia o cache
+func (r *Repository) Purge() {
+	r.cache.purge()
+}
+
+// Stats retorna as estatísticas de uso do cache
+func (r *Repository) Stats() Stats {
+	stats := Stats{
+		Hits:         r.hits.Load(),
+		NegativeHits: r.negativeHits.Load(),
+		Misses:       r.misses.Load(),
+		Evictions:    r.cache.evicted(),
+		Size:         r.cache.len(),
+	}
+	if total := stats.Hits + stats.NegativeHits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits+stats.NegativeHits) / float64(total)
+	}
+	return stats
+}
+
+func (r *Repository) readThrough(ctx context.Context, keyFor func(scope string) string, load func() (*domain.Company, error)) (*domain.Company, error) {
+	scope, ok := cacheScope(ctx)
+	if !ok {
+		return load()
+	}
+
+	key := keyFor(scope)
+	if value, ok := r.cache.get(key); ok {
+		if value == nil {
+			r.negativeHits.Add(1)
+			return nil, nil
+		}
+		r.hits.Add(1)
+		return clone(value.(*domain.Company)), nil
+	}
+
+	r.misses.Add(1)
+	// Uma invalidação durante a leitura indica que o valor lido pode ser anterior a uma
+	// escrita concorrente; nesse caso ele é retornado, mas não é cacheado
+	generation := r.cache.currentGeneration()
+	company, err := load()
+	if err != nil {
+		return nil, err // erros não são cacheados
+	}
+	if company == nil {
+		r.cache.setIf(generation, []string{key}, nil, r.cfg.NegativeTTL)
+		return nil, nil
+	}
+
+	keys := []string{idKey(scope, company.ID), cnpjKey(scope, company.CNPJ)}
+	r.cache.setIf(generation, keys, clone(company), r.cfg.TTL)
+	return company, nil
+}
+
+// keysFor retorna as chaves conhecidas de uma empresa, incluindo o CNPJ da versão em cache
+func (r *Repository) keysFor(scope, id string) []string {
+	keys := []string{idKey(scope, id)}
+	if value, ok := r.cache.get(idKey(scope, id)); ok && value != nil {
+		keys = append(keys, cnpjKey(scope, value.(*domain.Company).CNPJ))
+	}
+	return keys
+}
+
+// clone evita que alterações feitas pelo chamador contaminem o valor em cache
+func clone(company *domain.Company) *domain.Company {
+	c := *company
+	return &c
+}
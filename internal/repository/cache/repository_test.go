@@ -0,0 +1,172 @@
+package cache
+
+import (
+	"company-service/internal/domain"
+	"company-service/internal/repository"
+	"company-service/internal/tenant"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeRepository conta as leituras que chegam ao repositório decorado
+type fakeRepository struct {
+	repository.CompanyRepository
+	companies map[string]*domain.Company
+	reads     int
+	onRead    func() // executado após a leitura, antes do retorno
+}
+
+func (f *fakeRepository) GetByID(ctx context.Context, id string) (*domain.Company, error) {
+	f.reads++
+	company := f.companies[id]
+	if f.onRead != nil {
+		f.onRead()
+	}
+	return company, nil
+}
+
+func (f *fakeRepository) GetByCNPJ(ctx context.Context, cnpj string) (*domain.Company, error) {
+	f.reads++
+	for _, c := range f.companies {
+		if c.CNPJ == cnpj {
+			return c, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeRepository) Update(ctx context.Context, company *domain.Company) (*domain.Company, *domain.Company, error) {
+	previous := f.companies[company.ID]
+	f.companies[company.ID] = company
+	return company, previous, nil
+}
+
+func (f *fakeRepository) Delete(ctx context.Context, id string) (*domain.Company, error) {
+	deleted := f.companies[id]
+	delete(f.companies, id)
+	return deleted, nil
+}
+
+var ctx = tenant.WithTenant(context.Background(), "acme")
+
+func newCachedRepository() (*Repository, *fakeRepository) {
+	fake := &fakeRepository{companies: map[string]*domain.Company{
+		"1": {ID: "1", TenantID: "acme", CNPJ: "11444777000161", FantasyName: "Empresa Teste"},
+	}}
+	return NewRepository(fake, Config{Size: 100, TTL: time.Minute, NegativeTTL: time.Minute}), fake
+}
+
+func TestRepository_GetByID_SecondReadIsHit(t *testing.T) {
+	repo, fake := newCachedRepository()
+
+	_, _ = repo.GetByID(ctx, "1")
+	company, err := repo.GetByID(ctx, "1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Empresa Teste", company.FantasyName)
+	assert.Equal(t, 1, fake.reads)
+	assert.Equal(t, uint64(1), repo.Stats().Hits)
+	assert.Equal(t, uint64(1), repo.Stats().Misses)
+}
+
+func TestRepository_GetByID_PopulatesCNPJKey(t *testing.T) {
+	repo, fake := newCachedRepository()
+
+	_, _ = repo.GetByID(ctx, "1")
+	company, _ := repo.GetByCNPJ(ctx, "11444777000161")
+
+	assert.Equal(t, "1", company.ID)
+	assert.Equal(t, 1, fake.reads)
+}
+
+func TestRepository_GetByCNPJ_MissIsNegativelyCached(t *testing.T) {
+	repo, fake := newCachedRepository()
+
+	_, _ = repo.GetByCNPJ(ctx, "47960950000121")
+	company, err := repo.GetByCNPJ(ctx, "47960950000121")
+
+	assert.NoError(t, err)
+	assert.Nil(t, company)
+	assert.Equal(t, 1, fake.reads)
+	assert.Equal(t, uint64(1), repo.Stats().NegativeHits)
+}
+
+func TestRepository_Update_InvalidatesPreviousCNPJ(t *testing.T) {
+	repo, _ := newCachedRepository()
+	_, _ = repo.GetByID(ctx, "1")
+
+	_, _, err := repo.Update(ctx, &domain.Company{ID: "1", TenantID: "acme", CNPJ: "47960950000121", FantasyName: "Novo Nome"})
+	assert.NoError(t, err)
+
+	old, _ := repo.GetByCNPJ(ctx, "11444777000161")
+	updated, _ := repo.GetByID(ctx, "1")
+	assert.Nil(t, old)
+	assert.Equal(t, "Novo Nome", updated.FantasyName)
+}
+
+func TestRepository_Delete_InvalidatesCompany(t *testing.T) {
+	repo, _ := newCachedRepository()
+	_, _ = repo.GetByID(ctx, "1")
+
+	deleted, err := repo.Delete(ctx, "1")
+	assert.NoError(t, err)
+	assert.Equal(t, "1", deleted.ID)
+
+	company, _ := repo.GetByID(ctx, "1")
+	assert.Nil(t, company)
+}
+
+func TestRepository_ReturnedCompanyIsACopy(t *testing.T) {
+	repo, _ := newCachedRepository()
+	_, _ = repo.GetByID(ctx, "1")
+
+	company, _ := repo.GetByID(ctx, "1")
+	company.FantasyName = "Alterado"
+
+	again, _ := repo.GetByID(ctx, "1")
+	assert.Equal(t, "Empresa Teste", again.FantasyName)
+}
+
+func TestRepository_GetByID_IsScopedByTenant(t *testing.T) {
+	repo, fake := newCachedRepository()
+	_, _ = repo.GetByID(ctx, "1")
+
+	_, _ = repo.GetByID(tenant.WithTenant(context.Background(), "other"), "1")
+
+	assert.Equal(t, 2, fake.reads, "outro tenant não deve ler a entrada em cache")
+}
+
+func TestRepository_ReadRacingInvalidation_IsNotCached(t *testing.T) {
+	repo, fake := newCachedRepository()
+	// Outra réplica altera a empresa depois da leitura e antes de o valor ser cacheado
+	fake.onRead = func() {
+		fake.companies["1"] = &domain.Company{ID: "1", TenantID: "acme", CNPJ: "11444777000161", FantasyName: "Novo Nome"}
+		repo.Invalidate("acme", "1", "11444777000161")
+	}
+
+	stale, _ := repo.GetByID(ctx, "1")
+	fake.onRead = nil
+	fresh, _ := repo.GetByID(ctx, "1")
+
+	assert.Equal(t, "Empresa Teste", stale.FantasyName)
+	assert.Equal(t, "Novo Nome", fresh.FantasyName, "o valor lido antes da invalidação não deve ser cacheado")
+	assert.Equal(t, 2, fake.reads)
+}
+
+func TestRepository_InvalidateID_RemovesEveryScope(t *testing.T) {
+	repo, fake := newCachedRepository()
+	_, _ = repo.GetByID(ctx, "1")
+	_, _ = repo.GetByID(tenant.WithAllTenants(context.Background()), "1")
+
+	// Exclusão feita por outra réplica, conhecida apenas pelo ID
+	delete(fake.companies, "1")
+	repo.InvalidateID("1")
+
+	byID, _ := repo.GetByID(ctx, "1")
+	byCNPJ, _ := repo.GetByCNPJ(tenant.WithAllTenants(context.Background()), "11444777000161")
+	assert.Nil(t, byID)
+	assert.Nil(t, byCNPJ)
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Erros de validação de filtros e ordenação
+var (
+	ErrInvalidSortField   = errors.New("campo de ordenação inválido")
+	ErrInvalidFilterRange = errors.New("intervalo de filtro inválido")
+	// ErrEncryptedField indica filtro ou ordenação por um campo criptografado em repouso
+	ErrEncryptedField = errors.New("filtro ou ordenação por campo criptografado não suportado")
+)
+
+// SortableFields lista os campos (bson) indexados que podem ser usados na ordenação
+var SortableFields = map[string]bool{
+	"cnpj":                            true,
+	"fantasy_name":                    true,
+	"corporate_name":                  true,
+	"employee_count":                  true,
+	"required_min_pwd_employee_count": true,
+	"created_at":                      true,
+	"updated_at":                      true,
+}
+
+// SortField representa um critério de ordenação
+type SortField struct {
+	Field string
+	Desc  bool
+}
+
+// DefaultSort mantém a ordenação original: inseridos mais recentes primeiro
+var DefaultSort = []SortField{{Field: "created_at", Desc: true}}
+
+// CompanyFilter define os critérios de busca aceitos pela listagem de empresas.
+// Campos nulos ou vazios não são aplicados.
+type CompanyFilter struct {
+	IDs              []string // restringe às empresas com estes IDs
+	CNPJs            []string // restringe às empresas com estes CNPJs exatos
+	CNPJPrefix       string
+	Name             string // substring de Nome Fantasia ou Razão Social
+	MinEmployeeCount *int
+	MaxEmployeeCount *int
+	CreatedFrom      *time.Time
+	CreatedTo        *time.Time
+	UpdatedFrom      *time.Time
+	UpdatedTo        *time.Time
+	RequiresPWD      *bool  // true: exige PCD (mínimo > 0); false: não exige
+	TenantID         string // restringe a um tenant; aplicado apenas em consultas entre tenants
+	Sort             []SortField
+}
+
+// Validate verifica intervalos e campos de ordenação do filtro
+func (f *CompanyFilter) Validate() error {
+	for _, s := range f.Sort {
+		if !SortableFields[s.Field] {
+			return fmt.Errorf("%w: %s", ErrInvalidSortField, s.Field)
+		}
+	}
+
+	if f.MinEmployeeCount != nil && *f.MinEmployeeCount < 0 {
+		return fmt.Errorf("%w: employee_count mínimo não pode ser negativo", ErrInvalidFilterRange)
+	}
+	if f.MinEmployeeCount != nil && f.MaxEmployeeCount != nil && *f.MinEmployeeCount > *f.MaxEmployeeCount {
+		return fmt.Errorf("%w: employee_count mínimo maior que o máximo", ErrInvalidFilterRange)
+	}
+	if f.CreatedFrom != nil && f.CreatedTo != nil && f.CreatedFrom.After(*f.CreatedTo) {
+		return fmt.Errorf("%w: created_from posterior a created_to", ErrInvalidFilterRange)
+	}
+	if f.UpdatedFrom != nil && f.UpdatedTo != nil && f.UpdatedFrom.After(*f.UpdatedTo) {
+		return fmt.Errorf("%w: updated_from posterior a updated_to", ErrInvalidFilterRange)
+	}
+
+	return nil
+}
+
+// SortOrDefault retorna a ordenação do filtro ou a ordenação padrão
+func (f *CompanyFilter) SortOrDefault() []SortField {
+	if len(f.Sort) == 0 {
+		return DefaultSort
+	}
+	return f.Sort
+}
+
+// ParseSort converte uma expressão como "-employee_count,fantasy_name" em critérios de ordenação.
+// O prefixo "-" indica ordem decrescente.
+func ParseSort(expr string) ([]SortField, error) {
+	if strings.TrimSpace(expr) == "" {
+		return nil, nil
+	}
+
+	var fields []SortField
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(expr, ",") {
+		part = strings.TrimSpace(part)
+		desc := false
+		if strings.HasPrefix(part, "-") {
+			desc = true
+			part = part[1:]
+		} else if strings.HasPrefix(part, "+") {
+			part = part[1:]
+		}
+
+		if !SortableFields[part] {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidSortField, part)
+		}
+		if seen[part] {
+			continue
+		}
+		seen[part] = true
+		fields = append(fields, SortField{Field: part, Desc: desc})
+	}
+	return fields, nil
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSort_Empty_ReturnsNil(t *testing.T) {
+	fields, err := ParseSort("")
+	assert.NoError(t, err)
+	assert.Nil(t, fields)
+}
+
+func TestParseSort_MultipleFields_ReturnsOrderedCriteria(t *testing.T) {
+	fields, err := ParseSort("-employee_count, fantasy_name")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []SortField{
+		{Field: "employee_count", Desc: true},
+		{Field: "fantasy_name", Desc: false},
+	}, fields)
+}
+
+func TestParseSort_UnknownField_ReturnsError(t *testing.T) {
+	_, err := ParseSort("-password")
+	assert.ErrorIs(t, err, ErrInvalidSortField)
+}
+
+func TestParseSort_DuplicatedField_KeepsFirst(t *testing.T) {
+	fields, err := ParseSort("cnpj,-cnpj")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []SortField{{Field: "cnpj"}}, fields)
+}
+
+func TestCompanyFilter_Validate_InvertedEmployeeRange_ReturnsError(t *testing.T) {
+	minCount, maxCount := 50, 10
+	filter := CompanyFilter{MinEmployeeCount: &minCount, MaxEmployeeCount: &maxCount}
+
+	assert.ErrorIs(t, filter.Validate(), ErrInvalidFilterRange)
+}
+
+func TestCompanyFilter_Validate_InvertedDateRange_ReturnsError(t *testing.T) {
+	from := time.Now()
+	to := from.Add(-time.Hour)
+	filter := CompanyFilter{CreatedFrom: &from, CreatedTo: &to}
+
+	assert.ErrorIs(t, filter.Validate(), ErrInvalidFilterRange)
+}
+
+func TestCompanyFilter_SortOrDefault_NoSort_ReturnsNewestFirst(t *testing.T) {
+	filter := CompanyFilter{}
+	assert.Equal(t, DefaultSort, filter.SortOrDefault())
+}
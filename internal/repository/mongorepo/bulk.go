@@ -0,0 +1,256 @@
+package mongorepo
+
+import (
+	"company-service/internal/domain"
+	"company-service/internal/repository"
+	"context"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateMany insere empresas em lote (BulkWrite não ordenado). Cada item é um upsert com
+// $setOnInsert filtrado pelo CNPJ, de modo que CNPJs já cadastrados falham com
+// repository.ErrDuplicateCNPJ sem sobrescrever o registro existente.
+func (r *mongoRepository) CreateMany(ctx context.Context, companies []*domain.Company) ([]repository.BulkResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	results := make([]repository.BulkResult, len(companies))
+	if len(companies) == 0 {
+		return results, nil
+	}
+
+	models := make([]mongo.WriteModel, len(companies))
+	for i, company := range companies {
+		if err := assignTenant(ctx, company); err != nil {
+			return nil, err
+		}
+		company.BeforeCreate()
+		results[i].Company = company
+
+		doc, err := r.codec.document(company)
+		if err != nil {
+			return nil, err
+		}
+		models[i] = mongo.NewUpdateOneModel().
+			SetFilter(r.codec.cnpjQuery(company.TenantID, company.CNPJ)).
+			SetUpdate(bson.M{"$setOnInsert": doc}).
+			SetUpsert(true)
+	}
+
+	res, err := r.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	failed, err := writeErrors(err)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range results {
+		if itemErr, ok := failed[i]; ok {
+			results[i].Status, results[i].Err = repository.BulkFailed, itemErr
+			continue
+		}
+		id, ok := upsertedID(res, i)
+		if !ok {
+			results[i].Status, results[i].Err = repository.BulkFailed, repository.ErrDuplicateCNPJ
+			continue
+		}
+		results[i].Company.ID = id
+		results[i].Status = repository.BulkCreated
+	}
+	return results, nil
+}
+
+// UpsertManyByCNPJ cria ou atualiza empresas em lote usando o CNPJ como chave, em um único
+// BulkWrite não ordenado. Os documentos atuais são lidos antes, em uma consulta por tenant e CNPJ,
+// e cada atualização só é aplicada se o documento ainda estiver na sequência lida; assim o estado
+// anterior e a sequência gravada correspondem à escrita. Um CNPJ ausente na leitura é inserido
+// com $setOnInsert, como em CreateMany. Uma escrita concorrente no mesmo CNPJ entre a leitura e o
+// lote faz o item falhar com repository.ErrDuplicateCNPJ, sem alterar o registro.
+func (r *mongoRepository) UpsertManyByCNPJ(ctx context.Context, companies []*domain.Company) ([]repository.BulkResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	results := make([]repository.BulkResult, len(companies))
+	if len(companies) == 0 {
+		return results, nil
+	}
+
+	cnpjs := make([]string, len(companies))
+	for i, company := range companies {
+		if err := assignTenant(ctx, company); err != nil {
+			return nil, err
+		}
+		company.BeforeUpdate()
+		cnpjs[i] = company.CNPJ
+	}
+
+	current, err := r.currentByCNPJ(ctx, cnpjs)
+	if err != nil {
+		return nil, err
+	}
+
+	models := make([]mongo.WriteModel, len(companies))
+	for i, company := range companies {
+		previous := current[company.TenantID+":"+company.CNPJ]
+		results[i].Previous = previous
+		if previous == nil {
+			created := *company
+			created.CreatedAt = company.UpdatedAt
+			created.Sequence = 1
+			results[i].Company = &created
+
+			doc, err := r.codec.document(&created)
+			if err != nil {
+				return nil, err
+			}
+			models[i] = mongo.NewUpdateOneModel().
+				SetFilter(r.codec.cnpjQuery(company.TenantID, company.CNPJ)).
+				SetUpdate(bson.M{"$setOnInsert": doc}).
+				SetUpsert(true)
+			continue
+		}
+
+		results[i].Company = applyUpdate(company, previous)
+		fields, err := r.codec.fields(updateFields(company))
+		if err != nil {
+			return nil, err
+		}
+		// Se o documento mudou desde a leitura, o upsert tenta inserir o CNPJ e falha no índice único
+		filter := r.codec.cnpjQuery(company.TenantID, company.CNPJ)
+		filter["sequence"] = previous.Sequence
+		models[i] = mongo.NewUpdateOneModel().
+			SetFilter(filter).
+			SetUpdate(bson.M{
+				"$set":         fields,
+				"$setOnInsert": bson.M{"created_at": company.UpdatedAt},
+				"$inc":         bson.M{"sequence": 1},
+			}).
+			SetUpsert(true)
+	}
+
+	res, err := r.collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	failed, err := writeErrors(err)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range results {
+		if itemErr, ok := failed[i]; ok {
+			results[i].Status, results[i].Err, results[i].Previous = repository.BulkFailed, itemErr, nil
+			results[i].Company = companies[i]
+			continue
+		}
+		id, upserted := upsertedID(res, i)
+		switch {
+		case results[i].Previous == nil && !upserted:
+			// Inserido por outra escrita entre a leitura e o lote
+			results[i].Status, results[i].Err = repository.BulkFailed, repository.ErrDuplicateCNPJ
+			results[i].Company = companies[i]
+		case upserted:
+			// Removido entre a leitura e o lote: a escrita recriou o documento
+			results[i].Company.ID = id
+			results[i].Company.CreatedAt = results[i].Company.UpdatedAt
+			results[i].Previous = nil
+			results[i].Status = repository.BulkCreated
+		default:
+			results[i].Status = repository.BulkUpdated
+		}
+	}
+	return results, nil
+}
+
+// currentByCNPJ lê, em uma única consulta, os documentos atuais dos CNPJs informados no tenant
+// do contexto, indexados por tenant e CNPJ
+func (r *mongoRepository) currentByCNPJ(ctx context.Context, cnpjs []string) (map[string]*domain.Company, error) {
+	filter, err := scope(ctx, r.codec.filterQuery(repository.CompanyFilter{CNPJs: cnpjs}))
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	companies, err := r.codec.decodeAll(ctx, cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	current := make(map[string]*domain.Company, len(companies))
+	for _, company := range companies {
+		current[company.TenantID+":"+company.CNPJ] = company
+	}
+	return current, nil
+}
+
+// updateFields são os campos alterados por uma atualização de empresa
+func updateFields(company *domain.Company) bson.M {
+	return bson.M{
+		"cnpj":                            company.CNPJ,
+		"fantasy_name":                    company.FantasyName,
+		"corporate_name":                  company.CorporateName,
+		"address":                         company.Address,
+		"employee_count":                  company.EmployeeCount,
+		"required_min_pwd_employee_count": company.RequiredMinPWDEmployeeCount,
+		"updated_at":                      company.UpdatedAt,
+		"search_name":                     company.SearchName,
+		"search_address":                  company.SearchAddress,
+	}
+}
+
+// toDocument converte a empresa em documento sem _id, deixando o MongoDB gerar o ObjectID
+func toDocument(company *domain.Company) (bson.M, error) {
+	raw, err := bson.Marshal(company)
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.M
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	delete(doc, "_id")
+	return doc, nil
+}
+
+// duplicateKeyCode é o código de erro do MongoDB para violação de índice único
+const duplicateKeyCode = 11000
+
+// writeErrors separa erros por item de um BulkWriteException; violações do índice único de CNPJ
+// viram repository.ErrDuplicateCNPJ e outros erros são retornados
+func writeErrors(err error) (map[int]error, error) {
+	failed := map[int]error{}
+	if err == nil {
+		return failed, nil
+	}
+
+	var bulkErr mongo.BulkWriteException
+	if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil {
+		return nil, err
+	}
+	for _, writeErr := range bulkErr.WriteErrors {
+		if writeErr.Code == duplicateKeyCode {
+			failed[writeErr.Index] = repository.ErrDuplicateCNPJ
+			continue
+		}
+		failed[writeErr.Index] = errors.New(writeErr.Message)
+	}
+	return failed, nil
+}
+
+func upsertedID(res *mongo.BulkWriteResult, index int) (string, bool) {
+	if res == nil {
+		return "", false
+	}
+	id, ok := res.UpsertedIDs[int64(index)]
+	if !ok {
+		return "", false
+	}
+	if oid, ok := id.(primitive.ObjectID); ok {
+		return oid.Hex(), true
+	}
+	return "", false
+}
@@ -0,0 +1,255 @@
+package mongorepo
+
+import (
+	"company-service/internal/domain"
+	"company-service/internal/repository"
+	"company-service/internal/tenant"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func bulkCompanies(cnpjs ...string) []*domain.Company {
+	companies := make([]*domain.Company, len(cnpjs))
+	for i, cnpj := range cnpjs {
+		companies[i] = &domain.Company{CNPJ: cnpj, FantasyName: "Empresa " + cnpj}
+	}
+	return companies
+}
+
+// bulkReply é a resposta do comando update de um BulkWrite com os upserts e erros informados
+func bulkReply(matched int, upserted map[int]primitive.ObjectID, writeErrors ...bson.D) bson.D {
+	ups := bson.A{}
+	for index, id := range upserted {
+		ups = append(ups, bson.D{{Key: "index", Value: index}, {Key: "_id", Value: id}})
+	}
+	reply := mtest.CreateSuccessResponse(
+		bson.E{Key: "n", Value: matched + len(upserted)},
+		bson.E{Key: "nModified", Value: matched},
+		bson.E{Key: "upserted", Value: ups},
+	)
+	if len(writeErrors) > 0 {
+		errs := bson.A{}
+		for _, e := range writeErrors {
+			errs = append(errs, e)
+		}
+		reply = append(reply, bson.E{Key: "writeErrors", Value: errs})
+	}
+	return reply
+}
+
+func writeError(index int, code int, message string) bson.D {
+	return bson.D{{Key: "index", Value: index}, {Key: "code", Value: code}, {Key: "errmsg", Value: message}}
+}
+
+func TestCreateMany(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	ctx := tenant.WithTenant(context.Background(), "acme")
+
+	mt.Run("maps created, existing and failed items by position", func(mt *mtest.T) {
+		r := newRepository(mt.DB, "companies", time.Second, nil)
+		first, third := primitive.NewObjectID(), primitive.NewObjectID()
+		mt.AddMockResponses(bulkReply(0,
+			map[int]primitive.ObjectID{0: first, 3: third},
+			writeError(2, 2, "invalid document"),
+		))
+
+		results, err := r.CreateMany(ctx, bulkCompanies("11222333000181", "11444777000161", "47960950000121", "11222333000262"))
+		require.NoError(t, err)
+
+		require.Len(t, results, 4)
+		assert.Equal(t, repository.BulkCreated, results[0].Status)
+		assert.Equal(t, first.Hex(), results[0].Company.ID)
+		assert.Equal(t, "acme", results[0].Company.TenantID)
+
+		// Sem upsert nem erro: o filtro encontrou o CNPJ já cadastrado
+		assert.Equal(t, repository.BulkFailed, results[1].Status)
+		assert.ErrorIs(t, results[1].Err, repository.ErrDuplicateCNPJ)
+		assert.Empty(t, results[1].Company.ID)
+
+		assert.Equal(t, repository.BulkFailed, results[2].Status)
+		assert.EqualError(t, results[2].Err, "invalid document")
+
+		assert.Equal(t, repository.BulkCreated, results[3].Status)
+		assert.Equal(t, third.Hex(), results[3].Company.ID)
+
+		// Cada item é um upsert com $setOnInsert filtrado por tenant e CNPJ, sem sobrescrever existentes
+		update := mt.GetStartedEvent().Command.Lookup("updates").Array().Index(1).Value().Document()
+		assert.Equal(t, "11444777000161", update.Lookup("q", "cnpj").StringValue())
+		assert.Equal(t, "acme", update.Lookup("q", "tenant_id").StringValue())
+		assert.True(t, update.Lookup("upsert").Boolean())
+		_, err = update.LookupErr("u", "$set")
+		assert.Error(t, err, "CreateMany não deve alterar registros existentes")
+	})
+
+	mt.Run("empty batch does not hit the database", func(mt *mtest.T) {
+		r := newRepository(mt.DB, "companies", time.Second, nil)
+
+		results, err := r.CreateMany(ctx, nil)
+
+		require.NoError(t, err)
+		assert.Empty(t, results)
+		assert.Nil(t, mt.GetStartedEvent())
+	})
+
+	mt.Run("requires tenant", func(mt *mtest.T) {
+		r := newRepository(mt.DB, "companies", time.Second, nil)
+
+		_, err := r.CreateMany(context.Background(), bulkCompanies("11222333000181"))
+
+		assert.ErrorIs(t, err, tenant.ErrMissingTenant)
+	})
+
+	mt.Run("command failure fails the whole batch", func(mt *mtest.T) {
+		r := newRepository(mt.DB, "companies", time.Second, nil)
+		mt.AddMockResponses(mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 91, Message: "shutting down"}))
+
+		_, err := r.CreateMany(ctx, bulkCompanies("11222333000181"))
+
+		assert.Error(t, err)
+	})
+}
+
+// storedCompany é o documento gravado de uma empresa, como retornado pelas leituras
+func storedCompany(id primitive.ObjectID, cnpj, name string, createdAt time.Time, sequence int64) bson.D {
+	return bson.D{
+		{Key: "_id", Value: id},
+		{Key: "tenant_id", Value: "acme"},
+		{Key: "cnpj", Value: cnpj},
+		{Key: "fantasy_name", Value: name},
+		{Key: "created_at", Value: createdAt},
+		{Key: "sequence", Value: sequence},
+	}
+}
+
+// findAndModifyReply é a resposta do findAndModify com o documento anterior (nil quando insere)
+func findAndModifyReply(previous interface{}) bson.D {
+	return mtest.CreateSuccessResponse(bson.E{Key: "value", Value: previous})
+}
+
+// currentReply é a resposta da leitura dos documentos atuais do lote
+func currentReply(mt *mtest.T, docs ...bson.D) bson.D {
+	return mtest.CreateCursorResponse(0, mt.DB.Name()+".companies", mtest.FirstBatch, docs...)
+}
+
+func TestUpsertManyByCNPJ(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	ctx := tenant.WithTenant(context.Background(), "acme")
+
+	mt.Run("updates and creates in a single bulk write", func(mt *mtest.T) {
+		r := newRepository(mt.DB, "companies", time.Second, nil)
+		existingID, insertedID := primitive.NewObjectID(), primitive.NewObjectID()
+		createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		mt.AddMockResponses(
+			currentReply(mt, storedCompany(existingID, "11222333000181", "Nome Antigo", createdAt, 4)),
+			bulkReply(1, map[int]primitive.ObjectID{1: insertedID}),
+		)
+
+		results, err := r.UpsertManyByCNPJ(ctx, bulkCompanies("11222333000181", "11444777000161"))
+		require.NoError(t, err)
+		require.Len(t, results, 2)
+
+		updated := results[0]
+		assert.Equal(t, repository.BulkUpdated, updated.Status)
+		require.NotNil(t, updated.Previous)
+		assert.Equal(t, "Nome Antigo", updated.Previous.FantasyName)
+		assert.Equal(t, int64(4), updated.Previous.Sequence)
+		assert.Equal(t, existingID.Hex(), updated.Company.ID)
+		assert.Equal(t, "Empresa 11222333000181", updated.Company.FantasyName)
+		assert.Equal(t, int64(5), updated.Company.Sequence)
+		assert.True(t, createdAt.Equal(updated.Company.CreatedAt))
+
+		created := results[1]
+		assert.Equal(t, repository.BulkCreated, created.Status)
+		assert.Nil(t, created.Previous)
+		assert.Equal(t, insertedID.Hex(), created.Company.ID)
+		assert.Equal(t, int64(1), created.Company.Sequence)
+
+		find := mt.GetStartedEvent()
+		require.NotNil(t, find)
+		assert.Equal(t, "find", find.CommandName)
+		assert.Equal(t, "acme", find.Command.Lookup("filter", "tenant_id").StringValue())
+
+		bulk := mt.GetStartedEvent()
+		require.NotNil(t, bulk)
+		assert.Equal(t, "update", bulk.CommandName)
+		assert.False(t, bulk.Command.Lookup("ordered").Boolean())
+		updates := bulk.Command.Lookup("updates").Array()
+		values, err := updates.Values()
+		require.NoError(t, err)
+		assert.Len(t, values, 2)
+
+		// A atualização só se aplica se o documento ainda estiver na sequência lida
+		first := updates.Index(0).Value().Document()
+		assert.Equal(t, "11222333000181", first.Lookup("q", "cnpj").StringValue())
+		assert.Equal(t, int64(4), first.Lookup("q", "sequence").Int64())
+		assert.Equal(t, "Empresa 11222333000181", first.Lookup("u", "$set", "fantasy_name").StringValue())
+
+		// CNPJs ausentes na leitura são apenas inseridos
+		second := updates.Index(1).Value().Document()
+		_, err = second.LookupErr("u", "$set")
+		assert.Error(t, err)
+		assert.Equal(t, int64(1), second.Lookup("u", "$setOnInsert", "sequence").Int64())
+
+		assert.Nil(t, mt.GetStartedEvent(), "o lote deve ser gravado em um único comando update")
+	})
+
+	mt.Run("item failures and concurrent writes do not stop the batch", func(mt *mtest.T) {
+		r := newRepository(mt.DB, "companies", time.Second, nil)
+		createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		insertedID := primitive.NewObjectID()
+		mt.AddMockResponses(
+			currentReply(mt, storedCompany(primitive.NewObjectID(), "11222333000181", "Nome Antigo", createdAt, 4)),
+			bulkReply(0, map[int]primitive.ObjectID{3: insertedID},
+				writeError(0, 11000, "duplicate key"),
+				writeError(1, 2, "invalid document"),
+			),
+		)
+
+		results, err := r.UpsertManyByCNPJ(ctx, bulkCompanies("11222333000181", "11444777000161", "47960950000121", "11222333000262"))
+		require.NoError(t, err)
+		require.Len(t, results, 4)
+
+		// Documento alterado entre a leitura e o lote
+		assert.Equal(t, repository.BulkFailed, results[0].Status)
+		assert.ErrorIs(t, results[0].Err, repository.ErrDuplicateCNPJ)
+		assert.Nil(t, results[0].Previous)
+
+		assert.Equal(t, repository.BulkFailed, results[1].Status)
+		assert.EqualError(t, results[1].Err, "invalid document")
+
+		// CNPJ inserido por outra escrita entre a leitura e o lote
+		assert.Equal(t, repository.BulkFailed, results[2].Status)
+		assert.ErrorIs(t, results[2].Err, repository.ErrDuplicateCNPJ)
+
+		assert.Equal(t, repository.BulkCreated, results[3].Status)
+		assert.Equal(t, insertedID.Hex(), results[3].Company.ID)
+	})
+
+	mt.Run("command failure fails the whole batch", func(mt *mtest.T) {
+		r := newRepository(mt.DB, "companies", time.Second, nil)
+		mt.AddMockResponses(
+			currentReply(mt),
+			mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 91, Message: "shutting down"}),
+		)
+
+		_, err := r.UpsertManyByCNPJ(ctx, bulkCompanies("11222333000181"))
+
+		assert.Error(t, err)
+	})
+
+	mt.Run("requires tenant", func(mt *mtest.T) {
+		r := newRepository(mt.DB, "companies", time.Second, nil)
+
+		_, err := r.UpsertManyByCNPJ(context.Background(), bulkCompanies("11222333000181"))
+
+		assert.ErrorIs(t, err, tenant.ErrMissingTenant)
+		assert.Nil(t, mt.GetStartedEvent())
+	})
+}
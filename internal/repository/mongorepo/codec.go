@@ -0,0 +1,282 @@
+package mongorepo
+
+import (
+	"company-service/internal/domain"
+	"company-service/internal/encryption"
+	"company-service/internal/repository"
+	"company-service/pkg/utils"
+	"context"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// EncryptedFields são os campos (bson) de domain.Company cifrados em repouso quando o
+// repositório é criado com WithEncryption
+var EncryptedFields = []string{"cnpj", "address", "employee_count", "required_min_pwd_employee_count"}
+
+// cnpjIndexField guarda o HMAC do CNPJ, permitindo buscas por igualdade e unicidade
+// sem armazenar o CNPJ em claro
+const cnpjIndexField = "cnpj_hmac"
+
+// sealedValue é a representação de um campo cifrado: o ID da chave e nonce || ciphertext
+// do valor original codificado em BSON, preservando seu tipo
+type sealedValue struct {
+	KeyID string `bson:"k"`
+	Data  []byte `bson:"c"`
+}
+
+// codec converte empresas de e para documentos. Sem keyring os documentos são gravados em
+// claro; com keyring os campos sensíveis são cifrados na escrita e decifrados na leitura.
+// Documentos em claro continuam legíveis, o que permite habilitar a criptografia sem
+// interrupção e migrá-los depois com o re-encrypt.
+type codec struct {
+	keyring *encryption.Keyring
+}
+
+// NewDocumentDecoder retorna a função que converte documentos da coleção em empresas,
+// decifrando os campos sensíveis; keyring nil indica dados em claro
+func NewDocumentDecoder(keyring *encryption.Keyring) func(bson.Raw) (*domain.Company, error) {
+	return codec{keyring: keyring}.decode
+}
+
+// NewSearchFieldsEncoder retorna a função que monta os campos normalizados de busca da empresa
+// ($set de search_name e search_address) como o repositório os grava: com keyring, o endereço
+// é gravado apenas como tokens de busca
+func NewSearchFieldsEncoder(keyring *encryption.Keyring) func(*domain.Company) (bson.M, error) {
+	c := codec{keyring: keyring}
+	return func(company *domain.Company) (bson.M, error) {
+		company.RefreshSearchFields()
+		return c.fields(bson.M{
+			"search_name":    company.SearchName,
+			"search_address": company.SearchAddress,
+		})
+	}
+}
+
+// seal cifra os campos sensíveis presentes no documento e atualiza o índice do CNPJ.
+// O endereço normalizado da busca textual, que o revelaria em claro, é substituído pelos
+// tokens de busca de suas palavras.
+func (c codec) seal(doc bson.M) error {
+	if c.keyring == nil {
+		return nil
+	}
+
+	if cnpj, ok := doc["cnpj"].(string); ok {
+		doc[cnpjIndexField] = c.keyring.BlindIndex(cnpj)
+	}
+	if address, ok := doc["search_address"].(string); ok {
+		doc["search_address"] = c.searchTokens(address)
+	}
+
+	for _, field := range EncryptedFields {
+		value, ok := doc[field]
+		if !ok || isSealed(value) {
+			continue
+		}
+		plaintext, err := bson.Marshal(bson.D{{Key: "v", Value: value}})
+		if err != nil {
+			return err
+		}
+		keyID, data, err := c.keyring.Encrypt(plaintext, []byte(field))
+		if err != nil {
+			return fmt.Errorf("failed to encrypt %s: %w", field, err)
+		}
+		doc[field] = sealedValue{KeyID: keyID, Data: data}
+	}
+	return nil
+}
+
+// open decifra os campos cifrados do documento
+func (c codec) open(doc bson.M) error {
+	for _, field := range EncryptedFields {
+		sealed, ok := asSealed(doc[field])
+		if !ok {
+			continue
+		}
+		if c.keyring == nil {
+			return fmt.Errorf("field %s is encrypted but no key file is configured", field)
+		}
+
+		plaintext, err := c.keyring.Decrypt(sealed.KeyID, sealed.Data, []byte(field))
+		if err != nil {
+			return fmt.Errorf("failed to decrypt %s: %w", field, err)
+		}
+		var wrapper struct {
+			V bson.RawValue `bson:"v"`
+		}
+		if err := bson.Unmarshal(plaintext, &wrapper); err != nil {
+			return fmt.Errorf("failed to decode %s: %w", field, err)
+		}
+		doc[field] = wrapper.V
+	}
+	return nil
+}
+
+// document converte a empresa em documento pronto para inserção, sem _id
+func (c codec) document(company *domain.Company) (bson.M, error) {
+	doc, err := toDocument(company)
+	if err != nil {
+		return nil, err
+	}
+	return doc, c.seal(doc)
+}
+
+// fields cifra um conjunto de campos de atualização ($set)
+func (c codec) fields(fields bson.M) (bson.M, error) {
+	return fields, c.seal(fields)
+}
+
+// decode converte um documento da coleção em empresa
+func (c codec) decode(raw bson.Raw) (*domain.Company, error) {
+	var company domain.Company
+	if c.keyring == nil {
+		if err := bson.Unmarshal(raw, &company); err != nil {
+			return nil, err
+		}
+		return &company, nil
+	}
+
+	var doc bson.M
+	if err := bson.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	if err := c.open(doc); err != nil {
+		return nil, err
+	}
+	data, err := bson.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	if err := bson.Unmarshal(data, &company); err != nil {
+		return nil, err
+	}
+	return &company, nil
+}
+
+// decodeAll decodifica todos os documentos do cursor
+func (c codec) decodeAll(ctx context.Context, cursor *mongo.Cursor) ([]*domain.Company, error) {
+	var companies []*domain.Company
+	for cursor.Next(ctx) {
+		company, err := c.decode(cursor.Current)
+		if err != nil {
+			return nil, err
+		}
+		companies = append(companies, company)
+	}
+	return companies, cursor.Err()
+}
+
+// cnpjQuery monta a consulta por CNPJ exato. Com criptografia a busca usa o HMAC, aceitando
+// também documentos ainda em claro que não passaram pelo re-encrypt.
+func (c codec) cnpjQuery(tenantID, cnpj string) bson.M {
+	query := bson.M{"cnpj": cnpj}
+	if c.keyring != nil {
+		query = bson.M{"$or": bson.A{
+			bson.M{cnpjIndexField: c.keyring.BlindIndex(cnpj)},
+			bson.M{"cnpj": cnpj},
+		}}
+	}
+	if tenantID != "" {
+		query["tenant_id"] = tenantID
+	}
+	return query
+}
+
+// searchTokens substitui cada palavra do texto pelo seu blind index, truncado em 16 caracteres
+// hexadecimais. Os tokens são indexados pelo índice de texto como palavras comuns, o que mantém a
+// busca por palavras exatas do endereço sem gravá-lo em claro; como todo blind index, revelam
+// quais documentos compartilham uma mesma palavra.
+func (c codec) searchTokens(text string) string {
+	words := strings.Fields(utils.FoldText(text))
+	tokens := make([]string, 0, len(words))
+	seen := make(map[string]bool, len(words))
+	for _, word := range words {
+		if seen[word] {
+			continue
+		}
+		seen[word] = true
+		tokens = append(tokens, c.keyring.BlindIndex("search:" + word)[:16])
+	}
+	return strings.Join(tokens, " ")
+}
+
+// searchQuery monta os termos da busca textual. Com criptografia, os tokens das palavras são
+// acrescentados aos termos em claro, que continuam encontrando os nomes.
+func (c codec) searchQuery(query string) string {
+	folded := utils.FoldText(query)
+	if c.keyring == nil {
+		return folded
+	}
+	if tokens := c.searchTokens(folded); tokens != "" {
+		return folded + " " + tokens
+	}
+	return folded
+}
+
+// filterQuery converte o filtro em consulta. Com criptografia a lista de CNPJs exatos é
+// buscada pelo HMAC, aceitando também documentos ainda em claro.
+func (c codec) filterQuery(f repository.CompanyFilter) bson.M {
+	query := buildFilter(f)
+	if c.keyring == nil || f.CNPJs == nil {
+		return query
+	}
+
+	cnpjs := cleanCNPJs(f.CNPJs)
+	hmacs := make(bson.A, len(cnpjs))
+	for i, cnpj := range cnpjs {
+		hmacs[i] = c.keyring.BlindIndex(cnpj.(string))
+	}
+	delete(query, "cnpj")
+	query["$and"] = bson.A{bson.M{"$or": bson.A{
+		bson.M{cnpjIndexField: bson.M{"$in": hmacs}},
+		bson.M{"cnpj": bson.M{"$in": cnpjs}},
+	}}}
+	return query
+}
+
+// checkFilter rejeita filtros e ordenações sobre campos cifrados, que o banco não consegue avaliar
+func (c codec) checkFilter(f repository.CompanyFilter) error {
+	if c.keyring == nil {
+		return nil
+	}
+	if f.CNPJPrefix != "" || f.MinEmployeeCount != nil || f.MaxEmployeeCount != nil || f.RequiresPWD != nil {
+		return repository.ErrEncryptedField
+	}
+	for _, s := range f.Sort {
+		for _, field := range EncryptedFields {
+			if s.Field == field {
+				return fmt.Errorf("%w: %s", repository.ErrEncryptedField, field)
+			}
+		}
+	}
+	return nil
+}
+
+func isSealed(value interface{}) bool {
+	if _, ok := value.(sealedValue); ok {
+		return true
+	}
+	_, ok := asSealed(value)
+	return ok
+}
+
+// asSealed reconhece um campo cifrado lido do banco ({k, c})
+func asSealed(value interface{}) (sealedValue, bool) {
+	doc, ok := value.(bson.M)
+	if !ok {
+		return sealedValue{}, false
+	}
+	keyID, ok := doc["k"].(string)
+	if !ok {
+		return sealedValue{}, false
+	}
+	data, ok := doc["c"].(primitive.Binary)
+	if !ok {
+		return sealedValue{}, false
+	}
+	return sealedValue{KeyID: keyID, Data: data.Data}, true
+}
@@ -0,0 +1,159 @@
+package mongorepo
+
+import (
+	"company-service/internal/domain"
+	"company-service/internal/encryption"
+	"company-service/internal/repository"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func testKeyring(t *testing.T, active string) *encryption.Keyring {
+	t.Helper()
+	key := func(c string) string { return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(c, 32))) }
+	keyring, err := encryption.ParseKeyring([]byte(`{"active": "` + active + `", "keys": {"k1": "` + key("a") +
+		`", "k2": "` + key("b") + `"}, "index_key": "` + key("z") + `"}`))
+	require.NoError(t, err)
+	return keyring
+}
+
+func testCompany() *domain.Company {
+	company := &domain.Company{
+		ID:                          primitive.NewObjectID().Hex(),
+		TenantID:                    "acme",
+		CNPJ:                        "11444777000161",
+		FantasyName:                 "Empresa Teste",
+		CorporateName:               "Empresa Teste LTDA",
+		Address:                     "Rua das Flores, 100",
+		EmployeeCount:               150,
+		RequiredMinPWDEmployeeCount: 3,
+		CreatedAt:                   time.Now().UTC().Truncate(time.Millisecond),
+	}
+	company.BeforeUpdate()
+	company.UpdatedAt = company.UpdatedAt.UTC().Truncate(time.Millisecond)
+	return company
+}
+
+// stored simula a gravação e leitura do documento pelo MongoDB
+func stored(t *testing.T, doc bson.M, id string) bson.Raw {
+	t.Helper()
+	objectID, err := primitive.ObjectIDFromHex(id)
+	require.NoError(t, err)
+	doc["_id"] = objectID
+	raw, err := bson.Marshal(doc)
+	require.NoError(t, err)
+	return raw
+}
+
+func TestCodec_SealsSensitiveFieldsAndRoundTrips(t *testing.T) {
+	c := codec{keyring: testKeyring(t, "k1")}
+	company := testCompany()
+
+	doc, err := c.document(company)
+	require.NoError(t, err)
+
+	raw := stored(t, doc, company.ID)
+	for _, plaintext := range []string{company.CNPJ, company.Address, company.SearchAddress} {
+		assert.NotContains(t, string(raw), plaintext)
+	}
+	assert.Equal(t, c.keyring.BlindIndex(company.CNPJ), doc[cnpjIndexField])
+	assert.Equal(t, company.FantasyName, doc["fantasy_name"], "campos não sensíveis ficam em claro")
+
+	decoded, err := c.decode(raw)
+	require.NoError(t, err)
+	assert.Equal(t, company.CNPJ, decoded.CNPJ)
+	assert.Equal(t, company.Address, decoded.Address)
+	assert.Equal(t, company.EmployeeCount, decoded.EmployeeCount)
+	assert.Equal(t, company.RequiredMinPWDEmployeeCount, decoded.RequiredMinPWDEmployeeCount)
+	assert.Equal(t, company.TenantID, decoded.TenantID)
+}
+
+func TestCodec_DecodesPlaintextAndRotatedDocuments(t *testing.T) {
+	company := testCompany()
+
+	// Documento legado, gravado sem criptografia
+	plain, err := codec{}.document(company)
+	require.NoError(t, err)
+	decoded, err := codec{keyring: testKeyring(t, "k2")}.decode(stored(t, plain, company.ID))
+	require.NoError(t, err)
+	assert.Equal(t, company.Address, decoded.Address)
+
+	// Documento cifrado com a chave anterior continua legível após a rotação
+	old, err := codec{keyring: testKeyring(t, "k1")}.document(company)
+	require.NoError(t, err)
+	decoded, err = codec{keyring: testKeyring(t, "k2")}.decode(stored(t, old, company.ID))
+	require.NoError(t, err)
+	assert.Equal(t, company.EmployeeCount, decoded.EmployeeCount)
+
+	// Sem keyring, documentos cifrados não podem ser lidos
+	_, err = codec{}.decode(stored(t, old, company.ID))
+	assert.Error(t, err)
+}
+
+func TestCodec_CNPJQueryUsesBlindIndex(t *testing.T) {
+	c := codec{keyring: testKeyring(t, "k1")}
+
+	query := c.cnpjQuery("acme", "11444777000161")
+
+	assert.Equal(t, "acme", query["tenant_id"])
+	assert.Contains(t, query["$or"], bson.M{cnpjIndexField: c.keyring.BlindIndex("11444777000161")})
+	assert.Equal(t, bson.M{"cnpj": "11444777000161"}, codec{}.cnpjQuery("", "11444777000161"))
+}
+
+func TestCodec_FilterQueryMatchesIDAndCNPJLists(t *testing.T) {
+	id := primitive.NewObjectID()
+	filter := repository.CompanyFilter{IDs: []string{id.Hex(), "invalido"}, CNPJs: []string{"11.444.777/0001-61"}}
+
+	query := codec{}.filterQuery(filter)
+	assert.Equal(t, bson.M{"$in": bson.A{id}}, query["_id"])
+	assert.Equal(t, bson.M{"$in": bson.A{"11444777000161"}}, query["cnpj"])
+
+	c := codec{keyring: testKeyring(t, "k1")}
+	query = c.filterQuery(filter)
+	assert.NotContains(t, query, "cnpj")
+	assert.Equal(t, bson.A{bson.M{"$or": bson.A{
+		bson.M{cnpjIndexField: bson.M{"$in": bson.A{c.keyring.BlindIndex("11444777000161")}}},
+		bson.M{"cnpj": bson.M{"$in": bson.A{"11444777000161"}}},
+	}}}, query["$and"])
+
+	// Uma lista vazia não seleciona nenhuma empresa
+	assert.Equal(t, bson.M{"$in": bson.A{}}, codec{}.filterQuery(repository.CompanyFilter{IDs: []string{}})["_id"])
+}
+
+func TestCodec_CheckFilterRejectsEncryptedFields(t *testing.T) {
+	c := codec{keyring: testKeyring(t, "k1")}
+	minEmployees := 10
+
+	assert.NoError(t, c.checkFilter(repository.CompanyFilter{Name: "acme"}))
+	assert.ErrorIs(t, c.checkFilter(repository.CompanyFilter{CNPJPrefix: "114"}), repository.ErrEncryptedField)
+	assert.ErrorIs(t, c.checkFilter(repository.CompanyFilter{MinEmployeeCount: &minEmployees}), repository.ErrEncryptedField)
+	assert.ErrorIs(t, c.checkFilter(repository.CompanyFilter{Sort: []repository.SortField{{Field: "employee_count"}}}), repository.ErrEncryptedField)
+	assert.NoError(t, codec{}.checkFilter(repository.CompanyFilter{CNPJPrefix: "114"}))
+}
+
+func TestCodec_SearchTokensReplaceAddressWords(t *testing.T) {
+	c := codec{keyring: testKeyring(t, "k1")}
+	company := testCompany()
+
+	doc, err := c.document(company)
+	require.NoError(t, err)
+
+	tokens := strings.Fields(doc["search_address"].(string))
+	assert.Len(t, tokens, 4, "uma palavra por token: rua, das, flores, 100")
+	assert.NotContains(t, doc["search_address"], "flores")
+	assert.Equal(t, c.searchTokens("FLÔRES"), tokens[2], "tokens seguem a normalização da busca")
+
+	// A busca mantém os termos em claro, para os nomes, e acrescenta os tokens do endereço
+	query := c.searchQuery("Flores Teste")
+	assert.Equal(t, "flores teste "+c.searchTokens("flores teste"), query)
+	assert.Contains(t, query, tokens[2])
+
+	assert.Equal(t, "flores teste", codec{}.searchQuery("Flores Teste"), "sem criptografia a busca não muda")
+}
@@ -1,16 +1,35 @@
 package mongorepo
 
 import (
+	"company-service/internal/encryption"
 	"company-service/internal/repository"
 	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func NewCompanyRepository(db *mongo.Database, collectionName string, timeout time.Duration) repository.CompanyRepository {
-	return &mongoRepository{collection: db.Collection(collectionName), timeout: timeout}
+// Option configura recursos opcionais do repositório
+type Option func(*mongoRepository)
+
+// WithEncryption cifra em repouso os campos sensíveis (EncryptedFields) com o keyring informado
+func WithEncryption(keyring *encryption.Keyring) Option {
+	return func(r *mongoRepository) {
+		r.codec = codec{keyring: keyring}
+	}
+}
+
+func NewCompanyRepository(db *mongo.Database, collectionName string, timeout time.Duration, opts ...Option) repository.CompanyRepository {
+	return newRepository(db, collectionName, timeout, opts)
+}
+
+func NewCompanyRepositoryWithTimeout(db *mongo.Database, collectionName string, timeout time.Duration, opts ...Option) repository.CompanyRepository {
+	return newRepository(db, collectionName, timeout, opts)
 }
 
-func NewCompanyRepositoryWithTimeout(db *mongo.Database, collectionName string, timeout time.Duration) repository.CompanyRepository {
-	return &mongoRepository{collection: db.Collection(collectionName), timeout: timeout}
+func newRepository(db *mongo.Database, collectionName string, timeout time.Duration, opts []Option) *mongoRepository {
+	r := &mongoRepository{collection: db.Collection(collectionName), timeout: timeout}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
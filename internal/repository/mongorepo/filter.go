@@ -0,0 +1,102 @@
+package mongorepo
+
+import (
+	"company-service/internal/repository"
+	"company-service/pkg/utils"
+	"regexp"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// buildFilter converte o filtro tipado em uma consulta MongoDB
+func buildFilter(f repository.CompanyFilter) bson.M {
+	query := bson.M{}
+
+	// Listas informadas restringem a consulta mesmo que nenhum item seja válido
+	if f.IDs != nil {
+		ids := bson.A{}
+		for _, id := range f.IDs {
+			if objectID, err := primitive.ObjectIDFromHex(id); err == nil {
+				ids = append(ids, objectID)
+			}
+		}
+		query["_id"] = bson.M{"$in": ids}
+	}
+	if f.CNPJs != nil {
+		query["cnpj"] = bson.M{"$in": cleanCNPJs(f.CNPJs)}
+	}
+
+	if cnpj := utils.CleanCNPJ(f.CNPJPrefix); cnpj != "" {
+		query["cnpj"] = primitive.Regex{Pattern: "^" + regexp.QuoteMeta(cnpj)}
+	}
+
+	if f.TenantID != "" {
+		query["tenant_id"] = f.TenantID
+	}
+
+	if f.Name != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(f.Name), Options: "i"}
+		query["$or"] = bson.A{
+			bson.M{"fantasy_name": pattern},
+			bson.M{"corporate_name": pattern},
+		}
+	}
+
+	if rng := rangeFilter(f.MinEmployeeCount, f.MaxEmployeeCount); rng != nil {
+		query["employee_count"] = rng
+	}
+	if rng := rangeFilter(f.CreatedFrom, f.CreatedTo); rng != nil {
+		query["created_at"] = rng
+	}
+	if rng := rangeFilter(f.UpdatedFrom, f.UpdatedTo); rng != nil {
+		query["updated_at"] = rng
+	}
+
+	if f.RequiresPWD != nil {
+		if *f.RequiresPWD {
+			query["required_min_pwd_employee_count"] = bson.M{"$gt": 0}
+		} else {
+			query["required_min_pwd_employee_count"] = bson.M{"$lte": 0}
+		}
+	}
+
+	return query
+}
+
+// cleanCNPJs remove a formatação dos CNPJs informados
+func cleanCNPJs(cnpjs []string) bson.A {
+	cleaned := make(bson.A, 0, len(cnpjs))
+	for _, cnpj := range cnpjs {
+		cleaned = append(cleaned, utils.CleanCNPJ(cnpj))
+	}
+	return cleaned
+}
+
+// rangeFilter monta um intervalo fechado ($gte/$lte) ignorando limites nulos
+func rangeFilter[T any](from, to *T) bson.M {
+	if from == nil && to == nil {
+		return nil
+	}
+	rng := bson.M{}
+	if from != nil {
+		rng["$gte"] = *from
+	}
+	if to != nil {
+		rng["$lte"] = *to
+	}
+	return rng
+}
+
+// buildSort converte os critérios de ordenação, usando _id como desempate para paginação estável
+func buildSort(fields []repository.SortField) bson.D {
+	sort := make(bson.D, 0, len(fields)+1)
+	for _, s := range fields {
+		direction := 1
+		if s.Desc {
+			direction = -1
+		}
+		sort = append(sort, bson.E{Key: s.Field, Value: direction})
+	}
+	return append(sort, bson.E{Key: "_id", Value: 1})
+}
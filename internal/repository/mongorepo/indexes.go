@@ -0,0 +1,71 @@
+package mongorepo
+
+import (
+	"company-service/internal/repository"
+	"context"
+	"fmt"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// EnsureIndexes cria os índices usados pelos filtros e pela ordenação da listagem.
+// A criação é idempotente e pode ser executada a cada inicialização.
+func EnsureIndexes(ctx context.Context, db *mongo.Database, collectionName string) error {
+	fields := make([]string, 0, len(repository.SortableFields))
+	for field := range repository.SortableFields {
+		if field != "cnpj" {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+
+	// Todas as consultas são restritas ao tenant, por isso os índices começam por tenant_id
+	models := make([]mongo.IndexModel, 0, len(fields)+1)
+	for _, field := range fields {
+		models = append(models, mongo.IndexModel{
+			Keys:    bson.D{{Key: "tenant_id", Value: 1}, {Key: field, Value: 1}},
+			Options: options.Index().SetName("idx_tenant_" + field),
+		})
+	}
+
+	// Índice de texto da busca por nome/endereço: stemming e stopwords em português,
+	// com maior peso para os nomes na relevância
+	models = append(models, mongo.IndexModel{
+		Keys: bson.D{{Key: "search_name", Value: "text"}, {Key: "search_address", Value: "text"}},
+		Options: options.Index().
+			SetName("idx_search_text").
+			SetDefaultLanguage("portuguese").
+			SetWeights(bson.D{{Key: "search_name", Value: 10}, {Key: "search_address", Value: 2}}),
+	})
+
+	indexes := db.Collection(collectionName).Indexes()
+	if _, err := indexes.CreateMany(ctx, models); err != nil {
+		return fmt.Errorf("failed to create indexes: %w", err)
+	}
+
+	// CNPJ é único por tenant; criado à parte para que dados duplicados legados não
+	// impeçam a criação dos demais índices
+	_, err := indexes.CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "tenant_id", Value: 1}, {Key: "cnpj", Value: 1}},
+		Options: options.Index().SetName("uniq_tenant_cnpj").SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create unique CNPJ index: %w", err)
+	}
+
+	// Com criptografia em repouso a unicidade é garantida pelo HMAC do CNPJ
+	_, err = indexes.CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "tenant_id", Value: 1}, {Key: cnpjIndexField, Value: 1}},
+		Options: options.Index().
+			SetName("uniq_tenant_cnpj_hmac").
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{cnpjIndexField: bson.M{"$exists": true}}),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create unique CNPJ HMAC index: %w", err)
+	}
+	return nil
+}
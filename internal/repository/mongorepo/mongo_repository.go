@@ -2,6 +2,7 @@ package mongorepo
 
 import (
 	"company-service/internal/domain"
+	"company-service/internal/repository"
 	"context"
 	"errors"
 	"time"
@@ -15,6 +16,7 @@ import (
 type mongoRepository struct {
 	collection *mongo.Collection
 	timeout    time.Duration
+	codec      codec
 }
 
 // Cria uma nova empresa na base de dados
@@ -22,13 +24,24 @@ func (r *mongoRepository) Create(ctx context.Context, company *domain.Company) e
 	ctx, cancel := context.WithTimeout(ctx, r.timeout)
 	defer cancel()
 
+	if err := assignTenant(ctx, company); err != nil {
+		return err
+	}
 	company.BeforeCreate()
 
-	result, err := r.collection.InsertOne(ctx, company)
+	doc, err := r.codec.document(company)
 	if err != nil {
 		return err
 	}
 
+	result, err := r.collection.InsertOne(ctx, doc)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return repository.ErrDuplicateCNPJ
+		}
+		return err
+	}
+
 	// Define ID gerado pelo MongoDB
 	if oid, ok := result.InsertedID.(primitive.ObjectID); ok {
 		company.ID = oid.Hex()
@@ -46,15 +59,19 @@ func (r *mongoRepository) GetByID(ctx context.Context, id string) (*domain.Compa
 		return nil, errors.New("invalid company ID")
 	}
 
-	var company domain.Company
-	err = r.collection.FindOne(ctx, bson.M{"_id": objectID}).Decode(&company)
+	filter, err := scope(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := r.collection.FindOne(ctx, filter).Raw()
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
 		}
 		return nil, err
 	}
-	return &company, nil
+	return r.codec.decode(raw)
 }
 
 // GetByCNPJ busca uam empresa pelo CNPJ.
@@ -62,80 +79,105 @@ func (r *mongoRepository) GetByCNPJ(ctx context.Context, cnpj string) (*domain.C
 	ctx, cancel := context.WithTimeout(ctx, r.timeout)
 	defer cancel()
 
-	var company domain.Company
-	err := r.collection.FindOne(ctx, bson.M{"cnpj": cnpj}).Decode(&company)
+	filter, err := scope(ctx, r.codec.cnpjQuery("", cnpj))
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := r.collection.FindOne(ctx, filter).Raw()
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, nil
 		}
 		return nil, err
 	}
-	return &company, nil
+	return r.codec.decode(raw)
 }
 
-// Update atualiza uma empresa existente.
-func (r *mongoRepository) Update(ctx context.Context, company *domain.Company) (*domain.Company, error) {
+// Update atualiza uma empresa existente. O documento anterior é obtido na mesma operação
+// atômica da escrita, de modo que previous é exatamente o estado substituído.
+func (r *mongoRepository) Update(ctx context.Context, company *domain.Company) (*domain.Company, *domain.Company, error) {
 	ctx, cancel := context.WithTimeout(ctx, r.timeout)
 	defer cancel()
 
 	objectID, err := primitive.ObjectIDFromHex(company.ID)
 	if err != nil {
-		return nil, errors.New("invalid company ID")
+		return nil, nil, errors.New("invalid company ID")
+	}
+
+	filter, err := scope(ctx, bson.M{"_id": objectID})
+	if err != nil {
+		return nil, nil, err
 	}
 
 	company.BeforeUpdate()
 
-	filter := bson.M{"_id": objectID}
-	update := bson.M{
-		"$set": bson.M{
-			"cnpj":                            company.CNPJ,
-			"fantasy_name":                    company.FantasyName,
-			"corporate_name":                  company.CorporateName,
-			"address":                         company.Address,
-			"employee_count":                  company.EmployeeCount,
-			"required_min_pwd_employee_count": company.RequiredMinPWDEmployeeCount,
-			"updated_at":                      company.UpdatedAt,
-		},
+	fields, err := r.codec.fields(updateFields(company))
+	if err != nil {
+		return nil, nil, err
 	}
+	update := bson.M{"$set": fields, "$inc": bson.M{"sequence": 1}}
 
-	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
 
-	var updatedCompany domain.Company
-	err = r.collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updatedCompany)
+	raw, err := r.collection.FindOneAndUpdate(ctx, filter, update, opts).Raw()
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
-			return nil, errors.New("company not found")
+			return nil, nil, errors.New("company not found")
 		}
-		return nil, err
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, nil, repository.ErrDuplicateCNPJ
+		}
+		return nil, nil, err
 	}
 
-	return &updatedCompany, nil
+	previous, err := r.codec.decode(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	return applyUpdate(company, previous), previous, nil
+}
+
+// applyUpdate monta o estado gravado a partir dos campos atualizados e do documento anterior,
+// que fornece o que a atualização não altera (ID, tenant, criação) e a sequência incrementada
+func applyUpdate(company, previous *domain.Company) *domain.Company {
+	updated := *company
+	updated.ID = previous.ID
+	updated.TenantID = previous.TenantID
+	updated.CreatedAt = previous.CreatedAt
+	updated.Sequence = previous.Sequence + 1
+	return &updated
 }
 
-// Delete remove uma empresa pelo ID.
-func (r *mongoRepository) Delete(ctx context.Context, id string) error {
+// Delete remove uma empresa pelo ID. O documento removido é obtido na mesma operação atômica,
+// de modo que o evento de remoção parte exatamente do último estado gravado; retorna nil se a
+// empresa não existe.
+func (r *mongoRepository) Delete(ctx context.Context, id string) (*domain.Company, error) {
 	ctx, cancel := context.WithTimeout(ctx, r.timeout)
 	defer cancel()
 
 	objectID, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return errors.New("invalid company ID")
+		return nil, errors.New("invalid company ID")
 	}
 
-	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": objectID})
+	filter, err := scope(ctx, bson.M{"_id": objectID})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if result.DeletedCount == 0 {
-		return errors.New("company not found")
+	raw, err := r.collection.FindOneAndDelete(ctx, filter).Raw()
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
 	}
-
-	return nil
+	return r.codec.decode(raw)
 }
 
-// List lista empresas com paginação.
-func (r *mongoRepository) List(ctx context.Context, page int, limit int) ([]*domain.Company, error) {
+// List lista empresas com filtros, ordenação e paginação.
+func (r *mongoRepository) List(ctx context.Context, filter repository.CompanyFilter, page int, limit int) ([]*domain.Company, error) {
 	ctx, cancel := context.WithTimeout(ctx, r.timeout)
 	defer cancel()
 
@@ -146,31 +188,74 @@ func (r *mongoRepository) List(ctx context.Context, page int, limit int) ([]*dom
 		limit = 20
 	}
 
+	if err := r.codec.checkFilter(filter); err != nil {
+		return nil, err
+	}
+
 	skip := (page - 1) * limit
-	opts := options.Find().SetSkip(int64(skip)).SetSort(bson.D{{Key: "created_at", Value: -1}}) // prioridade de exibição para os inseridos mais recentes
+	opts := options.Find().
+		SetSkip(int64(skip)).
+		SetLimit(int64(limit)).
+		SetSort(buildSort(filter.SortOrDefault())) // padrão: prioridade de exibição para os inseridos mais recentes
 
-	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	query, err := scope(ctx, r.codec.filterQuery(filter))
 	if err != nil {
 		return nil, err
 	}
-	defer cursor.Close(ctx)
 
-	var companies []*domain.Company
-	for cursor.Next(ctx) {
-		var company domain.Company
-		if err := cursor.Decode(&company); err != nil {
-			return nil, err
-		}
-		companies = append(companies, &company)
+	cursor, err := r.collection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, err
 	}
+	defer cursor.Close(ctx)
 
-	return companies, nil
+	return r.codec.decodeAll(ctx, cursor)
 }
 
 // Count implements repository.CompanyRepository.
-func (r *mongoRepository) Count(ctx context.Context) (int64, error) {
+func (r *mongoRepository) Count(ctx context.Context, filter repository.CompanyFilter) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	if err := r.codec.checkFilter(filter); err != nil {
+		return 0, err
+	}
+
+	query, err := scope(ctx, r.codec.filterQuery(filter))
+	if err != nil {
+		return 0, err
+	}
+
+	return r.collection.CountDocuments(ctx, query)
+}
+
+// Search realiza busca textual (índice de texto em português) nos campos normalizados,
+// ordenando os resultados por relevância. Com criptografia, o endereço é encontrado pelos
+// tokens de busca de suas palavras.
+func (r *mongoRepository) Search(ctx context.Context, query string, limit int) ([]*domain.Company, error) {
 	ctx, cancel := context.WithTimeout(ctx, r.timeout)
 	defer cancel()
 
-	return r.collection.CountDocuments(ctx, bson.M{})
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	score := bson.M{"$meta": "textScore"}
+	opts := options.Find().
+		SetProjection(bson.M{"score": score}).
+		SetSort(bson.D{{Key: "score", Value: score}}).
+		SetLimit(int64(limit))
+
+	filter, err := scope(ctx, bson.M{"$text": bson.M{"$search": r.codec.searchQuery(query)}})
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	return r.codec.decodeAll(ctx, cursor)
 }
@@ -0,0 +1,87 @@
+package mongorepo
+
+import (
+	"company-service/internal/domain"
+	"company-service/internal/messaging"
+	"company-service/internal/tenant"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestUpdate_ReturnsStoredPreviousDocument(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	ctx := tenant.WithTenant(context.Background(), "acme")
+
+	mt.Run("previous comes from the write", func(mt *mtest.T) {
+		r := newRepository(mt.DB, "companies", time.Second, nil)
+		id := primitive.NewObjectID()
+		createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		mt.AddMockResponses(findAndModifyReply(storedCompany(id, "11222333000181", "Nome Gravado", createdAt, 7)))
+
+		updated, previous, err := r.Update(ctx, &domain.Company{ID: id.Hex(), CNPJ: "11222333000181", FantasyName: "Nome Novo"})
+		require.NoError(t, err)
+
+		assert.Equal(t, "Nome Gravado", previous.FantasyName)
+		assert.Equal(t, int64(7), previous.Sequence)
+		assert.Equal(t, "Nome Novo", updated.FantasyName)
+		assert.Equal(t, id.Hex(), updated.ID)
+		assert.Equal(t, "acme", updated.TenantID)
+		assert.Equal(t, int64(8), updated.Sequence)
+		assert.True(t, createdAt.Equal(updated.CreatedAt))
+
+		command := mt.GetStartedEvent().Command
+		assert.False(t, command.Lookup("new").Boolean())
+		assert.Equal(t, "acme", command.Lookup("query", "tenant_id").StringValue())
+	})
+
+	mt.Run("not found", func(mt *mtest.T) {
+		r := newRepository(mt.DB, "companies", time.Second, nil)
+		mt.AddMockResponses(findAndModifyReply(nil))
+
+		_, _, err := r.Update(ctx, &domain.Company{ID: primitive.NewObjectID().Hex(), CNPJ: "11222333000181"})
+
+		assert.EqualError(t, err, "company not found")
+	})
+}
+
+func TestDelete_ReturnsRemovedDocument(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	ctx := tenant.WithTenant(context.Background(), "acme")
+
+	mt.Run("removed document comes from the write", func(mt *mtest.T) {
+		r := newRepository(mt.DB, "companies", time.Second, nil)
+		id := primitive.NewObjectID()
+		mt.AddMockResponses(findAndModifyReply(storedCompany(id, "11222333000181", "Nome Gravado", time.Now().UTC(), 7)))
+
+		deleted, err := r.Delete(ctx, id.Hex())
+		require.NoError(t, err)
+		require.NotNil(t, deleted)
+		assert.Equal(t, id.Hex(), deleted.ID)
+		assert.Equal(t, "Nome Gravado", deleted.FantasyName)
+		assert.Equal(t, int64(7), deleted.Sequence)
+
+		command := mt.GetStartedEvent().Command
+		assert.True(t, command.Lookup("remove").Boolean())
+		assert.Equal(t, "acme", command.Lookup("query", "tenant_id").StringValue())
+
+		// O evento de remoção sucede a última escrita gravada
+		event := messaging.NewCompanyEvent(messaging.CompanyDeleted, deleted)
+		assert.Equal(t, int64(8), event.Sequence)
+		assert.Equal(t, messaging.EventID(messaging.CompanyDeleted, id.Hex(), 8), event.ID)
+	})
+
+	mt.Run("not found", func(mt *mtest.T) {
+		r := newRepository(mt.DB, "companies", time.Second, nil)
+		mt.AddMockResponses(findAndModifyReply(nil))
+
+		deleted, err := r.Delete(ctx, primitive.NewObjectID().Hex())
+		require.NoError(t, err)
+		assert.Nil(t, deleted)
+	})
+}
@@ -0,0 +1,93 @@
+package mongorepo
+
+import (
+	"company-service/internal/encryption"
+	"company-service/pkg/utils"
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ReEncryptResult resume uma execução do re-encrypt
+type ReEncryptResult struct {
+	Pending int64 // documentos com campos em claro, cifrados por chaves antigas ou sem tokens de busca
+	Updated int64 // documentos cifrados novamente com a chave ativa
+	Skipped int64 // documentos alterados durante a execução
+}
+
+// ReEncrypt cifra com a chave ativa os documentos que ainda têm campos sensíveis em claro
+// ou cifrados com chaves antigas, preenchendo também o HMAC do CNPJ e os tokens de busca do
+// endereço. Cada documento só é regravado se não tiver sido alterado desde a leitura; os
+// ignorados são processados numa nova execução. Com dryRun apenas conta os documentos pendentes.
+func ReEncrypt(ctx context.Context, db *mongo.Database, collectionName string, keyring *encryption.Keyring, dryRun bool) (ReEncryptResult, error) {
+	var result ReEncryptResult
+	coll := db.Collection(collectionName)
+	c := codec{keyring: keyring}
+
+	// Documentos sem os tokens de busca do endereço (gravados antes deles) também são regravados
+	pending := bson.A{
+		bson.M{cnpjIndexField: bson.M{"$exists": false}},
+		bson.M{"address": bson.M{"$exists": true}, "search_address": bson.M{"$in": bson.A{"", nil}}},
+	}
+	for _, field := range EncryptedFields {
+		pending = append(pending, bson.M{
+			field:        bson.M{"$exists": true},
+			field + ".k": bson.M{"$ne": keyring.ActiveKeyID()},
+		})
+	}
+	query := bson.M{"$or": pending}
+
+	count, err := coll.CountDocuments(ctx, query)
+	if err != nil {
+		return result, err
+	}
+	result.Pending = count
+	if dryRun || count == 0 {
+		return result, nil
+	}
+
+	cursor, err := coll.Find(ctx, query)
+	if err != nil {
+		return result, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		company, err := c.decode(cursor.Current)
+		if err != nil {
+			return result, err
+		}
+		objectID, err := primitive.ObjectIDFromHex(company.ID)
+		if err != nil {
+			return result, err
+		}
+
+		// Os campos cifrados com outra chave são decifrados por decode e cifrados novamente por seal
+		fields, err := c.fields(bson.M{
+			"cnpj":                            company.CNPJ,
+			"address":                         company.Address,
+			"employee_count":                  company.EmployeeCount,
+			"required_min_pwd_employee_count": company.RequiredMinPWDEmployeeCount,
+			"search_address":                  utils.FoldText(company.Address),
+		})
+		if err != nil {
+			return result, err
+		}
+
+		res, err := coll.UpdateOne(ctx,
+			bson.M{"_id": objectID, "updated_at": company.UpdatedAt},
+			bson.M{"$set": fields})
+		if err != nil {
+			return result, fmt.Errorf("failed to re-encrypt company %s: %w", company.ID, err)
+		}
+		if res.MatchedCount == 0 {
+			result.Skipped++
+			continue
+		}
+		result.Updated++
+	}
+	return result, cursor.Err()
+}
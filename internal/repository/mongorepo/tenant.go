@@ -0,0 +1,36 @@
+package mongorepo
+
+import (
+	"company-service/internal/domain"
+	"company-service/internal/tenant"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// scope restringe a consulta ao tenant do contexto. Contextos com acesso a todos os
+// tenants não recebem restrição; contextos sem tenant são rejeitados.
+func scope(ctx context.Context, query bson.M) (bson.M, error) {
+	if tenant.IsAllTenants(ctx) {
+		return query, nil
+	}
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return nil, tenant.ErrMissingTenant
+	}
+	query["tenant_id"] = tenantID
+	return query, nil
+}
+
+// assignTenant define o tenant de uma empresa a ser gravada: o do contexto ou, em contextos
+// com acesso a todos os tenants, o já informado na própria empresa
+func assignTenant(ctx context.Context, company *domain.Company) error {
+	if tenantID, ok := tenant.FromContext(ctx); ok && !tenant.IsAllTenants(ctx) {
+		company.TenantID = tenantID
+		return nil
+	}
+	if tenant.IsAllTenants(ctx) && company.TenantID != "" {
+		return nil
+	}
+	return tenant.ErrMissingTenant
+}
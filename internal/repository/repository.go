@@ -10,8 +10,13 @@ type CompanyRepository interface {
 	Create(ctx context.Context, company *domain.Company) error
 	GetByID(ctx context.Context, id string) (*domain.Company, error)
 	GetByCNPJ(ctx context.Context, cnpj string) (*domain.Company, error)
-	Update(ctx context.Context, company *domain.Company) (*domain.Company, error)
-	Delete(ctx context.Context, id string) error
-	List(ctx context.Context, page, limit int) ([]*domain.Company, error)
-	Count(ctx context.Context) (int64, error)
+	// Update retorna o estado gravado e o estado imediatamente anterior à atualização
+	Update(ctx context.Context, company *domain.Company) (updated, previous *domain.Company, err error)
+	// Delete retorna o documento removido, obtido na mesma operação, ou nil se não existia
+	Delete(ctx context.Context, id string) (*domain.Company, error)
+	List(ctx context.Context, filter CompanyFilter, page, limit int) ([]*domain.Company, error)
+	Count(ctx context.Context, filter CompanyFilter) (int64, error)
+	Search(ctx context.Context, query string, limit int) ([]*domain.Company, error)
+	CreateMany(ctx context.Context, companies []*domain.Company) ([]BulkResult, error)
+	UpsertManyByCNPJ(ctx context.Context, companies []*domain.Company) ([]BulkResult, error)
 }
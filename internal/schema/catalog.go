@@ -0,0 +1,73 @@
+package schema
+
+import (
+	"company-service/internal/dto"
+	"company-service/internal/messaging"
+	"reflect"
+	"sort"
+)
+
+// Document é um schema publicado em /schemas/<Name>.json. Input indica um payload recebido pelo
+// serviço; os demais (respostas e eventos) são produzidos por ele.
+type Document struct {
+	Name   string
+	Input  bool
+	Schema *Schema
+}
+
+// Path retorna o caminho em que o schema é servido, também usado como $id
+func (d Document) Path() string {
+	return "/schemas/" + d.Name + ".json"
+}
+
+// enums restringe os tipos nomeados aos valores publicados
+var enums = map[reflect.Type][]string{
+	reflect.TypeOf(messaging.EventType("")): eventTypeNames(),
+}
+
+// apiPayloads são os corpos de requisição (input) e de resposta da API
+var apiPayloads = []struct {
+	value interface{}
+	input bool
+}{
+	{dto.CreateCompanyRequest{}, true},
+	{dto.UpdateCompanyRequest{}, true},
+	{dto.CompanyResponse{}, false},
+	{dto.BatchCompaniesRequest{}, true},
+	{dto.BatchCompaniesResponse{}, false},
+	{dto.CreateWebhookRequest{}, true},
+	{dto.UpdateWebhookRequest{}, true},
+	{dto.WebhookResponse{}, false},
+}
+
+// All gera os schemas publicados, ordenados pelo nome: um por tipo de evento (events/<tipo>) e um
+// por payload da API (api/<tipo Go>)
+func All() []Document {
+	var docs []Document
+	for _, eventType := range messaging.EventTypes {
+		s := Generate(messaging.CompanyEvent{}, false, enums)
+		s.Title = string(eventType)
+		s.Description = "CloudEvent " + string(eventType) + " publicado no broker e nos webhooks"
+		s.Properties["type"] = &Schema{Type: "string", Const: string(eventType)}
+		s.Properties["specversion"] = &Schema{Type: "string", Const: messaging.SpecVersion}
+		docs = append(docs, Document{Name: "events/" + string(eventType), Schema: s})
+	}
+	for _, payload := range apiPayloads {
+		s := Generate(payload.value, payload.input, enums)
+		docs = append(docs, Document{Name: "api/" + s.Title, Input: payload.input, Schema: s})
+	}
+
+	for _, doc := range docs {
+		doc.Schema.ID = doc.Path()
+	}
+	sort.Slice(docs, func(i, j int) bool { return docs[i].Name < docs[j].Name })
+	return docs
+}
+
+func eventTypeNames() []string {
+	names := make([]string, len(messaging.EventTypes))
+	for i, eventType := range messaging.EventTypes {
+		names[i] = string(eventType)
+	}
+	return names
+}
@@ -0,0 +1,148 @@
+package schema
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Incompatibility é uma alteração do schema que quebra quem usa a versão anterior
+type Incompatibility struct {
+	Path   string // caminho JSON do campo alterado (ex.: data.employee_count)
+	Reason string
+}
+
+func (i Incompatibility) String() string {
+	return i.Path + ": " + i.Reason
+}
+
+// Compare lista as alterações incompatíveis entre a versão anterior e a atual de um schema.
+// Em qualquer payload, remover um campo ou mudar seu tipo, formato ou valor fixo é
+// incompatível. Em payloads produzidos pelo serviço (respostas e eventos), um campo obrigatório
+// não pode passar a opcional, pois os consumidores contam com ele; em payloads de entrada
+// (input), nenhum campo pode passar a obrigatório nem deixar de aceitar um valor.
+func Compare(previous, current *Schema, input bool) []Incompatibility {
+	c := &comparison{
+		previousRoot: previous,
+		currentRoot:  current,
+		input:        input,
+		visited:      map[string]bool{},
+	}
+	c.compare("$", previous, current)
+	sort.Slice(c.found, func(i, j int) bool { return c.found[i].String() < c.found[j].String() })
+	return c.found
+}
+
+type comparison struct {
+	previousRoot, currentRoot *Schema
+	input                     bool
+	visited                   map[string]bool // pares de $ref já comparados, para tipos recursivos
+	found                     []Incompatibility
+}
+
+func (c *comparison) report(path, format string, args ...interface{}) {
+	c.found = append(c.found, Incompatibility{Path: path, Reason: fmt.Sprintf(format, args...)})
+}
+
+func (c *comparison) compare(path string, previous, current *Schema) {
+	if previous.Ref != "" || current.Ref != "" {
+		key := previous.Ref + "|" + current.Ref
+		if c.visited[key] {
+			return
+		}
+		c.visited[key] = true
+	}
+	previous = resolve(c.previousRoot, previous)
+	current = resolve(c.currentRoot, current)
+	if previous == nil || current == nil {
+		c.report(path, "unresolvable $ref")
+		return
+	}
+
+	if previous.Type != "" && current.Type != previous.Type {
+		c.report(path, "type changed from %s to %s", previous.Type, typeName(current.Type))
+		return
+	}
+	if current.Format != previous.Format {
+		c.report(path, "format changed from %q to %q", previous.Format, current.Format)
+	}
+	if current.Const != previous.Const {
+		c.report(path, "const changed from %q to %q", previous.Const, current.Const)
+	}
+	if c.input && len(current.Enum) > 0 {
+		for _, value := range previous.Enum {
+			if !slices.Contains(current.Enum, value) {
+				c.report(path, "value %q is no longer accepted", value)
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(previous.Properties) {
+		property := join(path, name)
+		next, ok := current.Properties[name]
+		if !ok {
+			c.report(property, "field removed")
+			continue
+		}
+		wasRequired, isRequired := slices.Contains(previous.Required, name), slices.Contains(current.Required, name)
+		if !c.input && wasRequired && !isRequired {
+			c.report(property, "field is no longer required")
+		}
+		if c.input && !wasRequired && isRequired {
+			c.report(property, "field became required")
+		}
+		c.compare(property, previous.Properties[name], next)
+	}
+	if c.input {
+		for _, name := range current.Required {
+			if _, existed := previous.Properties[name]; !existed {
+				c.report(join(path, name), "new required field")
+			}
+		}
+	}
+
+	if previous.Items != nil && current.Items != nil {
+		c.compare(path+"[]", previous.Items, current.Items)
+	}
+	if previous.AdditionalProperties != nil && current.AdditionalProperties != nil {
+		c.compare(path+"{}", previous.AdditionalProperties, current.AdditionalProperties)
+	}
+}
+
+// resolve segue o $ref do subschema dentro do documento raiz
+func resolve(root, s *Schema) *Schema {
+	switch {
+	case s.Ref == "":
+		return s
+	case s.Ref == "#":
+		return root
+	case strings.HasPrefix(s.Ref, "#/$defs/"):
+		return root.Defs[strings.TrimPrefix(s.Ref, "#/$defs/")]
+	default:
+		return nil
+	}
+}
+
+func join(path, name string) string {
+	if path == "$" {
+		return name
+	}
+	return path + "." + name
+}
+
+func typeName(t string) string {
+	if t == "" {
+		return "any"
+	}
+	return t
+}
+
+func sortedKeys(properties map[string]*Schema) []string {
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
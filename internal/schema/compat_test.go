@@ -0,0 +1,136 @@
+package schema
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// release grava os schemas atuais como a versão publicada, após a verificação de compatibilidade:
+//
+//	go test ./internal/schema -run TestSchemas_BackwardCompatible -release
+var release = flag.Bool("release", false, "record the current schemas as the released version")
+
+const releasedDir = "testdata/released"
+
+func object(required []string, properties map[string]*Schema) *Schema {
+	return &Schema{Type: "object", Properties: properties, Required: required}
+}
+
+func reasons(found []Incompatibility) []string {
+	var list []string
+	for _, incompatibility := range found {
+		list = append(list, incompatibility.String())
+	}
+	return list
+}
+
+func TestCompare_CompatibleChanges(t *testing.T) {
+	previous := object([]string{"id"}, map[string]*Schema{
+		"id":   {Type: "string"},
+		"note": {Type: "string"},
+	})
+	current := object([]string{"id", "note"}, map[string]*Schema{
+		"id":    {Type: "string"},
+		"note":  {Type: "string"},
+		"extra": {Type: "integer"},
+	})
+
+	assert.Empty(t, Compare(previous, current, false), "campos novos e campos que passam a obrigatórios não quebram consumidores")
+}
+
+func TestCompare_OutputIncompatibilities(t *testing.T) {
+	previous := object([]string{"id", "count", "at"}, map[string]*Schema{
+		"id":    {Type: "string"},
+		"count": {Type: "integer"},
+		"at":    {Type: "string", Format: "date-time"},
+		"data":  {Ref: "#/$defs/data"},
+	})
+	previous.Defs = map[string]*Schema{"data": object(nil, map[string]*Schema{
+		"name":   {Type: "string"},
+		"parent": {Ref: "#/$defs/data"},
+	})}
+
+	current := object([]string{"id", "count"}, map[string]*Schema{
+		"count": {Type: "string"},
+		"at":    {Type: "string"},
+		"data":  {Ref: "#/$defs/data"},
+	})
+	current.Defs = map[string]*Schema{"data": object(nil, map[string]*Schema{
+		"parent": {Ref: "#/$defs/data"},
+	})}
+
+	assert.Equal(t, []string{
+		`at: field is no longer required`,
+		`at: format changed from "date-time" to ""`,
+		`count: type changed from integer to string`,
+		`data.name: field removed`,
+		`id: field removed`,
+	}, reasons(Compare(previous, current, false)))
+}
+
+func TestCompare_InputIncompatibilities(t *testing.T) {
+	previous := object([]string{"url"}, map[string]*Schema{
+		"url":   {Type: "string"},
+		"mode":  {Type: "string"},
+		"types": {Type: "array", Items: &Schema{Type: "string", Enum: []string{"a", "b"}}},
+	})
+	current := object([]string{"url", "mode", "secret"}, map[string]*Schema{
+		"url":    {Type: "string"},
+		"mode":   {Type: "string"},
+		"secret": {Type: "string"},
+		"types":  {Type: "array", Items: &Schema{Type: "string", Enum: []string{"a", "c"}}},
+	})
+
+	assert.Equal(t, []string{
+		`mode: field became required`,
+		`secret: new required field`,
+		`types[]: value "b" is no longer accepted`,
+	}, reasons(Compare(previous, current, true)))
+}
+
+// TestSchemas_BackwardCompatible compara os schemas atuais com a última versão publicada, em
+// testdata/released. Uma alteração incompatível exige um novo tipo de evento (ex.: .v2) ou uma
+// nova versão da API, em vez de alterar o schema publicado.
+func TestSchemas_BackwardCompatible(t *testing.T) {
+	current := map[string]Document{}
+	for _, doc := range All() {
+		current[doc.Name] = doc
+	}
+
+	released, err := filepath.Glob(filepath.Join(releasedDir, "*", "*.json"))
+	require.NoError(t, err)
+	for _, path := range released {
+		rel, err := filepath.Rel(releasedDir, path)
+		require.NoError(t, err)
+		name := filepath.ToSlash(rel[:len(rel)-len(".json")])
+
+		body, err := os.ReadFile(path)
+		require.NoError(t, err)
+		var previous Schema
+		require.NoError(t, json.Unmarshal(body, &previous))
+
+		doc, ok := current[name]
+		if !assert.True(t, ok, "schema %s was released and can not be removed", name) {
+			continue
+		}
+		for _, incompatibility := range Compare(&previous, doc.Schema, doc.Input) {
+			t.Errorf("%s: incompatible change: %s", name, incompatibility)
+		}
+	}
+
+	if *release && !t.Failed() {
+		for _, doc := range All() {
+			path := filepath.Join(releasedDir, filepath.FromSlash(doc.Name)+".json")
+			body, err := json.MarshalIndent(doc.Schema, "", "  ")
+			require.NoError(t, err)
+			require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+			require.NoError(t, os.WriteFile(path, append(body, '\n'), 0o644))
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Draft é a versão da especificação JSON Schema dos documentos gerados
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema é um documento (ou subschema) JSON Schema, com as palavras-chave usadas pelo gerador
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	ID                   string             `json:"$id,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Title                string             `json:"title,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Const                string             `json:"const,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+// generator converte tipos Go em schemas seguindo as regras do encoding/json. Structs nomeadas
+// aninhadas vão para $defs; referências ao próprio tipo raiz usam "#".
+type generator struct {
+	root  reflect.Type
+	input bool
+	enums map[reflect.Type][]string
+	defs  map[string]*Schema
+}
+
+// Generate gera o schema do tipo de v. Em payloads de entrada (input), os campos obrigatórios são
+// os marcados com validate:"required"; nos de saída, todos os campos sem omitempty, que estão
+// sempre presentes. enums restringe os valores de tipos nomeados (ex.: messaging.EventType).
+func Generate(v interface{}, input bool, enums map[reflect.Type][]string) *Schema {
+	t := indirect(reflect.TypeOf(v))
+	g := &generator{root: t, input: input, enums: enums, defs: map[string]*Schema{}}
+
+	s := g.structSchema(t)
+	s.Schema = Draft
+	s.Title = t.Name()
+	if len(g.defs) > 0 {
+		s.Defs = g.defs
+	}
+	return s
+}
+
+func (g *generator) schemaOf(t reflect.Type) *Schema {
+	t = indirect(t)
+	if values, ok := g.enums[t]; ok {
+		return &Schema{Type: "string", Enum: values}
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawMessageType:
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"} // []byte é codificado em base64
+		}
+		return &Schema{Type: "array", Items: g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t == g.root {
+			return &Schema{Ref: "#"}
+		}
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		if _, ok := g.defs[t.Name()]; !ok {
+			g.defs[t.Name()] = nil // reserva o nome antes de gerar, para tipos recursivos
+			g.defs[t.Name()] = g.structSchema(t)
+		}
+		return &Schema{Ref: "#/$defs/" + t.Name()}
+	default:
+		// interface{} e demais tipos aceitam qualquer valor
+		return &Schema{}
+	}
+}
+
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, omitempty, skip := jsonName(field)
+		if skip {
+			continue
+		}
+		// Structs embutidas sem nome no JSON têm os campos promovidos, como no encoding/json
+		if field.Anonymous && field.Tag.Get("json") == "" && indirect(field.Type).Kind() == reflect.Struct {
+			embedded := g.structSchema(indirect(field.Type))
+			for property, schema := range embedded.Properties {
+				s.Properties[property] = schema
+			}
+			s.Required = append(s.Required, embedded.Required...)
+			continue
+		}
+		s.Properties[name] = g.schemaOf(field.Type)
+		if g.required(field, omitempty) {
+			s.Required = append(s.Required, name)
+		}
+	}
+	return s
+}
+
+func (g *generator) required(field reflect.StructField, omitempty bool) bool {
+	if g.input {
+		rules := strings.Split(field.Tag.Get("validate"), ",")
+		for _, rule := range rules {
+			if rule == "required" {
+				return true
+			}
+		}
+		return false
+	}
+	return !omitempty
+}
+
+// jsonName retorna o nome do campo no JSON e se ele é omitido quando vazio ou sempre ignorado
+func jsonName(field reflect.StructField) (name string, omitempty, skip bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	for _, option := range parts[1:] {
+		if option == "omitempty" {
+			omitempty = true
+		}
+	}
+	return name, omitempty, false
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
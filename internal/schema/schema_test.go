@@ -0,0 +1,97 @@
+package schema
+
+import (
+	"company-service/internal/messaging"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type node struct {
+	Name     string            `json:"name" validate:"required"`
+	Tags     []string          `json:"tags,omitempty"`
+	Parent   *node             `json:"parent,omitempty"`
+	Meta     map[string]int    `json:"meta"`
+	Child    leaf              `json:"child"`
+	At       time.Time         `json:"at"`
+	Raw      json.RawMessage   `json:"raw,omitempty"`
+	Internal string            `json:"-"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	hidden   string
+}
+
+type leaf struct {
+	Weight float64 `json:"weight"`
+	Active bool    `json:"active,omitempty"`
+}
+
+func TestGenerate_FollowsJSONEncoding(t *testing.T) {
+	s := Generate(node{}, false, nil)
+
+	assert.Equal(t, Draft, s.Schema)
+	assert.Equal(t, "node", s.Title)
+	assert.ElementsMatch(t, []string{"name", "tags", "parent", "meta", "child", "at", "raw", "labels"}, keys(s.Properties))
+	assert.Equal(t, []string{"name", "meta", "child", "at"}, s.Required, "campos sem omitempty estão sempre presentes")
+
+	assert.Equal(t, &Schema{Type: "array", Items: &Schema{Type: "string"}}, s.Properties["tags"])
+	assert.Equal(t, &Schema{Ref: "#"}, s.Properties["parent"])
+	assert.Equal(t, &Schema{Type: "object", AdditionalProperties: &Schema{Type: "integer"}}, s.Properties["meta"])
+	assert.Equal(t, &Schema{Type: "string", Format: "date-time"}, s.Properties["at"])
+	assert.Equal(t, &Schema{}, s.Properties["raw"])
+
+	assert.Equal(t, &Schema{Ref: "#/$defs/leaf"}, s.Properties["child"])
+	require.Contains(t, s.Defs, "leaf")
+	assert.Equal(t, []string{"weight"}, s.Defs["leaf"].Required)
+	assert.Equal(t, &Schema{Type: "number"}, s.Defs["leaf"].Properties["weight"])
+}
+
+func TestGenerate_InputRequiresValidatedFields(t *testing.T) {
+	s := Generate(node{}, true, nil)
+	assert.Equal(t, []string{"name"}, s.Required)
+}
+
+func TestAll_PublishesEventsAndAPIPayloads(t *testing.T) {
+	docs := All()
+
+	names := make([]string, len(docs))
+	for i, doc := range docs {
+		names[i] = doc.Name
+		assert.Equal(t, "/schemas/"+doc.Name+".json", doc.Schema.ID)
+	}
+	for _, eventType := range messaging.EventTypes {
+		assert.Contains(t, names, "events/"+string(eventType))
+	}
+	assert.Contains(t, names, "api/CreateCompanyRequest")
+	assert.Contains(t, names, "api/CompanyResponse")
+
+	doc, ok := find(docs, "events/br.company.deleted.v1")
+	require.True(t, ok)
+	assert.Equal(t, "br.company.deleted.v1", doc.Schema.Properties["type"].Const)
+	assert.Equal(t, &Schema{Ref: "#/$defs/CompanyEventData"}, doc.Schema.Defs["CompanyEventData"].Properties["previous"])
+
+	webhook, ok := find(docs, "api/CreateWebhookRequest")
+	require.True(t, ok)
+	assert.True(t, webhook.Input)
+	assert.Equal(t, []string{"url"}, webhook.Schema.Required)
+	assert.Len(t, webhook.Schema.Properties["event_types"].Items.Enum, len(messaging.EventTypes))
+}
+
+func keys(properties map[string]*Schema) []string {
+	var names []string
+	for name := range properties {
+		names = append(names, name)
+	}
+	return names
+}
+
+func find(docs []Document, name string) (Document, bool) {
+	for _, doc := range docs {
+		if doc.Name == name {
+			return doc, true
+		}
+	}
+	return Document{}, false
+}
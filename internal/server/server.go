@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"os"
 	"os/signal"
@@ -19,19 +20,124 @@ type Server struct {
 	router *mux.Router
 	logger *zap.Logger
 	cfg    *config.Config
+
+	// deadline é o prazo do encerramento, definido quando o sinal chega
+	deadline time.Time
+}
+
+// Routes agrupa os roteadores nos quais componentes opcionais registram suas rotas
+type Routes struct {
+	Root      *mux.Router // rotas públicas
+	Companies *mux.Router // rotas sob /companies, com tenant obrigatório
+	Admin     *mux.Router // rotas sob /admin, restritas a administradores
+
+	WithTenant mux.MiddlewareFunc // exige e resolve o tenant, para rotas fora de /companies
+}
+
+// Option registra rotas de componentes opcionais no servidor
+type Option func(routes *Routes)
+
+// WithCacheHandler expõe as estatísticas do cache de leitura
+func WithCacheHandler(cacheHandler *handler.CacheHandler) Option {
+	return func(routes *Routes) {
+		routes.Admin.HandleFunc("/cache/stats", cacheHandler.StatsHandler).Methods("GET")
+	}
+}
+
+// WithMessagingHandler expõe as estatísticas de publicação de eventos e o estado do broker
+func WithMessagingHandler(messagingHandler *handler.MessagingHandler) Option {
+	return func(routes *Routes) {
+		routes.Admin.HandleFunc("/messaging/stats", messagingHandler.StatsHandler).Methods("GET")
+		routes.Admin.HandleFunc("/messaging/health", messagingHandler.HealthHandler).Methods("GET")
+	}
+}
+
+// WithSinksHandler expõe o estado de cada destino do fan-out de eventos
+func WithSinksHandler(sinksHandler *handler.MessagingHandler) Option {
+	return func(routes *Routes) {
+		routes.Admin.HandleFunc("/messaging/sinks", sinksHandler.HealthHandler).Methods("GET")
+	}
 }
 
-func NewServer(companyHandler *handler.CompanyHandler, logger *zap.Logger, cfg *config.Config) *Server {
+// WithDispatchHandler expõe a fila e os contadores do dispatcher de eventos
+func WithDispatchHandler(dispatchHandler *handler.DispatchHandler) Option {
+	return func(routes *Routes) {
+		routes.Admin.HandleFunc("/messaging/dispatch", dispatchHandler.StatsHandler).Methods("GET")
+	}
+}
+
+// WithSchemaHandler publica os JSON Schemas dos eventos e dos payloads da API, sem autenticação
+func WithSchemaHandler(schemaHandler *handler.SchemaHandler) Option {
+	return func(routes *Routes) {
+		routes.Root.HandleFunc("/schemas", schemaHandler.ListHandler).Methods("GET")
+		routes.Root.HandleFunc("/schemas/{group}/{name}", schemaHandler.GetHandler).Methods("GET")
+	}
+}
+
+// WithSpoolHandler expõe a inspeção e o descarte dos eventos no spool local
+func WithSpoolHandler(spoolHandler *handler.SpoolHandler) Option {
+	return func(routes *Routes) {
+		routes.Admin.HandleFunc("/spool", spoolHandler.ListHandler).Methods("GET")
+		routes.Admin.HandleFunc("/spool", spoolHandler.PurgeHandler).Methods("DELETE")
+		routes.Admin.HandleFunc("/spool/{seq:[0-9]+}", spoolHandler.DiscardHandler).Methods("DELETE")
+	}
+}
+
+// WithReplayHandler expõe o replay dos eventos de empresas
+func WithReplayHandler(replayHandler *handler.ReplayHandler) Option {
+	return func(routes *Routes) {
+		routes.Admin.HandleFunc("/events/replay", replayHandler.StartHandler).Methods("POST")
+		routes.Admin.HandleFunc("/events/replay", replayHandler.ListHandler).Methods("GET")
+		routes.Admin.HandleFunc("/events/replay/{id}", replayHandler.GetHandler).Methods("GET")
+		routes.Admin.HandleFunc("/events/replay/{id}", replayHandler.CancelHandler).Methods("DELETE")
+	}
+}
+
+// WithWebhookHandler expõe o cadastro de webhooks e o log de entregas, restritos ao tenant
+func WithWebhookHandler(webhookHandler *handler.WebhookHandler) Option {
+	return func(routes *Routes) {
+		webhooks := routes.Root.PathPrefix("/webhooks").Subrouter()
+		webhooks.Use(routes.WithTenant)
+		webhooks.HandleFunc("", webhookHandler.CreateHandler).Methods("POST")
+		webhooks.HandleFunc("", webhookHandler.ListHandler).Methods("GET")
+		webhooks.HandleFunc("/{id}", webhookHandler.GetHandler).Methods("GET")
+		webhooks.HandleFunc("/{id}", webhookHandler.UpdateHandler).Methods("PUT")
+		webhooks.HandleFunc("/{id}", webhookHandler.DeleteHandler).Methods("DELETE")
+		webhooks.HandleFunc("/{id}/deliveries", webhookHandler.ListDeliveriesHandler).Methods("GET")
+		webhooks.HandleFunc("/{id}/deliveries/{delivery_id}", webhookHandler.GetDeliveryHandler).Methods("GET")
+	}
+}
+
+func NewServer(companyHandler *handler.CompanyHandler, logger *zap.Logger, cfg *config.Config, opts ...Option) *Server {
 	router := mux.NewRouter()
 
-	// Configurar rotas
-	router.HandleFunc("/companies", companyHandler.CreateCompanyHandler).Methods("POST")
-	router.HandleFunc("/companies/{id}", companyHandler.GetCompanyHandler).Methods("GET")
-	router.HandleFunc("/companies/{id}", companyHandler.UpdateCompanyHandler).Methods("PUT")
-	router.HandleFunc("/companies/{id}", companyHandler.DeleteCompanyHandler).Methods("DELETE")
-	router.HandleFunc("/companies", companyHandler.ListCompaniesHandler).Methods("GET")
+	// Rotas de empresas, sempre restritas ao tenant da requisição. O lote fica fora do
+	// subrouter porque "/companies:batch" não é um subcaminho de "/companies".
+	withTenant := tenantMiddleware(cfg, logger)
+	router.Handle("/companies:batch", withTenant(http.HandlerFunc(companyHandler.BatchCompaniesHandler))).Methods("POST")
+
+	companies := router.PathPrefix("/companies").Subrouter()
+	companies.Use(withTenant)
+	companies.HandleFunc("", companyHandler.CreateCompanyHandler).Methods("POST")
+	companies.HandleFunc("/search", companyHandler.SearchCompaniesHandler).Methods("GET")
+	companies.HandleFunc("/autocomplete", companyHandler.AutocompleteCompaniesHandler).Methods("GET")
+	companies.HandleFunc("/{id}", companyHandler.GetCompanyHandler).Methods("GET")
+	companies.HandleFunc("/{id}", companyHandler.UpdateCompanyHandler).Methods("PUT")
+	companies.HandleFunc("/{id}", companyHandler.DeleteCompanyHandler).Methods("DELETE")
+	companies.HandleFunc("", companyHandler.ListCompaniesHandler).Methods("GET")
+
+	// Rotas administrativas
+	admin := router.PathPrefix("/admin").Subrouter()
+	admin.Use(adminMiddleware(cfg, logger))
+	admin.HandleFunc("/companies", companyHandler.ListAllCompaniesHandler).Methods("GET")
+
 	router.HandleFunc("/health", companyHandler.HealthCheckHandler).Methods("GET")
 
+	routes := &Routes{Root: router, Companies: companies, Admin: admin, WithTenant: withTenant}
+	for _, opt := range opts {
+		opt(routes)
+	}
+
 	// Middleware para logging
 	router.Use(loggingMiddleware(logger))
 
@@ -42,25 +148,30 @@ func NewServer(companyHandler *handler.CompanyHandler, logger *zap.Logger, cfg *
 	}
 }
 
+// Start atende as requisições até receber SIGINT ou SIGTERM; no encerramento aguarda as requisições
+// em andamento (até SHUTDOWN_TIMEOUT) e retorna nil. O prazo do encerramento, contado a partir
+// do sinal, fica disponível em ShutdownDeadline para as demais etapas.
 func (s *Server) Start() error {
 	server := &http.Server{
 		Addr:         ":" + s.cfg.ServerPort,
 		Handler:      s.router,
-		ReadTimeout:  parseDuration(s.cfg.ReadTimeout, 5*time.Second),
-		WriteTimeout: parseDuration(s.cfg.WriteTimeout, 10*time.Second),
-		IdleTimeout:  parseDuration(s.cfg.IdleTimeout, 60*time.Second),
+		ReadTimeout:  config.ParseDuration(s.cfg.ReadTimeout, 5*time.Second),
+		WriteTimeout: config.ParseDuration(s.cfg.WriteTimeout, 10*time.Second),
+		IdleTimeout:  config.ParseDuration(s.cfg.IdleTimeout, 60*time.Second),
 	}
 
 	// Canal para shutdown graceful
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 
+	stopped := make(chan struct{})
 	go func() {
+		defer close(stopped)
 		<-stop
 		s.logger.Info("Shutting down server gracefully...")
 
-		ctx, cancel := context.WithTimeout(context.Background(),
-			parseDuration(s.cfg.ShutdownTimeout, 10*time.Second))
+		s.deadline = time.Now().Add(config.ParseDuration(s.cfg.ShutdownTimeout, 10*time.Second))
+		ctx, cancel := context.WithDeadline(context.Background(), s.deadline)
 		defer cancel()
 
 		if err := server.Shutdown(ctx); err != nil {
@@ -69,14 +180,20 @@ func (s *Server) Start() error {
 	}()
 
 	s.logger.Info("Server starting", zap.String("address", server.Addr))
-	return server.ListenAndServe()
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	// Encerramento por sinal: retorna após as requisições em andamento terminarem
+	<-stopped
+	return nil
 }
 
-func parseDuration(durationStr string, defaultDuration time.Duration) time.Duration {
-	if duration, err := time.ParseDuration(durationStr); err == nil {
-		return duration
-	}
-	return defaultDuration
+// ShutdownDeadline retorna o prazo do encerramento, definido pelo sinal recebido por Start. O
+// consumidor de comandos, o dispatcher e o fan-out usam o mesmo prazo, de modo que o
+// encerramento completo respeita SHUTDOWN_TIMEOUT. Deve ser chamado após Start retornar.
+func (s *Server) ShutdownDeadline() time.Time {
+	return s.deadline
 }
 
 // loggingMiddleware adiciona logging para todas as requests
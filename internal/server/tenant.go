@@ -0,0 +1,111 @@
+package server
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"company-service/internal/config"
+	"company-service/internal/tenant"
+
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// identity é o resultado da autenticação de uma requisição
+type identity struct {
+	tenant string
+	admin  bool
+}
+
+// resolveIdentity extrai o tenant e o papel de administrador da requisição. Com JWT_SECRET
+// configurado, o tenant vem exclusivamente do Bearer token, e requisições sem ele são rejeitadas,
+// exceto as autenticadas pela chave administrativa (cabeçalho X-Admin-Key). O cabeçalho de
+// tenant e o tenant padrão só são usados com JWT desabilitado ou pela chave administrativa.
+func resolveIdentity(r *http.Request, cfg *config.Config) (identity, error) {
+	var id identity
+
+	if cfg.AdminAPIKey != "" {
+		key := r.Header.Get("X-Admin-Key")
+		if key != "" && subtle.ConstantTimeCompare([]byte(key), []byte(cfg.AdminAPIKey)) == 1 {
+			id.admin = true
+		}
+	}
+
+	if cfg.JWTSecret != "" {
+		auth := r.Header.Get("Authorization")
+		if strings.HasPrefix(auth, "Bearer ") {
+			claims, err := tenant.ParseToken(strings.TrimPrefix(auth, "Bearer "), []byte(cfg.JWTSecret), cfg.TenantClaim)
+			if err != nil {
+				return identity{}, err
+			}
+			id.tenant = claims.Tenant
+			id.admin = id.admin || claims.Admin
+			return id, nil
+		}
+		if auth != "" {
+			return identity{}, tenant.ErrInvalidToken
+		}
+		if !id.admin {
+			return identity{}, tenant.ErrMissingToken
+		}
+	}
+
+	id.tenant = strings.TrimSpace(r.Header.Get(cfg.TenantHeader))
+	if id.tenant == "" {
+		id.tenant = cfg.DefaultTenant
+	}
+	return id, nil
+}
+
+// tenantMiddleware exige um tenant em todas as rotas de empresas e o propaga pelo contexto
+func tenantMiddleware(cfg *config.Config, logger *zap.Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, err := resolveIdentity(r, cfg)
+			if err != nil {
+				logger.Warn("Invalid credentials", zap.Error(err))
+				http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusUnauthorized)
+				return
+			}
+			if id.tenant == "" {
+				http.Error(w, `{"error": "`+tenant.ErrMissingTenant.Error()+`"}`, http.StatusBadRequest)
+				return
+			}
+
+			ctx := tenant.WithTenant(r.Context(), id.tenant)
+			if id.admin {
+				ctx = tenant.WithAdmin(ctx)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// errAdminRequired indica uma rota administrativa acessada sem credenciais de administrador
+var errAdminRequired = errors.New("acesso restrito a administradores")
+
+// adminMiddleware restringe as rotas administrativas a administradores
+func adminMiddleware(cfg *config.Config, logger *zap.Logger) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, err := resolveIdentity(r, cfg)
+			if err != nil {
+				logger.Warn("Invalid credentials", zap.Error(err))
+				http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusUnauthorized)
+				return
+			}
+			if !id.admin {
+				http.Error(w, `{"error": "`+errAdminRequired.Error()+`"}`, http.StatusForbidden)
+				return
+			}
+
+			ctx := tenant.WithAdmin(r.Context())
+			if id.tenant != "" {
+				ctx = tenant.WithTenant(ctx, id.tenant)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"company-service/internal/config"
+	"company-service/internal/tenant"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func signToken(t *testing.T, secret string, payload map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	body, err := json.Marshal(payload)
+	require.NoError(t, err)
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(body)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// serveTenant executa a requisição pelo tenantMiddleware e retorna o status e o tenant propagado
+func serveTenant(cfg *config.Config, headers map[string]string) (int, string) {
+	var tenantID string
+	handler := tenantMiddleware(cfg, zap.NewNop())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenantID, _ = tenant.FromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/companies", nil)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec.Code, tenantID
+}
+
+func TestTenantMiddleware_JWTDisabled_UsesHeaderAndDefault(t *testing.T) {
+	cfg := &config.Config{TenantHeader: "X-Tenant-ID"}
+
+	code, tenantID := serveTenant(cfg, map[string]string{"X-Tenant-ID": "acme"})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "acme", tenantID)
+
+	code, _ = serveTenant(cfg, nil)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	cfg.DefaultTenant = "default"
+	code, tenantID = serveTenant(cfg, nil)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "default", tenantID)
+}
+
+func TestTenantMiddleware_JWTConfigured(t *testing.T) {
+	cfg := &config.Config{TenantHeader: "X-Tenant-ID", TenantClaim: "tenant_id", JWTSecret: "segredo", DefaultTenant: "default", AdminAPIKey: "chave"}
+	token := signToken(t, "segredo", map[string]interface{}{"tenant_id": "acme"})
+
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		wantCode   int
+		wantTenant string
+	}{
+		{name: "valid token", headers: map[string]string{"Authorization": "Bearer " + token}, wantCode: http.StatusOK, wantTenant: "acme"},
+		{
+			name:       "token takes precedence over header",
+			headers:    map[string]string{"Authorization": "Bearer " + token, "X-Tenant-ID": "outro"},
+			wantCode:   http.StatusOK,
+			wantTenant: "acme",
+		},
+		{name: "no token, X-Tenant-ID set", headers: map[string]string{"X-Tenant-ID": "outro"}, wantCode: http.StatusUnauthorized},
+		{name: "no token, default tenant", headers: nil, wantCode: http.StatusUnauthorized},
+		{name: "non-Bearer authorization", headers: map[string]string{"Authorization": "Basic dXNlcjpwYXNz", "X-Tenant-ID": "outro"}, wantCode: http.StatusUnauthorized},
+		{name: "invalid token", headers: map[string]string{"Authorization": "Bearer " + signToken(t, "errado", map[string]interface{}{"tenant_id": "acme"})}, wantCode: http.StatusUnauthorized},
+		{name: "admin key without token", headers: map[string]string{"X-Admin-Key": "chave", "X-Tenant-ID": "outro"}, wantCode: http.StatusOK, wantTenant: "outro"},
+		{name: "wrong admin key without token", headers: map[string]string{"X-Admin-Key": "errada", "X-Tenant-ID": "outro"}, wantCode: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, tenantID := serveTenant(cfg, tt.headers)
+
+			assert.Equal(t, tt.wantCode, code)
+			assert.Equal(t, tt.wantTenant, tenantID)
+		})
+	}
+}
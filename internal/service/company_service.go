@@ -2,35 +2,61 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"time"
+	"strings"
 
+	"company-service/internal/autocomplete"
 	"company-service/internal/domain"
 	"company-service/internal/messaging"
+	"company-service/internal/messaging/dispatch"
 	"company-service/internal/repository"
+	"company-service/internal/tenant"
+	"company-service/pkg/utils"
 
 	"go.uber.org/zap"
 )
 
 type companyService struct {
-	repo            repository.CompanyRepository
-	messageProducer messaging.MessageProducer
-	logger          *zap.Logger
-	maxRetries      int
-	retryDelay      time.Duration
+	repo         repository.CompanyRepository
+	producer     messaging.MessageProducer
+	logger       *zap.Logger
+	dispatcher   *dispatch.Dispatcher
+	autocomplete *autocomplete.Index
 }
 
-// NewCompanyService cria uma nova instância de CompanyService.
-func NewCompanyService(repo repository.CompanyRepository, messageProducer messaging.MessageProducer, logger *zap.Logger) CompanyService {
-	return &companyService{
-		repo:            repo,
-		messageProducer: messageProducer,
-		logger:          logger,
-		maxRetries:      3,               // Número máximo de tentativas
-		retryDelay:      1 * time.Second, // Delay inicial entre tentativas
+// Option configura dependências opcionais do CompanyService.
+type Option func(*companyService)
+
+// WithAutocompleteIndex mantém o índice de autocomplete sincronizado com as escritas do serviço.
+func WithAutocompleteIndex(index *autocomplete.Index) Option {
+	return func(s *companyService) {
+		s.autocomplete = index
+	}
+}
+
+// WithDispatcher publica os eventos das escritas pelo dispatcher informado, com sua fila,
+// política de overflow e novas tentativas.
+func WithDispatcher(d *dispatch.Dispatcher) Option {
+	return func(s *companyService) {
+		s.dispatcher = d
 	}
 }
 
+// NewCompanyService cria uma nova instância de CompanyService. Sem WithDispatcher, os eventos
+// são publicados de forma síncrona pelo producer, na própria escrita.
+func NewCompanyService(repo repository.CompanyRepository, messageProducer messaging.MessageProducer, logger *zap.Logger, opts ...Option) CompanyService {
+	s := &companyService{
+		repo:     repo,
+		producer: messageProducer,
+		logger:   logger,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
 // CreateCompany cria uma nova empresa.
 func (s *companyService) CreateCompany(ctx context.Context, company *domain.Company) error {
 	// Validação dos dados de entrada
@@ -38,7 +64,14 @@ func (s *companyService) CreateCompany(ctx context.Context, company *domain.Comp
 		return NewServiceError(err, "dados da empresa inválidos", "VALIDATION_ERROR")
 	}
 
-	// Verifica se o CNPJ já existe
+	// Empresa pertence ao tenant da requisição
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return err
+	}
+	company.TenantID = tenantID
+
+	// Verifica se o CNPJ já existe no tenant
 	existing, err := s.repo.GetByCNPJ(ctx, company.CNPJ)
 	if err != nil {
 		return NewServiceError(err, "erro ao verificar CNPJ", "REPOSITORY_ERROR")
@@ -52,11 +85,18 @@ func (s *companyService) CreateCompany(ctx context.Context, company *domain.Comp
 
 	// Persiste empresa no repositório
 	if err := s.repo.Create(ctx, company); err != nil {
+		if errors.Is(err, repository.ErrDuplicateCNPJ) {
+			return NewServiceError(ErrCNPJAlreadyExists, fmt.Sprintf("CNPJ %s já cadastrado", company.CNPJ), "CNPJ_CONFLICT")
+		}
 		return NewServiceError(err, "erro ao criar empresa", "REPOSITORY_ERROR")
 	}
 
-	// Envia mensagem para o RabbitMQ com retry (async - não bloqueia)
-	go s.sendCompanyCreatedMessage(context.Background(), company)
+	if s.autocomplete != nil {
+		s.autocomplete.Upsert(company)
+	}
+
+	// Agenda o envio do evento (async - não aguarda a publicação)
+	s.publish(ctx, messaging.CompanyCreated, nil, company)
 
 	s.logger.Info("Empresa criada com sucesso",
 		zap.String("company_id", company.ID),
@@ -88,7 +128,13 @@ func (s *companyService) UpdateCompany(ctx context.Context, company *domain.Comp
 		return nil, NewServiceError(err, "dados da empresa inválidos", "VALIDATION_ERROR")
 	}
 
-	// Verifica se a empresa existe
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	company.TenantID = tenantID
+
+	// Verifica se a empresa existe no tenant
 	existing, err := s.repo.GetByID(ctx, company.ID)
 	if err != nil {
 		return nil, NewServiceError(err, "erro ao buscar empresa", "REPOSITORY_ERROR")
@@ -112,13 +158,21 @@ func (s *companyService) UpdateCompany(ctx context.Context, company *domain.Comp
 	company.BeforeUpdate()
 
 	// Persiste empresa no repositório
-	updateCompany, err := s.repo.Update(ctx, company)
+	updateCompany, previous, err := s.repo.Update(ctx, company)
 	if err != nil {
+		if errors.Is(err, repository.ErrDuplicateCNPJ) {
+			return nil, NewServiceError(ErrCNPJAlreadyExists, fmt.Sprintf("CNPJ %s já cadastrado", company.CNPJ), "CNPJ_CONFLICT")
+		}
 		return nil, NewServiceError(err, "erro ao atualizar empresa", "REPOSITORY_ERROR")
 	}
 
-	// Envia mensagem para o RabbitMQ com retry (async - não bloqueia)
-	go s.sendCompanyUpdatedMessage(context.Background(), updateCompany)
+	if s.autocomplete != nil {
+		s.autocomplete.Upsert(updateCompany)
+	}
+
+	// Agenda o envio do evento (async - não aguarda a publicação). O estado anterior é o
+	// substituído pela escrita, não o lido na validação, que pode ter sido alterado depois.
+	s.publish(ctx, messaging.CompanyUpdated, previous, updateCompany)
 
 	s.logger.Info("Empresa atualizada com sucesso",
 		zap.String("company_id", updateCompany.ID))
@@ -132,21 +186,22 @@ func (s *companyService) DeleteCompany(ctx context.Context, id string) error {
 		return NewServiceError(ErrInvalidCompanyData, "ID é obrigatório", "VALIDATION_ERROR")
 	}
 
-	company, err := s.repo.GetByID(ctx, id)
+	// Remove empresa do repositório; o documento removido, lido na mesma operação, dá ao evento
+	// a sequência seguinte à última escrita
+	company, err := s.repo.Delete(ctx, id)
 	if err != nil {
-		return NewServiceError(err, "erro ao buscar empresa", "REPOSITORY_ERROR")
+		return NewServiceError(err, "erro ao deletar empresa", "REPOSITORY_ERROR")
 	}
 	if company == nil {
 		return NewServiceError(ErrCompanyNotFound, fmt.Sprintf("Empresa com ID %s não encontrada", id), "NOT_FOUND")
 	}
 
-	// Remove empresa do repositório
-	if err := s.repo.Delete(ctx, id); err != nil {
-		return NewServiceError(err, "erro ao deletar empresa", "REPOSITORY_ERROR")
+	if s.autocomplete != nil {
+		s.autocomplete.Remove(id)
 	}
 
-	// Envia mensagem para o RabbitMQ com retry (async - não bloqueia)
-	go s.sendCompanyDeletedMessage(context.Background(), company)
+	// Agenda o envio do evento (async - não aguarda a publicação)
+	s.publish(ctx, messaging.CompanyDeleted, nil, company)
 
 	s.logger.Info("Empresa removida com sucesso",
 		zap.String("company_id", company.ID))
@@ -154,8 +209,12 @@ func (s *companyService) DeleteCompany(ctx context.Context, id string) error {
 	return nil
 }
 
-// ListCompanies lista empresas com paginação.
-func (s *companyService) ListCompanies(ctx context.Context, page int, limit int) ([]*domain.Company, error) {
+// ListCompanies lista empresas com filtros, ordenação e paginação.
+func (s *companyService) ListCompanies(ctx context.Context, filter repository.CompanyFilter, page int, limit int) ([]*domain.Company, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, NewServiceError(err, err.Error(), "VALIDATION_ERROR")
+	}
+
 	if page < 1 {
 		page = 1
 	}
@@ -163,7 +222,10 @@ func (s *companyService) ListCompanies(ctx context.Context, page int, limit int)
 		limit = 20
 	}
 
-	companies, err := s.repo.List(ctx, page, limit)
+	companies, err := s.repo.List(ctx, filter, page, limit)
+	if errors.Is(err, repository.ErrEncryptedField) {
+		return nil, NewServiceError(err, err.Error(), "VALIDATION_ERROR")
+	}
 	if err != nil {
 		return nil, NewServiceError(err, "erro ao listar empresas", "REPOSITORY_ERROR")
 	}
@@ -171,47 +233,189 @@ func (s *companyService) ListCompanies(ctx context.Context, page int, limit int)
 	return companies, nil
 }
 
-// sendWithRetry implementa mecanismo de retry para envio de mensagens
-func (s *companyService) sendWithRetry(ctx context.Context, sendFunc func(ctx context.Context) error, operation string, companyID string) {
-	for attempt := 1; attempt <= s.maxRetries; attempt++ {
-		err := sendFunc(ctx)
-		if err == nil {
-			s.logger.Info("Mensagem enviada com sucesso",
-				zap.String("operation", operation),
-				zap.String("company_id", companyID))
-			return
+// SearchCompanies busca empresas por nome ou endereço, ignorando acentos e maiúsculas, ordenadas por relevância.
+func (s *companyService) SearchCompanies(ctx context.Context, query string, limit int) ([]*domain.Company, error) {
+	if utils.FoldText(query) == "" {
+		return nil, NewServiceError(ErrInvalidCompanyData, "termo de busca é obrigatório", "VALIDATION_ERROR")
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	companies, err := s.repo.Search(ctx, query, limit)
+	if err != nil {
+		return nil, NewServiceError(err, "erro ao buscar empresas", "REPOSITORY_ERROR")
+	}
+
+	return companies, nil
+}
+
+// BatchCompanies cria (ou, com upsert, cria/atualiza por CNPJ) empresas em lote. Cada item é
+// validado individualmente e o resultado é retornado na mesma posição da entrada.
+func (s *companyService) BatchCompanies(ctx context.Context, companies []*domain.Company, upsert bool) ([]repository.BulkResult, error) {
+	if len(companies) == 0 {
+		return nil, NewServiceError(ErrInvalidCompanyData, "lote vazio", "VALIDATION_ERROR")
+	}
+	if len(companies) > MaxBatchSize {
+		return nil, NewServiceError(ErrInvalidCompanyData, fmt.Sprintf("lote excede o limite de %d empresas", MaxBatchSize), "VALIDATION_ERROR")
+	}
+
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]repository.BulkResult, len(companies))
+	valid := make([]*domain.Company, 0, len(companies))
+	positions := make([]int, 0, len(companies))
+	seen := make(map[string]bool, len(companies))
+
+	for i, company := range companies {
+		company.TenantID = tenantID
+		results[i] = repository.BulkResult{Status: repository.BulkFailed, Company: company}
+
+		if err := company.Validate(); err != nil {
+			results[i].Err = NewServiceError(err, err.Error(), "VALIDATION_ERROR")
+			continue
+		}
+		if seen[company.CNPJ] {
+			results[i].Err = NewServiceError(ErrCNPJAlreadyExists, fmt.Sprintf("CNPJ %s repetido no lote", company.CNPJ), "CNPJ_CONFLICT")
+			continue
 		}
+		seen[company.CNPJ] = true
+		valid = append(valid, company)
+		positions = append(positions, i)
+	}
 
-		s.logger.Warn("Falha ao enviar mensagem, tentando novamente",
-			zap.String("operation", operation),
-			zap.String("company_id", companyID),
-			zap.Int("attempt", attempt),
-			zap.Error(err))
+	if len(valid) > 0 {
+		var stored []repository.BulkResult
+		var err error
+		if upsert {
+			stored, err = s.repo.UpsertManyByCNPJ(ctx, valid)
+		} else {
+			stored, err = s.repo.CreateMany(ctx, valid)
+		}
+		if err != nil {
+			return nil, NewServiceError(err, "erro ao persistir lote de empresas", "REPOSITORY_ERROR")
+		}
+
+		for j, result := range stored {
+			switch {
+			case result.Err == nil:
+			case errors.Is(result.Err, repository.ErrDuplicateCNPJ):
+				result.Err = NewServiceError(ErrCNPJAlreadyExists, fmt.Sprintf("CNPJ %s já cadastrado", result.Company.CNPJ), "CNPJ_CONFLICT")
+			default:
+				result.Err = NewServiceError(result.Err, "erro ao persistir empresa", "REPOSITORY_ERROR")
+			}
+			results[positions[j]] = result
+		}
+	}
 
-		if attempt < s.maxRetries {
-			time.Sleep(time.Duration(attempt) * s.retryDelay)
+	var created []*domain.Company
+	var updated []repository.BulkResult
+	for _, result := range results {
+		switch result.Status {
+		case repository.BulkCreated:
+			created = append(created, result.Company)
+		case repository.BulkUpdated:
+			updated = append(updated, result)
+		default:
+			continue
 		}
+		if s.autocomplete != nil {
+			s.autocomplete.Upsert(result.Company)
+		}
+	}
+
+	// Agenda o envio dos eventos do lote (async - não aguarda a publicação)
+	for _, company := range created {
+		s.publish(ctx, messaging.CompanyCreated, nil, company)
+	}
+	for _, result := range updated {
+		s.publish(ctx, messaging.CompanyUpdated, result.Previous, result.Company)
+	}
+
+	s.logger.Info("Lote de empresas processado",
+		zap.Int("total", len(companies)),
+		zap.Int("created", len(created)),
+		zap.Int("updated", len(updated)),
+		zap.Int("failed", len(companies)-len(created)-len(updated)))
+
+	return results, nil
+}
+
+// AutocompleteCompanies sugere empresas pelo prefixo do nome ou por parte do CNPJ, a partir do índice em memória.
+func (s *companyService) AutocompleteCompanies(ctx context.Context, prefix string, limit int) ([]autocomplete.Suggestion, error) {
+	if s.autocomplete == nil {
+		return nil, NewServiceError(ErrAutocompleteUnavailable, "autocomplete indisponível", "UNAVAILABLE")
+	}
+	tenantID, err := requireTenant(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(prefix) == "" {
+		return nil, NewServiceError(ErrInvalidCompanyData, "prefixo é obrigatório", "VALIDATION_ERROR")
+	}
+	if limit < 1 || limit > 50 {
+		limit = 10
 	}
 
-	s.logger.Error("Falha ao enviar mensagem após todas as tentativas",
-		zap.String("operation", operation),
-		zap.String("company_id", companyID))
+	return s.autocomplete.Suggest(tenantID, prefix, limit), nil
 }
 
-func (s *companyService) sendCompanyCreatedMessage(ctx context.Context, company *domain.Company) {
-	s.sendWithRetry(ctx, func(ctx context.Context) error {
-		return s.messageProducer.SendCompanyCreated(ctx, company)
-	}, "company_created", company.ID)
+// ListAllCompanies lista empresas de todos os tenants (ou de um tenant específico via filtro).
+// Restrito a administradores.
+func (s *companyService) ListAllCompanies(ctx context.Context, filter repository.CompanyFilter, page int, limit int) ([]*domain.Company, error) {
+	if !tenant.IsAdmin(ctx) {
+		return nil, NewServiceError(ErrForbidden, "acesso restrito a administradores", "FORBIDDEN")
+	}
+	if err := filter.Validate(); err != nil {
+		return nil, NewServiceError(err, err.Error(), "VALIDATION_ERROR")
+	}
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	companies, err := s.repo.List(tenant.WithAllTenants(ctx), filter, page, limit)
+	if errors.Is(err, repository.ErrEncryptedField) {
+		return nil, NewServiceError(err, err.Error(), "VALIDATION_ERROR")
+	}
+	if err != nil {
+		return nil, NewServiceError(err, "erro ao listar empresas", "REPOSITORY_ERROR")
+	}
+
+	return companies, nil
 }
 
-func (s *companyService) sendCompanyUpdatedMessage(ctx context.Context, company *domain.Company) {
-	s.sendWithRetry(ctx, func(ctx context.Context) error {
-		return s.messageProducer.SendCompanyUpdated(ctx, company)
-	}, "company_updated", company.ID)
+// requireTenant obtém o tenant da requisição, obrigatório para operações de escrita
+func requireTenant(ctx context.Context) (string, error) {
+	tenantID, ok := tenant.FromContext(ctx)
+	if !ok {
+		return "", NewServiceError(tenant.ErrMissingTenant, "tenant não informado", "TENANT_REQUIRED")
+	}
+	return tenantID, nil
 }
 
-func (s *companyService) sendCompanyDeletedMessage(ctx context.Context, company *domain.Company) {
-	s.sendWithRetry(ctx, func(ctx context.Context) error {
-		return s.messageProducer.SendCompanyDeleted(ctx, company)
-	}, "company_deleted", company.ID)
+// publish agenda o envio do evento pelo dispatcher. As novas tentativas, o spool e os
+// descartes ficam a cargo do dispatcher; aqui só é registrado o evento não agendado. Sem
+// dispatcher, o evento é enviado diretamente pelo producer e a falha apenas registrada.
+func (s *companyService) publish(ctx context.Context, eventType messaging.EventType, previous, company *domain.Company) {
+	if s.dispatcher == nil {
+		if err := messaging.Send(ctx, s.producer, eventType, previous, company); err != nil {
+			s.logger.Error("Falha ao enviar evento",
+				zap.String("operation", "company_"+eventType.Operation()),
+				zap.String("company_id", company.ID),
+				zap.Error(err))
+		}
+		return
+	}
+	if err := s.dispatcher.Dispatch(ctx, eventType, previous, company); err != nil {
+		s.logger.Error("Falha ao agendar envio do evento",
+			zap.String("operation", "company_"+eventType.Operation()),
+			zap.String("company_id", company.ID),
+			zap.Error(err))
+	}
 }
@@ -0,0 +1,345 @@
+package service
+
+import (
+	"company-service/internal/domain"
+	"company-service/internal/messaging/dispatch"
+	"company-service/internal/repository"
+	"company-service/internal/tenant"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// fakeRepository guarda as empresas em memória, indexadas por ID
+type fakeRepository struct {
+	repository.CompanyRepository
+	companies map[string]*domain.Company
+	failCNPJ  map[string]error // erros por item retornados pelas operações em lote
+	nextID    int
+
+	// beforeUpdate simula uma escrita concorrente entre a leitura do service e o Update
+	beforeUpdate func()
+}
+
+func newFakeRepository(companies ...*domain.Company) *fakeRepository {
+	repo := &fakeRepository{companies: map[string]*domain.Company{}, failCNPJ: map[string]error{}}
+	for _, company := range companies {
+		repo.companies[company.ID] = company
+	}
+	return repo
+}
+
+func (r *fakeRepository) GetByID(ctx context.Context, id string) (*domain.Company, error) {
+	return r.companies[id], nil
+}
+
+func (r *fakeRepository) Update(ctx context.Context, company *domain.Company) (*domain.Company, *domain.Company, error) {
+	if r.beforeUpdate != nil {
+		r.beforeUpdate()
+	}
+	previous := *r.companies[company.ID]
+	updated := *company
+	updated.Sequence = previous.Sequence + 1
+	r.companies[company.ID] = &updated
+	return &updated, &previous, nil
+}
+
+// Delete remove a empresa; beforeUpdate também simula uma escrita concorrente antes da remoção
+func (r *fakeRepository) Delete(ctx context.Context, id string) (*domain.Company, error) {
+	if r.beforeUpdate != nil {
+		r.beforeUpdate()
+	}
+	deleted := r.companies[id]
+	delete(r.companies, id)
+	return deleted, nil
+}
+
+func (r *fakeRepository) byCNPJ(tenantID, cnpj string) *domain.Company {
+	for _, company := range r.companies {
+		if company.TenantID == tenantID && company.CNPJ == cnpj {
+			return company
+		}
+	}
+	return nil
+}
+
+func (r *fakeRepository) create(company *domain.Company) {
+	r.nextID++
+	company.ID = fmt.Sprintf("id-%d", r.nextID)
+	company.Sequence = 1
+	stored := *company
+	r.companies[company.ID] = &stored
+}
+
+func (r *fakeRepository) CreateMany(ctx context.Context, companies []*domain.Company) ([]repository.BulkResult, error) {
+	results := make([]repository.BulkResult, len(companies))
+	for i, company := range companies {
+		results[i].Company = company
+		switch {
+		case r.failCNPJ[company.CNPJ] != nil:
+			results[i].Status, results[i].Err = repository.BulkFailed, r.failCNPJ[company.CNPJ]
+		case r.byCNPJ(company.TenantID, company.CNPJ) != nil:
+			results[i].Status, results[i].Err = repository.BulkFailed, repository.ErrDuplicateCNPJ
+		default:
+			r.create(company)
+			results[i].Status = repository.BulkCreated
+		}
+	}
+	return results, nil
+}
+
+func (r *fakeRepository) UpsertManyByCNPJ(ctx context.Context, companies []*domain.Company) ([]repository.BulkResult, error) {
+	results := make([]repository.BulkResult, len(companies))
+	for i, company := range companies {
+		results[i].Company = company
+		if err := r.failCNPJ[company.CNPJ]; err != nil {
+			results[i].Status, results[i].Err = repository.BulkFailed, err
+			continue
+		}
+		existing := r.byCNPJ(company.TenantID, company.CNPJ)
+		if existing == nil {
+			r.create(company)
+			results[i].Status = repository.BulkCreated
+			continue
+		}
+		previous := *existing
+		company.ID, company.Sequence = existing.ID, existing.Sequence+1
+		stored := *company
+		r.companies[company.ID] = &stored
+		results[i].Status, results[i].Previous = repository.BulkUpdated, &previous
+	}
+	return results, nil
+}
+
+// recordingProducer registra os eventos publicados
+type recordingProducer struct {
+	mu     sync.Mutex
+	events []recordedEvent
+}
+
+type recordedEvent struct {
+	Type     string
+	Previous *domain.Company
+	Company  *domain.Company
+}
+
+func (p *recordingProducer) SendCompanyCreated(ctx context.Context, company *domain.Company) error {
+	return p.record("created", nil, company)
+}
+
+func (p *recordingProducer) SendCompanyUpdated(ctx context.Context, previous, company *domain.Company) error {
+	return p.record("updated", previous, company)
+}
+
+func (p *recordingProducer) SendCompanyDeleted(ctx context.Context, company *domain.Company) error {
+	return p.record("deleted", nil, company)
+}
+
+func (p *recordingProducer) Close() error { return nil }
+
+func (p *recordingProducer) record(eventType string, previous, company *domain.Company) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, recordedEvent{Type: eventType, Previous: previous, Company: company})
+	return nil
+}
+
+// newTestService cria o service com um dispatcher próprio; flush aguarda a publicação dos
+// eventos agendados
+func newTestService(t *testing.T, repo repository.CompanyRepository) (CompanyService, *recordingProducer, func()) {
+	t.Helper()
+	producer := &recordingProducer{}
+	dispatcher, err := dispatch.New(producer, zap.NewNop(), dispatch.Config{Workers: 1})
+	require.NoError(t, err)
+
+	flush := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		require.NoError(t, dispatcher.Shutdown(ctx))
+	}
+	return NewCompanyService(repo, producer, zap.NewNop(), WithDispatcher(dispatcher)), producer, flush
+}
+
+var tenantCtx = tenant.WithTenant(context.Background(), "acme")
+
+func validCompany(cnpj, name string) *domain.Company {
+	return &domain.Company{
+		CNPJ:                        cnpj,
+		FantasyName:                 name,
+		CorporateName:               name + " LTDA",
+		Address:                     "Rua das Flores, 100",
+		EmployeeCount:               10,
+		RequiredMinPWDEmployeeCount: 1,
+	}
+}
+
+func serviceErrorCode(t *testing.T, err error) string {
+	t.Helper()
+	var serviceErr *ServiceError
+	require.True(t, errors.As(err, &serviceErr), "esperado ServiceError, obtido %v", err)
+	return serviceErr.Code
+}
+
+func TestBatchCompanies_Upsert_MixedResults(t *testing.T) {
+	existing := validCompany("11444777000161", "Existente")
+	existing.ID, existing.TenantID, existing.Sequence = "id-existing", "acme", 4
+	repo := newFakeRepository(existing)
+	repo.failCNPJ["47960950000121"] = errors.New("write conflict")
+	svc, producer, flush := newTestService(t, repo)
+
+	invalid := validCompany("123", "Inválida")
+	results, err := svc.BatchCompanies(tenantCtx, []*domain.Company{
+		validCompany("11222333000181", "Nova"),
+		validCompany("11.444.777/0001-61", "Atualizada"),
+		invalid,
+		validCompany("11222333000181", "Repetida"),
+		validCompany("47960950000121", "Com Falha"),
+	}, true)
+	require.NoError(t, err)
+	flush()
+
+	require.Len(t, results, 5)
+	assert.Equal(t, repository.BulkCreated, results[0].Status)
+	assert.Equal(t, "acme", results[0].Company.TenantID)
+	assert.NotEmpty(t, results[0].Company.ID)
+
+	assert.Equal(t, repository.BulkUpdated, results[1].Status)
+	assert.Equal(t, "id-existing", results[1].Company.ID)
+	assert.Equal(t, "11444777000161", results[1].Company.CNPJ, "CNPJ formatado é normalizado pela validação")
+
+	assert.Equal(t, repository.BulkFailed, results[2].Status)
+	assert.Equal(t, "VALIDATION_ERROR", serviceErrorCode(t, results[2].Err))
+	assert.Same(t, invalid, results[2].Company)
+
+	assert.Equal(t, repository.BulkFailed, results[3].Status, "CNPJ repetido no lote")
+	assert.Equal(t, "CNPJ_CONFLICT", serviceErrorCode(t, results[3].Err))
+	assert.Equal(t, "Repetida", results[3].Company.FantasyName)
+
+	assert.Equal(t, repository.BulkFailed, results[4].Status)
+	assert.Equal(t, "REPOSITORY_ERROR", serviceErrorCode(t, results[4].Err))
+
+	// Eventos apenas dos itens persistidos: criação e atualização com o estado anterior
+	require.Len(t, producer.events, 2)
+	assert.Equal(t, "created", producer.events[0].Type)
+	assert.Equal(t, "Nova", producer.events[0].Company.FantasyName)
+	assert.Equal(t, "updated", producer.events[1].Type)
+	assert.Equal(t, "Existente", producer.events[1].Previous.FantasyName)
+	assert.Equal(t, "Atualizada", producer.events[1].Company.FantasyName)
+}
+
+func TestBatchCompanies_Create_ExistingCNPJIsConflict(t *testing.T) {
+	existing := validCompany("11444777000161", "Existente")
+	existing.ID, existing.TenantID = "id-existing", "acme"
+	svc, producer, flush := newTestService(t, newFakeRepository(existing))
+
+	results, err := svc.BatchCompanies(tenantCtx, []*domain.Company{
+		validCompany("11444777000161", "Duplicada"),
+		validCompany("11222333000181", "Nova"),
+	}, false)
+	require.NoError(t, err)
+	flush()
+
+	assert.Equal(t, repository.BulkFailed, results[0].Status)
+	assert.Equal(t, "CNPJ_CONFLICT", serviceErrorCode(t, results[0].Err))
+	assert.Equal(t, repository.BulkCreated, results[1].Status)
+	require.Len(t, producer.events, 1)
+	assert.Equal(t, "Nova", producer.events[0].Company.FantasyName)
+}
+
+func TestBatchCompanies_SizeLimits(t *testing.T) {
+	svc, _, _ := newTestService(t, newFakeRepository())
+
+	_, err := svc.BatchCompanies(tenantCtx, nil, false)
+	assert.Equal(t, "VALIDATION_ERROR", serviceErrorCode(t, err))
+
+	tooMany := make([]*domain.Company, MaxBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = validCompany("11222333000181", "Empresa")
+	}
+	_, err = svc.BatchCompanies(tenantCtx, tooMany, false)
+	assert.Equal(t, "VALIDATION_ERROR", serviceErrorCode(t, err))
+
+	results, err := svc.BatchCompanies(tenantCtx, tooMany[:MaxBatchSize], false)
+	require.NoError(t, err)
+	assert.Len(t, results, MaxBatchSize)
+}
+
+func TestBatchCompanies_RequiresTenant(t *testing.T) {
+	svc, _, _ := newTestService(t, newFakeRepository())
+
+	_, err := svc.BatchCompanies(context.Background(), []*domain.Company{validCompany("11222333000181", "Nova")}, false)
+
+	assert.Equal(t, "TENANT_REQUIRED", serviceErrorCode(t, err))
+}
+
+func TestUpdateCompany_PublishesStoredPreviousDocument(t *testing.T) {
+	existing := validCompany("11444777000161", "Nome Lido")
+	existing.ID, existing.TenantID, existing.Sequence = "id-existing", "acme", 2
+	repo := newFakeRepository(existing)
+	repo.beforeUpdate = func() {
+		concurrent := *existing
+		concurrent.FantasyName, concurrent.Sequence = "Nome Concorrente", 3
+		repo.companies[existing.ID] = &concurrent
+	}
+	svc, producer, flush := newTestService(t, repo)
+
+	company := validCompany("11444777000161", "Nome Novo")
+	company.ID = existing.ID
+	updated, err := svc.UpdateCompany(tenantCtx, company)
+	require.NoError(t, err)
+	flush()
+
+	assert.Equal(t, int64(4), updated.Sequence)
+	require.Len(t, producer.events, 1)
+	event := producer.events[0]
+	assert.Equal(t, "updated", event.Type)
+	require.NotNil(t, event.Previous)
+	assert.Equal(t, "Nome Concorrente", event.Previous.FantasyName, "o evento carrega o estado substituído pela escrita")
+	assert.Equal(t, int64(3), event.Previous.Sequence)
+	assert.Equal(t, "Nome Novo", event.Company.FantasyName)
+}
+
+func TestDeleteCompany_PublishesRemovedDocument(t *testing.T) {
+	existing := validCompany("11444777000161", "Nome Lido")
+	existing.ID, existing.TenantID, existing.Sequence = "id-existing", "acme", 2
+	repo := newFakeRepository(existing)
+	repo.beforeUpdate = func() {
+		concurrent := *existing
+		concurrent.FantasyName, concurrent.Sequence = "Nome Concorrente", 3
+		repo.companies[existing.ID] = &concurrent
+	}
+	svc, producer, flush := newTestService(t, repo)
+
+	require.NoError(t, svc.DeleteCompany(tenantCtx, existing.ID))
+	flush()
+
+	require.Len(t, producer.events, 1)
+	event := producer.events[0]
+	assert.Equal(t, "deleted", event.Type)
+	assert.Equal(t, "Nome Concorrente", event.Company.FantasyName, "o evento carrega o documento removido")
+	assert.Equal(t, int64(3), event.Company.Sequence, "o evento de remoção usa a sequência seguinte a esta")
+
+	repo.beforeUpdate = nil
+	err := svc.DeleteCompany(tenantCtx, existing.ID)
+	assert.Equal(t, "NOT_FOUND", serviceErrorCode(t, err))
+}
+
+func TestBatchCompanies_WithoutDispatcherPublishesSynchronously(t *testing.T) {
+	producer := &recordingProducer{}
+	svc := NewCompanyService(newFakeRepository(), producer, zap.NewNop())
+
+	_, err := svc.BatchCompanies(tenantCtx, []*domain.Company{validCompany("11222333000181", "Nova")}, false)
+	require.NoError(t, err)
+
+	// Sem dispatcher não há fila nem workers: o evento já foi enviado ao retornar
+	require.Len(t, producer.events, 1)
+	assert.Equal(t, "created", producer.events[0].Type)
+	assert.Equal(t, "Nova", producer.events[0].Company.FantasyName)
+}
@@ -7,6 +7,9 @@ var (
 	ErrCompanyNotFound    = errors.New("empresa não encontrada")
 	ErrCNPJAlreadyExists  = errors.New("CNPJ já cadastrado")
 	ErrInvalidCompanyData = errors.New("dados da empresa inválidos")
+
+	ErrAutocompleteUnavailable = errors.New("índice de autocomplete não configurado")
+	ErrForbidden               = errors.New("operação não permitida")
 )
 
 // ServiceError representa um erro na camada de serviço e encapsula erros com contexto adicional
@@ -1,15 +1,24 @@
 package service
 
 import (
+	"company-service/internal/autocomplete"
 	"company-service/internal/domain"
+	"company-service/internal/repository"
 	"context"
 )
 
+// MaxBatchSize é a quantidade máxima de empresas aceitas em uma operação em lote
+const MaxBatchSize = 1000
+
 // CompanyService define a interface para a camada de serviço
 type CompanyService interface {
 	CreateCompany(ctx context.Context, company *domain.Company) error
 	GetCompany(ctx context.Context, id string) (*domain.Company, error)
 	UpdateCompany(ctx context.Context, company *domain.Company) (*domain.Company, error)
 	DeleteCompany(ctx context.Context, id string) error
-	ListCompanies(ctx context.Context, page, limit int) ([]*domain.Company, error)
+	ListCompanies(ctx context.Context, filter repository.CompanyFilter, page, limit int) ([]*domain.Company, error)
+	SearchCompanies(ctx context.Context, query string, limit int) ([]*domain.Company, error)
+	BatchCompanies(ctx context.Context, companies []*domain.Company, upsert bool) ([]repository.BulkResult, error)
+	AutocompleteCompanies(ctx context.Context, prefix string, limit int) ([]autocomplete.Suggestion, error)
+	ListAllCompanies(ctx context.Context, filter repository.CompanyFilter, page, limit int) ([]*domain.Company, error)
 }
@@ -0,0 +1,51 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrMissingTenant indica uma operação sem tenant definido no contexto
+var ErrMissingTenant = errors.New("tenant não informado")
+
+type contextKey int
+
+const (
+	tenantKey contextKey = iota
+	allTenantsKey
+	adminKey
+)
+
+// WithTenant retorna um contexto com o tenant da requisição
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey, tenantID)
+}
+
+// FromContext retorna o tenant do contexto, se houver
+func FromContext(ctx context.Context) (string, bool) {
+	tenantID, ok := ctx.Value(tenantKey).(string)
+	return tenantID, ok && tenantID != ""
+}
+
+// WithAllTenants libera o acesso a dados de todos os tenants. Deve ser usado apenas por
+// rotinas internas (ex.: reconstrução de índices) e operações administrativas.
+func WithAllTenants(ctx context.Context) context.Context {
+	return context.WithValue(ctx, allTenantsKey, true)
+}
+
+// IsAllTenants informa se o contexto tem acesso a todos os tenants
+func IsAllTenants(ctx context.Context) bool {
+	all, _ := ctx.Value(allTenantsKey).(bool)
+	return all
+}
+
+// WithAdmin marca o contexto como pertencente a um administrador
+func WithAdmin(ctx context.Context) context.Context {
+	return context.WithValue(ctx, adminKey, true)
+}
+
+// IsAdmin informa se a requisição foi autenticada como administrador
+func IsAdmin(ctx context.Context) bool {
+	admin, _ := ctx.Value(adminKey).(bool)
+	return admin
+}
@@ -0,0 +1,84 @@
+package tenant
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func signToken(t *testing.T, secret []byte, payload map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	body, err := json.Marshal(payload)
+	assert.NoError(t, err)
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(body)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestFromContext_WithTenant_ReturnsTenant(t *testing.T) {
+	ctx := WithTenant(context.Background(), "acme")
+
+	tenantID, ok := FromContext(ctx)
+
+	assert.True(t, ok)
+	assert.Equal(t, "acme", tenantID)
+}
+
+func TestFromContext_Empty_ReturnsFalse(t *testing.T) {
+	_, ok := FromContext(context.Background())
+	assert.False(t, ok)
+
+	_, ok = FromContext(WithTenant(context.Background(), ""))
+	assert.False(t, ok)
+}
+
+func TestIsAllTenants_And_IsAdmin(t *testing.T) {
+	assert.False(t, IsAllTenants(context.Background()))
+	assert.True(t, IsAllTenants(WithAllTenants(context.Background())))
+	assert.False(t, IsAdmin(context.Background()))
+	assert.True(t, IsAdmin(WithAdmin(context.Background())))
+}
+
+func TestParseToken_ValidToken_ReturnsClaims(t *testing.T) {
+	secret := []byte("segredo")
+	token := signToken(t, secret, map[string]interface{}{
+		"tenant_id": "acme",
+		"roles":     []string{"user", "admin"},
+		"exp":       time.Now().Add(time.Hour).Unix(),
+	})
+
+	claims, err := ParseToken(token, secret, "tenant_id")
+
+	assert.NoError(t, err)
+	assert.Equal(t, Claims{Tenant: "acme", Admin: true}, claims)
+}
+
+func TestParseToken_WrongSecret_ReturnsError(t *testing.T) {
+	token := signToken(t, []byte("segredo"), map[string]interface{}{"tenant_id": "acme"})
+
+	_, err := ParseToken(token, []byte("outro"), "tenant_id")
+
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestParseToken_Expired_ReturnsError(t *testing.T) {
+	secret := []byte("segredo")
+	token := signToken(t, secret, map[string]interface{}{"tenant_id": "acme", "exp": time.Now().Add(-time.Minute).Unix()})
+
+	_, err := ParseToken(token, secret, "tenant_id")
+
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestParseToken_Malformed_ReturnsError(t *testing.T) {
+	_, err := ParseToken("abc.def", []byte("segredo"), "tenant_id")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
@@ -0,0 +1,76 @@
+package tenant
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidToken = errors.New("token inválido")
+	// ErrMissingToken indica uma requisição sem Bearer token com JWT habilitado
+	ErrMissingToken = errors.New("token de autenticação não informado")
+)
+
+// Claims são as informações extraídas de um token JWT
+type Claims struct {
+	Tenant string
+	Admin  bool
+}
+
+// ParseToken valida um JWT assinado com HS256 e extrai o tenant da claim informada.
+// O papel de administrador vem da claim "role" ou da lista "roles" contendo "admin".
+func ParseToken(token string, secret []byte, tenantClaim string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Claims{}, ErrInvalidToken
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return Claims{}, ErrInvalidToken
+	}
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, mac.Sum(nil)) {
+		return Claims{}, ErrInvalidToken
+	}
+
+	var payload map[string]interface{}
+	if err := decodeSegment(parts[1], &payload); err != nil {
+		return Claims{}, ErrInvalidToken
+	}
+	if exp, ok := payload["exp"].(float64); ok && time.Now().Unix() >= int64(exp) {
+		return Claims{}, ErrInvalidToken
+	}
+
+	claims := Claims{}
+	claims.Tenant, _ = payload[tenantClaim].(string)
+	if role, _ := payload["role"].(string); role == "admin" {
+		claims.Admin = true
+	}
+	if roles, ok := payload["roles"].([]interface{}); ok {
+		for _, role := range roles {
+			if role == "admin" {
+				claims.Admin = true
+			}
+		}
+	}
+	return claims, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, v)
+}
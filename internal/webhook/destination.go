@@ -0,0 +1,133 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedDestination indica um destino na rede interna do serviço: loopback, redes
+// privadas (incluindo fd00:ec2::254), link-local (incluindo 169.254.169.254, os metadados das
+// nuvens) e endereços especiais
+var ErrBlockedDestination = errors.New("destino de webhook não permitido")
+
+// blockedHosts são nomes que apontam para o próprio host ou para serviços de metadados
+var blockedHosts = []string{"localhost", "metadata.google.internal", "metadata"}
+
+// blockedPrefixes completam as faixas reconhecidas por netip que não devem receber entregas
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "esta rede"
+	netip.MustParsePrefix("100.64.0.0/10"),   // CGNAT, usada também por redes internas de nuvem
+	netip.MustParsePrefix("192.0.0.0/24"),    // atribuições de protocolo do IETF
+	netip.MustParsePrefix("198.18.0.0/15"),   // testes de desempenho
+	netip.MustParsePrefix("240.0.0.0/4"),     // reservada
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64, que pode mapear endereços IPv4 internos
+	netip.MustParsePrefix("64:ff9b:1::/48"),  // NAT64 local
+	netip.MustParsePrefix("2001:db8::/32"),   // documentação
+	netip.MustParsePrefix("fec0::/10"),       // site-local (obsoleta)
+	netip.MustParsePrefix("100::/64"),        // descarte
+	netip.MustParsePrefix("2002::/16"),       // 6to4, que embute um endereço IPv4 qualquer
+	netip.MustParsePrefix("2001::/32"),       // Teredo, idem
+	netip.MustParsePrefix("::ffff:0:0:0/96"), // IPv4 traduzido (SIIT)
+}
+
+// blockedAddr informa se o endereço pertence à rede interna ou a uma faixa especial
+func blockedAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() {
+		return true
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkHost rejeita nomes locais e endereços literais bloqueados. Nomes DNS comuns só podem ser
+// verificados na conexão, sobre o endereço resolvido.
+func checkHost(host string) error {
+	name := strings.TrimSuffix(strings.ToLower(host), ".")
+	for _, blocked := range blockedHosts {
+		if name == blocked || strings.HasSuffix(name, "."+blocked) {
+			return fmt.Errorf("%w: %s", ErrBlockedDestination, host)
+		}
+	}
+	if addr, err := netip.ParseAddr(strings.Trim(name, "[]")); err == nil {
+		if blockedAddr(addr) {
+			return fmt.Errorf("%w: %s", ErrBlockedDestination, host)
+		}
+		return nil
+	}
+	// Formas alternativas de IPv4 aceitas por alguns resolvedores (ex.: 2130706433, 0x7f.1)
+	if numericHost(name) {
+		return fmt.Errorf("%w: %s", ErrBlockedDestination, host)
+	}
+	return nil
+}
+
+// numericHost identifica hosts cujos rótulos são todos números decimais ou hexadecimais com
+// prefixo 0x: não são nomes DNS e, fora da forma canônica, não são reconhecidos por netip
+func numericHost(name string) bool {
+	for _, label := range strings.Split(name, ".") {
+		digits, base := label, "0123456789"
+		if strings.HasPrefix(label, "0x") {
+			digits, base = label[2:], "0123456789abcdef"
+		}
+		if digits == "" || strings.Trim(digits, base) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// guardConnection é o Control do dialer: verifica o endereço efetivamente conectado, depois da
+// resolução DNS, de modo que um nome que passe a apontar para a rede interna (DNS rebinding)
+// também é recusado
+func guardConnection(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrBlockedDestination, address)
+	}
+	if blockedAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", ErrBlockedDestination, addrPort.Addr())
+	}
+	return nil
+}
+
+// newTransport cria o transporte das entregas. Proxies do ambiente não são usados, pois a
+// verificação do endereço seria feita sobre o proxy e não sobre o destino.
+func newTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   guardConnection,
+	}
+	return &http.Transport{
+		Proxy: nil,
+		DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return nil, err
+			}
+			if err := checkHost(host); err != nil {
+				return nil, err
+			}
+			return dialer.DialContext(ctx, network, address)
+		},
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+}
@@ -0,0 +1,71 @@
+package webhook
+
+import (
+	"company-service/internal/messaging"
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestBlockedAddr(t *testing.T) {
+	for _, addr := range []string{
+		"127.0.0.1", "::1", "10.1.2.3", "172.16.0.1", "192.168.0.10", "169.254.169.254",
+		"0.0.0.0", "100.64.0.1", "fd00:ec2::254", "fe80::1", "::ffff:127.0.0.1", "224.0.0.1",
+	} {
+		assert.True(t, blockedAddr(netip.MustParseAddr(addr)), addr)
+	}
+	for _, addr := range []string{"93.184.216.34", "2606:2800:220:1:248:1893:25c8:1946"} {
+		assert.False(t, blockedAddr(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	for _, host := range []string{
+		"localhost", "api.localhost", "LOCALHOST.", "metadata.google.internal", "127.0.0.1", "[::1]",
+		"2130706433", "0x7f.1", "127.1",
+	} {
+		assert.ErrorIs(t, checkHost(host), ErrBlockedDestination, host)
+	}
+	for _, host := range []string{"hooks.example.com", "93.184.216.34", "cafe.de"} {
+		assert.NoError(t, checkHost(host), host)
+	}
+}
+
+func TestGuardConnection_ChecksResolvedAddress(t *testing.T) {
+	// O endereço verificado é o resolvido: um nome que passe a apontar para a rede interna
+	// depois do cadastro é recusado na conexão
+	assert.ErrorIs(t, guardConnection("tcp4", "127.0.0.1:443", nil), ErrBlockedDestination)
+	assert.ErrorIs(t, guardConnection("tcp6", "[fd00:ec2::254]:80", nil), ErrBlockedDestination)
+	assert.NoError(t, guardConnection("tcp4", "93.184.216.34:443", nil))
+}
+
+func TestDispatcher_RefusesInternalDestinations(t *testing.T) {
+	target := newReceiver(t)
+	store := newMemoryStore()
+	subscription := subscribe(t, store, "acme", target.URL, messaging.CompanyCreated)
+
+	// Sem substituir o transporte, como em produção
+	dispatcher := NewDispatcher(store, zap.NewNop(), Config{Workers: 1, MaxAttempts: 1, Timeout: time.Second})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		dispatcher.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	require.True(t, dispatcher.Enqueue(messaging.NewCompanyEvent(messaging.CompanyCreated, testCompany("a", "acme"))))
+
+	deliveries := waitForDeliveries(t, store, subscription, DeliveryFailed, 1)
+	require.Len(t, deliveries[0].Attempts, 1)
+	assert.Contains(t, deliveries[0].Attempts[0].Error, ErrBlockedDestination.Error())
+	assert.Empty(t, target.received())
+}
@@ -0,0 +1,378 @@
+package webhook
+
+import (
+	"bytes"
+	"company-service/internal/messaging"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// Config define o paralelismo, as novas tentativas e a desativação automática das entregas
+type Config struct {
+	Workers          int           // entregas simultâneas
+	QueueSize        int           // eventos aguardando distribuição; excedentes são descartados
+	MaxAttempts      int           // tentativas por entrega, incluindo a primeira
+	InitialBackoff   time.Duration // espera antes da segunda tentativa, dobrada a cada falha
+	MaxBackoff       time.Duration // limite da espera entre tentativas
+	FailureThreshold int           // entregas seguidas com falha até desativar a assinatura; 0 nunca desativa
+	Timeout          time.Duration // prazo de cada requisição
+}
+
+func (c Config) withDefaults() Config {
+	if c.Workers <= 0 {
+		c.Workers = 4
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = 1000
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = 6
+	}
+	if c.InitialBackoff <= 0 {
+		c.InitialBackoff = 10 * time.Second
+	}
+	if c.MaxBackoff < c.InitialBackoff {
+		c.MaxBackoff = c.InitialBackoff
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 10 * time.Second
+	}
+	return c
+}
+
+// job é uma entrega em andamento com o corpo do evento, igual em todas as tentativas
+type job struct {
+	delivery *Delivery
+	body     []byte
+}
+
+// Dispatcher distribui os eventos de empresa para as assinaturas ativas do tenant e faz as
+// entregas com novas tentativas. Cada tentativa é registrada no log de entregas; uma
+// assinatura cujas entregas esgotam as tentativas FailureThreshold vezes seguidas é desativada.
+type Dispatcher struct {
+	store   Store
+	client  *http.Client
+	logger  *zap.Logger
+	cfg     Config
+	events  chan messaging.CompanyEvent
+	jobs    chan *job
+	retries sync.WaitGroup
+}
+
+func NewDispatcher(store Store, logger *zap.Logger, cfg Config) *Dispatcher {
+	cfg = cfg.withDefaults()
+	return &Dispatcher{
+		store: store,
+		client: &http.Client{
+			// Conexões com a rede interna são recusadas mesmo que o nome da URL passe a resolver
+			// para ela depois do cadastro
+			Transport: newTransport(),
+			// Redirecionamentos não são seguidos: a URL cadastrada é o destino da entrega
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		logger: logger,
+		cfg:    cfg,
+		events: make(chan messaging.CompanyEvent, cfg.QueueSize),
+		jobs:   make(chan *job),
+	}
+}
+
+// Enqueue agenda a distribuição do evento sem bloquear; retorna false se a fila estiver cheia
+func (d *Dispatcher) Enqueue(event messaging.CompanyEvent) bool {
+	select {
+	case d.events <- event:
+		return true
+	default:
+		d.logger.Warn("Fila de webhooks cheia, evento descartado",
+			zap.String("event_id", event.ID),
+			zap.String("event_type", string(event.Type)),
+			zap.String("company_id", event.Subject))
+		return false
+	}
+}
+
+// staleMargin é a folga, além da espera máxima e do prazo de uma tentativa, após a qual uma
+// entrega sem atualização é considerada abandonada
+const staleMargin = 5 * time.Minute
+
+// staleAfter é o tempo sem atualização após o qual uma entrega pendente ou aguardando nova
+// tentativa não pertence mais a nenhuma instância em execução
+func (d *Dispatcher) staleAfter() time.Duration {
+	return d.cfg.MaxBackoff + d.cfg.Timeout + staleMargin
+}
+
+// Run distribui os eventos e executa as entregas até o contexto ser cancelado. As novas
+// tentativas são agendadas em memória: as ainda agendadas no encerramento são abandonadas. Na
+// inicialização e depois a cada staleAfter, as entregas abandonadas (por esta ou por outra
+// instância) são concluídas como falhas, para não ficarem indefinidamente como retrying.
+func (d *Dispatcher) Run(ctx context.Context) {
+	d.failStaleDeliveries(ctx)
+	sweep := time.NewTicker(d.staleAfter())
+	defer sweep.Stop()
+
+	var workers sync.WaitGroup
+	for i := 0; i < d.cfg.Workers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case j := <-d.jobs:
+					d.attempt(ctx, j)
+				}
+			}
+		}()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			workers.Wait()
+			d.retries.Wait()
+			return
+		case event := <-d.events:
+			d.fanOut(ctx, event)
+		case <-sweep.C:
+			d.failStaleDeliveries(ctx)
+		}
+	}
+}
+
+// failStaleDeliveries conclui como falhas as entregas abandonadas
+func (d *Dispatcher) failStaleDeliveries(ctx context.Context) {
+	failed, err := d.store.FailStaleDeliveries(ctx, time.Now().Add(-d.staleAfter()).UTC(), "entrega interrompida pelo encerramento do serviço")
+	if err != nil {
+		d.logger.Error("Falha ao concluir entregas de webhook abandonadas", zap.Error(err))
+		return
+	}
+	if failed > 0 {
+		d.logger.Warn("Entregas de webhook abandonadas concluídas como falhas", zap.Int64("deliveries", failed))
+	}
+}
+
+// fanOut cria uma entrega para cada assinatura ativa do tenant que recebe o tipo do evento
+func (d *Dispatcher) fanOut(ctx context.Context, event messaging.CompanyEvent) {
+	subscriptions, err := d.store.ActiveSubscriptions(ctx, event.TenantID, event.Type)
+	if err != nil {
+		d.logger.Error("Falha ao buscar webhooks do evento",
+			zap.String("event_id", event.ID),
+			zap.String("tenant_id", event.TenantID),
+			zap.Error(err))
+		return
+	}
+	if len(subscriptions) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		d.logger.Error("Falha ao serializar evento para webhook", zap.String("event_id", event.ID), zap.Error(err))
+		return
+	}
+
+	for _, subscription := range subscriptions {
+		now := time.Now().UTC()
+		delivery := &Delivery{
+			ID:             primitive.NewObjectID().Hex(),
+			SubscriptionID: subscription.ID,
+			TenantID:       subscription.TenantID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Subject:        event.Subject,
+			Replay:         event.Replay,
+			Status:         DeliveryPending,
+			Attempts:       []Attempt{},
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		}
+		if err := d.store.SaveDelivery(ctx, delivery); err != nil {
+			d.logger.Error("Falha ao registrar entrega de webhook",
+				zap.String("subscription_id", subscription.ID),
+				zap.String("event_id", event.ID),
+				zap.Error(err))
+			continue
+		}
+
+		select {
+		case d.jobs <- &job{delivery: delivery, body: body}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// attempt faz uma tentativa de entrega com a configuração atual da assinatura e decide entre
+// concluir, agendar nova tentativa ou registrar a falha definitiva
+func (d *Dispatcher) attempt(ctx context.Context, j *job) {
+	delivery := j.delivery
+
+	subscription, err := d.store.GetSubscription(ctx, delivery.TenantID, delivery.SubscriptionID)
+	switch {
+	case errors.Is(err, ErrSubscriptionNotFound):
+		d.finish(ctx, delivery, DeliveryFailed, "webhook removido")
+		return
+	case err == nil && !subscription.Active:
+		d.finish(ctx, delivery, DeliveryFailed, "webhook desativado")
+		return
+	}
+
+	start := time.Now()
+	record := Attempt{Number: len(delivery.Attempts) + 1, At: start.UTC()}
+	if err == nil {
+		record.URL = subscription.URL
+		record.StatusCode, err = d.post(ctx, subscription, delivery, j.body)
+	}
+	record.DurationMs = float64(time.Since(start).Microseconds()) / 1000
+	if err != nil {
+		record.Error = err.Error()
+	}
+	delivery.Attempts = append(delivery.Attempts, record)
+
+	if err == nil {
+		d.finish(ctx, delivery, DeliverySucceeded, "")
+		if _, err := d.store.RecordResult(ctx, delivery.TenantID, delivery.SubscriptionID, true); err != nil {
+			d.logger.Error("Falha ao registrar sucesso do webhook", zap.String("subscription_id", delivery.SubscriptionID), zap.Error(err))
+		}
+		return
+	}
+
+	d.logger.Warn("Falha na entrega de webhook",
+		zap.String("delivery_id", delivery.ID),
+		zap.String("subscription_id", delivery.SubscriptionID),
+		zap.Int("attempt", record.Number),
+		zap.Error(err))
+
+	if record.Number < d.cfg.MaxAttempts && ctx.Err() == nil {
+		d.retry(ctx, j, d.backoff(record.Number))
+		return
+	}
+
+	d.finish(ctx, delivery, DeliveryFailed, "")
+	d.recordFailure(ctx, delivery)
+}
+
+// post envia o evento assinado; respostas fora da faixa 2xx são falhas
+func (d *Dispatcher) post(ctx context.Context, subscription *Subscription, delivery *Delivery, body []byte) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", messaging.ContentType)
+	req.Header.Set("User-Agent", "company-service-webhooks/1.0")
+	req.Header.Set(HeaderDeliveryID, delivery.ID)
+	req.Header.Set(HeaderEventType, string(delivery.EventType))
+	req.Header.Set(HeaderTimestamp, fmt.Sprint(timestamp))
+	req.Header.Set(HeaderSignature, Sign(subscription.Secret, timestamp, body))
+	if delivery.Replay {
+		req.Header.Set(HeaderReplay, "true")
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// retry registra a falha e reenfileira a entrega após a espera
+func (d *Dispatcher) retry(ctx context.Context, j *job, wait time.Duration) {
+	next := time.Now().Add(wait).UTC()
+	j.delivery.Status = DeliveryRetrying
+	j.delivery.NextAttemptAt = &next
+	d.save(ctx, j.delivery)
+
+	d.retries.Add(1)
+	go func() {
+		defer d.retries.Done()
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return
+		}
+		select {
+		case d.jobs <- j:
+		case <-ctx.Done():
+		}
+	}()
+}
+
+// finish conclui a entrega com o status informado; reason registra por que não houve tentativa
+func (d *Dispatcher) finish(ctx context.Context, delivery *Delivery, status DeliveryStatus, reason string) {
+	if reason != "" {
+		delivery.Attempts = append(delivery.Attempts, Attempt{
+			Number: len(delivery.Attempts) + 1,
+			At:     time.Now().UTC(),
+			Error:  reason,
+		})
+	}
+	delivery.Status = status
+	delivery.NextAttemptAt = nil
+	d.save(ctx, delivery)
+}
+
+// recordFailure conta a entrega que esgotou as tentativas e desativa a assinatura ao atingir
+// o limite de falhas consecutivas
+func (d *Dispatcher) recordFailure(ctx context.Context, delivery *Delivery) {
+	failures, err := d.store.RecordResult(ctx, delivery.TenantID, delivery.SubscriptionID, false)
+	if err != nil {
+		d.logger.Error("Falha ao registrar falha do webhook", zap.String("subscription_id", delivery.SubscriptionID), zap.Error(err))
+		return
+	}
+	if d.cfg.FailureThreshold <= 0 || failures < d.cfg.FailureThreshold {
+		return
+	}
+
+	reason := fmt.Sprintf("%d entregas consecutivas sem sucesso", failures)
+	if err := d.store.Disable(ctx, delivery.TenantID, delivery.SubscriptionID, reason); err != nil {
+		d.logger.Error("Falha ao desativar webhook", zap.String("subscription_id", delivery.SubscriptionID), zap.Error(err))
+		return
+	}
+	d.logger.Warn("Webhook desativado por falhas consecutivas",
+		zap.String("subscription_id", delivery.SubscriptionID),
+		zap.String("tenant_id", delivery.TenantID),
+		zap.Int("failures", failures))
+}
+
+func (d *Dispatcher) save(ctx context.Context, delivery *Delivery) {
+	delivery.UpdatedAt = time.Now().UTC()
+	// O registro é gravado mesmo durante o desligamento, para não perder o resultado da tentativa
+	if err := d.store.SaveDelivery(context.WithoutCancel(ctx), delivery); err != nil {
+		d.logger.Error("Falha ao registrar entrega de webhook", zap.String("delivery_id", delivery.ID), zap.Error(err))
+	}
+}
+
+// backoff retorna a espera após a tentativa informada: InitialBackoff dobrado a cada falha,
+// limitado a MaxBackoff
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	wait := d.cfg.InitialBackoff
+	for i := 1; i < attempt && wait < d.cfg.MaxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > d.cfg.MaxBackoff {
+		wait = d.cfg.MaxBackoff
+	}
+	return wait
+}
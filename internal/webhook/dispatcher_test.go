@@ -0,0 +1,530 @@
+package webhook
+
+import (
+	"company-service/internal/domain"
+	"company-service/internal/messaging"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// memoryStore implementa Store em memória
+type memoryStore struct {
+	mu            sync.Mutex
+	subscriptions map[string]*Subscription
+	deliveries    map[string]*Delivery
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{subscriptions: map[string]*Subscription{}, deliveries: map[string]*Delivery{}}
+}
+
+func (s *memoryStore) CreateSubscription(ctx context.Context, subscription *Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := *subscription
+	s.subscriptions[subscription.ID] = &copied
+	return nil
+}
+
+func (s *memoryStore) GetSubscription(ctx context.Context, tenantID, id string) (*Subscription, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	subscription, ok := s.subscriptions[id]
+	if !ok || subscription.TenantID != tenantID {
+		return nil, ErrSubscriptionNotFound
+	}
+	copied := *subscription
+	return &copied, nil
+}
+
+func (s *memoryStore) ListSubscriptions(ctx context.Context, tenantID string) ([]*Subscription, error) {
+	return s.find(func(sub *Subscription) bool { return sub.TenantID == tenantID }), nil
+}
+
+func (s *memoryStore) ActiveSubscriptions(ctx context.Context, tenantID string, eventType messaging.EventType) ([]*Subscription, error) {
+	return s.find(func(sub *Subscription) bool {
+		return sub.TenantID == tenantID && sub.Active && sub.Matches(eventType)
+	}), nil
+}
+
+func (s *memoryStore) find(match func(*Subscription) bool) []*Subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var found []*Subscription
+	for _, subscription := range s.subscriptions {
+		if match(subscription) {
+			copied := *subscription
+			found = append(found, &copied)
+		}
+	}
+	return found
+}
+
+func (s *memoryStore) UpdateSubscription(ctx context.Context, subscription *Subscription) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.subscriptions[subscription.ID]; !ok {
+		return ErrSubscriptionNotFound
+	}
+	copied := *subscription
+	s.subscriptions[subscription.ID] = &copied
+	return nil
+}
+
+func (s *memoryStore) DeleteSubscription(ctx context.Context, tenantID, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if subscription, ok := s.subscriptions[id]; !ok || subscription.TenantID != tenantID {
+		return ErrSubscriptionNotFound
+	}
+	delete(s.subscriptions, id)
+	return nil
+}
+
+func (s *memoryStore) RecordResult(ctx context.Context, tenantID, id string, success bool) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	subscription, ok := s.subscriptions[id]
+	if !ok {
+		return 0, ErrSubscriptionNotFound
+	}
+	if success {
+		subscription.ConsecutiveFailures = 0
+	} else {
+		subscription.ConsecutiveFailures++
+	}
+	return subscription.ConsecutiveFailures, nil
+}
+
+func (s *memoryStore) Disable(ctx context.Context, tenantID, id, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	subscription, ok := s.subscriptions[id]
+	if !ok {
+		return ErrSubscriptionNotFound
+	}
+	now := time.Now()
+	subscription.Active = false
+	subscription.DisabledReason = reason
+	subscription.DisabledAt = &now
+	return nil
+}
+
+func (s *memoryStore) SaveDelivery(ctx context.Context, delivery *Delivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	copied := *delivery
+	copied.Attempts = append([]Attempt(nil), delivery.Attempts...)
+	s.deliveries[delivery.ID] = &copied
+	return nil
+}
+
+func (s *memoryStore) FailStaleDeliveries(ctx context.Context, before time.Time, reason string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var failed int64
+	for _, delivery := range s.deliveries {
+		if (delivery.Status == DeliveryPending || delivery.Status == DeliveryRetrying) && delivery.UpdatedAt.Before(before) {
+			delivery.Attempts = append(delivery.Attempts, Attempt{Number: len(delivery.Attempts) + 1, At: time.Now().UTC(), Error: reason})
+			delivery.Status, delivery.NextAttemptAt = DeliveryFailed, nil
+			failed++
+		}
+	}
+	return failed, nil
+}
+
+func (s *memoryStore) GetDelivery(ctx context.Context, tenantID, subscriptionID, id string) (*Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delivery, ok := s.deliveries[id]
+	if !ok || delivery.TenantID != tenantID || delivery.SubscriptionID != subscriptionID {
+		return nil, ErrDeliveryNotFound
+	}
+	copied := *delivery
+	return &copied, nil
+}
+
+func (s *memoryStore) ListDeliveries(ctx context.Context, tenantID, subscriptionID string, limit int) ([]*Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var deliveries []*Delivery
+	for _, delivery := range s.deliveries {
+		if delivery.TenantID == tenantID && delivery.SubscriptionID == subscriptionID {
+			copied := *delivery
+			deliveries = append(deliveries, &copied)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool { return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt) })
+	if limit > 0 && len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
+
+// receiver é um destino de webhooks que responde com os status informados, na ordem (o último
+// se repete), e guarda as requisições recebidas
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	requests []receivedRequest
+}
+
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	t.Helper()
+	r := &receiver{statuses: statuses}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		r.requests = append(r.requests, receivedRequest{header: req.Header.Clone(), body: body})
+		status := http.StatusOK
+		if n := len(r.requests); len(r.statuses) > 0 {
+			status = r.statuses[min(n, len(r.statuses))-1]
+		}
+		r.mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) received() []receivedRequest {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]receivedRequest(nil), r.requests...)
+}
+
+func testCompany(id, tenantID string) *domain.Company {
+	return &domain.Company{ID: id, TenantID: tenantID, CNPJ: "11444777000161", FantasyName: "Empresa " + id}
+}
+
+func subscribe(t *testing.T, store Store, tenantID, url string, eventTypes ...messaging.EventType) *Subscription {
+	t.Helper()
+	subscription, err := NewSubscription(tenantID, "https://hooks.example.com", eventTypes, "")
+	require.NoError(t, err)
+	// Os receptores dos testes escutam em loopback, recusado na validação e nas entregas
+	subscription.URL = url
+	require.NoError(t, store.CreateSubscription(context.Background(), subscription))
+	return subscription
+}
+
+func startDispatcher(t *testing.T, store Store, cfg Config) *Dispatcher {
+	t.Helper()
+	dispatcher := NewDispatcher(store, zap.NewNop(), cfg)
+	dispatcher.client.Transport = http.DefaultTransport
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		dispatcher.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return dispatcher
+}
+
+func fastConfig() Config {
+	return Config{Workers: 2, MaxAttempts: 3, InitialBackoff: 5 * time.Millisecond, MaxBackoff: 20 * time.Millisecond, Timeout: time.Second}
+}
+
+func waitForDeliveries(t *testing.T, store Store, subscription *Subscription, status DeliveryStatus, count int) []*Delivery {
+	t.Helper()
+	var deliveries []*Delivery
+	require.Eventually(t, func() bool {
+		all, _ := store.ListDeliveries(context.Background(), subscription.TenantID, subscription.ID, 0)
+		deliveries = nil
+		for _, delivery := range all {
+			if delivery.Status == status {
+				deliveries = append(deliveries, delivery)
+			}
+		}
+		return len(deliveries) == count
+	}, 5*time.Second, 5*time.Millisecond)
+	return deliveries
+}
+
+func TestDispatcher_DeliversSignedEvent(t *testing.T) {
+	target := newReceiver(t)
+	store := newMemoryStore()
+	subscription := subscribe(t, store, "acme", target.URL, messaging.CompanyCreated)
+	dispatcher := startDispatcher(t, store, fastConfig())
+
+	event := messaging.NewCompanyEvent(messaging.CompanyCreated, testCompany("a", "acme"))
+	require.True(t, dispatcher.Enqueue(event))
+
+	deliveries := waitForDeliveries(t, store, subscription, DeliverySucceeded, 1)
+	delivery := deliveries[0]
+	assert.Equal(t, event.ID, delivery.EventID)
+	assert.Equal(t, "a", delivery.Subject)
+	require.Len(t, delivery.Attempts, 1)
+	assert.Equal(t, http.StatusOK, delivery.Attempts[0].StatusCode)
+	assert.Equal(t, target.URL, delivery.Attempts[0].URL)
+
+	requests := target.received()
+	require.Len(t, requests, 1)
+	header := requests[0].header
+	assert.Equal(t, messaging.ContentType, header.Get("Content-Type"))
+	assert.Equal(t, delivery.ID, header.Get(HeaderDeliveryID))
+	assert.Equal(t, string(messaging.CompanyCreated), header.Get(HeaderEventType))
+	assert.Empty(t, header.Get(HeaderReplay))
+	assert.NoError(t, Verify(subscription.Secret, header.Get(HeaderTimestamp), header.Get(HeaderSignature), requests[0].body, time.Minute))
+	assert.ErrorIs(t, Verify("outro-segredo-qualquer", header.Get(HeaderTimestamp), header.Get(HeaderSignature), requests[0].body, time.Minute), ErrInvalidSignature)
+}
+
+func TestDispatcher_FiltersByTenantAndEventType(t *testing.T) {
+	target := newReceiver(t)
+	store := newMemoryStore()
+	created := subscribe(t, store, "acme", target.URL, messaging.CompanyCreated)
+	deleted := subscribe(t, store, "acme", target.URL, messaging.CompanyDeleted)
+	otherTenant := subscribe(t, store, "globex", target.URL, messaging.CompanyCreated)
+	dispatcher := startDispatcher(t, store, fastConfig())
+
+	dispatcher.Enqueue(messaging.NewCompanyEvent(messaging.CompanyCreated, testCompany("a", "acme")))
+
+	waitForDeliveries(t, store, created, DeliverySucceeded, 1)
+	assert.Len(t, target.received(), 1)
+	for _, subscription := range []*Subscription{deleted, otherTenant} {
+		deliveries, _ := store.ListDeliveries(context.Background(), subscription.TenantID, subscription.ID, 0)
+		assert.Empty(t, deliveries)
+	}
+}
+
+func TestDispatcher_RetriesWithBackoffUntilSuccess(t *testing.T) {
+	target := newReceiver(t, http.StatusInternalServerError, http.StatusServiceUnavailable, http.StatusNoContent)
+	store := newMemoryStore()
+	subscription := subscribe(t, store, "acme", target.URL, messaging.CompanyUpdated)
+	dispatcher := startDispatcher(t, store, fastConfig())
+
+	dispatcher.Enqueue(messaging.NewCompanyUpdatedEvent(testCompany("a", "acme"), testCompany("a", "acme")))
+
+	delivery := waitForDeliveries(t, store, subscription, DeliverySucceeded, 1)[0]
+	require.Len(t, delivery.Attempts, 3)
+	assert.Equal(t, http.StatusInternalServerError, delivery.Attempts[0].StatusCode)
+	assert.Equal(t, "unexpected status 500", delivery.Attempts[0].Error)
+	assert.Equal(t, http.StatusNoContent, delivery.Attempts[2].StatusCode)
+	assert.Nil(t, delivery.NextAttemptAt)
+
+	requests := target.received()
+	require.Len(t, requests, 3)
+	assert.Equal(t, requests[0].header.Get(HeaderDeliveryID), requests[2].header.Get(HeaderDeliveryID), "o ID da entrega se mantém entre as tentativas")
+	assert.Equal(t, requests[0].body, requests[2].body)
+
+	current, err := store.GetSubscription(context.Background(), "acme", subscription.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 0, current.ConsecutiveFailures)
+	assert.True(t, current.Active)
+}
+
+func TestDispatcher_DisablesSubscriptionAfterRepeatedFailures(t *testing.T) {
+	target := newReceiver(t, http.StatusGone)
+	store := newMemoryStore()
+	subscription := subscribe(t, store, "acme", target.URL, messaging.CompanyDeleted)
+	cfg := fastConfig()
+	cfg.MaxAttempts = 2
+	cfg.FailureThreshold = 2
+	dispatcher := startDispatcher(t, store, cfg)
+
+	dispatcher.Enqueue(messaging.NewCompanyEvent(messaging.CompanyDeleted, testCompany("a", "acme")))
+	waitForDeliveries(t, store, subscription, DeliveryFailed, 1)
+
+	current, err := store.GetSubscription(context.Background(), "acme", subscription.ID)
+	require.NoError(t, err)
+	assert.True(t, current.Active, "uma entrega com falha ainda não desativa")
+	assert.Equal(t, 1, current.ConsecutiveFailures)
+
+	dispatcher.Enqueue(messaging.NewCompanyEvent(messaging.CompanyDeleted, testCompany("b", "acme")))
+	waitForDeliveries(t, store, subscription, DeliveryFailed, 2)
+
+	current, err = store.GetSubscription(context.Background(), "acme", subscription.ID)
+	require.NoError(t, err)
+	assert.False(t, current.Active)
+	assert.NotEmpty(t, current.DisabledReason)
+	assert.Len(t, target.received(), 4, "duas tentativas por entrega")
+
+	// Desativada, a assinatura não recebe novos eventos
+	dispatcher.Enqueue(messaging.NewCompanyEvent(messaging.CompanyDeleted, testCompany("c", "acme")))
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, target.received(), 4)
+}
+
+func TestDispatcher_StopsRetryingWhenSubscriptionIsDeleted(t *testing.T) {
+	var calls atomic.Int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer target.Close()
+
+	store := newMemoryStore()
+	subscription := subscribe(t, store, "acme", target.URL, messaging.CompanyCreated)
+	cfg := fastConfig()
+	cfg.MaxAttempts = 10
+	cfg.InitialBackoff = 50 * time.Millisecond
+	cfg.MaxBackoff = 50 * time.Millisecond
+	dispatcher := startDispatcher(t, store, cfg)
+
+	dispatcher.Enqueue(messaging.NewCompanyEvent(messaging.CompanyCreated, testCompany("a", "acme")))
+	waitForDeliveries(t, store, subscription, DeliveryRetrying, 1)
+	require.NoError(t, store.DeleteSubscription(context.Background(), "acme", subscription.ID))
+
+	delivery := waitForDeliveries(t, store, subscription, DeliveryFailed, 1)[0]
+	assert.Equal(t, "webhook removido", delivery.Attempts[len(delivery.Attempts)-1].Error)
+	assert.Less(t, int(calls.Load()), 10)
+}
+
+func TestDispatcher_Backoff(t *testing.T) {
+	dispatcher := NewDispatcher(newMemoryStore(), zap.NewNop(), Config{InitialBackoff: time.Second, MaxBackoff: 10 * time.Second})
+	assert.Equal(t, time.Second, dispatcher.backoff(1))
+	assert.Equal(t, 2*time.Second, dispatcher.backoff(2))
+	assert.Equal(t, 8*time.Second, dispatcher.backoff(4))
+	assert.Equal(t, 10*time.Second, dispatcher.backoff(5))
+	assert.Equal(t, 10*time.Second, dispatcher.backoff(50))
+}
+
+// brokerProducer simula o produtor do broker
+type brokerProducer struct {
+	err  error
+	sent int
+}
+
+func (p *brokerProducer) SendCompanyCreated(ctx context.Context, c *domain.Company) error {
+	return p.send()
+}
+func (p *brokerProducer) SendCompanyUpdated(ctx context.Context, previous, c *domain.Company) error {
+	return p.send()
+}
+func (p *brokerProducer) SendCompanyDeleted(ctx context.Context, c *domain.Company) error {
+	return p.send()
+}
+func (p *brokerProducer) Close() error { return nil }
+
+func (p *brokerProducer) send() error {
+	if p.err != nil {
+		return p.err
+	}
+	p.sent++
+	return nil
+}
+
+func TestProducer_EnqueuesOnlyEventsAcceptedByBroker(t *testing.T) {
+	dispatcher := NewDispatcher(newMemoryStore(), zap.NewNop(), Config{QueueSize: 10})
+	broker := &brokerProducer{err: errors.New("broker down")}
+	producer := NewProducer(broker, dispatcher)
+
+	assert.Error(t, producer.SendCompanyCreated(context.Background(), testCompany("a", "acme")))
+	assert.Len(t, dispatcher.events, 0)
+
+	broker.err = nil
+	require.NoError(t, producer.SendCompanyDeleted(context.Background(), testCompany("a", "acme")))
+	require.Len(t, dispatcher.events, 1)
+	event := <-dispatcher.events
+	assert.Equal(t, messaging.CompanyDeleted, event.Type)
+	assert.Equal(t, "acme", event.TenantID)
+}
+
+func TestProducer_MarksReplayedEvents(t *testing.T) {
+	target := newReceiver(t)
+	store := newMemoryStore()
+	subscription := subscribe(t, store, "acme", target.URL, messaging.CompanyUpdated)
+	producer := NewProducer(&brokerProducer{}, startDispatcher(t, store, fastConfig()))
+
+	require.NoError(t, producer.SendCompanyUpdated(messaging.WithReplay(context.Background()), nil, testCompany("a", "acme")))
+
+	deliveries := waitForDeliveries(t, store, subscription, DeliverySucceeded, 1)
+	assert.True(t, deliveries[0].Replay)
+
+	requests := target.received()
+	require.Len(t, requests, 1)
+	assert.Equal(t, "true", requests[0].header.Get(HeaderReplay))
+
+	var event messaging.CompanyEvent
+	require.NoError(t, json.Unmarshal(requests[0].body, &event))
+	assert.True(t, event.Replay)
+}
+
+func TestSink_FailsWhenQueueIsFull(t *testing.T) {
+	dispatcher := NewDispatcher(newMemoryStore(), zap.NewNop(), Config{QueueSize: 1})
+	sink := NewSink(dispatcher)
+
+	require.NoError(t, sink.SendCompanyCreated(context.Background(), testCompany("a", "acme")))
+	assert.ErrorIs(t, sink.SendCompanyUpdated(context.Background(), nil, testCompany("a", "acme")), ErrQueueFull)
+
+	event := <-dispatcher.events
+	assert.Equal(t, messaging.CompanyCreated, event.Type)
+}
+
+func TestSubscription_Validate(t *testing.T) {
+	for name, tc := range map[string]struct {
+		url        string
+		eventTypes []messaging.EventType
+		secret     string
+	}{
+		"url relativa":        {"/hooks", []messaging.EventType{messaging.CompanyCreated}, ""},
+		"esquema inválido":    {"ftp://example.com", []messaging.EventType{messaging.CompanyCreated}, ""},
+		"sem tipos de evento": {"https://example.com/hooks", nil, ""},
+		"tipo desconhecido":   {"https://example.com/hooks", []messaging.EventType{"br.company.merged.v1"}, ""},
+		"segredo curto":       {"https://example.com/hooks", []messaging.EventType{messaging.CompanyCreated}, "curto"},
+		"loopback":            {"http://127.0.0.1:8080/hooks", []messaging.EventType{messaging.CompanyCreated}, ""},
+		"localhost":           {"http://localhost/hooks", []messaging.EventType{messaging.CompanyCreated}, ""},
+		"rede privada":        {"https://10.0.0.5/hooks", []messaging.EventType{messaging.CompanyCreated}, ""},
+		"metadados":           {"http://169.254.169.254/latest/meta-data", []messaging.EventType{messaging.CompanyCreated}, ""},
+		"ipv6 loopback":       {"http://[::1]/hooks", []messaging.EventType{messaging.CompanyCreated}, ""},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewSubscription("acme", tc.url, tc.eventTypes, tc.secret)
+			assert.ErrorIs(t, err, ErrInvalidSubscription)
+		})
+	}
+
+	subscription, err := NewSubscription("acme", "https://example.com/hooks", []messaging.EventType{messaging.CompanyCreated}, "")
+	require.NoError(t, err)
+	assert.Len(t, subscription.Secret, 64)
+	assert.True(t, subscription.Active)
+}
+
+func TestDispatcher_FailsStaleDeliveriesAtStartup(t *testing.T) {
+	store := newMemoryStore()
+	cfg := fastConfig()
+	old := time.Now().Add(-time.Hour).UTC()
+	stale := &Delivery{ID: "stale", SubscriptionID: "s", TenantID: "acme", Status: DeliveryRetrying,
+		Attempts: []Attempt{{Number: 1, Error: "timeout"}}, NextAttemptAt: &old, UpdatedAt: old}
+	recent := &Delivery{ID: "recent", SubscriptionID: "s", TenantID: "acme", Status: DeliveryRetrying, UpdatedAt: time.Now().UTC()}
+	require.NoError(t, store.SaveDelivery(context.Background(), stale))
+	require.NoError(t, store.SaveDelivery(context.Background(), recent))
+
+	startDispatcher(t, store, cfg)
+
+	require.Eventually(t, func() bool {
+		delivery, err := store.GetDelivery(context.Background(), "acme", "s", "stale")
+		return err == nil && delivery.Status == DeliveryFailed
+	}, time.Second, 5*time.Millisecond)
+	delivery, err := store.GetDelivery(context.Background(), "acme", "s", "stale")
+	require.NoError(t, err)
+	require.Len(t, delivery.Attempts, 2)
+	assert.NotEmpty(t, delivery.Attempts[1].Error)
+	assert.Nil(t, delivery.NextAttemptAt)
+
+	delivery, err = store.GetDelivery(context.Background(), "acme", "s", "recent")
+	require.NoError(t, err)
+	assert.Equal(t, DeliveryRetrying, delivery.Status, "entregas recentes podem pertencer a outra instância")
+}
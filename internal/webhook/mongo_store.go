@@ -0,0 +1,333 @@
+package webhook
+
+import (
+	"company-service/internal/encryption"
+	"company-service/internal/messaging"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoStore struct {
+	subscriptions *mongo.Collection
+	deliveries    *mongo.Collection
+	timeout       time.Duration
+	keyring       *encryption.Keyring
+}
+
+// StoreOption configura recursos opcionais do store
+type StoreOption func(*mongoStore)
+
+// WithKeyring cifra o segredo das assinaturas com a chave ativa do keyring. Assinaturas
+// gravadas antes da criptografia continuam legíveis e são cifradas na próxima alteração.
+func WithKeyring(keyring *encryption.Keyring) StoreOption {
+	return func(s *mongoStore) {
+		s.keyring = keyring
+	}
+}
+
+// NewMongoStore persiste as assinaturas e as entregas nas coleções informadas
+func NewMongoStore(db *mongo.Database, subscriptions, deliveries string, timeout time.Duration, opts ...StoreOption) Store {
+	s := &mongoStore{
+		subscriptions: db.Collection(subscriptions),
+		deliveries:    db.Collection(deliveries),
+		timeout:       timeout,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// subscriptionDocument é a assinatura como gravada: com keyring, o segredo fica em SealedSecret
+// e o campo secret é gravado vazio
+type subscriptionDocument struct {
+	Subscription `bson:",inline"`
+	SealedSecret *sealedSecret `bson:"sealed_secret,omitempty"`
+}
+
+// sealedSecret é o segredo cifrado: o ID da chave e nonce || ciphertext
+type sealedSecret struct {
+	KeyID string `bson:"key_id"`
+	Data  []byte `bson:"data"`
+}
+
+// secretContext vincula o segredo cifrado à assinatura, impedindo que seja copiado para outra
+func secretContext(subscription *Subscription) []byte {
+	return []byte("webhook-secret:" + subscription.ID)
+}
+
+// seal monta o documento da assinatura, cifrando o segredo quando há keyring
+func (s *mongoStore) seal(subscription *Subscription) (*subscriptionDocument, error) {
+	doc := &subscriptionDocument{Subscription: *subscription}
+	if s.keyring == nil {
+		return doc, nil
+	}
+	keyID, data, err := s.keyring.Encrypt([]byte(subscription.Secret), secretContext(subscription))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt webhook secret: %w", err)
+	}
+	doc.Secret = ""
+	doc.SealedSecret = &sealedSecret{KeyID: keyID, Data: data}
+	return doc, nil
+}
+
+// open extrai a assinatura do documento, decifrando o segredo se necessário
+func (s *mongoStore) open(doc *subscriptionDocument) (*Subscription, error) {
+	subscription := doc.Subscription
+	if doc.SealedSecret == nil {
+		return &subscription, nil
+	}
+	if s.keyring == nil {
+		return nil, errors.New("webhook secret is encrypted but no key file is configured")
+	}
+	secret, err := s.keyring.Decrypt(doc.SealedSecret.KeyID, doc.SealedSecret.Data, secretContext(&subscription))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt secret of webhook %s: %w", subscription.ID, err)
+	}
+	subscription.Secret = string(secret)
+	return &subscription, nil
+}
+
+// EnsureIndexes cria os índices das consultas por tenant e o índice TTL que remove as entregas
+// mais antigas que retention (0 mantém indefinidamente). A criação é idempotente.
+func EnsureIndexes(ctx context.Context, db *mongo.Database, subscriptions, deliveries string, retention time.Duration) error {
+	_, err := db.Collection(subscriptions).Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "tenant_id", Value: 1}, {Key: "active", Value: 1}, {Key: "event_types", Value: 1}},
+		Options: options.Index().SetName("idx_tenant_active_event_types"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create webhook indexes: %w", err)
+	}
+
+	models := []mongo.IndexModel{{
+		Keys:    bson.D{{Key: "tenant_id", Value: 1}, {Key: "subscription_id", Value: 1}, {Key: "created_at", Value: -1}},
+		Options: options.Index().SetName("idx_tenant_subscription_created_at"),
+	}, {
+		Keys:    bson.D{{Key: "status", Value: 1}, {Key: "updated_at", Value: 1}},
+		Options: options.Index().SetName("idx_status_updated_at"),
+	}}
+	if retention > 0 {
+		models = append(models, mongo.IndexModel{
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetName("ttl_created_at").SetExpireAfterSeconds(int32(retention.Seconds())),
+		})
+	}
+	if _, err := db.Collection(deliveries).Indexes().CreateMany(ctx, models); err != nil {
+		return fmt.Errorf("failed to create webhook delivery indexes: %w", err)
+	}
+	return nil
+}
+
+func (s *mongoStore) CreateSubscription(ctx context.Context, subscription *Subscription) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	doc, err := s.seal(subscription)
+	if err != nil {
+		return err
+	}
+	_, err = s.subscriptions.InsertOne(ctx, doc)
+	return err
+}
+
+func (s *mongoStore) GetSubscription(ctx context.Context, tenantID, id string) (*Subscription, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	var doc subscriptionDocument
+	err := s.subscriptions.FindOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}).Decode(&doc)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.open(&doc)
+}
+
+func (s *mongoStore) ListSubscriptions(ctx context.Context, tenantID string) ([]*Subscription, error) {
+	return s.findSubscriptions(ctx, bson.M{"tenant_id": tenantID})
+}
+
+func (s *mongoStore) ActiveSubscriptions(ctx context.Context, tenantID string, eventType messaging.EventType) ([]*Subscription, error) {
+	return s.findSubscriptions(ctx, bson.M{"tenant_id": tenantID, "active": true, "event_types": eventType})
+}
+
+func (s *mongoStore) findSubscriptions(ctx context.Context, filter bson.M) ([]*Subscription, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	cursor, err := s.subscriptions.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	var docs []*subscriptionDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	subscriptions := make([]*Subscription, 0, len(docs))
+	for _, doc := range docs {
+		subscription, err := s.open(doc)
+		if err != nil {
+			return nil, err
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+	return subscriptions, nil
+}
+
+func (s *mongoStore) UpdateSubscription(ctx context.Context, subscription *Subscription) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	doc, err := s.seal(subscription)
+	if err != nil {
+		return err
+	}
+	result, err := s.subscriptions.ReplaceOne(ctx,
+		bson.M{"_id": subscription.ID, "tenant_id": subscription.TenantID}, doc)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+func (s *mongoStore) DeleteSubscription(ctx context.Context, tenantID, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	result, err := s.subscriptions.DeleteOne(ctx, bson.M{"_id": id, "tenant_id": tenantID})
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+func (s *mongoStore) RecordResult(ctx context.Context, tenantID, id string, success bool) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	update := bson.M{"$inc": bson.M{"consecutive_failures": 1}}
+	if success {
+		update = bson.M{"$set": bson.M{"consecutive_failures": 0}}
+	}
+
+	var subscription Subscription
+	err := s.subscriptions.FindOneAndUpdate(ctx, bson.M{"_id": id, "tenant_id": tenantID}, update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&subscription)
+	if err == mongo.ErrNoDocuments {
+		return 0, ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return subscription.ConsecutiveFailures, nil
+}
+
+func (s *mongoStore) Disable(ctx context.Context, tenantID, id, reason string) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	now := time.Now().UTC()
+	result, err := s.subscriptions.UpdateOne(ctx, bson.M{"_id": id, "tenant_id": tenantID}, bson.M{"$set": bson.M{
+		"active":          false,
+		"disabled_reason": reason,
+		"disabled_at":     now,
+		"updated_at":      now,
+	}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+func (s *mongoStore) SaveDelivery(ctx context.Context, delivery *Delivery) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	_, err := s.deliveries.ReplaceOne(ctx, bson.M{"_id": delivery.ID}, delivery, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (s *mongoStore) FailStaleDeliveries(ctx context.Context, before time.Time, reason string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	now := time.Now().UTC()
+	filter := bson.M{
+		"status":     bson.M{"$in": bson.A{DeliveryPending, DeliveryRetrying}},
+		"updated_at": bson.M{"$lt": before},
+	}
+	// Pipeline para registrar o motivo como uma tentativa numerada após as existentes
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"status":     DeliveryFailed,
+			"updated_at": now,
+			"attempts": bson.M{"$concatArrays": bson.A{
+				bson.M{"$ifNull": bson.A{"$attempts", bson.A{}}},
+				bson.A{bson.M{
+					"number":      bson.M{"$add": bson.A{bson.M{"$size": bson.M{"$ifNull": bson.A{"$attempts", bson.A{}}}}, 1}},
+					"at":          now,
+					"error":       reason,
+					"duration_ms": 0,
+				}},
+			}},
+		}}},
+		{{Key: "$unset", Value: "next_attempt_at"}},
+	}
+
+	result, err := s.deliveries.UpdateMany(ctx, filter, update)
+	if err != nil {
+		return 0, err
+	}
+	return result.ModifiedCount, nil
+}
+
+func (s *mongoStore) GetDelivery(ctx context.Context, tenantID, subscriptionID, id string) (*Delivery, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	var delivery Delivery
+	err := s.deliveries.FindOne(ctx, bson.M{"_id": id, "tenant_id": tenantID, "subscription_id": subscriptionID}).Decode(&delivery)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func (s *mongoStore) ListDeliveries(ctx context.Context, tenantID, subscriptionID string, limit int) ([]*Delivery, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	if limit > 0 {
+		opts.SetLimit(int64(limit))
+	}
+	cursor, err := s.deliveries.Find(ctx, bson.M{"tenant_id": tenantID, "subscription_id": subscriptionID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	deliveries := []*Delivery{}
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
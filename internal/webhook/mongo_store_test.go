@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"company-service/internal/encryption"
+	"company-service/internal/messaging"
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func testKeyring(t *testing.T) *encryption.Keyring {
+	t.Helper()
+	key := base64.StdEncoding.EncodeToString([]byte(strings.Repeat("a", 32)))
+	keyring, err := encryption.ParseKeyring([]byte(`{"active": "k1", "keys": {"k1": "` + key + `"}, "index_key": "` + key + `"}`))
+	require.NoError(t, err)
+	return keyring
+}
+
+// subscriptionReply é a resposta do find com o documento gravado da assinatura
+func subscriptionReply(t *testing.T, doc *subscriptionDocument) bson.D {
+	t.Helper()
+	raw, err := bson.Marshal(doc)
+	require.NoError(t, err)
+	var d bson.D
+	require.NoError(t, bson.Unmarshal(raw, &d))
+	return mtest.CreateCursorResponse(0, "db.webhooks", mtest.FirstBatch, d)
+}
+
+func TestMongoStore_EncryptsSecret(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+	keyring := testKeyring(t)
+
+	mt.Run("secret is sealed on insert", func(mt *mtest.T) {
+		store := NewMongoStore(mt.DB, "webhooks", "deliveries", time.Second, WithKeyring(keyring))
+		subscription, err := NewSubscription("acme", "https://hooks.example.com", []messaging.EventType{messaging.CompanyCreated}, "")
+		require.NoError(t, err)
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+		require.NoError(t, store.CreateSubscription(context.Background(), subscription))
+
+		inserted := mt.GetStartedEvent().Command.Lookup("documents").Array().Index(0).Value().Document()
+		assert.Empty(t, inserted.Lookup("secret").StringValue())
+		assert.Equal(t, "k1", inserted.Lookup("sealed_secret", "key_id").StringValue())
+		assert.NotContains(t, inserted.String(), subscription.Secret)
+	})
+
+	mt.Run("secret is opened on read", func(mt *mtest.T) {
+		store := NewMongoStore(mt.DB, "webhooks", "deliveries", time.Second, WithKeyring(keyring)).(*mongoStore)
+		subscription, err := NewSubscription("acme", "https://hooks.example.com", []messaging.EventType{messaging.CompanyCreated}, "")
+		require.NoError(t, err)
+		doc, err := store.seal(subscription)
+		require.NoError(t, err)
+		mt.AddMockResponses(subscriptionReply(t, doc))
+
+		found, err := store.GetSubscription(context.Background(), "acme", subscription.ID)
+		require.NoError(t, err)
+		assert.Equal(t, subscription.Secret, found.Secret)
+	})
+
+	mt.Run("plaintext secrets remain readable", func(mt *mtest.T) {
+		store := NewMongoStore(mt.DB, "webhooks", "deliveries", time.Second, WithKeyring(keyring))
+		subscription, err := NewSubscription("acme", "https://hooks.example.com", []messaging.EventType{messaging.CompanyCreated}, "")
+		require.NoError(t, err)
+		mt.AddMockResponses(subscriptionReply(t, &subscriptionDocument{Subscription: *subscription}))
+
+		found, err := store.GetSubscription(context.Background(), "acme", subscription.ID)
+		require.NoError(t, err)
+		assert.Equal(t, subscription.Secret, found.Secret)
+	})
+
+	mt.Run("sealed secret without keyring fails", func(mt *mtest.T) {
+		sealer := &mongoStore{keyring: keyring}
+		subscription, err := NewSubscription("acme", "https://hooks.example.com", []messaging.EventType{messaging.CompanyCreated}, "")
+		require.NoError(t, err)
+		doc, err := sealer.seal(subscription)
+		require.NoError(t, err)
+		store := NewMongoStore(mt.DB, "webhooks", "deliveries", time.Second)
+		mt.AddMockResponses(subscriptionReply(t, doc))
+
+		_, err = store.GetSubscription(context.Background(), "acme", subscription.ID)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,105 @@
+package webhook
+
+import (
+	"company-service/internal/domain"
+	"company-service/internal/messaging"
+	"context"
+	"errors"
+)
+
+// producer publica no broker e, após a confirmação, entrega o evento aos webhooks. Assim as
+// novas tentativas de publicação não geram entregas duplicadas, e os eventos reenviados a
+// partir do spool também chegam aos webhooks.
+type producer struct {
+	next       messaging.MessageProducer
+	dispatcher *Dispatcher
+}
+
+// NewProducer envolve o produtor do broker para alimentar o dispatcher de webhooks
+func NewProducer(next messaging.MessageProducer, dispatcher *Dispatcher) messaging.MessageProducer {
+	return &producer{next: next, dispatcher: dispatcher}
+}
+
+func (p *producer) SendCompanyCreated(ctx context.Context, company *domain.Company) error {
+	if err := p.next.SendCompanyCreated(ctx, company); err != nil {
+		return err
+	}
+	p.enqueue(ctx, messaging.NewCompanyEvent(messaging.CompanyCreated, company))
+	return nil
+}
+
+func (p *producer) SendCompanyUpdated(ctx context.Context, previous, company *domain.Company) error {
+	if err := p.next.SendCompanyUpdated(ctx, previous, company); err != nil {
+		return err
+	}
+	p.enqueue(ctx, messaging.NewCompanyUpdatedEvent(previous, company))
+	return nil
+}
+
+func (p *producer) SendCompanyDeleted(ctx context.Context, company *domain.Company) error {
+	if err := p.next.SendCompanyDeleted(ctx, company); err != nil {
+		return err
+	}
+	p.enqueue(ctx, messaging.NewCompanyEvent(messaging.CompanyDeleted, company))
+	return nil
+}
+
+// enqueue entrega o evento ao dispatcher, preservando a marcação de replay do contexto
+func (p *producer) enqueue(ctx context.Context, event messaging.CompanyEvent) {
+	if messaging.IsReplay(ctx) {
+		event.MarkReplay()
+	}
+	p.dispatcher.Enqueue(event)
+}
+
+// Healthy repassa o estado do broker, usado pela reentrega do spool
+func (p *producer) Healthy() bool {
+	if checker, ok := p.next.(messaging.HealthChecker); ok {
+		return checker.Healthy()
+	}
+	return true
+}
+
+func (p *producer) Close() error {
+	return p.next.Close()
+}
+
+// ErrQueueFull indica que a fila do dispatcher está cheia
+var ErrQueueFull = errors.New("webhook queue is full")
+
+// sink entrega os eventos apenas aos webhooks, sem depender do broker
+type sink struct {
+	dispatcher *Dispatcher
+}
+
+// NewSink cria um produtor que entrega os eventos ao dispatcher, para uso como destino do fan-out
+// em paralelo ao broker. Com a fila do dispatcher cheia, o envio falha com ErrQueueFull.
+func NewSink(dispatcher *Dispatcher) messaging.MessageProducer {
+	return &sink{dispatcher: dispatcher}
+}
+
+func (s *sink) SendCompanyCreated(ctx context.Context, company *domain.Company) error {
+	return s.enqueue(ctx, messaging.NewCompanyEvent(messaging.CompanyCreated, company))
+}
+
+func (s *sink) SendCompanyUpdated(ctx context.Context, previous, company *domain.Company) error {
+	return s.enqueue(ctx, messaging.NewCompanyUpdatedEvent(previous, company))
+}
+
+func (s *sink) SendCompanyDeleted(ctx context.Context, company *domain.Company) error {
+	return s.enqueue(ctx, messaging.NewCompanyEvent(messaging.CompanyDeleted, company))
+}
+
+func (s *sink) enqueue(ctx context.Context, event messaging.CompanyEvent) error {
+	if messaging.IsReplay(ctx) {
+		event.MarkReplay()
+	}
+	if !s.dispatcher.Enqueue(event) {
+		return ErrQueueFull
+	}
+	return nil
+}
+
+func (s *sink) Close() error {
+	return nil
+}
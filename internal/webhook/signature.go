@@ -0,0 +1,54 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cabeçalhos das entregas
+const (
+	HeaderDeliveryID = "X-Webhook-ID"
+	HeaderEventType  = "X-Webhook-Event"
+	HeaderTimestamp  = "X-Webhook-Timestamp"
+	HeaderSignature  = "X-Webhook-Signature"
+	HeaderReplay     = "X-Webhook-Replay" // "true" nos eventos republicados por replay
+
+	signaturePrefix = "sha256="
+)
+
+// ErrInvalidSignature indica uma assinatura ausente, malformada ou que não confere
+var ErrInvalidSignature = errors.New("assinatura do webhook inválida")
+
+// Sign calcula a assinatura enviada em X-Webhook-Signature: "sha256=" seguido do HMAC-SHA256
+// em hexadecimal de "<timestamp>.<corpo>", com o segredo da assinatura como chave
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify confere a assinatura e o timestamp recebidos. tolerance limita a diferença entre o
+// timestamp e o relógio local, para recusar entregas reaproveitadas; 0 não verifica.
+func Verify(secret, timestampHeader, signatureHeader string, body []byte, tolerance time.Duration) error {
+	timestamp, err := strconv.ParseInt(timestampHeader, 10, 64)
+	if err != nil || !strings.HasPrefix(signatureHeader, signaturePrefix) {
+		return ErrInvalidSignature
+	}
+	if tolerance > 0 {
+		age := time.Since(time.Unix(timestamp, 0))
+		if age > tolerance || age < -tolerance {
+			return ErrInvalidSignature
+		}
+	}
+	if !hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signatureHeader)) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
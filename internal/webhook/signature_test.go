@@ -0,0 +1,34 @@
+package webhook
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSign_IsHMACSHA256OfTimestampAndBody(t *testing.T) {
+	// echo -n '1700000000.{"id":"1"}' | openssl dgst -sha256 -hmac segredo-de-teste
+	assert.Equal(t,
+		"sha256=1151475d04f208f1dc8f637cf2f6d472320901028aa705820a8261c00e47d78b",
+		Sign("segredo-de-teste", 1700000000, []byte(`{"id":"1"}`)))
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+	now := time.Now().Unix()
+	timestamp := strconv.FormatInt(now, 10)
+	signature := Sign("segredo-de-teste", now, body)
+
+	assert.NoError(t, Verify("segredo-de-teste", timestamp, signature, body, time.Minute))
+	assert.ErrorIs(t, Verify("segredo-de-teste", timestamp, signature, []byte(`{"id":"2"}`), time.Minute), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("segredo-de-teste", strconv.FormatInt(now+1, 10), signature, body, time.Minute), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("segredo-de-teste", timestamp, "", body, time.Minute), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("segredo-de-teste", "agora", signature, body, time.Minute), ErrInvalidSignature)
+
+	old := now - 3600
+	oldSignature := Sign("segredo-de-teste", old, body)
+	assert.ErrorIs(t, Verify("segredo-de-teste", strconv.FormatInt(old, 10), oldSignature, body, time.Minute), ErrInvalidSignature)
+	assert.NoError(t, Verify("segredo-de-teste", strconv.FormatInt(old, 10), oldSignature, body, 0))
+}
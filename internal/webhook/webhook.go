@@ -0,0 +1,164 @@
+package webhook
+
+import (
+	"company-service/internal/messaging"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Erros das assinaturas e entregas
+var (
+	ErrSubscriptionNotFound = errors.New("webhook não encontrado")
+	ErrDeliveryNotFound     = errors.New("entrega não encontrada")
+	ErrInvalidSubscription  = errors.New("webhook inválido")
+)
+
+// Subscription é a assinatura de um parceiro: os eventos dos tipos informados, das empresas do
+// tenant, são enviados por POST para a URL e assinados com o segredo
+type Subscription struct {
+	ID                  string                `bson:"_id" json:"id"`
+	TenantID            string                `bson:"tenant_id" json:"tenant_id"`
+	URL                 string                `bson:"url" json:"url"`
+	EventTypes          []messaging.EventType `bson:"event_types" json:"event_types"`
+	Secret              string                `bson:"secret" json:"-"`
+	Active              bool                  `bson:"active" json:"active"`
+	ConsecutiveFailures int                   `bson:"consecutive_failures" json:"consecutive_failures"` // entregas seguidas que esgotaram as tentativas
+	DisabledReason      string                `bson:"disabled_reason,omitempty" json:"disabled_reason,omitempty"`
+	DisabledAt          *time.Time            `bson:"disabled_at,omitempty" json:"disabled_at,omitempty"`
+	CreatedAt           time.Time             `bson:"created_at" json:"created_at"`
+	UpdatedAt           time.Time             `bson:"updated_at" json:"updated_at"`
+}
+
+// NewSubscription cria uma assinatura ativa; sem segredo informado, gera um aleatório
+func NewSubscription(tenantID, targetURL string, eventTypes []messaging.EventType, secret string) (*Subscription, error) {
+	if secret == "" {
+		secret = NewSecret()
+	}
+	now := time.Now().UTC()
+	subscription := &Subscription{
+		ID:         primitive.NewObjectID().Hex(),
+		TenantID:   tenantID,
+		URL:        targetURL,
+		EventTypes: eventTypes,
+		Secret:     secret,
+		Active:     true,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := subscription.Validate(); err != nil {
+		return nil, err
+	}
+	return subscription, nil
+}
+
+// NewSecret gera um segredo aleatório de 32 bytes em hexadecimal
+func NewSecret() string {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("failed to generate webhook secret: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
+
+// Validate verifica a URL (http ou https absoluta, fora da rede interna), os tipos de evento e
+// o segredo. Nomes DNS são verificados novamente a cada entrega, sobre o endereço resolvido.
+func (s *Subscription) Validate() error {
+	target, err := url.Parse(s.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("%w: url deve ser uma URL http ou https absoluta", ErrInvalidSubscription)
+	}
+	if err := checkHost(target.Hostname()); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidSubscription, err)
+	}
+	if len(s.EventTypes) == 0 {
+		return fmt.Errorf("%w: informe ao menos um tipo de evento", ErrInvalidSubscription)
+	}
+	for _, eventType := range s.EventTypes {
+		if !eventType.Valid() {
+			return fmt.Errorf("%w: tipo de evento desconhecido %s", ErrInvalidSubscription, eventType)
+		}
+	}
+	if len(s.Secret) < 16 {
+		return fmt.Errorf("%w: o segredo deve ter pelo menos 16 caracteres", ErrInvalidSubscription)
+	}
+	return nil
+}
+
+// Matches informa se a assinatura recebe eventos do tipo informado
+func (s *Subscription) Matches(eventType messaging.EventType) bool {
+	for _, t := range s.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// DeliveryStatus é o estado de uma entrega
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"   // aguardando a primeira tentativa
+	DeliveryRetrying  DeliveryStatus = "retrying"  // falhou e aguarda nova tentativa
+	DeliverySucceeded DeliveryStatus = "succeeded" // o destino respondeu 2xx
+	DeliveryFailed    DeliveryStatus = "failed"    // tentativas esgotadas ou assinatura desativada
+)
+
+// Delivery é o registro do envio de um evento para uma assinatura, com todas as tentativas
+type Delivery struct {
+	ID             string              `bson:"_id" json:"id"`
+	SubscriptionID string              `bson:"subscription_id" json:"subscription_id"`
+	TenantID       string              `bson:"tenant_id" json:"tenant_id"`
+	EventID        string              `bson:"event_id" json:"event_id"`
+	EventType      messaging.EventType `bson:"event_type" json:"event_type"`
+	Subject        string              `bson:"subject" json:"subject"`                   // ID da empresa
+	Replay         bool                `bson:"replay,omitempty" json:"replay,omitempty"` // evento republicado por replay
+	Status         DeliveryStatus      `bson:"status" json:"status"`
+	Attempts       []Attempt           `bson:"attempts" json:"attempts"`
+	NextAttemptAt  *time.Time          `bson:"next_attempt_at,omitempty" json:"next_attempt_at,omitempty"`
+	CreatedAt      time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// Attempt é uma tentativa de entrega
+type Attempt struct {
+	Number     int       `bson:"number" json:"number"`
+	At         time.Time `bson:"at" json:"at"`
+	URL        string    `bson:"url" json:"url"`
+	StatusCode int       `bson:"status_code,omitempty" json:"status_code,omitempty"`
+	Error      string    `bson:"error,omitempty" json:"error,omitempty"`
+	DurationMs float64   `bson:"duration_ms" json:"duration_ms"`
+}
+
+// Store persiste as assinaturas e o registro das entregas. Todas as operações são restritas
+// ao tenant informado.
+type Store interface {
+	CreateSubscription(ctx context.Context, subscription *Subscription) error
+	GetSubscription(ctx context.Context, tenantID, id string) (*Subscription, error)
+	ListSubscriptions(ctx context.Context, tenantID string) ([]*Subscription, error)
+	UpdateSubscription(ctx context.Context, subscription *Subscription) error
+	DeleteSubscription(ctx context.Context, tenantID, id string) error
+
+	// ActiveSubscriptions retorna as assinaturas ativas do tenant que recebem o tipo de evento
+	ActiveSubscriptions(ctx context.Context, tenantID string, eventType messaging.EventType) ([]*Subscription, error)
+	// RecordResult zera o contador de falhas consecutivas após um sucesso ou o incrementa após
+	// uma entrega que esgotou as tentativas, retornando o novo valor
+	RecordResult(ctx context.Context, tenantID, id string, success bool) (int, error)
+	// Disable desativa a assinatura, registrando o motivo
+	Disable(ctx context.Context, tenantID, id, reason string) error
+
+	SaveDelivery(ctx context.Context, delivery *Delivery) error
+	// FailStaleDeliveries conclui como falhas as entregas pendentes ou aguardando nova tentativa
+	// sem atualização desde before, registrando o motivo, e retorna quantas foram alteradas
+	FailStaleDeliveries(ctx context.Context, before time.Time, reason string) (int64, error)
+	GetDelivery(ctx context.Context, tenantID, subscriptionID, id string) (*Delivery, error)
+	// ListDeliveries retorna as entregas da assinatura, das mais recentes para as mais antigas
+	ListDeliveries(ctx context.Context, tenantID, subscriptionID string, limit int) ([]*Delivery, error)
+}
@@ -0,0 +1,213 @@
+// Package eventconsumer ajuda os consumidores dos eventos de empresa a descartar duplicatas e
+// detectar eventos perdidos ou fora de ordem, a partir do ID e da sequência publicados em cada
+// CloudEvent.
+package eventconsumer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// Event são os atributos do CloudEvent usados pelo Tracker
+type Event struct {
+	ID        string `json:"id"`
+	CompanyID string `json:"subject"`
+	Sequence  int64  `json:"sequence,omitempty"`
+	Replay    bool   `json:"replay,omitempty"`
+}
+
+// Decode lê os atributos de um CloudEvent de empresa em JSON
+func Decode(body []byte) (Event, error) {
+	var event Event
+	if err := json.Unmarshal(body, &event); err != nil {
+		return Event{}, fmt.Errorf("failed to decode event: %w", err)
+	}
+	if event.ID == "" || event.CompanyID == "" {
+		return Event{}, errors.New("event without id or subject")
+	}
+	return event, nil
+}
+
+// Status é a classificação de um evento recebido
+type Status int
+
+const (
+	// InOrder é o próximo evento da empresa, ou o primeiro recebido dela
+	InOrder Status = iota
+	// Gap é um evento posterior ao esperado: os anteriores ainda não chegaram (ou foram perdidos)
+	Gap
+	// Late é um evento que faltava, recebido depois de um posterior
+	Late
+	// Duplicate é um evento já recebido, que deve ser descartado
+	Duplicate
+	// Replay é um evento republicado por replay; não altera o estado do Tracker
+	Replay
+)
+
+func (s Status) String() string {
+	switch s {
+	case InOrder:
+		return "in_order"
+	case Gap:
+		return "gap"
+	case Late:
+		return "late"
+	case Duplicate:
+		return "duplicate"
+	case Replay:
+		return "replay"
+	default:
+		return fmt.Sprintf("status(%d)", int(s))
+	}
+}
+
+// Result é o resultado de Observe. Em Gap, From e To delimitam (inclusive) as sequências que
+// faltam entre a última recebida e a do evento.
+type Result struct {
+	Status   Status
+	From, To int64
+}
+
+// Tracker acompanha, por empresa, a última sequência recebida e as que faltam, e guarda os IDs
+// dos eventos recentes para descartar duplicatas dos eventos sem sequência. É seguro para uso
+// concorrente.
+type Tracker struct {
+	mu        sync.Mutex
+	companies map[string]*companyState
+
+	// IDs recentes, em um buffer circular
+	ids    map[string]struct{}
+	recent []string
+	next   int
+
+	maxMissing int
+}
+
+type companyState struct {
+	last    int64
+	missing map[int64]struct{}
+}
+
+// NewTracker cria um Tracker que lembra os últimos recentIDs IDs de evento e até maxMissing
+// sequências faltando por empresa (as mais antigas são esquecidas e passam a ser tratadas como
+// duplicatas se chegarem)
+func NewTracker(recentIDs, maxMissing int) *Tracker {
+	if recentIDs <= 0 {
+		recentIDs = 10000
+	}
+	if maxMissing <= 0 {
+		maxMissing = 1000
+	}
+	return &Tracker{
+		companies:  make(map[string]*companyState),
+		ids:        make(map[string]struct{}, recentIDs),
+		recent:     make([]string, recentIDs),
+		maxMissing: maxMissing,
+	}
+}
+
+// Observe classifica o evento e registra o seu recebimento. Eventos sem sequência (de empresas
+// gravadas antes dela) são descartados apenas pelo ID.
+func (t *Tracker) Observe(event Event) Result {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if event.Replay {
+		return Result{Status: Replay}
+	}
+	if _, seen := t.ids[event.ID]; seen {
+		return Result{Status: Duplicate}
+	}
+	t.remember(event.ID)
+
+	if event.Sequence <= 0 {
+		return Result{Status: InOrder}
+	}
+
+	state, ok := t.companies[event.CompanyID]
+	if !ok {
+		t.companies[event.CompanyID] = &companyState{last: event.Sequence}
+		return Result{Status: InOrder}
+	}
+
+	switch {
+	case event.Sequence == state.last+1:
+		state.last = event.Sequence
+		return Result{Status: InOrder}
+	case event.Sequence > state.last+1:
+		result := Result{Status: Gap, From: state.last + 1, To: event.Sequence - 1}
+		for seq := max(result.From, event.Sequence-int64(t.maxMissing)); seq <= result.To; seq++ {
+			state.addMissing(seq, t.maxMissing)
+		}
+		state.last = event.Sequence
+		return result
+	default:
+		if _, ok := state.missing[event.Sequence]; ok {
+			delete(state.missing, event.Sequence)
+			return Result{Status: Late}
+		}
+		return Result{Status: Duplicate}
+	}
+}
+
+// Last retorna a última sequência recebida da empresa (0 se nenhuma)
+func (t *Tracker) Last(companyID string) int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if state, ok := t.companies[companyID]; ok {
+		return state.last
+	}
+	return 0
+}
+
+// Missing retorna as sequências da empresa que ainda não chegaram, em ordem crescente
+func (t *Tracker) Missing(companyID string) []int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state, ok := t.companies[companyID]
+	if !ok {
+		return nil
+	}
+	var missing []int64
+	for seq := range state.missing {
+		missing = append(missing, seq)
+	}
+	slices.Sort(missing)
+	return missing
+}
+
+// Restore define a última sequência processada da empresa, para retomar o estado persistido
+// pelo consumidor após uma reinicialização
+func (t *Tracker) Restore(companyID string, sequence int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.companies[companyID] = &companyState{last: sequence}
+}
+
+// remember registra o ID, esquecendo o mais antigo quando o buffer está cheio
+func (t *Tracker) remember(id string) {
+	if old := t.recent[t.next]; old != "" {
+		delete(t.ids, old)
+	}
+	t.recent[t.next] = id
+	t.ids[id] = struct{}{}
+	t.next = (t.next + 1) % len(t.recent)
+}
+
+// addMissing registra a sequência que falta, esquecendo a menor quando o limite é atingido
+func (s *companyState) addMissing(seq int64, limit int) {
+	if s.missing == nil {
+		s.missing = make(map[int64]struct{})
+	}
+	if len(s.missing) >= limit {
+		oldest := seq
+		for missing := range s.missing {
+			oldest = min(oldest, missing)
+		}
+		delete(s.missing, oldest)
+	}
+	s.missing[seq] = struct{}{}
+}
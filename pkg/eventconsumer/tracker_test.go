@@ -0,0 +1,84 @@
+package eventconsumer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func event(id string, sequence int64) Event {
+	return Event{ID: id, CompanyID: "acme-1", Sequence: sequence}
+}
+
+func TestTracker_InOrderAndDuplicates(t *testing.T) {
+	tracker := NewTracker(10, 10)
+
+	assert.Equal(t, InOrder, tracker.Observe(event("e1", 1)).Status)
+	assert.Equal(t, InOrder, tracker.Observe(event("e2", 2)).Status)
+
+	// Reentrega do mesmo evento (mesmo ID) e evento antigo com outro ID
+	assert.Equal(t, Duplicate, tracker.Observe(event("e2", 2)).Status)
+	assert.Equal(t, Duplicate, tracker.Observe(event("other", 1)).Status)
+	assert.Equal(t, int64(2), tracker.Last("acme-1"))
+}
+
+func TestTracker_DetectsGapsAndLateEvents(t *testing.T) {
+	tracker := NewTracker(10, 10)
+	tracker.Observe(event("e1", 1))
+
+	result := tracker.Observe(event("e5", 5))
+	assert.Equal(t, Result{Status: Gap, From: 2, To: 4}, result)
+	assert.Equal(t, []int64{2, 3, 4}, tracker.Missing("acme-1"))
+
+	assert.Equal(t, Late, tracker.Observe(event("e3", 3)).Status)
+	assert.Equal(t, Duplicate, tracker.Observe(event("e3-retry", 3)).Status)
+	assert.Equal(t, []int64{2, 4}, tracker.Missing("acme-1"))
+	assert.Equal(t, InOrder, tracker.Observe(event("e6", 6)).Status)
+}
+
+func TestTracker_LimitsMissingSequences(t *testing.T) {
+	tracker := NewTracker(10, 3)
+	tracker.Observe(event("e1", 1))
+
+	result := tracker.Observe(event("e1000", 1000))
+	assert.Equal(t, Result{Status: Gap, From: 2, To: 999}, result)
+	assert.Equal(t, []int64{997, 998, 999}, tracker.Missing("acme-1"))
+	assert.Equal(t, Duplicate, tracker.Observe(event("e2", 2)).Status, "sequências esquecidas contam como duplicatas")
+}
+
+func TestTracker_EventsWithoutSequenceUseID(t *testing.T) {
+	tracker := NewTracker(2, 10)
+
+	assert.Equal(t, InOrder, tracker.Observe(event("a", 0)).Status)
+	assert.Equal(t, Duplicate, tracker.Observe(event("a", 0)).Status)
+
+	// Apenas os IDs mais recentes são lembrados
+	tracker.Observe(event("b", 0))
+	tracker.Observe(event("c", 0))
+	assert.Equal(t, InOrder, tracker.Observe(event("a", 0)).Status)
+}
+
+func TestTracker_ReplayAndRestore(t *testing.T) {
+	tracker := NewTracker(10, 10)
+	tracker.Restore("acme-1", 7)
+
+	replayed := event("e7", 7)
+	replayed.Replay = true
+	assert.Equal(t, Replay, tracker.Observe(replayed).Status)
+	assert.Equal(t, InOrder, tracker.Observe(event("e8", 8)).Status)
+	assert.Equal(t, Duplicate, tracker.Observe(event("e7", 7)).Status)
+}
+
+func TestDecode(t *testing.T) {
+	body := []byte(`{"specversion":"1.0","id":"e1","type":"br.company.updated.v1","subject":"acme-1","sequence":3,"data":{}}`)
+
+	decoded, err := Decode(body)
+	require.NoError(t, err)
+	assert.Equal(t, Event{ID: "e1", CompanyID: "acme-1", Sequence: 3}, decoded)
+
+	_, err = Decode([]byte(`{"id":"e1"}`))
+	assert.Error(t, err)
+	_, err = Decode([]byte(`{`))
+	assert.Error(t, err)
+}
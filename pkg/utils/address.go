@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// ufs são as siglas das unidades federativas brasileiras
+var ufs = map[string]bool{
+	"AC": true, "AL": true, "AP": true, "AM": true, "BA": true, "CE": true, "DF": true,
+	"ES": true, "GO": true, "MA": true, "MT": true, "MS": true, "MG": true, "PA": true,
+	"PB": true, "PR": true, "PE": true, "PI": true, "RJ": true, "RN": true, "RS": true,
+	"RO": true, "RR": true, "SC": true, "SP": true, "SE": true, "TO": true,
+}
+
+// ExtractUF retorna a sigla da UF presente no endereço (ex.: "Av. Paulista, 1000 - São Paulo/SP"),
+// ou vazio se não houver. Considera a última sigla em maiúsculas, evitando confundir
+// palavras como "do" e "to" com siglas.
+func ExtractUF(address string) string {
+	tokens := strings.FieldsFunc(address, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	for i := len(tokens) - 1; i >= 0; i-- {
+		if ufs[tokens[i]] {
+			return tokens[i]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtractUF_CitySlashUF_ReturnsUF(t *testing.T) {
+	assert.Equal(t, "SP", ExtractUF("Av. Paulista, 1000 - São Paulo/SP"))
+}
+
+func TestExtractUF_UFBeforeZipCode_ReturnsUF(t *testing.T) {
+	assert.Equal(t, "RJ", ExtractUF("Rua do Ouvidor, 50, Centro, Rio de Janeiro, RJ, 20040-030"))
+}
+
+func TestExtractUF_LowercaseWords_AreIgnored(t *testing.T) {
+	assert.Equal(t, "", ExtractUF("Rua do Porto, 10, centro"))
+}
+
+func TestExtractUF_MultipleCandidates_ReturnsLast(t *testing.T) {
+	assert.Equal(t, "MG", ExtractUF("Rua Santa Catarina SC, 20 - Belo Horizonte - MG"))
+}
@@ -0,0 +1,23 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFoldText_RemovesDiacriticsAndCase(t *testing.T) {
+	assert.Equal(t, "sao joao comercio ltda", FoldText("São João Comércio LTDA"))
+}
+
+func TestFoldText_CollapsesPunctuationAndSpaces(t *testing.T) {
+	assert.Equal(t, "rua a 123 centro sp", FoldText("  Rua A, 123 -- Centro/SP "))
+}
+
+func TestFoldText_Cedilla_ReturnsPlainC(t *testing.T) {
+	assert.Equal(t, "acucar", FoldText("Açúcar"))
+}
+
+func TestFoldText_Empty_ReturnsEmpty(t *testing.T) {
+	assert.Equal(t, "", FoldText(""))
+}